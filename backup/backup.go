@@ -0,0 +1,514 @@
+// Package backup snapshots the data directory into timestamped, checksummed
+// archives and restores the data directory from them.
+//
+// An archive is a gzipped tar of every data file, starting with a manifest
+// that lists the size and SHA-256 of each file. Next to it a ".sha256" file
+// holds the SHA-256 of the whole archive in the format sha256sum -c reads.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	archivePrefix  = "data-"
+	archiveSuffix  = ".tar.gz"
+	checksumSuffix = ".sha256"
+	manifestName   = "MANIFEST.json"
+	timeLayout     = "20060102T150405Z"
+
+	// maxReadAttempts bounds how often a file is read again because it was
+	// replaced while being backed up.
+	maxReadAttempts = 5
+)
+
+var (
+	ErrArchiveNotFound  = errors.New("backup archive not found")
+	ErrChecksumMismatch = errors.New("backup archive checksum mismatch")
+	ErrInvalidArchive   = errors.New("invalid backup archive")
+)
+
+// Archive is one backup of the data directory.
+type Archive struct {
+	Path      string
+	CreatedAt time.Time
+}
+
+type manifest struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// dataFile is the content of one file in the data directory.
+type dataFile struct {
+	name    string
+	content []byte
+}
+
+// Archiver backs up dataDir into archives in backupDir and restores it from
+// them. Only the newest keep archives are kept; keep 0 keeps every archive.
+type Archiver struct {
+	dataDir   string
+	backupDir string
+	keep      int
+	now       func() time.Time
+}
+
+func NewArchiver(dataDir string, backupDir string, keep int, now func() time.Time) *Archiver {
+	return &Archiver{dataDir: dataDir, backupDir: backupDir, keep: keep, now: now}
+}
+
+// Backup snapshots the data directory into a new archive, then removes the
+// oldest archives beyond the retention count. The data directory is read
+// under utils.Quiesce, so writes made in this process through utils.BeginWrite
+// are either wholly in the archive or wholly after it.
+func (a *Archiver) Backup() (Archive, error) {
+	var files []dataFile
+	err := utils.Quiesce(func() error {
+		var err error
+		files, err = snapshot(a.dataDir)
+		return err
+	})
+	if err != nil {
+		return Archive{}, err
+	}
+
+	createdAt := a.now().UTC().Truncate(time.Second)
+	archive := Archive{
+		Path:      filepath.Join(a.backupDir, archivePrefix+createdAt.Format(timeLayout)+archiveSuffix),
+		CreatedAt: createdAt,
+	}
+	if err := os.MkdirAll(a.backupDir, 0755); err != nil {
+		return Archive{}, err
+	}
+	if err := writeArchive(archive, files); err != nil {
+		return Archive{}, fmt.Errorf("error while writing backup archive: %v", err)
+	}
+
+	return archive, a.prune()
+}
+
+// Start takes a backup every interval until ctx is cancelled.
+func (a *Archiver) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.Backup(); err != nil {
+				log.Printf("Error backing up data: %v", err)
+			}
+		}
+	}
+}
+
+// Archives returns the archives in the backup directory, oldest first.
+func (a *Archiver) Archives() ([]Archive, error) {
+	entries, err := os.ReadDir(a.backupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, archivePrefix) || !strings.HasSuffix(name, archiveSuffix) {
+			continue
+		}
+		createdAt, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, archivePrefix), archiveSuffix))
+		if err != nil {
+			continue
+		}
+		archives = append(archives, Archive{Path: filepath.Join(a.backupDir, name), CreatedAt: createdAt})
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].CreatedAt.Before(archives[j].CreatedAt)
+	})
+	return archives, nil
+}
+
+// ArchiveAt returns the newest archive taken at or before t.
+func (a *Archiver) ArchiveAt(t time.Time) (Archive, error) {
+	archives, err := a.Archives()
+	if err != nil {
+		return Archive{}, err
+	}
+	for i := len(archives) - 1; i >= 0; i-- {
+		if !archives[i].CreatedAt.After(t) {
+			return archives[i], nil
+		}
+	}
+	return Archive{}, fmt.Errorf("%w at or before %s", ErrArchiveNotFound, t.UTC().Format(time.RFC3339))
+}
+
+// Restore replaces the data directory with the files in the archive at path.
+// The archive is verified first, and the data directory lock is taken so the
+// restore fails while the server runs. The data being replaced is backed up
+// first; that archive is returned.
+func (a *Archiver) Restore(path string) (Archive, error) {
+	files, err := readArchive(path)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	lock, err := utils.LockDir(a.dataDir)
+	if err != nil {
+		return Archive{}, fmt.Errorf("stop the server before restoring: %w", err)
+	}
+	defer lock.Unlock()
+
+	previous, err := a.Backup()
+	if err != nil {
+		return Archive{}, fmt.Errorf("error while backing up the data being replaced: %w", err)
+	}
+
+	if err := replace(a.dataDir, files); err != nil {
+		return Archive{}, fmt.Errorf("error while restoring %s, the replaced data is in %s: %w", path, previous.Path, err)
+	}
+	return previous, nil
+}
+
+// prune removes the oldest archives beyond the retention count.
+func (a *Archiver) prune() error {
+	if a.keep <= 0 {
+		return nil
+	}
+
+	archives, err := a.Archives()
+	if err != nil {
+		return err
+	}
+	for len(archives) > a.keep {
+		if err := os.Remove(archives[0].Path); err != nil {
+			return err
+		}
+		if err := os.Remove(archives[0].Path + checksumSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		archives = archives[1:]
+	}
+	return nil
+}
+
+// backedUp tells whether the file name in the data directory is data to back
+// up and restore. The lock file and temporary files start with a dot.
+func backedUp(name string) bool {
+	return !strings.HasPrefix(name, ".")
+}
+
+// snapshot reads every data file in dir.
+func snapshot(dir string) ([]dataFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []dataFile
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case !entry.Type().IsRegular() || !backedUp(name):
+			continue
+		case strings.HasSuffix(name, "-wal") || strings.HasSuffix(name, "-shm"):
+			// Part of a SQLite database, captured through the database itself.
+			continue
+		case isJournal(dir, name):
+			// Read together with the file it belongs to.
+			continue
+		case strings.HasSuffix(name, ".db"):
+			content, err := snapshotDatabase(path)
+			if err != nil {
+				return nil, fmt.Errorf("error while backing up %s: %v", name, err)
+			}
+			files = append(files, dataFile{name: name, content: content})
+		default:
+			read, err := readWithJournal(dir, name)
+			if err != nil {
+				return nil, fmt.Errorf("error while backing up %s: %v", name, err)
+			}
+			files = append(files, read...)
+		}
+	}
+	return files, nil
+}
+
+// isJournal tells whether name is the journal of another file in dir.
+func isJournal(dir string, name string) bool {
+	snapshot := strings.TrimSuffix(name, ".journal")
+	if snapshot == name || utils.JournalPath(snapshot) != name {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, snapshot))
+	return err == nil
+}
+
+// readWithJournal reads the file name in dir and its journal, if any. A
+// compaction between the two reads would pair the old file with an emptied
+// journal, so they are read again until the file was not replaced meanwhile.
+func readWithJournal(dir string, name string) ([]dataFile, error) {
+	path := filepath.Join(dir, name)
+	journalPath := utils.JournalPath(path)
+
+	for attempt := 0; attempt < maxReadAttempts; attempt++ {
+		before, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		journal, err := os.ReadFile(journalPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		after, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !os.SameFile(before, after) {
+			continue
+		}
+
+		files := []dataFile{{name: name, content: content}}
+		if journal != nil {
+			files = append(files, dataFile{name: filepath.Base(journalPath), content: journal})
+		}
+		return files, nil
+	}
+	return nil, errors.New("file kept changing while being backed up")
+}
+
+// snapshotDatabase returns a consistent copy of the SQLite database at path,
+// made by the database itself while other processes may be writing it.
+func snapshotDatabase(path string) ([]byte, error) {
+	db, err := database.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	dir, err := os.MkdirTemp("", "backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := db.Exec("VACUUM INTO ?", target); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(target)
+}
+
+// writeArchive writes files to the archive and its checksum file next to it.
+func writeArchive(archive Archive, files []dataFile) error {
+	m := manifest{CreatedAt: archive.CreatedAt}
+	for _, file := range files {
+		sum := sha256.Sum256(file.content)
+		m.Files = append(m.Files, manifestFile{Name: file.name, Size: int64(len(file.content)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	manifestContent, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	archiveHash := sha256.New()
+	err = utils.WriteFileAtomic(archive.Path, func(w io.Writer) error {
+		gz := gzip.NewWriter(io.MultiWriter(w, archiveHash))
+		tw := tar.NewWriter(gz)
+		entries := append([]dataFile{{name: manifestName, content: manifestContent}}, files...)
+		for _, entry := range entries {
+			header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), ModTime: archive.CreatedAt}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := tw.Write(entry.content); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(archive.Path+checksumSuffix, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(archiveHash.Sum(nil)), filepath.Base(archive.Path))
+		return err
+	})
+}
+
+// Verify checks the archive at path against its checksum file and its
+// manifest, and that every JSON file in it is valid.
+func Verify(path string) error {
+	_, err := readArchive(path)
+	return err
+}
+
+// readArchive verifies the archive at path like Verify and returns its files.
+func readArchive(path string) ([]dataFile, error) {
+	if err := verifyChecksum(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	var entries []dataFile
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		entries = append(entries, dataFile{name: header.Name, content: content})
+	}
+
+	var m manifest
+	if len(entries) == 0 || entries[0].name != manifestName {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, manifestName)
+	}
+	if err := json.Unmarshal(entries[0].content, &m); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %v", ErrInvalidArchive, err)
+	}
+	files := entries[1:]
+
+	if len(files) != len(m.Files) {
+		return nil, fmt.Errorf("%w: manifest lists %d files, archive has %d", ErrInvalidArchive, len(m.Files), len(files))
+	}
+	for i, file := range files {
+		expected := m.Files[i]
+		sum := sha256.Sum256(file.content)
+		switch {
+		case file.name != expected.Name:
+			return nil, fmt.Errorf("%w: expected %s, found %s", ErrInvalidArchive, expected.Name, file.name)
+		case filepath.Base(file.name) != file.name || !backedUp(file.name):
+			return nil, fmt.Errorf("%w: unexpected file name %q", ErrInvalidArchive, file.name)
+		case int64(len(file.content)) != expected.Size || hex.EncodeToString(sum[:]) != expected.SHA256:
+			return nil, fmt.Errorf("%w: %s does not match the manifest", ErrChecksumMismatch, file.name)
+		case strings.HasSuffix(file.name, ".json") && !json.Valid(file.content):
+			return nil, fmt.Errorf("%w: %s is not valid JSON", ErrInvalidArchive, file.name)
+		}
+	}
+	return files, nil
+}
+
+// verifyChecksum compares the archive at path with its checksum file.
+func verifyChecksum(path string) error {
+	checksum, err := os.Open(path + checksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s is missing", ErrInvalidArchive, filepath.Base(path)+checksumSuffix)
+	}
+	if err != nil {
+		return err
+	}
+	defer checksum.Close()
+
+	line, err := bufio.NewReader(checksum).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	expected, _, _ := strings.Cut(line, " ")
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != expected {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, filepath.Base(path))
+	}
+	return nil
+}
+
+// replace writes files into dir. Data files the archive does not hold are
+// kept, except the journal and WAL of a restored file: written after the
+// backup, they would be replayed over it.
+func replace(dir string, files []dataFile) error {
+	restored := make(map[string]bool, len(files))
+	for _, file := range files {
+		restored[file.name] = true
+	}
+	for _, file := range files {
+		var stale []string
+		switch {
+		case strings.HasSuffix(file.name, ".db"):
+			// A stale WAL would be applied on top of the restored database.
+			stale = []string{file.name + "-wal", file.name + "-shm"}
+		case !strings.HasSuffix(file.name, ".journal") && !restored[utils.JournalPath(file.name)]:
+			// So would a journal the restored file was not backed up with.
+			stale = []string{utils.JournalPath(file.name)}
+		}
+		for _, name := range stale {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	for _, file := range files {
+		err := utils.WriteFileAtomic(filepath.Join(dir, file.name), func(w io.Writer) error {
+			_, err := w.Write(file.content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeCustomers = "[\n  {\n    \"id\": \"cust-001\",\n    \"balance\": 1000\n  }\n]\n"
+	fakeJournal   = "{\"id\":\"cust-001\",\"balance\":900}\n"
+	fakeMerchants = "[\n  {\n    \"id\": \"merchant-001\",\n    \"balance\": 500\n  }\n]\n"
+)
+
+var fakeNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+// Helper function untuk membuat folder data berisi file JSON dan journal
+func setupDataDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeFile(t, dir, "customers.json", fakeCustomers)
+	writeFile(t, dir, "customers.json.journal", fakeJournal)
+	writeFile(t, dir, "merchants.json", fakeMerchants)
+	return dir
+}
+
+// Helper function untuk membuat Archiver dengan jam palsu yang maju satu jam
+// setiap kali dipanggil
+func setupArchiver(t *testing.T, dataDir string, keep int) *Archiver {
+	now := fakeNow
+	return NewArchiver(dataDir, filepath.Join(t.TempDir(), "backups"), keep, func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	})
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func readFile(t *testing.T, dir string, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestBackup_Success(t *testing.T) {
+	dataDir := setupDataDir(t)
+	writeFile(t, dataDir, utils.LockFileName, "123\n")
+	archiver := setupArchiver(t, dataDir, 0)
+
+	archive, err := archiver.Backup()
+
+	require.NoError(t, err)
+	assert.Equal(t, fakeNow.Add(time.Hour), archive.CreatedAt)
+	assert.Equal(t, "data-20261019T130000Z.tar.gz", filepath.Base(archive.Path))
+	assert.FileExists(t, archive.Path+".sha256")
+	files, err := readArchive(archive.Path)
+	require.NoError(t, err)
+	assert.Equal(t, []dataFile{
+		{name: "customers.json", content: []byte(fakeCustomers)},
+		{name: "customers.json.journal", content: []byte(fakeJournal)},
+		{name: "merchants.json", content: []byte(fakeMerchants)},
+	}, files)
+}
+
+func TestBackup_KeepsNewestArchives(t *testing.T) {
+	archiver := setupArchiver(t, setupDataDir(t), 2)
+
+	for i := 0; i < 4; i++ {
+		_, err := archiver.Backup()
+		require.NoError(t, err)
+	}
+
+	archives, err := archiver.Archives()
+	require.NoError(t, err)
+	require.Len(t, archives, 2)
+	assert.Equal(t, fakeNow.Add(3*time.Hour), archives[0].CreatedAt)
+	assert.Equal(t, fakeNow.Add(4*time.Hour), archives[1].CreatedAt)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(archives[0].Path), "data-20261019T130000Z.tar.gz.sha256"))
+}
+
+func TestBackup_SQLiteDatabase(t *testing.T) {
+	dataDir := t.TempDir()
+	db, err := database.Open(filepath.Join(dataDir, "app.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("INSERT INTO merchants (id, name) VALUES ('merchant-001', 'Shop')")
+	require.NoError(t, err)
+	archiver := setupArchiver(t, dataDir, 0)
+
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM merchants")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = archiver.Restore(archive.Path)
+	require.NoError(t, err)
+
+	restored, err := database.Open(filepath.Join(dataDir, "app.db"))
+	require.NoError(t, err)
+	defer restored.Close()
+	var count int
+	require.NoError(t, restored.QueryRow("SELECT COUNT(*) FROM merchants").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestBackup_WaitsForWritesInFlight(t *testing.T) {
+	dataDir := setupDataDir(t)
+	archiver := setupArchiver(t, dataDir, 0)
+
+	// The write changes the snapshot and its journal; the backup must hold
+	// both or neither.
+	end := utils.BeginWrite()
+	done := make(chan Archive)
+	go func() {
+		archive, err := archiver.Backup()
+		assert.NoError(t, err)
+		done <- archive
+	}()
+	writeFile(t, dataDir, "customers.json", "[]\n")
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, dataDir, "customers.json.journal", "")
+	end()
+
+	files, err := readArchive((<-done).Path)
+	require.NoError(t, err)
+	assert.Equal(t, dataFile{name: "customers.json", content: []byte("[]\n")}, files[0])
+	assert.Equal(t, dataFile{name: "customers.json.journal", content: []byte{}}, files[1])
+}
+
+func TestArchiveAt_Success(t *testing.T) {
+	archiver := setupArchiver(t, setupDataDir(t), 0)
+	for i := 0; i < 3; i++ {
+		_, err := archiver.Backup()
+		require.NoError(t, err)
+	}
+
+	archive, err := archiver.ArchiveAt(fakeNow.Add(150 * time.Minute))
+
+	require.NoError(t, err)
+	assert.Equal(t, fakeNow.Add(2*time.Hour), archive.CreatedAt)
+}
+
+func TestRestore_Success(t *testing.T) {
+	dataDir := setupDataDir(t)
+	archiver := setupArchiver(t, dataDir, 0)
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+
+	// Changes made after the backup, including a new journal, are replaced.
+	// A file the archive does not hold is kept.
+	writeFile(t, dataDir, "customers.json", "[]\n")
+	writeFile(t, dataDir, "merchants.json.journal", "{\"id\":\"merchant-001\",\"balance\":0}\n")
+	writeFile(t, dataDir, "payments.json", "[]\n")
+
+	previous, err := archiver.Restore(archive.Path)
+
+	require.NoError(t, err)
+	assert.Equal(t, fakeCustomers, readFile(t, dataDir, "customers.json"))
+	assert.Equal(t, fakeJournal, readFile(t, dataDir, "customers.json.journal"))
+	assert.NoFileExists(t, filepath.Join(dataDir, "merchants.json.journal"))
+	assert.Equal(t, "[]\n", readFile(t, dataDir, "payments.json"))
+	files, err := readArchive(previous.Path)
+	require.NoError(t, err)
+	assert.Equal(t, dataFile{name: "customers.json", content: []byte("[]\n")}, files[0])
+}
+
+// ========== ERROR CASES ==========
+
+func TestVerify_ChecksumMismatch(t *testing.T) {
+	archiver := setupArchiver(t, setupDataDir(t), 0)
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+	content, err := os.ReadFile(archive.Path)
+	require.NoError(t, err)
+	content[len(content)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(archive.Path, content, 0644))
+
+	err = Verify(archive.Path)
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestVerify_MissingChecksumFile(t *testing.T) {
+	archiver := setupArchiver(t, setupDataDir(t), 0)
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(archive.Path+".sha256"))
+
+	err = Verify(archive.Path)
+
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+func TestBackup_InvalidJSONIsRejectedOnRestore(t *testing.T) {
+	dataDir := setupDataDir(t)
+	writeFile(t, dataDir, "merchants.json", "[{\"id\":")
+	archiver := setupArchiver(t, dataDir, 0)
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+
+	_, err = archiver.Restore(archive.Path)
+
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+	assert.EqualError(t, err, "invalid backup archive: merchants.json is not valid JSON")
+}
+
+func TestRestore_ServerRunning(t *testing.T) {
+	dataDir := setupDataDir(t)
+	archiver := setupArchiver(t, dataDir, 0)
+	archive, err := archiver.Backup()
+	require.NoError(t, err)
+	lock, err := utils.LockDir(dataDir)
+	require.NoError(t, err)
+	defer lock.Unlock()
+	writeFile(t, dataDir, "customers.json", "[]\n")
+
+	_, err = archiver.Restore(archive.Path)
+
+	require.ErrorIs(t, err, utils.ErrDirLocked)
+	assert.Equal(t, "[]\n", readFile(t, dataDir, "customers.json"))
+}
+
+func TestArchiveAt_NotFound(t *testing.T) {
+	archiver := setupArchiver(t, setupDataDir(t), 0)
+	_, err := archiver.Backup()
+	require.NoError(t, err)
+
+	_, err = archiver.ArchiveAt(fakeNow)
+
+	assert.ErrorIs(t, err, ErrArchiveNotFound)
+}
@@ -0,0 +1,91 @@
+// Command migrate saves the data files that have migrations of their own (see
+// utils/schema_migrations.go) at the version this build reads, applying those
+// migrations. Other JSON files in the data directory are left alone. The
+// server migrates files in memory as it loads them and saves them at the new
+// version on their next write; this command does it at once. Stop the server
+// first: it takes the data directory lock. With -dry-run it only reports what
+// would change.
+//
+//	go run ./cmd/migrate -dry-run
+//	go run ./cmd/migrate
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/utils"
+)
+
+func main() {
+	dataDir := flag.String("data", "./data", "data directory holding the files to migrate")
+	dryRun := flag.Bool("dry-run", false, "only report what would change")
+	flag.Parse()
+
+	keyring, err := utils.LoadKeyring(os.Getenv("DATA_ENCRYPTION_KEY_FILE"), os.Getenv("DATA_ENCRYPTION_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to load data encryption keys: %v", err)
+	}
+	utils.SetEncryption(keyring, utils.EncryptedPaths(*dataDir)...)
+
+	if !*dryRun {
+		lock, err := utils.LockDir(*dataDir)
+		if err != nil {
+			log.Fatalf("Failed to lock data directory, stop the server before migrating: %v", err)
+		}
+		defer lock.Unlock()
+	}
+
+	for _, name := range utils.MigratedFiles() {
+		path := filepath.Join(*dataDir, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("%s: not found, skipped\n", path)
+			continue
+		}
+		plan, err := utils.PlanMigration(path)
+		if err != nil {
+			log.Fatalf("Failed to plan migration of %s: %v", path, err)
+		}
+		printPlan(plan)
+
+		if *dryRun || len(plan.Migrations) == 0 {
+			continue
+		}
+		if err := utils.MigrateFile(path); err != nil {
+			log.Fatalf("Failed to migrate %s: %v", path, err)
+		}
+	}
+	if *dryRun {
+		fmt.Println("Dry run, no file was written")
+	}
+}
+
+func printPlan(plan utils.MigrationPlan) {
+	if len(plan.Migrations) == 0 {
+		fmt.Printf("%s: up to date at version %d\n", plan.Path, plan.ToVersion)
+		return
+	}
+	fmt.Printf("%s: version %d -> %d\n", plan.Path, plan.FromVersion, plan.ToVersion)
+	for _, migration := range plan.Migrations {
+		fmt.Printf("  migration %s\n", migration)
+	}
+	for _, change := range plan.Changes {
+		fmt.Printf("  %s %s: %s -> %s\n", change.Record, change.Field, formatValue(change.Before), formatValue(change.After))
+	}
+}
+
+// formatValue prints a field value as JSON, or (none) for a missing field.
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
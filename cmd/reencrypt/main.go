@@ -0,0 +1,44 @@
+// Command reencrypt rewrites the customer and merchant data files, and their
+// journals, with the current data encryption key. Run it after making a new key
+// current to rotate keys, or after turning encryption on to encrypt the data
+// right away. Stop the server first: it takes the data directory lock.
+//
+//	DATA_ENCRYPTION_KEY_FILE=/etc/simple-golang-tdd/data.keys go run ./cmd/reencrypt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"simple-golang-tdd/utils"
+)
+
+func main() {
+	dataDir := flag.String("data", "./data", "data directory holding the files to re-encrypt")
+	flag.Parse()
+
+	keyring, err := utils.LoadKeyring(os.Getenv("DATA_ENCRYPTION_KEY_FILE"), os.Getenv("DATA_ENCRYPTION_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to load data encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Fatal("Set DATA_ENCRYPTION_KEY_FILE or DATA_ENCRYPTION_KEYS to the keys to re-encrypt with")
+	}
+
+	lock, err := utils.LockDir(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to lock data directory, stop the server before re-encrypting: %v", err)
+	}
+	defer lock.Unlock()
+
+	paths := utils.EncryptedPaths(*dataDir)
+	utils.SetEncryption(keyring, paths...)
+
+	for _, path := range paths {
+		if err := utils.ReencryptFile(path); err != nil {
+			log.Fatalf("Failed to re-encrypt %s: %v", path, err)
+		}
+		fmt.Printf("Re-encrypted %s with key %s\n", path, keyring.CurrentKeyID())
+	}
+}
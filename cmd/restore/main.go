@@ -0,0 +1,77 @@
+// Command restore replaces the data directory with a backup archive taken by
+// the server. Stop the server first: the restore takes the data directory lock.
+//
+//	go run ./cmd/restore -list
+//	go run ./cmd/restore -at 2026-10-19T12:00:00Z
+//	go run ./cmd/restore -archive ./backups/data-20261019T120000Z.tar.gz
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"simple-golang-tdd/backup"
+	"time"
+)
+
+func main() {
+	defaultBackupDir := os.Getenv("BACKUP_DIR")
+	if defaultBackupDir == "" {
+		defaultBackupDir = "./backups"
+	}
+
+	dataDir := flag.String("data", "./data", "data directory to restore into")
+	backupDir := flag.String("backups", defaultBackupDir, "directory holding the backup archives")
+	archivePath := flag.String("archive", "", "archive to restore")
+	at := flag.String("at", "", "restore the newest archive taken at or before this RFC3339 time")
+	list := flag.Bool("list", false, "list the archives and exit")
+	verify := flag.Bool("verify", false, "only verify the archive, do not restore it")
+	flag.Parse()
+
+	archiver := backup.NewArchiver(*dataDir, *backupDir, 0, time.Now)
+
+	if *list {
+		archives, err := archiver.Archives()
+		if err != nil {
+			log.Fatalf("Failed to list backup archives: %v", err)
+		}
+		for _, archive := range archives {
+			fmt.Printf("%s  %s\n", archive.CreatedAt.Format(time.RFC3339), archive.Path)
+		}
+		return
+	}
+
+	path := *archivePath
+	switch {
+	case path != "" && *at != "":
+		log.Fatal("Use either -archive or -at, not both")
+	case *at != "":
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			log.Fatalf("Invalid -at time: %v", err)
+		}
+		archive, err := archiver.ArchiveAt(t)
+		if err != nil {
+			log.Fatalf("Failed to find backup archive: %v", err)
+		}
+		path = archive.Path
+	case path == "":
+		log.Fatal("Missing -archive or -at, use -list to see the archives")
+	}
+
+	if *verify {
+		if err := backup.Verify(path); err != nil {
+			log.Fatalf("Backup archive %s is invalid: %v", path, err)
+		}
+		fmt.Printf("Backup archive %s is valid\n", path)
+		return
+	}
+
+	previous, err := archiver.Restore(path)
+	if err != nil {
+		log.Fatalf("Failed to restore %s: %v", path, err)
+	}
+	fmt.Printf("Restored %s into %s\n", path, *dataDir)
+	fmt.Printf("The replaced data was backed up to %s\n", previous.Path)
+}
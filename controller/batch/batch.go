@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"simple-golang-tdd/dto"
+	batchService "simple-golang-tdd/service/batch"
+	"simple-golang-tdd/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchController handles bulk payment submissions
+type BatchController struct {
+	batchService batchService.BatchService
+}
+
+func NewBatchController(service batchService.BatchService) *BatchController {
+	return &BatchController{batchService: service}
+}
+
+// maxBatchBodyBytes caps the size of a submitted batch, well above what
+// batchService.MaxBatchLines instructions take.
+const maxBatchBodyBytes = 1 << 20
+
+// SubmitBatch godoc
+// @Summary      Submit Batch Payment
+// @Description  Accepts a JSON array of payment instructions, or a CSV with a merchant_id,amount[,currency] header when sent as text/csv. The whole batch is validated up front and then paid line by line in the background.
+// @Tags         Batch
+// @Accept       json
+// @Accept       text/csv
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  []dto.PaymentRequest  true  "Payment Instructions"
+// @Success      202  {object} dto.SuccessResponse{data=model.Batch}  "batch accepted"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      413  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/batches [post]
+func (bc *BatchController) SubmitBatch(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBodyBytes)
+	var requests []dto.PaymentRequest
+	var err error
+	if c.ContentType() == "text/csv" {
+		requests, err = parseBatchCSV(body)
+	} else {
+		err = json.NewDecoder(body).Decode(&requests)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.ErrorResponse(c, 413, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit))
+		return
+	}
+	if err != nil && c.ContentType() == "text/csv" {
+		// The CSV error tells the customer which line and column to fix.
+		utils.ErrorResponse(c, 400, "invalid request body: "+err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	batch, err := bc.batchService.SubmitBatch(requests, strUsername)
+	if errors.Is(err, batchService.ErrInvalidBatch) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 202, "batch accepted", batch)
+}
+
+// GetBatch godoc
+// @Summary      Get Batch Payment Status
+// @Description  Returns a batch of the logged in customer with the result of every line processed so far
+// @Tags         Batch
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Batch ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Batch}  "batch found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/batches/{id} [get]
+func (bc *BatchController) GetBatch(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	batch, err := bc.batchService.GetBatch(c.Param("id"), strUsername)
+	if errors.Is(err, batchService.ErrBatchNotFound) {
+		utils.ErrorResponse(c, 404, "batch not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "batch found", batch)
+}
+
+// parseBatchCSV reads payment instructions from a CSV whose header names the
+// merchant_id and amount columns, and optionally currency, in any order.
+func parseBatchCSV(r io.Reader) ([]dto.PaymentRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	merchantColumn, hasMerchant := columns["merchant_id"]
+	amountColumn, hasAmount := columns["amount"]
+	currencyColumn, hasCurrency := columns["currency"]
+	if !hasMerchant || !hasAmount {
+		return nil, errors.New("CSV header must contain merchant_id and amount")
+	}
+
+	var requests []dto.PaymentRequest
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(record[amountColumn]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", len(requests)+1, record[amountColumn])
+		}
+
+		request := dto.PaymentRequest{MerchantID: strings.TrimSpace(record[merchantColumn]), Amount: amount}
+		if hasCurrency {
+			request.Currency = strings.TrimSpace(record[currencyColumn])
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBatchService is a mock of the BatchService interface
+type MockBatchService struct {
+	mock.Mock
+}
+
+func (m *MockBatchService) SubmitBatch(requests []dto.PaymentRequest, username string) (model.Batch, error) {
+	args := m.Called(requests, username)
+	return args.Get(0).(model.Batch), args.Error(1)
+}
+
+func (m *MockBatchService) GetBatch(id string, username string) (model.Batch, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Batch), args.Error(1)
+}
+
+func (m *MockBatchService) ResumeBatches() error {
+	args := m.Called()
+	return args.Error(0)
+}
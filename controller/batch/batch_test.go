@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"strings"
+	"testing"
+
+	batchService "simple-golang-tdd/service/batch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(service *MockBatchService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.Use(middleware.JWTAuthMiddleware())
+
+	batchCtrl := NewBatchController(service)
+	r.POST("/v1/customer/batches", batchCtrl.SubmitBatch)
+	r.GET("/v1/customer/batches/:id", batchCtrl.GetBatch)
+
+	return r
+}
+
+func authorize(t *testing.T, req *http.Request) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var fakeRequests = []dto.PaymentRequest{
+	{MerchantID: "merchant-001", Amount: 100},
+	{MerchantID: "merchant-003", Amount: 25.5, Currency: "USD"},
+}
+
+var fakeBatch = model.Batch{ID: "batch-001", CustomerID: "cust-001", Username: "user", Status: model.BatchStatusPending, Total: 2}
+
+func TestSubmitBatch_JSON(t *testing.T) {
+	mockService := new(MockBatchService)
+	mockService.On("SubmitBatch", fakeRequests, "user").Return(fakeBatch, nil)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/batches", fakeRequests)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	expected := dto.SuccessResponse{Status: 202, Message: "batch accepted", Data: fakeBatch}
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestSubmitBatch_CSV(t *testing.T) {
+	mockService := new(MockBatchService)
+	mockService.On("SubmitBatch", fakeRequests, "user").Return(fakeBatch, nil)
+
+	body := "amount,merchant_id,currency\n100,merchant-001,\n25.5, merchant-003,USD\n"
+	req, _ := http.NewRequest(http.MethodPost, "/v1/customer/batches", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSubmitBatch_MalformedBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		message     string
+	}{
+		{"JSON object instead of array", "application/json", `{"merchant_id":"merchant-001","amount":100}`, "invalid request body"},
+		{"CSV without amount column", "text/csv", "merchant_id\nmerchant-001\n", "invalid request body: CSV header must contain merchant_id and amount"},
+		{"CSV with non-numeric amount", "text/csv", "merchant_id,amount\nmerchant-001,ten\n", `invalid request body: line 1: invalid amount "ten"`},
+		{"CSV with stray quote", "text/csv", "merchant_id,amount\nmerchant-001,1\"0\n", `invalid request body: failed to read CSV: parse error on line 2, column 15: bare " in non-quoted-field`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockBatchService)
+
+			req, _ := http.NewRequest(http.MethodPost, "/v1/customer/batches", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+			expected := dto.ErrorResponse{Status: 400, Message: tt.message}
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+			mockService.AssertNotCalled(t, "SubmitBatch", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSubmitBatch_BodyTooLarge(t *testing.T) {
+	mockService := new(MockBatchService)
+
+	body := "merchant_id,amount\n" + strings.Repeat("merchant-001,100\n", maxBatchBodyBytes/17+1)
+	req, _ := http.NewRequest(http.MethodPost, "/v1/customer/batches", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	mockService.AssertNotCalled(t, "SubmitBatch", mock.Anything, mock.Anything)
+}
+
+func TestSubmitBatch_RejectedByService(t *testing.T) {
+	mockService := new(MockBatchService)
+	serviceErr := fmt.Errorf("%w: line 2: merchant merchant-003 not found", batchService.ErrInvalidBatch)
+	mockService.On("SubmitBatch", fakeRequests, "user").Return(model.Batch{}, serviceErr)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/batches", fakeRequests)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	expected := dto.ErrorResponse{Status: 400, Message: serviceErr.Error()}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetBatch_Success(t *testing.T) {
+	mockService := new(MockBatchService)
+	mockService.On("GetBatch", "batch-001", "user").Return(fakeBatch, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/customer/batches/batch-001", nil)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "batch found", Data: fakeBatch}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetBatch_NotFound(t *testing.T) {
+	mockService := new(MockBatchService)
+	mockService.On("GetBatch", "batch-404", "user").Return(model.Batch{}, batchService.ErrBatchNotFound)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/customer/batches/batch-404", nil)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorize(t, req))
+
+	expected := dto.ErrorResponse{Status: 404, Message: "batch not found"}
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
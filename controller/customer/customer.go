@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"errors"
 	"simple-golang-tdd/dto"
 	customerService "simple-golang-tdd/service/customer"
+	limitService "simple-golang-tdd/service/limit"
 	"simple-golang-tdd/utils"
 
 	"github.com/gin-gonic/gin"
@@ -22,15 +24,16 @@ func NewCustomerController(service customerService.CustomerService) *CustomerCon
 
 // Paymentgodoc
 // @Summary      Customer Payment to Merchant
-// @Description  Customer payment reduces balance and send to merchant
+// @Description  Customer payment reduces balance and send to merchant. With escrow set the funds are held until the customer confirms delivery or the escrow period ends
 // @Tags         Customer
 // @Accept       json
 // @Produce      json
 // @Param        Authorization header string true "Bearer Token"
 // @Param        body  body  dto.PaymentRequest  true  "Payment Request"
-// @Success      200  {object} dto.SuccessResponse  ""payment successful"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment successful"
 // @Failure      400  {object} dto.ErrorResponse
 // @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse  "spending limit exceeded"
 // @Failure      500  {object} dto.ErrorResponse
 // @Router       /api/v1/customer/payment [post]
 func (cc *CustomerController) Payment(c *gin.Context) {
@@ -56,11 +59,138 @@ func (cc *CustomerController) Payment(c *gin.Context) {
 	// You should cast the username from context to a string
 	strUsername, _ := username.(string)
 
-	customer, err := cc.customerService.Payment(paymentRequest, strUsername)
+	payment, err := cc.customerService.Payment(paymentRequest, strUsername)
+	var limitErr *limitService.LimitError
+	if errors.As(err, &limitErr) {
+		utils.ErrorCodeResponse(c, 403, limitErr.Code, limitErr.Message)
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, 200, "payment successful", customer)
+	utils.SuccessResponse(c, 200, "payment successful", payment)
+}
+
+// SplitPayment godoc
+// @Summary      Customer Split Payment to Several Merchants
+// @Description  Debits the customer once and distributes the amount over the merchant legs, whose amounts must add up to the total
+// @Tags         Customer
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.SplitPaymentRequest  true  "Split Payment Request"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment successful"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse  "spending limit exceeded"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/payment/split [post]
+func (cc *CustomerController) SplitPayment(c *gin.Context) {
+	var splitPaymentRequest dto.SplitPaymentRequest
+	err := c.BindJSON(&splitPaymentRequest)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	payment, err := cc.customerService.SplitPayment(splitPaymentRequest, strUsername)
+	var limitErr *limitService.LimitError
+	if errors.As(err, &limitErr) {
+		utils.ErrorCodeResponse(c, 403, limitErr.Code, limitErr.Message)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "payment successful", payment)
+}
+
+// QRPayment godoc
+// @Summary      Customer Payment from a QR Code
+// @Description  Pays the merchant in a scanned QRIS payload. A dynamic QR carries its own amount; a static one needs the amount in the request
+// @Tags         Customer
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.QRPaymentRequest  true  "QR Payment Request"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment successful"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse  "spending limit exceeded"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/payment/qr [post]
+func (cc *CustomerController) QRPayment(c *gin.Context) {
+	var qrPaymentRequest dto.QRPaymentRequest
+	err := c.BindJSON(&qrPaymentRequest)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	payment, err := cc.customerService.QRPayment(qrPaymentRequest, strUsername)
+	var limitErr *limitService.LimitError
+	if errors.As(err, &limitErr) {
+		utils.ErrorCodeResponse(c, 403, limitErr.Code, limitErr.Message)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "payment successful", payment)
+}
+
+// GetPayment godoc
+// @Summary      Get Customer Payment
+// @Description  Returns a payment of the logged in customer with its current status and transitions
+// @Tags         Customer
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Payment ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/payments/{id} [get]
+func (cc *CustomerController) GetPayment(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	payment, err := cc.customerService.GetPayment(c.Param("id"), strUsername)
+	if errors.Is(err, customerService.ErrPaymentNotFound) {
+		utils.ErrorResponse(c, 404, "payment not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "payment found", payment)
 }
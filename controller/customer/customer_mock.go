@@ -13,7 +13,40 @@ type MockCustomerService struct {
 }
 
 // Payment mocks the Payment method of AuthService
-func (m *MockCustomerService) Payment(request dto.PaymentRequest, username string) (model.Customer, error) {
-    args := m.Called(request, username) // expects two arguments
-    return args.Get(0).(model.Customer), args.Error(1)
+func (m *MockCustomerService) Payment(request dto.PaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username) // expects two arguments
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// SplitPayment mocks the SplitPayment method of CustomerService
+func (m *MockCustomerService) SplitPayment(request dto.SplitPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// QRPayment mocks the QRPayment method of CustomerService
+func (m *MockCustomerService) QRPayment(request dto.QRPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// GetPayment mocks the GetPayment method of CustomerService
+func (m *MockCustomerService) GetPayment(id string, username string) (model.Payment, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) RefundPayment(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) ReleaseEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) CancelEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
 }
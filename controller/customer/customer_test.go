@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"simple-golang-tdd/dto"
@@ -10,9 +11,14 @@ import (
 	"simple-golang-tdd/model"
 	"simple-golang-tdd/utils"
 	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+	limitService "simple-golang-tdd/service/limit"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,6 +31,9 @@ func setupRouter(service *MockCustomerService) *gin.Engine {
 
 	customerCtrl := NewCustomerController(service)
 	r.POST("/v1/customer/payment", customerCtrl.Payment) // Fixed path
+	r.POST("/v1/customer/payment/split", customerCtrl.SplitPayment)
+	r.POST("/v1/customer/payment/qr", customerCtrl.QRPayment)
+	r.GET("/v1/customer/payments/:id", customerCtrl.GetPayment)
 
 	return r
 }
@@ -45,21 +54,17 @@ func TestPayment_Success(t *testing.T) {
 
 	fakeUsername := "user"
 
-	fakeUserData := model.Customer{
-		ID:       "1",
-		Username: "user",
-		Password: "password", // password matches
-		Balance:  1000.0,
-	}
+	fakePaymentData := model.NewPayment("pay-001", "1", "merchant123", 100.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+	_ = fakePaymentData.TransitionTo(model.PaymentStatusSucceeded, "", time.Date(2024, 4, 27, 12, 0, 1, 0, time.UTC))
 
 	fakeResponseMessage := dto.SuccessResponse{
 		Status:  200,
 		Message: "payment successful",
-		Data:    fakeUserData,
+		Data:    fakePaymentData,
 	}
 
 	// Mock the Payment method on the service
-	mockService.On("Payment", fakePaymentRequest, fakeUsername).Return(fakeUserData, nil)
+	mockService.On("Payment", fakePaymentRequest, fakeUsername).Return(fakePaymentData, nil)
 
 	// Create a JWT token for the user
 	token, err := utils.GenerateAccessToken(fakeUsername) // Assuming this is your JWT generation function
@@ -134,6 +139,63 @@ func TestPayment_InvalidFieldType(t *testing.T) {
 	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
 }
 
+func TestPayment_InvalidAmountOrCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+	}{
+		{"negative", map[string]interface{}{"merchant_id": "merchant123", "amount": -100.0}},
+		{"zero", map[string]interface{}{"merchant_id": "merchant123", "amount": 0}},
+		{"string NaN", map[string]interface{}{"merchant_id": "merchant123", "amount": "NaN"}},
+		{"unknown currency", map[string]interface{}{"merchant_id": "merchant123", "amount": 100.0, "currency": "RUPIAH"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCustomerService)
+			rec, router := newRecorderAndRouter(mockService)
+
+			token, err := utils.GenerateAccessToken("user")
+			require.NoError(t, err)
+
+			req, _ := utils.NewJSONRequest("POST", "/v1/customer/payment", tt.payload)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			router.ServeHTTP(rec, req)
+
+			expected := dto.ErrorResponse{Status: 400, Message: "invalid request body"}
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+			mockService.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPayment_AmountRejectedByService(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	fakePaymentRequest := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     10.001,
+	}
+
+	mockService.On("Payment", fakePaymentRequest, "user").Return(model.Payment{}, fmt.Errorf("%w: IDR amounts allow at most 2 decimal places", customerService.ErrInvalidAmount))
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := utils.NewJSONRequest("POST", "/v1/customer/payment", fakePaymentRequest)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.ErrorResponse{Status: 400, Message: "invalid amount: IDR amounts allow at most 2 decimal places"}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
 func TestPayment_InvalidToken(t *testing.T) {
 	mockService := new(MockCustomerService)
 	rec, router := newRecorderAndRouter(mockService)
@@ -185,7 +247,7 @@ func TestPayment_ServiceFailure(t *testing.T) {
 	fakeUsername := "user"
 
 	// Mock the Payment service call to return an error
-	mockService.On("Payment", fakePaymentRequest, fakeUsername).Return(model.Customer{}, errors.New("insufficient balance"))
+	mockService.On("Payment", fakePaymentRequest, fakeUsername).Return(model.Payment{}, errors.New("insufficient balance"))
 
 	// Create a JWT token for the user
 	token, err := utils.GenerateAccessToken(fakeUsername)
@@ -208,3 +270,261 @@ func TestPayment_ServiceFailure(t *testing.T) {
 	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
 	mockService.AssertExpectations(t)
 }
+
+func TestPayment_LimitExceeded(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	fakePaymentRequest := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     100.0,
+	}
+
+	limitErr := &limitService.LimitError{
+		Code:    limitService.CodeDailyCountLimitExceeded,
+		Message: "payment exceeds the daily limit of 20 transactions",
+	}
+	mockService.On("Payment", fakePaymentRequest, "user").Return(model.Payment{}, limitErr)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := utils.NewJSONRequest("POST", "/v1/customer/payment", fakePaymentRequest)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.ErrorResponse{
+		Status:  403,
+		Code:    "DAILY_COUNT_LIMIT_EXCEEDED",
+		Message: "payment exceeds the daily limit of 20 transactions",
+	}
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetPayment_Success(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	fakePaymentData := model.NewPayment("pay-001", "1", "merchant123", 100.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+
+	mockService.On("GetPayment", "pay-001", "user").Return(fakePaymentData, nil)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/customer/payments/pay-001", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{
+		Status:  200,
+		Message: "payment found",
+		Data:    fakePaymentData,
+	}
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetPayment_NotFound(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	mockService.On("GetPayment", "pay-404", "user").Return(model.Payment{}, customerService.ErrPaymentNotFound)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/customer/payments/pay-404", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.ErrorResponse{Status: 404, Message: "payment not found"}
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestSplitPayment_Success(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	request := dto.SplitPaymentRequest{
+		Amount: 1000.0,
+		Legs: []dto.SplitPaymentLeg{
+			{MerchantID: "seller", Amount: 900.0},
+			{MerchantID: "logistics", Amount: 100.0},
+		},
+	}
+	fakePaymentData := model.NewPayment("pay-001", "1", "", 1000.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+	fakePaymentData.ApplyLegs([]model.PaymentLeg{
+		{MerchantID: "seller", Amount: 900.0, NetAmount: 900.0},
+		{MerchantID: "logistics", Amount: 100.0, NetAmount: 100.0},
+	})
+
+	mockService.On("SplitPayment", request, "user").Return(fakePaymentData, nil)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/split", request)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{Status: 200, Message: "payment successful", Data: fakePaymentData}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestSplitPayment_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"single leg", dto.SplitPaymentRequest{Amount: 100.0, Legs: []dto.SplitPaymentLeg{{MerchantID: "seller", Amount: 100.0}}}},
+		{"leg without merchant", dto.SplitPaymentRequest{Amount: 100.0, Legs: []dto.SplitPaymentLeg{{Amount: 50.0}, {MerchantID: "seller", Amount: 50.0}}}},
+		{"missing legs", map[string]interface{}{"amount": 100.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCustomerService)
+			rec, router := newRecorderAndRouter(mockService)
+
+			token, err := utils.GenerateAccessToken("user")
+			require.NoError(t, err)
+
+			req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/split", tt.payload)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "SplitPayment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSplitPayment_RejectedByService(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	request := dto.SplitPaymentRequest{
+		Amount: 1000.0,
+		Legs: []dto.SplitPaymentLeg{
+			{MerchantID: "seller", Amount: 900.0},
+			{MerchantID: "logistics", Amount: 50.0},
+		},
+	}
+	serviceErr := fmt.Errorf("%w: leg amounts add up to 950.00 instead of 1000.00", customerService.ErrInvalidSplit)
+	mockService.On("SplitPayment", request, "user").Return(model.Payment{}, serviceErr)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/split", request)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.ErrorResponse{Status: 400, Message: serviceErr.Error()}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestQRPayment_Success(t *testing.T) {
+	mockService := new(MockCustomerService)
+	rec, router := newRecorderAndRouter(mockService)
+
+	request := dto.QRPaymentRequest{Payload: "00020101021226450025ID.CO.SIMPLEGOLANGTDD.WWW", Amount: 150.0}
+	fakePaymentData := model.NewPayment("pay-001", "1", "merchant-001", 150.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+
+	mockService.On("QRPayment", request, "user").Return(fakePaymentData, nil)
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/qr", request)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router.ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{Status: 200, Message: "payment successful", Data: fakePaymentData}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestQRPayment_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"missing payload", map[string]interface{}{"amount": 100.0}},
+		{"negative amount", dto.QRPaymentRequest{Payload: "000201", Amount: -1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCustomerService)
+			rec, router := newRecorderAndRouter(mockService)
+
+			token, err := utils.GenerateAccessToken("user")
+			require.NoError(t, err)
+
+			req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/qr", tt.payload)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "QRPayment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestQRPayment_RejectedByService(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantCode   int
+	}{
+		{"invalid QR", fmt.Errorf("%w: checksum mismatch", customerService.ErrInvalidQR), http.StatusBadRequest},
+		{"limit exceeded", &limitService.LimitError{Code: limitService.CodeDailyAmountLimitExceeded, Message: "payment exceeds the daily spending limit of 500.00"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCustomerService)
+			rec, router := newRecorderAndRouter(mockService)
+
+			request := dto.QRPaymentRequest{Payload: "000201", Amount: 100.0}
+			mockService.On("QRPayment", request, "user").Return(model.Payment{}, tt.serviceErr)
+
+			token, err := utils.GenerateAccessToken("user")
+			require.NoError(t, err)
+
+			req, err := utils.NewJSONRequest(http.MethodPost, "/v1/customer/payment/qr", request)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
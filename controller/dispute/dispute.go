@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	customerService "simple-golang-tdd/service/customer"
+	disputeService "simple-golang-tdd/service/dispute"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DisputeController handles payment disputes for customers, merchants and admins
+type DisputeController struct {
+	disputeService disputeService.DisputeService
+}
+
+func NewDisputeController(service disputeService.DisputeService) *DisputeController {
+	return &DisputeController{disputeService: service}
+}
+
+// OpenDispute godoc
+// @Summary      Open Dispute
+// @Description  Disputes a succeeded payment of the logged in customer. The merchant has 7 days to respond before the dispute goes to review.
+// @Tags         Dispute
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.DisputeRequest  true  "Dispute Request"
+// @Success      201  {object} dto.SuccessResponse{data=model.Dispute}  "dispute opened"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse  "payment not found"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/disputes [post]
+func (dc *DisputeController) OpenDispute(c *gin.Context) {
+	var disputeRequest dto.DisputeRequest
+	if err := c.BindJSON(&disputeRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	dispute, err := dc.disputeService.OpenDispute(disputeRequest, username)
+	if err != nil {
+		writeDisputeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "dispute opened", dispute)
+}
+
+// GetDisputes godoc
+// @Summary      Get Disputes
+// @Description  Lists the disputes of the logged in customer
+// @Tags         Dispute
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Success      200  {object} dto.SuccessResponse{data=[]model.Dispute}  "disputes found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/disputes [get]
+func (dc *DisputeController) GetDisputes(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	disputes, err := dc.disputeService.GetDisputes(username)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "disputes found", disputes)
+}
+
+// GetDispute godoc
+// @Summary      Get Dispute
+// @Description  Returns a dispute of the logged in customer with its evidence and status history
+// @Tags         Dispute
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Dispute ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Dispute}  "dispute found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/disputes/{id} [get]
+func (dc *DisputeController) GetDispute(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	dispute, err := dc.disputeService.GetDispute(c.Param("id"), username)
+	if err != nil {
+		utils.ErrorResponse(c, 404, "dispute not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "dispute found", dispute)
+}
+
+// AddEvidence godoc
+// @Summary      Add Dispute Evidence
+// @Description  Attaches more evidence to an unresolved dispute of the logged in customer. Only the file metadata is stored.
+// @Tags         Dispute
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Dispute ID"
+// @Param        body  body  dto.EvidenceListRequest  true  "Evidence"
+// @Success      200  {object} dto.SuccessResponse{data=model.Dispute}  "evidence added"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/disputes/{id}/evidence [post]
+func (dc *DisputeController) AddEvidence(c *gin.Context) {
+	var evidenceRequest dto.EvidenceListRequest
+	if err := c.BindJSON(&evidenceRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	dispute, err := dc.disputeService.AddEvidence(c.Param("id"), evidenceRequest, username)
+	if err != nil {
+		writeDisputeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "evidence added", dispute)
+}
+
+// GetMerchantDisputes godoc
+// @Summary      Get Merchant Disputes
+// @Description  Lists the disputes against payments to the authenticated merchant
+// @Tags         Merchant
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Success      200  {object} dto.SuccessResponse{data=[]model.Dispute}  "disputes found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/disputes [get]
+func (dc *DisputeController) GetMerchantDisputes(c *gin.Context) {
+	disputes, err := dc.disputeService.GetMerchantDisputes(c.GetString("merchant_id"))
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "disputes found", disputes)
+}
+
+// GetMerchantDispute godoc
+// @Summary      Get Merchant Dispute
+// @Description  Returns a dispute against a payment to the authenticated merchant
+// @Tags         Merchant
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        id  path  string  true  "Dispute ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Dispute}  "dispute found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/disputes/{id} [get]
+func (dc *DisputeController) GetMerchantDispute(c *gin.Context) {
+	dispute, err := dc.disputeService.GetMerchantDispute(c.Param("id"), c.GetString("merchant_id"))
+	if err != nil {
+		utils.ErrorResponse(c, 404, "dispute not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "dispute found", dispute)
+}
+
+// RespondToDispute godoc
+// @Summary      Respond to Dispute
+// @Description  Records the authenticated merchant's response and evidence before the deadline and sends the dispute to review
+// @Tags         Merchant
+// @Accept       json
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        id  path  string  true  "Dispute ID"
+// @Param        body  body  dto.DisputeResponseRequest  true  "Dispute Response"
+// @Success      200  {object} dto.SuccessResponse{data=model.Dispute}  "response recorded"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      409  {object} dto.ErrorResponse  "dispute no longer open"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/disputes/{id}/respond [post]
+func (dc *DisputeController) RespondToDispute(c *gin.Context) {
+	var responseRequest dto.DisputeResponseRequest
+	if err := c.BindJSON(&responseRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	dispute, err := dc.disputeService.RespondToDispute(c.Param("id"), responseRequest, c.GetString("merchant_id"))
+	if err != nil {
+		writeDisputeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "response recorded", dispute)
+}
+
+// ResolveDispute godoc
+// @Summary      Resolve Dispute
+// @Description  Resolves a dispute under review, either refunding the payment to the customer or upholding it
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Key header string true "Admin API Key"
+// @Param        id  path  string  true  "Dispute ID"
+// @Param        body  body  dto.DisputeResolutionRequest  true  "Dispute Resolution"
+// @Success      200  {object} dto.SuccessResponse{data=model.Dispute}  "dispute resolved"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      409  {object} dto.ErrorResponse  "dispute not under review"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/admin/disputes/{id}/resolve [post]
+func (dc *DisputeController) ResolveDispute(c *gin.Context) {
+	var resolutionRequest dto.DisputeResolutionRequest
+	if err := c.BindJSON(&resolutionRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	dispute, err := dc.disputeService.ResolveDispute(c.Param("id"), resolutionRequest)
+	if err != nil {
+		writeDisputeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "dispute resolved", dispute)
+}
+
+// writeDisputeError maps a dispute service error to its response.
+func writeDisputeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, disputeService.ErrDisputeNotFound):
+		utils.ErrorResponse(c, 404, "dispute not found")
+	case errors.Is(err, customerService.ErrPaymentNotFound):
+		utils.ErrorResponse(c, 404, "payment not found")
+	case errors.Is(err, disputeService.ErrInvalidDispute):
+		utils.ErrorResponse(c, 400, err.Error())
+	case errors.Is(err, model.ErrInvalidDisputeTransition):
+		utils.ErrorResponse(c, 409, err.Error())
+	default:
+		utils.ErrorResponse(c, 500, "internal server error")
+	}
+}
+
+// usernameFromContext returns the username set by the JWT middleware, writing
+// a 401 response when it is missing.
+func usernameFromContext(c *gin.Context) (string, bool) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return "", false
+	}
+
+	strUsername, _ := username.(string)
+	return strUsername, true
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDisputeService struct {
+	mock.Mock
+}
+
+func (m *MockDisputeService) OpenDispute(request dto.DisputeRequest, username string) (model.Dispute, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) GetDisputes(username string) ([]model.Dispute, error) {
+	args := m.Called(username)
+	return args.Get(0).([]model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) GetDispute(id string, username string) (model.Dispute, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) AddEvidence(id string, request dto.EvidenceListRequest, username string) (model.Dispute, error) {
+	args := m.Called(id, request, username)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) GetMerchantDisputes(merchantID string) ([]model.Dispute, error) {
+	args := m.Called(merchantID)
+	return args.Get(0).([]model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) GetMerchantDispute(id string, merchantID string) (model.Dispute, error) {
+	args := m.Called(id, merchantID)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) RespondToDispute(id string, request dto.DisputeResponseRequest, merchantID string) (model.Dispute, error) {
+	args := m.Called(id, request, merchantID)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) ResolveDispute(id string, request dto.DisputeResolutionRequest) (model.Dispute, error) {
+	args := m.Called(id, request)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeService) EscalateOverdueDisputes() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockDisputeService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+// MockMerchantRepository is a mock of the MerchantRepository interface, used
+// to authenticate merchants through the API key middleware
+type MockMerchantRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
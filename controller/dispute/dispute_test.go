@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+	disputeService "simple-golang-tdd/service/dispute"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeAPIKey   = "mk_test_merchant001"
+	fakeAdminKey = "admin_test_key"
+)
+
+func setupRouter(service *MockDisputeService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByAPIKey", fakeAPIKey).Return(model.Merchant{ID: "merchant-001"}, nil)
+	merchantRepository.On("GetMerchantByAPIKey", mock.Anything).Return(model.Merchant{}, errors.New("merchant not found by API key"))
+
+	disputeCtrl := NewDisputeController(service)
+
+	customerGroup := r.Group("/v1/customer", middleware.JWTAuthMiddleware())
+	customerGroup.POST("/disputes", disputeCtrl.OpenDispute)
+	customerGroup.GET("/disputes", disputeCtrl.GetDisputes)
+	customerGroup.GET("/disputes/:id", disputeCtrl.GetDispute)
+	customerGroup.POST("/disputes/:id/evidence", disputeCtrl.AddEvidence)
+
+	merchantGroup := r.Group("/v1/merchant", middleware.MerchantAuthMiddleware(merchantRepository))
+	merchantGroup.GET("/disputes", disputeCtrl.GetMerchantDisputes)
+	merchantGroup.GET("/disputes/:id", disputeCtrl.GetMerchantDispute)
+	merchantGroup.POST("/disputes/:id/respond", disputeCtrl.RespondToDispute)
+
+	adminGroup := r.Group("/v1/admin", middleware.AdminAuthMiddleware(fakeAdminKey))
+	adminGroup.POST("/disputes/:id/resolve", disputeCtrl.ResolveDispute)
+
+	return r
+}
+
+func authorizedRequest(t *testing.T, method, url string, payload interface{}) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	var req *http.Request
+	if payload != nil {
+		req, err = utils.NewJSONRequest(method, url, payload)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var fakeDisputeRequest = dto.DisputeRequest{
+	PaymentID:   "pay-001",
+	Reason:      "not_received",
+	Description: "Order never arrived",
+	Evidence: []dto.EvidenceRequest{
+		{FileName: "receipt.png", ContentType: "image/png", Size: 2048, URL: "https://files.example.com/receipt.png"},
+	},
+}
+
+var fakeDispute = model.Dispute{
+	ID:          "dsp-001",
+	PaymentID:   "pay-001",
+	CustomerID:  "cust-001",
+	MerchantID:  "merchant-001",
+	Amount:      150.0,
+	Currency:    model.DefaultCurrency,
+	Reason:      model.DisputeReasonNotReceived,
+	Description: "Order never arrived",
+	Evidence:    []model.DisputeEvidence{},
+	RespondBy:   time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC),
+	Status:      model.DisputeStatusOpen,
+}
+
+func TestOpenDispute_Success(t *testing.T) {
+	mockService := new(MockDisputeService)
+	mockService.On("OpenDispute", fakeDisputeRequest, "user").Return(fakeDispute, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/disputes", fakeDisputeRequest))
+
+	expected := dto.SuccessResponse{Status: 201, Message: "dispute opened", Data: fakeDispute}
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestOpenDispute_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.DisputeRequest
+	}{
+		{"unknown reason", dto.DisputeRequest{PaymentID: "pay-001", Reason: "changed_mind", Description: "No"}},
+		{"missing description", dto.DisputeRequest{PaymentID: "pay-001", Reason: "other"}},
+		{"evidence without url", dto.DisputeRequest{PaymentID: "pay-001", Reason: "other", Description: "No", Evidence: []dto.EvidenceRequest{{FileName: "a.png", ContentType: "image/png", Size: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDisputeService)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/disputes", tt.request))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "OpenDispute", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOpenDispute_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"payment not found", customerService.ErrPaymentNotFound, http.StatusNotFound},
+		{"not disputable", fmt.Errorf("%w: only succeeded payments can be disputed", disputeService.ErrInvalidDispute), http.StatusBadRequest},
+		{"repository failure", errors.New("failed to create dispute: disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDisputeService)
+			mockService.On("OpenDispute", fakeDisputeRequest, "user").Return(model.Dispute{}, tt.err)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/disputes", fakeDisputeRequest))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestGetDisputes_Success(t *testing.T) {
+	mockService := new(MockDisputeService)
+	mockService.On("GetDisputes", "user").Return([]model.Dispute{fakeDispute}, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/disputes", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "disputes found", Data: []model.Dispute{fakeDispute}}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetDispute_NotFound(t *testing.T) {
+	mockService := new(MockDisputeService)
+	mockService.On("GetDispute", "dsp-404", "user").Return(model.Dispute{}, disputeService.ErrDisputeNotFound)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/disputes/dsp-404", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAddEvidence_Success(t *testing.T) {
+	mockService := new(MockDisputeService)
+	request := dto.EvidenceListRequest{Evidence: fakeDisputeRequest.Evidence}
+	mockService.On("AddEvidence", "dsp-001", request, "user").Return(fakeDispute, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/disputes/dsp-001/evidence", request))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMerchantDispute_Success(t *testing.T) {
+	mockService := new(MockDisputeService)
+	mockService.On("GetMerchantDispute", "dsp-001", "merchant-001").Return(fakeDispute, nil)
+	rec := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/merchant/disputes/dsp-001", nil)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{Status: 200, Message: "dispute found", Data: fakeDispute}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestRespondToDispute_AlreadyUnderReview(t *testing.T) {
+	mockService := new(MockDisputeService)
+	request := dto.DisputeResponseRequest{Response: "Delivered on 25 April"}
+	mockService.On("RespondToDispute", "dsp-001", request, "merchant-001").Return(model.Dispute{}, fmt.Errorf("%w: under_review to under_review", model.ErrInvalidDisputeTransition))
+	rec := httptest.NewRecorder()
+
+	req, _ := utils.NewJSONRequest(http.MethodPost, "/v1/merchant/disputes/dsp-001/respond", request)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestResolveDispute_Success(t *testing.T) {
+	mockService := new(MockDisputeService)
+	request := dto.DisputeResolutionRequest{Outcome: "refund", Note: "Courier confirmed loss"}
+	resolved := fakeDispute
+	resolved.Status = model.DisputeStatusRefunded
+	mockService.On("ResolveDispute", "dsp-001", request).Return(resolved, nil)
+	rec := httptest.NewRecorder()
+
+	req, _ := utils.NewJSONRequest(http.MethodPost, "/v1/admin/disputes/dsp-001/resolve", request)
+	req.Header.Set(middleware.AdminAPIKeyHeader, fakeAdminKey)
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{Status: 200, Message: "dispute resolved", Data: resolved}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestResolveDispute_Unauthorized(t *testing.T) {
+	for name, key := range map[string]string{"missing key": "", "wrong key": "guess"} {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockDisputeService)
+			rec := httptest.NewRecorder()
+
+			req, _ := utils.NewJSONRequest(http.MethodPost, "/v1/admin/disputes/dsp-001/resolve", dto.DisputeResolutionRequest{Outcome: "uphold", Note: "Fine"})
+			if key != "" {
+				req.Header.Set(middleware.AdminAPIKeyHeader, key)
+			}
+			setupRouter(mockService).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			mockService.AssertNotCalled(t, "ResolveDispute", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"errors"
+	customerService "simple-golang-tdd/service/customer"
+	escrowService "simple-golang-tdd/service/escrow"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EscrowController handles releasing and cancelling escrow payments
+type EscrowController struct {
+	escrowService escrowService.EscrowService
+}
+
+func NewEscrowController(service escrowService.EscrowService) *EscrowController {
+	return &EscrowController{escrowService: service}
+}
+
+// ConfirmDelivery godoc
+// @Summary      Confirm Delivery
+// @Description  Confirms the order of an escrow payment was received, releasing the held funds to the merchant
+// @Tags         Customer
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Payment ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment released"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      409  {object} dto.ErrorResponse  "payment not held in escrow"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/payments/{id}/confirm [post]
+func (ec *EscrowController) ConfirmDelivery(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	payment, err := ec.escrowService.ConfirmDelivery(c.Param("id"), strUsername)
+	if err != nil {
+		writeEscrowError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "payment released", payment)
+}
+
+// CancelPayment godoc
+// @Summary      Cancel Escrow Payment
+// @Description  Cancels an escrow payment to the authenticated merchant, returning the held funds to the customer
+// @Tags         Merchant
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        id  path  string  true  "Payment ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment cancelled"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      409  {object} dto.ErrorResponse  "payment not held in escrow"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/payments/{id}/cancel [post]
+func (ec *EscrowController) CancelPayment(c *gin.Context) {
+	payment, err := ec.escrowService.CancelPayment(c.Param("id"), c.GetString("merchant_id"))
+	if err != nil {
+		writeEscrowError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "payment cancelled", payment)
+}
+
+// writeEscrowError maps an escrow service error to its response.
+func writeEscrowError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, customerService.ErrPaymentNotFound):
+		utils.ErrorResponse(c, 404, "payment not found")
+	case errors.Is(err, customerService.ErrInvalidEscrow):
+		utils.ErrorResponse(c, 409, err.Error())
+	default:
+		utils.ErrorResponse(c, 500, "internal server error")
+	}
+}
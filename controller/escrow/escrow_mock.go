@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEscrowService struct {
+	mock.Mock
+}
+
+func (m *MockEscrowService) ConfirmDelivery(paymentID string, username string) (model.Payment, error) {
+	args := m.Called(paymentID, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockEscrowService) CancelPayment(paymentID string, merchantID string) (model.Payment, error) {
+	args := m.Called(paymentID, merchantID)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockEscrowService) ReleaseDuePayments() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockEscrowService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+// MockMerchantRepository is a mock of the MerchantRepository interface, used
+// to authenticate merchants through the API key middleware
+type MockMerchantRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeAPIKey = "mk_test_merchant001"
+
+func setupRouter(service *MockEscrowService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByAPIKey", fakeAPIKey).Return(model.Merchant{ID: "merchant-001"}, nil)
+	merchantRepository.On("GetMerchantByAPIKey", mock.Anything).Return(model.Merchant{}, errors.New("merchant not found by API key"))
+
+	escrowCtrl := NewEscrowController(service)
+
+	customerGroup := r.Group("/v1/customer", middleware.JWTAuthMiddleware())
+	customerGroup.POST("/payments/:id/confirm", escrowCtrl.ConfirmDelivery)
+
+	merchantGroup := r.Group("/v1/merchant", middleware.MerchantAuthMiddleware(merchantRepository))
+	merchantGroup.POST("/payments/:id/cancel", escrowCtrl.CancelPayment)
+
+	return r
+}
+
+func customerRequest(t *testing.T, method, url string) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func merchantRequest(method, url string) *http.Request {
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	return req
+}
+
+func fakePayment(status model.PaymentStatus) model.Payment {
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 150.0, time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC))
+	payment.Status = status
+	return payment
+}
+
+func TestConfirmDelivery_Success(t *testing.T) {
+	mockService := new(MockEscrowService)
+	released := fakePayment(model.PaymentStatusSucceeded)
+	mockService.On("ConfirmDelivery", "pay-001", "user").Return(released, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, customerRequest(t, http.MethodPost, "/v1/customer/payments/pay-001/confirm"))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "payment released", Data: released}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestConfirmDelivery_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"not found", customerService.ErrPaymentNotFound, http.StatusNotFound},
+		{"not in escrow", fmt.Errorf("%w: payment is succeeded, not held in escrow", customerService.ErrInvalidEscrow), http.StatusConflict},
+		{"release failed", errors.New("failed to get merchant: disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockEscrowService)
+			mockService.On("ConfirmDelivery", "pay-001", "user").Return(model.Payment{}, tt.err)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, customerRequest(t, http.MethodPost, "/v1/customer/payments/pay-001/confirm"))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestCancelPayment_Success(t *testing.T) {
+	mockService := new(MockEscrowService)
+	cancelled := fakePayment(model.PaymentStatusCancelled)
+	mockService.On("CancelPayment", "pay-001", "merchant-001").Return(cancelled, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, merchantRequest(http.MethodPost, "/v1/merchant/payments/pay-001/cancel"))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "payment cancelled", Data: cancelled}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCancelPayment_Unauthorized(t *testing.T) {
+	mockService := new(MockEscrowService)
+	rec := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/merchant/payments/pay-001/cancel", nil)
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockService.AssertNotCalled(t, "CancelPayment", mock.Anything, mock.Anything)
+}
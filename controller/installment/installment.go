@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	customerService "simple-golang-tdd/service/customer"
+	installmentService "simple-golang-tdd/service/installment"
+	limitService "simple-golang-tdd/service/limit"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InstallmentController handles pay-later purchases and their installments
+type InstallmentController struct {
+	installmentService installmentService.InstallmentService
+}
+
+func NewInstallmentController(service installmentService.InstallmentService) *InstallmentController {
+	return &InstallmentController{installmentService: service}
+}
+
+// CreatePlan godoc
+// @Summary      Pay Later
+// @Description  Pays the merchant in full from the platform credit line and splits the amount into 3, 6 or 12 monthly installments. Not available while installments are overdue.
+// @Tags         Installment
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.InstallmentPaymentRequest  true  "Installment Payment Request"
+// @Success      201  {object} dto.SuccessResponse{data=model.InstallmentPlan}  "installment plan created"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse  "installments overdue, credit or spending limit exceeded"
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/payment/installments [post]
+func (ic *InstallmentController) CreatePlan(c *gin.Context) {
+	var installmentRequest dto.InstallmentPaymentRequest
+	if err := c.BindJSON(&installmentRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	plan, err := ic.installmentService.CreatePlan(installmentRequest, username)
+	if err != nil {
+		writeInstallmentError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "installment plan created", plan)
+}
+
+// GetInstallments godoc
+// @Summary      Get Installments
+// @Description  Returns the pay-later plans of the logged in customer with the outstanding and overdue amounts
+// @Tags         Installment
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Success      200  {object} dto.SuccessResponse{data=model.InstallmentSummary}  "installments found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/installments [get]
+func (ic *InstallmentController) GetInstallments(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	summary, err := ic.installmentService.GetInstallments(username)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "installments found", summary)
+}
+
+// GetPlan godoc
+// @Summary      Get Installment Plan
+// @Description  Returns a pay-later plan of the logged in customer with its installment schedule
+// @Tags         Installment
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Installment Plan ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.InstallmentPlan}  "installment plan found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/installments/{id} [get]
+func (ic *InstallmentController) GetPlan(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	plan, err := ic.installmentService.GetPlan(c.Param("id"), username)
+	if err != nil {
+		utils.ErrorResponse(c, 404, "installment plan not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "installment plan found", plan)
+}
+
+// PayInstallment godoc
+// @Summary      Pay Installment
+// @Description  Pays the next unpaid installment of a pay-later plan from the customer's balance, late fee included
+// @Tags         Installment
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Installment Plan ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.InstallmentPlan}  "installment paid"
+// @Failure      400  {object} dto.ErrorResponse  "insufficient balance or plan already repaid"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/installments/{id}/pay [post]
+func (ic *InstallmentController) PayInstallment(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	plan, err := ic.installmentService.PayInstallment(c.Param("id"), username)
+	if err != nil {
+		writeInstallmentError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "installment paid", plan)
+}
+
+// writeInstallmentError maps an installment service error to its response.
+func writeInstallmentError(c *gin.Context, err error) {
+	var limitErr *limitService.LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		utils.ErrorCodeResponse(c, 403, limitErr.Code, limitErr.Message)
+	case errors.Is(err, installmentService.ErrInstallmentOverdue), errors.Is(err, installmentService.ErrCreditLimitExceeded):
+		utils.ErrorResponse(c, 403, err.Error())
+	case errors.Is(err, installmentService.ErrPlanNotFound):
+		utils.ErrorResponse(c, 404, "installment plan not found")
+	case errors.Is(err, installmentService.ErrInvalidInstallment), errors.Is(err, customerService.ErrInvalidAmount), errors.Is(err, customerService.ErrInsufficientBalance):
+		utils.ErrorResponse(c, 400, err.Error())
+	default:
+		utils.ErrorResponse(c, 500, "internal server error")
+	}
+}
+
+// usernameFromContext returns the username set by the JWT middleware, writing
+// a 401 response when it is missing.
+func usernameFromContext(c *gin.Context) (string, bool) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return "", false
+	}
+
+	strUsername, _ := username.(string)
+	return strUsername, true
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInstallmentService struct {
+	mock.Mock
+}
+
+func (m *MockInstallmentService) CreatePlan(request dto.InstallmentPaymentRequest, username string) (model.InstallmentPlan, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentService) GetInstallments(username string) (model.InstallmentSummary, error) {
+	args := m.Called(username)
+	return args.Get(0).(model.InstallmentSummary), args.Error(1)
+}
+
+func (m *MockInstallmentService) GetPlan(id string, username string) (model.InstallmentPlan, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentService) PayInstallment(id string, username string) (model.InstallmentPlan, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentService) CollectDueInstallments() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockInstallmentService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+	installmentService "simple-golang-tdd/service/installment"
+	limitService "simple-golang-tdd/service/limit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(service *MockInstallmentService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	installmentCtrl := NewInstallmentController(service)
+
+	customerGroup := r.Group("/v1/customer", middleware.JWTAuthMiddleware())
+	customerGroup.POST("/payment/installments", installmentCtrl.CreatePlan)
+	customerGroup.GET("/installments", installmentCtrl.GetInstallments)
+	customerGroup.GET("/installments/:id", installmentCtrl.GetPlan)
+	customerGroup.POST("/installments/:id/pay", installmentCtrl.PayInstallment)
+
+	return r
+}
+
+func authorizedRequest(t *testing.T, method, url string, payload interface{}) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	var req *http.Request
+	if payload != nil {
+		req, err = utils.NewJSONRequest(method, url, payload)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var fakeInstallmentRequest = dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 3}
+
+func fakePlan() model.InstallmentPlan {
+	createdAt := time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 300.0, createdAt)
+	return model.NewInstallmentPlan("plan-001", payment, 3, createdAt)
+}
+
+func TestCreatePlan_Success(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	plan := fakePlan()
+	mockService.On("CreatePlan", fakeInstallmentRequest, "user").Return(plan, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/payment/installments", fakeInstallmentRequest))
+
+	expected := dto.SuccessResponse{Status: 201, Message: "installment plan created", Data: plan}
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreatePlan_InvalidBody(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 4}
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/payment/installments", request))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "CreatePlan")
+}
+
+func TestCreatePlan_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"overdue", fmt.Errorf("%w: 100.00 IDR must be repaid first", installmentService.ErrInstallmentOverdue), http.StatusForbidden},
+		{"credit limit exceeded", fmt.Errorf("%w: 9900000.00 of 10000000.00 IDR is still outstanding", installmentService.ErrCreditLimitExceeded), http.StatusForbidden},
+		{"spending limit exceeded", &limitService.LimitError{Code: limitService.CodeDailyAmountLimitExceeded, Message: "payment exceeds the daily spending limit of 500.00"}, http.StatusForbidden},
+		{"invalid amount", fmt.Errorf("%w: IDR amounts allow at most 2 decimal places", customerService.ErrInvalidAmount), http.StatusBadRequest},
+		{"foreign merchant", fmt.Errorf("%w: merchant settles in USD", installmentService.ErrInvalidInstallment), http.StatusBadRequest},
+		{"funding failed", errors.New("failed to update platform-credit balance: disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockInstallmentService)
+			mockService.On("CreatePlan", fakeInstallmentRequest, "user").Return(model.InstallmentPlan{}, tt.err)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/payment/installments", fakeInstallmentRequest))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestCreatePlan_LimitErrorCode(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	limitErr := &limitService.LimitError{Code: limitService.CodeDailyAmountLimitExceeded, Message: "payment exceeds the daily spending limit of 500.00"}
+	mockService.On("CreatePlan", fakeInstallmentRequest, "user").Return(model.InstallmentPlan{}, limitErr)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/payment/installments", fakeInstallmentRequest))
+
+	expected := dto.ErrorResponse{Status: 403, Code: limitService.CodeDailyAmountLimitExceeded, Message: limitErr.Message}
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetInstallments_Success(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	summary := model.InstallmentSummary{Outstanding: 300.0, Plans: []model.InstallmentPlan{fakePlan()}}
+	mockService.On("GetInstallments", "user").Return(summary, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/installments", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "installments found", Data: summary}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetInstallments_Unauthorized(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	req, _ := http.NewRequest(http.MethodGet, "/v1/customer/installments", nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockService.AssertNotCalled(t, "GetInstallments")
+}
+
+func TestGetPlan_NotFound(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	mockService.On("GetPlan", "plan-404", "user").Return(model.InstallmentPlan{}, installmentService.ErrPlanNotFound)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/installments/plan-404", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestPayInstallment_Success(t *testing.T) {
+	mockService := new(MockInstallmentService)
+	plan := fakePlan()
+	plan.Installments[0].Status = model.InstallmentStatusPaid
+	mockService.On("PayInstallment", "plan-001", "user").Return(plan, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/installments/plan-001/pay", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "installment paid", Data: plan}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestPayInstallment_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"not found", installmentService.ErrPlanNotFound, http.StatusNotFound},
+		{"insufficient balance", customerService.ErrInsufficientBalance, http.StatusBadRequest},
+		{"already repaid", fmt.Errorf("%w: plan is already repaid", installmentService.ErrInvalidInstallment), http.StatusBadRequest},
+		{"update failed", errors.New("failed to update installment plan: disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockInstallmentService)
+			mockService.On("PayInstallment", "plan-001", "user").Return(model.InstallmentPlan{}, tt.err)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/installments/plan-001/pay", nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
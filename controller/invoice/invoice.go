@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	invoiceService "simple-golang-tdd/service/invoice"
+	limitService "simple-golang-tdd/service/limit"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceController handles merchant invoices and the customer side of payment links
+type InvoiceController struct {
+	invoiceService invoiceService.InvoiceService
+}
+
+func NewInvoiceController(service invoiceService.InvoiceService) *InvoiceController {
+	return &InvoiceController{invoiceService: service}
+}
+
+// CreateInvoice godoc
+// @Summary      Create Invoice
+// @Description  Creates an invoice for the authenticated merchant. Its token is the shareable payment link customers pay through.
+// @Tags         Merchant
+// @Accept       json
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        body  body  dto.InvoiceRequest  true  "Invoice Request"
+// @Success      201  {object} dto.SuccessResponse{data=model.Invoice}  "invoice created"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/invoices [post]
+func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
+	var invoiceRequest dto.InvoiceRequest
+	if err := c.BindJSON(&invoiceRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	merchantID := c.GetString("merchant_id")
+
+	invoice, err := ic.invoiceService.CreateInvoice(invoiceRequest, merchantID)
+	if errors.Is(err, invoiceService.ErrInvalidInvoice) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "invoice created", invoice)
+}
+
+// GetInvoice godoc
+// @Summary      Get Invoice
+// @Description  Returns an invoice of the authenticated merchant with the payments made on it
+// @Tags         Merchant
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        id  path  string  true  "Invoice ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Invoice}  "invoice found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/invoices/{id} [get]
+func (ic *InvoiceController) GetInvoice(c *gin.Context) {
+	invoice, err := ic.invoiceService.GetInvoice(c.Param("id"), c.GetString("merchant_id"))
+	if err != nil {
+		utils.ErrorResponse(c, 404, "invoice not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "invoice found", invoice)
+}
+
+// GetInvoiceByToken godoc
+// @Summary      Get Invoice by Payment Link
+// @Description  Returns the invoice behind a payment-link token so the customer can review it before paying
+// @Tags         Customer
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        token  path  string  true  "Payment Link Token"
+// @Success      200  {object} dto.SuccessResponse{data=model.Invoice}  "invoice found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/invoices/{token} [get]
+func (ic *InvoiceController) GetInvoiceByToken(c *gin.Context) {
+	invoice, err := ic.invoiceService.GetInvoiceByToken(c.Param("token"))
+	if err != nil {
+		utils.ErrorResponse(c, 404, "invoice not found")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "invoice found", invoice)
+}
+
+// PayInvoice godoc
+// @Summary      Pay Invoice
+// @Description  Pays the invoice behind a payment-link token with the customer's balance
+// @Tags         Customer
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        token  path  string  true  "Payment Link Token"
+// @Success      200  {object} dto.SuccessResponse{data=model.Payment}  "payment successful"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse  "spending limit exceeded"
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      409  {object} dto.ErrorResponse  "invoice already paid"
+// @Failure      410  {object} dto.ErrorResponse  "invoice expired"
+// @Router       /api/v1/customer/invoices/{token}/pay [post]
+func (ic *InvoiceController) PayInvoice(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return
+	}
+
+	strUsername, _ := username.(string)
+
+	payment, err := ic.invoiceService.PayInvoice(c.Param("token"), strUsername)
+	var limitErr *limitService.LimitError
+	switch {
+	case err == nil:
+		utils.SuccessResponse(c, 200, "payment successful", payment)
+	case errors.Is(err, invoiceService.ErrInvoiceNotFound):
+		utils.ErrorResponse(c, 404, "invoice not found")
+	case errors.Is(err, model.ErrInvoicePaid):
+		utils.ErrorResponse(c, 409, err.Error())
+	case errors.Is(err, model.ErrInvoiceExpired):
+		utils.ErrorResponse(c, 410, err.Error())
+	case errors.As(err, &limitErr):
+		utils.ErrorCodeResponse(c, 403, limitErr.Code, limitErr.Message)
+	default:
+		utils.ErrorResponse(c, 400, err.Error())
+	}
+}
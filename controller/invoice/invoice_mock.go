@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInvoiceService is a mock of the InvoiceService interface
+type MockInvoiceService struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceService) CreateInvoice(request dto.InvoiceRequest, merchantID string) (model.Invoice, error) {
+	args := m.Called(request, merchantID)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) GetInvoice(id string, merchantID string) (model.Invoice, error) {
+	args := m.Called(id, merchantID)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) GetInvoiceByToken(token string) (model.Invoice, error) {
+	args := m.Called(token)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceService) PayInvoice(token string, username string) (model.Payment, error) {
+	args := m.Called(token, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// MockMerchantRepository is a mock of the MerchantRepository interface, used
+// to authenticate merchants through the API key middleware
+type MockMerchantRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	invoiceService "simple-golang-tdd/service/invoice"
+	limitService "simple-golang-tdd/service/limit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeAPIKey = "mk_test_merchant001"
+
+func setupRouter(service *MockInvoiceService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByAPIKey", fakeAPIKey).Return(model.Merchant{ID: "merchant-001"}, nil)
+	merchantRepository.On("GetMerchantByAPIKey", mock.Anything).Return(model.Merchant{}, errors.New("merchant not found by API key"))
+
+	invoiceCtrl := NewInvoiceController(service)
+
+	merchantGroup := r.Group("/v1/merchant", middleware.MerchantAuthMiddleware(merchantRepository))
+	merchantGroup.POST("/invoices", invoiceCtrl.CreateInvoice)
+	merchantGroup.GET("/invoices/:id", invoiceCtrl.GetInvoice)
+
+	customerGroup := r.Group("/v1/customer", middleware.JWTAuthMiddleware())
+	customerGroup.GET("/invoices/:token", invoiceCtrl.GetInvoiceByToken)
+	customerGroup.POST("/invoices/:token/pay", invoiceCtrl.PayInvoice)
+
+	return r
+}
+
+func customerRequest(t *testing.T, method, url string) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var fakeInvoiceRequest = dto.InvoiceRequest{
+	Amount:      150.0,
+	Description: "Order #1001",
+	ExpiresAt:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	SingleUse:   true,
+}
+
+var fakeInvoice = model.Invoice{
+	ID:          "inv-001",
+	MerchantID:  "merchant-001",
+	Amount:      150.0,
+	Currency:    model.DefaultCurrency,
+	Description: "Order #1001",
+	Token:       "tok-001",
+	SingleUse:   true,
+	ExpiresAt:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	Status:      model.InvoiceStatusOpen,
+	PaymentIDs:  []string{},
+}
+
+func TestCreateInvoice_Success(t *testing.T) {
+	mockService := new(MockInvoiceService)
+	mockService.On("CreateInvoice", fakeInvoiceRequest, "merchant-001").Return(fakeInvoice, nil)
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/merchant/invoices", fakeInvoiceRequest)
+	require.NoError(t, err)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	expected := dto.SuccessResponse{Status: 201, Message: "invoice created", Data: fakeInvoice}
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateInvoice_InvalidAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+	}{
+		{"missing key", ""},
+		{"unknown key", "mk_unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockInvoiceService)
+
+			req, err := utils.NewJSONRequest(http.MethodPost, "/v1/merchant/invoices", fakeInvoiceRequest)
+			require.NoError(t, err)
+			if tt.apiKey != "" {
+				req.Header.Set(middleware.MerchantAPIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			setupRouter(mockService).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			mockService.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateInvoice_MissingDescription(t *testing.T) {
+	mockService := new(MockInvoiceService)
+	request := fakeInvoiceRequest
+	request.Description = ""
+
+	req, err := utils.NewJSONRequest(http.MethodPost, "/v1/merchant/invoices", request)
+	require.NoError(t, err)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "CreateInvoice", mock.Anything, mock.Anything)
+}
+
+func TestGetInvoice_NotFound(t *testing.T) {
+	mockService := new(MockInvoiceService)
+	mockService.On("GetInvoice", "inv-404", "merchant-001").Return(model.Invoice{}, invoiceService.ErrInvoiceNotFound)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/merchant/invoices/inv-404", nil)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetInvoiceByToken_Success(t *testing.T) {
+	mockService := new(MockInvoiceService)
+	mockService.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice, nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, customerRequest(t, http.MethodGet, "/v1/customer/invoices/tok-001"))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "invoice found", Data: fakeInvoice}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestPayInvoice_Success(t *testing.T) {
+	mockService := new(MockInvoiceService)
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 150.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+	mockService.On("PayInvoice", "tok-001", "user").Return(payment, nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, customerRequest(t, http.MethodPost, "/v1/customer/invoices/tok-001/pay"))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "payment successful", Data: payment}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestPayInvoice_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"unknown token", invoiceService.ErrInvoiceNotFound, http.StatusNotFound},
+		{"already paid", model.ErrInvoicePaid, http.StatusConflict},
+		{"expired", model.ErrInvoiceExpired, http.StatusGone},
+		{"limit exceeded", &limitService.LimitError{Code: limitService.CodeDailyAmountLimitExceeded, Message: "daily limit"}, http.StatusForbidden},
+		{"payment failed", errors.New("insufficient balance"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockInvoiceService)
+			mockService.On("PayInvoice", "tok-001", "user").Return(model.Payment{}, tt.err)
+
+			rec := httptest.NewRecorder()
+			setupRouter(mockService).ServeHTTP(rec, customerRequest(t, http.MethodPost, "/v1/customer/invoices/tok-001/pay"))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
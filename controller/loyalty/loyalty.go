@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	loyaltyService "simple-golang-tdd/service/loyalty"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoyaltyController handles the customer's loyalty points and cashback
+type LoyaltyController struct {
+	loyaltyService loyaltyService.LoyaltyService
+}
+
+func NewLoyaltyController(service loyaltyService.LoyaltyService) *LoyaltyController {
+	return &LoyaltyController{loyaltyService: service}
+}
+
+// GetLoyalty godoc
+// @Summary      Get Loyalty Rewards
+// @Description  Returns the points of the logged in customer together with the rewards still held and already credited
+// @Tags         Loyalty
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Success      200  {object} dto.SuccessResponse{data=model.LoyaltySummary}  "loyalty found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/loyalty [get]
+func (lc *LoyaltyController) GetLoyalty(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	summary, err := lc.loyaltyService.GetLoyalty(username)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "loyalty found", summary)
+}
+
+// RedeemPoints godoc
+// @Summary      Redeem Loyalty Points
+// @Description  Converts loyalty points into wallet balance
+// @Tags         Loyalty
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.RedeemPointsRequest  true  "Redeem Points Request"
+// @Success      200  {object} dto.SuccessResponse{data=model.LoyaltySummary}  "points redeemed"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/loyalty/redeem [post]
+func (lc *LoyaltyController) RedeemPoints(c *gin.Context) {
+	var redeemRequest dto.RedeemPointsRequest
+	if err := c.BindJSON(&redeemRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	summary, err := lc.loyaltyService.RedeemPoints(redeemRequest, username)
+	if errors.Is(err, loyaltyService.ErrInsufficientPoints) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "points redeemed", summary)
+}
+
+// usernameFromContext returns the username set by the JWT middleware, writing
+// a 401 response when it is missing.
+func usernameFromContext(c *gin.Context) (string, bool) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return "", false
+	}
+
+	strUsername, _ := username.(string)
+	return strUsername, true
+}
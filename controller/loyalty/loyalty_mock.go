@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLoyaltyService is a mock of the LoyaltyService interface
+type MockLoyaltyService struct {
+	mock.Mock
+}
+
+func (m *MockLoyaltyService) AccrueRewards(payment model.Payment) ([]model.Reward, error) {
+	args := m.Called(payment)
+	return args.Get(0).([]model.Reward), args.Error(1)
+}
+
+func (m *MockLoyaltyService) GetLoyalty(username string) (model.LoyaltySummary, error) {
+	args := m.Called(username)
+	return args.Get(0).(model.LoyaltySummary), args.Error(1)
+}
+
+func (m *MockLoyaltyService) RedeemPoints(request dto.RedeemPointsRequest, username string) (model.LoyaltySummary, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.LoyaltySummary), args.Error(1)
+}
+
+func (m *MockLoyaltyService) CreditDueRewards() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockLoyaltyService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
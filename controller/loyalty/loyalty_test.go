@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+
+	loyaltyService "simple-golang-tdd/service/loyalty"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(service *MockLoyaltyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.Use(middleware.JWTAuthMiddleware())
+
+	loyaltyCtrl := NewLoyaltyController(service)
+	r.GET("/v1/customer/loyalty", loyaltyCtrl.GetLoyalty)
+	r.POST("/v1/customer/loyalty/redeem", loyaltyCtrl.RedeemPoints)
+
+	return r
+}
+
+func authorizedRequest(t *testing.T, method, url string, payload interface{}) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	var req *http.Request
+	if payload != nil {
+		req, err = utils.NewJSONRequest(method, url, payload)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestGetLoyalty_Success(t *testing.T) {
+	mockService := new(MockLoyaltyService)
+	summary := model.LoyaltySummary{Points: 500, Balance: 1000.0, PendingPoints: 100}
+	mockService.On("GetLoyalty", "user").Return(summary, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/loyalty", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "loyalty found", Data: summary}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetLoyalty_ServiceFailure(t *testing.T) {
+	mockService := new(MockLoyaltyService)
+	mockService.On("GetLoyalty", "user").Return(model.LoyaltySummary{}, errors.New("failed to get rewards"))
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/loyalty", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestRedeemPoints_Success(t *testing.T) {
+	mockService := new(MockLoyaltyService)
+	request := dto.RedeemPointsRequest{Points: 300}
+	summary := model.LoyaltySummary{Points: 200, Balance: 1300.0}
+	mockService.On("RedeemPoints", request, "user").Return(summary, nil)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/loyalty/redeem", request))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "points redeemed", Data: summary}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestRedeemPoints_InvalidBody(t *testing.T) {
+	for _, payload := range []interface{}{dto.RedeemPointsRequest{Points: 0}, map[string]interface{}{"points": -5}, map[string]interface{}{"points": "many"}} {
+		mockService := new(MockLoyaltyService)
+		rec := httptest.NewRecorder()
+
+		setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/loyalty/redeem", payload))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "RedeemPoints", mock.Anything, mock.Anything)
+	}
+}
+
+func TestRedeemPoints_InsufficientPoints(t *testing.T) {
+	mockService := new(MockLoyaltyService)
+	request := dto.RedeemPointsRequest{Points: 300}
+	serviceErr := fmt.Errorf("%w: 100 points available", loyaltyService.ErrInsufficientPoints)
+	mockService.On("RedeemPoints", request, "user").Return(model.LoyaltySummary{}, serviceErr)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/loyalty/redeem", request))
+
+	expected := dto.ErrorResponse{Status: 400, Message: serviceErr.Error()}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"errors"
+	qrService "simple-golang-tdd/service/qr"
+	"simple-golang-tdd/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QRController handles the QRIS codes merchants show to customers
+type QRController struct {
+	qrService qrService.QRService
+}
+
+func NewQRController(service qrService.QRService) *QRController {
+	return &QRController{qrService: service}
+}
+
+// GenerateQR godoc
+// @Summary      Generate QRIS Code
+// @Description  Returns the QRIS payload of the authenticated merchant. Without an amount the code is static; with one it is dynamic and fixes the amount.
+// @Tags         Merchant
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API Key"
+// @Param        amount  query  number  false  "Amount of a dynamic QR code"
+// @Success      200  {object} dto.SuccessResponse{data=dto.QRResponse}  "QR code generated"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/merchant/qr [get]
+func (qc *QRController) GenerateQR(c *gin.Context) {
+	var amount float64
+	if rawAmount := c.Query("amount"); rawAmount != "" {
+		parsed, err := strconv.ParseFloat(rawAmount, 64)
+		if err != nil || parsed <= 0 {
+			utils.ErrorResponse(c, 400, "invalid amount")
+			return
+		}
+		amount = parsed
+	}
+
+	qr, err := qc.qrService.GenerateQR(c.GetString("merchant_id"), amount)
+	if errors.Is(err, qrService.ErrInvalidQRAmount) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "QR code generated", qr)
+}
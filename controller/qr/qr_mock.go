@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQRService is a mock of the QRService interface
+type MockQRService struct {
+	mock.Mock
+}
+
+func (m *MockQRService) GenerateQR(merchantID string, amount float64) (dto.QRResponse, error) {
+	args := m.Called(merchantID, amount)
+	return args.Get(0).(dto.QRResponse), args.Error(1)
+}
+
+// MockMerchantRepository is a mock of the MerchantRepository interface, used
+// to authenticate merchants through the API key middleware
+type MockMerchantRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
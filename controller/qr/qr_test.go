@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+
+	qrService "simple-golang-tdd/service/qr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const fakeAPIKey = "mk_test_merchant001"
+
+func setupRouter(service *MockQRService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByAPIKey", fakeAPIKey).Return(model.Merchant{ID: "merchant-001"}, nil)
+	merchantRepository.On("GetMerchantByAPIKey", mock.Anything).Return(model.Merchant{}, errors.New("merchant not found by API key"))
+
+	qrCtrl := NewQRController(service)
+	r.GET("/v1/merchant/qr", middleware.MerchantAuthMiddleware(merchantRepository), qrCtrl.GenerateQR)
+
+	return r
+}
+
+func merchantRequest(url string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(middleware.MerchantAPIKeyHeader, fakeAPIKey)
+	return req
+}
+
+func TestGenerateQR_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		amount   float64
+		response dto.QRResponse
+	}{
+		{"static", "/v1/merchant/qr", 0, dto.QRResponse{Payload: "000201010211"}},
+		{"dynamic", "/v1/merchant/qr?amount=150.5", 150.5, dto.QRResponse{Payload: "000201010212", Dynamic: true, Amount: 150.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockQRService)
+			mockService.On("GenerateQR", "merchant-001", tt.amount).Return(tt.response, nil)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, merchantRequest(tt.url))
+
+			expected := dto.SuccessResponse{Status: 200, Message: "QR code generated", Data: tt.response}
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGenerateQR_InvalidAmount(t *testing.T) {
+	for _, amount := range []string{"abc", "-5", "0"} {
+		t.Run(amount, func(t *testing.T) {
+			mockService := new(MockQRService)
+			rec := httptest.NewRecorder()
+
+			setupRouter(mockService).ServeHTTP(rec, merchantRequest("/v1/merchant/qr?amount="+amount))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "GenerateQR", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGenerateQR_RejectedByService(t *testing.T) {
+	mockService := new(MockQRService)
+	serviceErr := fmt.Errorf("%w: amount 10.001 has more than 2 decimals", qrService.ErrInvalidQRAmount)
+	mockService.On("GenerateQR", "merchant-001", 10.001).Return(dto.QRResponse{}, serviceErr)
+	rec := httptest.NewRecorder()
+
+	setupRouter(mockService).ServeHTTP(rec, merchantRequest("/v1/merchant/qr?amount=10.001"))
+
+	expected := dto.ErrorResponse{Status: 400, Message: serviceErr.Error()}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGenerateQR_Unauthorized(t *testing.T) {
+	mockService := new(MockQRService)
+	rec := httptest.NewRecorder()
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/merchant/qr", nil)
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockService.AssertNotCalled(t, "GenerateQR", mock.Anything, mock.Anything)
+}
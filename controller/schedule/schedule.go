@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	scheduleService "simple-golang-tdd/service/schedule"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleController handles the customer's scheduled and recurring payments
+type ScheduleController struct {
+	scheduleService scheduleService.ScheduleService
+}
+
+func NewScheduleController(service scheduleService.ScheduleService) *ScheduleController {
+	return &ScheduleController{scheduleService: service}
+}
+
+// CreateSchedule godoc
+// @Summary      Create Scheduled Payment
+// @Description  Schedules a one-off payment at start_at, or a daily, weekly or monthly payment from start_at until end_at
+// @Tags         Schedule
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        body  body  dto.ScheduleRequest  true  "Schedule Request"
+// @Success      201  {object} dto.SuccessResponse{data=model.Schedule}  "schedule created"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/schedules [post]
+func (sc *ScheduleController) CreateSchedule(c *gin.Context) {
+	var scheduleRequest dto.ScheduleRequest
+	if err := c.BindJSON(&scheduleRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduleService.CreateSchedule(scheduleRequest, username)
+	if errors.Is(err, scheduleService.ErrInvalidSchedule) {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "schedule created", schedule)
+}
+
+// GetSchedules godoc
+// @Summary      List Scheduled Payments
+// @Description  Returns the scheduled payments of the logged in customer
+// @Tags         Schedule
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Success      200  {object} dto.SuccessResponse{data=[]model.Schedule}  "schedules found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/schedules [get]
+func (sc *ScheduleController) GetSchedules(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	schedules, err := sc.scheduleService.GetSchedules(username)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "schedules found", schedules)
+}
+
+// GetSchedule godoc
+// @Summary      Get Scheduled Payment
+// @Description  Returns a scheduled payment of the logged in customer with the outcome of its last run
+// @Tags         Schedule
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Schedule ID"
+// @Success      200  {object} dto.SuccessResponse{data=model.Schedule}  "schedule found"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/schedules/{id} [get]
+func (sc *ScheduleController) GetSchedule(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduleService.GetSchedule(c.Param("id"), username)
+	if errors.Is(err, scheduleService.ErrScheduleNotFound) {
+		utils.ErrorResponse(c, 404, "schedule not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "schedule found", schedule)
+}
+
+// UpdateSchedule godoc
+// @Summary      Update Scheduled Payment
+// @Description  Replaces a scheduled payment and restarts it from start_at
+// @Tags         Schedule
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Schedule ID"
+// @Param        body  body  dto.ScheduleRequest  true  "Schedule Request"
+// @Success      200  {object} dto.SuccessResponse{data=model.Schedule}  "schedule updated"
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/schedules/{id} [put]
+func (sc *ScheduleController) UpdateSchedule(c *gin.Context) {
+	var scheduleRequest dto.ScheduleRequest
+	if err := c.BindJSON(&scheduleRequest); err != nil {
+		utils.ErrorResponse(c, 400, "invalid request body")
+		return
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduleService.UpdateSchedule(c.Param("id"), scheduleRequest, username)
+	switch {
+	case errors.Is(err, scheduleService.ErrScheduleNotFound):
+		utils.ErrorResponse(c, 404, "schedule not found")
+		return
+	case errors.Is(err, scheduleService.ErrInvalidSchedule):
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "schedule updated", schedule)
+}
+
+// DeleteSchedule godoc
+// @Summary      Delete Scheduled Payment
+// @Description  Cancels a scheduled payment; payments already made are kept
+// @Tags         Schedule
+// @Produce      json
+// @Param        Authorization header string true "Bearer Token"
+// @Param        id  path  string  true  "Schedule ID"
+// @Success      200  {object} dto.SuccessResponse  "schedule deleted"
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /api/v1/customer/schedules/{id} [delete]
+func (sc *ScheduleController) DeleteSchedule(c *gin.Context) {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return
+	}
+
+	err := sc.scheduleService.DeleteSchedule(c.Param("id"), username)
+	if errors.Is(err, scheduleService.ErrScheduleNotFound) {
+		utils.ErrorResponse(c, 404, "schedule not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "schedule deleted", nil)
+}
+
+// usernameFromContext returns the username set by the JWT middleware, writing
+// a 401 response when it is missing.
+func usernameFromContext(c *gin.Context) (string, bool) {
+	username, exists := c.Get("username")
+	if !exists {
+		utils.ErrorResponse(c, 401, "Unauthorized: Invalid username in token")
+		return "", false
+	}
+
+	strUsername, _ := username.(string)
+	return strUsername, true
+}
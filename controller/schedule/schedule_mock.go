@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockScheduleService is a mock of the ScheduleService interface
+type MockScheduleService struct {
+	mock.Mock
+}
+
+func (m *MockScheduleService) CreateSchedule(request dto.ScheduleRequest, username string) (model.Schedule, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Schedule), args.Error(1)
+}
+
+func (m *MockScheduleService) GetSchedules(username string) ([]model.Schedule, error) {
+	args := m.Called(username)
+	return args.Get(0).([]model.Schedule), args.Error(1)
+}
+
+func (m *MockScheduleService) GetSchedule(id string, username string) (model.Schedule, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Schedule), args.Error(1)
+}
+
+func (m *MockScheduleService) UpdateSchedule(id string, request dto.ScheduleRequest, username string) (model.Schedule, error) {
+	args := m.Called(id, request, username)
+	return args.Get(0).(model.Schedule), args.Error(1)
+}
+
+func (m *MockScheduleService) DeleteSchedule(id string, username string) error {
+	args := m.Called(id, username)
+	return args.Error(0)
+}
+
+func (m *MockScheduleService) RunDueSchedules() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockScheduleService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
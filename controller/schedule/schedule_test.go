@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/middleware"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+	"time"
+
+	scheduleService "simple-golang-tdd/service/schedule"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(service *MockScheduleService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.Use(middleware.JWTAuthMiddleware())
+
+	scheduleCtrl := NewScheduleController(service)
+	r.POST("/v1/customer/schedules", scheduleCtrl.CreateSchedule)
+	r.GET("/v1/customer/schedules", scheduleCtrl.GetSchedules)
+	r.GET("/v1/customer/schedules/:id", scheduleCtrl.GetSchedule)
+	r.PUT("/v1/customer/schedules/:id", scheduleCtrl.UpdateSchedule)
+	r.DELETE("/v1/customer/schedules/:id", scheduleCtrl.DeleteSchedule)
+
+	return r
+}
+
+func authorizedRequest(t *testing.T, method, url string, payload interface{}) *http.Request {
+	t.Helper()
+
+	token, err := utils.GenerateAccessToken("user")
+	require.NoError(t, err)
+
+	var req *http.Request
+	if payload != nil {
+		req, err = utils.NewJSONRequest(method, url, payload)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+var (
+	fakeStartAt         = time.Date(2030, 1, 31, 9, 0, 0, 0, time.UTC)
+	fakeScheduleRequest = dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "monthly", StartAt: fakeStartAt}
+	fakeSchedule        = model.Schedule{
+		ID:         "sched-001",
+		CustomerID: "cust-001",
+		Username:   "user",
+		MerchantID: "merchant-001",
+		Amount:     100,
+		Frequency:  model.ScheduleFrequencyMonthly,
+		StartAt:    fakeStartAt,
+		Status:     model.ScheduleStatusActive,
+		NextRunAt:  fakeStartAt,
+	}
+)
+
+func TestCreateSchedule_Success(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("CreateSchedule", fakeScheduleRequest, "user").Return(fakeSchedule, nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/schedules", fakeScheduleRequest))
+
+	expected := dto.SuccessResponse{Status: 201, Message: "schedule created", Data: fakeSchedule}
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateSchedule_InvalidBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"missing merchant", dto.ScheduleRequest{Amount: 100, Frequency: "daily", StartAt: fakeStartAt}},
+		{"unknown frequency", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "yearly", StartAt: fakeStartAt}},
+		{"negative amount", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: -1, Frequency: "daily", StartAt: fakeStartAt}},
+		{"missing start", map[string]interface{}{"merchant_id": "merchant-001", "amount": 100, "frequency": "daily"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockScheduleService)
+
+			rec := httptest.NewRecorder()
+			setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/schedules", tt.payload))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "CreateSchedule", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateSchedule_RejectedByService(t *testing.T) {
+	mockService := new(MockScheduleService)
+	serviceErr := fmt.Errorf("%w: start_at must be in the future", scheduleService.ErrInvalidSchedule)
+	mockService.On("CreateSchedule", fakeScheduleRequest, "user").Return(model.Schedule{}, serviceErr)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPost, "/v1/customer/schedules", fakeScheduleRequest))
+
+	expected := dto.ErrorResponse{Status: 400, Message: serviceErr.Error()}
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetSchedules_Success(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("GetSchedules", "user").Return([]model.Schedule{fakeSchedule}, nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/schedules", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "schedules found", Data: []model.Schedule{fakeSchedule}}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestGetSchedule_NotFound(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("GetSchedule", "sched-404", "user").Return(model.Schedule{}, scheduleService.ErrScheduleNotFound)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/v1/customer/schedules/sched-404", nil))
+
+	expected := dto.ErrorResponse{Status: 404, Message: "schedule not found"}
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestUpdateSchedule_Success(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("UpdateSchedule", "sched-001", fakeScheduleRequest, "user").Return(fakeSchedule, nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPut, "/v1/customer/schedules/sched-001", fakeScheduleRequest))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "schedule updated", Data: fakeSchedule}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+}
+
+func TestUpdateSchedule_NotFound(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("UpdateSchedule", "sched-404", fakeScheduleRequest, "user").Return(model.Schedule{}, scheduleService.ErrScheduleNotFound)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodPut, "/v1/customer/schedules/sched-404", fakeScheduleRequest))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteSchedule_Success(t *testing.T) {
+	mockService := new(MockScheduleService)
+	mockService.On("DeleteSchedule", "sched-001", "user").Return(nil)
+
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, authorizedRequest(t, http.MethodDelete, "/v1/customer/schedules/sched-001", nil))
+
+	expected := dto.SuccessResponse{Status: 200, Message: "schedule deleted"}
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, utils.MarshalJSON(t, expected), rec.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteSchedule_InvalidToken(t *testing.T) {
+	mockService := new(MockScheduleService)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/v1/customer/schedules/sched-001", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec := httptest.NewRecorder()
+	setupRouter(mockService).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockService.AssertNotCalled(t, "DeleteSchedule", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, registered as "sqlite"
+)
+
+// Querier is what the SQLite repositories need from a *sql.DB or a *sql.Tx, so
+// the same repository code runs inside and outside a transaction.
+type Querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Open opens the SQLite database at path, creating it if needed, and migrates
+// its schema to the latest version. Transactions take the write lock when they
+// begin, and writers wait up to 5 seconds for one another instead of failing.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error while opening database: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenReadOnly opens the existing SQLite database at path for reading only,
+// so it can run next to the process that writes it. Its schema must already
+// be at the latest version.
+func OpenReadOnly(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?mode=ro&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error while opening database: %v", err)
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if latest := migrations[len(migrations)-1].version; version != latest {
+		db.Close()
+		return nil, fmt.Errorf("database schema is at version %d, want %d", version, latest)
+	}
+
+	return db, nil
+}
+
+// WithTransaction runs fn in one transaction, committing it when fn returns
+// nil and rolling it back otherwise.
+func WithTransaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error while starting transaction: %v", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error while committing transaction: %v", err)
+	}
+	return nil
+}
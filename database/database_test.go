@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk membuka database SQLite baru di direktori sementara
+func setupDatabase(t *testing.T) *sql.DB {
+	db, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count))
+	return count
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestOpen_MigratesToLatestVersion(t *testing.T) {
+	db := setupDatabase(t)
+
+	version, err := SchemaVersion(db)
+
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].version, version)
+	assert.Equal(t, 0, countRows(t, db, "customers"))
+	assert.Equal(t, 0, countRows(t, db, "merchants"))
+	assert.Equal(t, 0, countRows(t, db, "histories"))
+}
+
+func TestMigrate_AppliesEachMigrationOnce(t *testing.T) {
+	db := setupDatabase(t)
+
+	require.NoError(t, Migrate(db))
+	require.NoError(t, Migrate(db))
+
+	assert.Equal(t, len(migrations), countRows(t, db, "schema_migrations"))
+}
+
+func TestMigrate_AppliesOnlyNewMigrations(t *testing.T) {
+	db := setupDatabase(t)
+	next := append(append([]migration{}, migrations...), migration{
+		version:     len(migrations) + 1,
+		description: "add customers email",
+		statements:  []string{"ALTER TABLE customers ADD COLUMN email TEXT NOT NULL DEFAULT ''"},
+	})
+
+	require.NoError(t, migrate(db, next))
+
+	version, err := SchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, len(next), version)
+	_, err = db.Exec("INSERT INTO customers (id, name, username, password, email) VALUES ('cust-001', 'John', 'johndoe', 'x', 'john@example.com')")
+	assert.NoError(t, err)
+}
+
+func TestWithTransaction_Commit(t *testing.T) {
+	db := setupDatabase(t)
+
+	err := WithTransaction(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO merchants (id, name) VALUES ('merchant-001', 'Shop')")
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, countRows(t, db, "merchants"))
+}
+
+func TestOpenReadOnly_ReadsButDoesNotWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("INSERT INTO merchants (id, name) VALUES ('merchant-001', 'Shop')")
+	require.NoError(t, err)
+
+	readOnly, err := OpenReadOnly(path)
+	require.NoError(t, err)
+	defer readOnly.Close()
+
+	assert.Equal(t, 1, countRows(t, readOnly, "merchants"))
+	_, err = readOnly.Exec("INSERT INTO merchants (id, name) VALUES ('merchant-002', 'Cafe')")
+	assert.Error(t, err)
+}
+
+// ========== ERROR CASES ==========
+
+func TestMigrate_FailingMigrationIsRolledBack(t *testing.T) {
+	db := setupDatabase(t)
+	next := append(append([]migration{}, migrations...), migration{
+		version:     len(migrations) + 1,
+		description: "broken",
+		statements: []string{
+			"CREATE TABLE payments (id TEXT PRIMARY KEY)",
+			"ALTER TABLE missing ADD COLUMN x TEXT",
+		},
+	})
+
+	err := migrate(db, next)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("error while applying migration %d (broken)", len(next)))
+	version, err := SchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), version)
+	_, err = db.Exec("SELECT * FROM payments")
+	assert.Error(t, err)
+}
+
+func TestWithTransaction_Rollback(t *testing.T) {
+	db := setupDatabase(t)
+
+	err := WithTransaction(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO merchants (id, name) VALUES ('merchant-001', 'Shop')"); err != nil {
+			return err
+		}
+		return errors.New("insufficient balance")
+	})
+
+	require.EqualError(t, err, "insufficient balance")
+	assert.Equal(t, 0, countRows(t, db, "merchants"))
+}
+
+func TestOpen_Error(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "missing", "app.db"))
+
+	require.Error(t, err)
+	assert.Nil(t, db)
+}
+
+func TestOpenReadOnly_MissingDatabase(t *testing.T) {
+	db, err := OpenReadOnly(filepath.Join(t.TempDir(), "app.db"))
+
+	require.Error(t, err)
+	assert.Nil(t, db)
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one schema change. Migrations are applied in version order and
+// each one only once, recorded in the schema_migrations table.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations lists every schema change. Append new ones with the next version;
+// never edit one that has been released.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create customers, merchants and histories",
+		statements: []string{
+			`CREATE TABLE customers (
+				id       TEXT PRIMARY KEY,
+				name     TEXT NOT NULL,
+				username TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				tier     TEXT NOT NULL DEFAULT '',
+				balance  REAL NOT NULL DEFAULT 0,
+				wallets  TEXT NOT NULL DEFAULT '{}',
+				points   INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE merchants (
+				id           TEXT PRIMARY KEY,
+				name         TEXT NOT NULL,
+				bank_account TEXT NOT NULL DEFAULT '',
+				bank_name    TEXT NOT NULL DEFAULT '',
+				category     TEXT NOT NULL DEFAULT '',
+				currency     TEXT NOT NULL DEFAULT '',
+				api_key_hash TEXT NOT NULL DEFAULT '',
+				balance      REAL NOT NULL DEFAULT 0,
+				wallets      TEXT NOT NULL DEFAULT '{}'
+			)`,
+			`CREATE INDEX merchants_api_key_hash ON merchants (api_key_hash) WHERE api_key_hash <> ''`,
+			`CREATE TABLE histories (
+				seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+				id          TEXT NOT NULL,
+				customer_id TEXT NOT NULL DEFAULT '',
+				status      TEXT NOT NULL DEFAULT '',
+				action      TEXT NOT NULL DEFAULT '',
+				details     TEXT NOT NULL DEFAULT 'null',
+				timestamp   TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX histories_customer_id ON histories (customer_id)`,
+		},
+	},
+	{
+		version:     2,
+		description: "make customer usernames unique regardless of case",
+		statements: []string{
+			`CREATE UNIQUE INDEX customers_username_nocase ON customers (username COLLATE NOCASE)`,
+		},
+	},
+	{
+		version:     3,
+		description: "add record versions to customers and merchants",
+		statements: []string{
+			`ALTER TABLE customers ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE merchants ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     4,
+		description: "create promotions and promotion redemptions",
+		statements: []string{
+			`CREATE TABLE promotions (
+				id             TEXT PRIMARY KEY,
+				code           TEXT NOT NULL UNIQUE COLLATE NOCASE,
+				description    TEXT NOT NULL DEFAULT '',
+				discount_type  TEXT NOT NULL DEFAULT '',
+				discount_value REAL NOT NULL DEFAULT 0,
+				max_discount   REAL NOT NULL DEFAULT 0,
+				min_spend      REAL NOT NULL DEFAULT 0,
+				merchant_ids   TEXT NOT NULL DEFAULT '[]',
+				usage_limit    INTEGER NOT NULL DEFAULT 0,
+				per_user_limit INTEGER NOT NULL DEFAULT 0,
+				starts_at      TEXT NOT NULL DEFAULT '',
+				ends_at        TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE promotion_redemptions (
+				promotion_id TEXT NOT NULL REFERENCES promotions (id),
+				payment_id   TEXT NOT NULL,
+				customer_id  TEXT NOT NULL DEFAULT '',
+				merchant_id  TEXT NOT NULL DEFAULT '',
+				amount       REAL NOT NULL DEFAULT 0,
+				discount     REAL NOT NULL DEFAULT 0,
+				redeemed_at  TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (promotion_id, payment_id)
+			)`,
+		},
+	},
+}
+
+// Migrate brings the schema of db up to the latest version.
+func Migrate(db *sql.DB) error {
+	return migrate(db, migrations)
+}
+
+func migrate(db *sql.DB, migrations []migration) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error while creating schema_migrations: %v", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		err := WithTransaction(db, func(tx *sql.Tx) error {
+			for _, statement := range m.statements {
+				if _, err := tx.Exec(statement); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)",
+				m.version, m.description, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error while applying migration %d (%s): %v", m.version, m.description, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the last migration applied to db, or 0
+// for a new database.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error while reading schema version: %v", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,30 @@
+package dto
+
+type DisputeRequest struct {
+	PaymentID   string            `json:"payment_id"  binding:"required"`
+	Reason      string            `json:"reason"  binding:"required,oneof=not_received not_as_described duplicate unauthorized other"`
+	Description string            `json:"description"  binding:"required,max=1000"`
+	Evidence    []EvidenceRequest `json:"evidence,omitempty"  binding:"omitempty,max=10,dive"`
+}
+
+// EvidenceRequest describes an uploaded file; only its metadata is stored.
+type EvidenceRequest struct {
+	FileName    string `json:"file_name"  binding:"required,max=255"`
+	ContentType string `json:"content_type"  binding:"required"`
+	Size        int64  `json:"size"  binding:"required,gt=0,max=10485760"` // at most 10 MB
+	URL         string `json:"url"  binding:"required,url"`
+}
+
+type EvidenceListRequest struct {
+	Evidence []EvidenceRequest `json:"evidence"  binding:"required,min=1,max=10,dive"`
+}
+
+type DisputeResponseRequest struct {
+	Response string            `json:"response"  binding:"required,max=1000"`
+	Evidence []EvidenceRequest `json:"evidence,omitempty"  binding:"omitempty,max=10,dive"`
+}
+
+type DisputeResolutionRequest struct {
+	Outcome string `json:"outcome"  binding:"required,oneof=refund uphold"`
+	Note    string `json:"note"  binding:"required,max=1000"`
+}
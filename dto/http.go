@@ -2,6 +2,7 @@ package dto
 
 type ErrorResponse struct {
 	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
 
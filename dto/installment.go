@@ -0,0 +1,9 @@
+package dto
+
+// InstallmentPaymentRequest buys now and pays later: the merchant is paid in
+// full and the customer repays Amount in monthly installments.
+type InstallmentPaymentRequest struct {
+	MerchantID   string  `json:"merchant_id"  binding:"required"`
+	Amount       float64 `json:"amount"  binding:"required,gt=0"`
+	Installments int     `json:"installments"  binding:"required,oneof=3 6 12"`
+}
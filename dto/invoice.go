@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type InvoiceRequest struct {
+	Amount      float64   `json:"amount"  binding:"required,gt=0"`
+	Currency    string    `json:"currency,omitempty"  binding:"omitempty,iso4217"` // defaults to IDR
+	Description string    `json:"description"  binding:"required,max=255"`
+	ExpiresAt   time.Time `json:"expires_at"  binding:"required"`
+	SingleUse   bool      `json:"single_use"`
+}
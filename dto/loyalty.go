@@ -0,0 +1,6 @@
+package dto
+
+// RedeemPointsRequest converts loyalty points into wallet balance.
+type RedeemPointsRequest struct {
+	Points int64 `json:"points"  binding:"required,gt=0"`
+}
@@ -2,5 +2,35 @@ package dto
 
 type PaymentRequest struct {
 	MerchantID string  `json:"merchant_id"  binding:"required"`
-	Amount     float64 `json:"amount"  binding:"required"`
+	Amount     float64 `json:"amount"  binding:"required,gt=0"`
+	Currency   string  `json:"currency,omitempty"  binding:"omitempty,iso4217"`  // defaults to IDR
+	PromoCode  string  `json:"promo_code,omitempty"  binding:"omitempty,max=32"` // only for IDR payments, rejected with any other currency
+	Escrow     bool    `json:"escrow,omitempty"`                                 // holds the funds until the customer confirms delivery
+}
+
+// SplitPaymentRequest debits the customer once for Amount and distributes it
+// over the legs, whose amounts must add up to Amount.
+type SplitPaymentRequest struct {
+	Amount   float64           `json:"amount"  binding:"required,gt=0"`
+	Currency string            `json:"currency,omitempty"  binding:"omitempty,iso4217"` // defaults to IDR
+	Legs     []SplitPaymentLeg `json:"legs"  binding:"required,min=2,dive"`
+}
+
+type SplitPaymentLeg struct {
+	MerchantID string  `json:"merchant_id"  binding:"required"`
+	Amount     float64 `json:"amount"  binding:"required,gt=0"`
+}
+
+// QRPaymentRequest pays the merchant in a scanned QR payload. Amount is only
+// needed for static QR codes; a dynamic one carries its own amount.
+type QRPaymentRequest struct {
+	Payload   string  `json:"payload"  binding:"required,max=512"`
+	Amount    float64 `json:"amount,omitempty"  binding:"omitempty,gt=0"`
+	PromoCode string  `json:"promo_code,omitempty"  binding:"omitempty,max=32"` // only for QR codes in IDR, rejected with any other currency
+}
+
+type QRResponse struct {
+	Payload string  `json:"payload"`
+	Dynamic bool    `json:"dynamic"`
+	Amount  float64 `json:"amount,omitempty"`
 }
@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type ScheduleRequest struct {
+	MerchantID string     `json:"merchant_id"  binding:"required"`
+	Amount     float64    `json:"amount"  binding:"required,gt=0"`
+	Currency   string     `json:"currency,omitempty"  binding:"omitempty,iso4217"` // defaults to IDR
+	Frequency  string     `json:"frequency"  binding:"required,oneof=once daily weekly monthly"`
+	StartAt    time.Time  `json:"start_at"  binding:"required"`
+	EndAt      *time.Time `json:"end_at,omitempty"`
+}
@@ -1,20 +1,60 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"simple-golang-tdd/backup"
 	AuthController "simple-golang-tdd/controller/auth"
+	BatchController "simple-golang-tdd/controller/batch"
 	CustomerController "simple-golang-tdd/controller/customer"
+	DisputeController "simple-golang-tdd/controller/dispute"
+	EscrowController "simple-golang-tdd/controller/escrow"
+	InstallmentController "simple-golang-tdd/controller/installment"
+	InvoiceController "simple-golang-tdd/controller/invoice"
+	LoyaltyController "simple-golang-tdd/controller/loyalty"
+	QRController "simple-golang-tdd/controller/qr"
+	ScheduleController "simple-golang-tdd/controller/schedule"
+	"simple-golang-tdd/database"
 	"simple-golang-tdd/middleware"
 	"simple-golang-tdd/routes"
+	"simple-golang-tdd/utils"
+	"strconv"
+	"time"
 
+	AccountRepository "simple-golang-tdd/repository/account"
+	BatchRepository "simple-golang-tdd/repository/batch"
 	CustomerRepository "simple-golang-tdd/repository/customer"
+	DisputeRepository "simple-golang-tdd/repository/dispute"
+	FeeRepository "simple-golang-tdd/repository/fee"
+	FXRepository "simple-golang-tdd/repository/fx"
 	HistoryRepository "simple-golang-tdd/repository/history"
+	InstallmentRepository "simple-golang-tdd/repository/installment"
+	InvoiceRepository "simple-golang-tdd/repository/invoice"
+	LimitRepository "simple-golang-tdd/repository/limit"
+	LoyaltyRepository "simple-golang-tdd/repository/loyalty"
 	MerchantRepository "simple-golang-tdd/repository/merchant"
+	PaymentRepository "simple-golang-tdd/repository/payment"
+	PromotionRepository "simple-golang-tdd/repository/promotion"
+	RewardRepository "simple-golang-tdd/repository/reward"
+	ScheduleRepository "simple-golang-tdd/repository/schedule"
+	TransactionRepository "simple-golang-tdd/repository/transaction"
 
 	AuthService "simple-golang-tdd/service/auth"
+	BatchService "simple-golang-tdd/service/batch"
 	CustomerService "simple-golang-tdd/service/customer"
+	DisputeService "simple-golang-tdd/service/dispute"
+	EscrowService "simple-golang-tdd/service/escrow"
+	FeeService "simple-golang-tdd/service/fee"
+	FXService "simple-golang-tdd/service/fx"
+	InstallmentService "simple-golang-tdd/service/installment"
+	InvoiceService "simple-golang-tdd/service/invoice"
+	LimitService "simple-golang-tdd/service/limit"
+	LoyaltyService "simple-golang-tdd/service/loyalty"
+	QRService "simple-golang-tdd/service/qr"
+	ScheduleService "simple-golang-tdd/service/schedule"
 
 	_ "simple-golang-tdd/docs"
 
@@ -42,9 +82,47 @@ import (
 func main() {
 
 	// source data path
+	const dataDir = "./data"
 	const customerDataPath = "./data/customers.json"
 	const historyDataPath = "./data/histories.json"
 	const merhacntDataPath = "./data/merchants.json"
+	const paymentDataPath = "./data/payments.json"
+	const accountDataPath = "./data/accounts.json"
+	const feeRuleDataPath = "./data/fee_rules.json"
+	const spendingLimitDataPath = "./data/spending_limits.json"
+	const exchangeRateDataPath = "./data/exchange_rates.json"
+	const scheduleDataPath = "./data/schedules.json"
+	const batchDataPath = "./data/batches.json"
+	const invoiceDataPath = "./data/invoices.json"
+	const promotionDataPath = "./data/promotions.json"
+	const rewardRuleDataPath = "./data/reward_rules.json"
+	const rewardDataPath = "./data/rewards.json"
+	const disputeDataPath = "./data/disputes.json"
+	const installmentPlanDataPath = "./data/installment_plans.json"
+	const defaultSQLitePath = "./data/app.db"
+
+	// READ_ONLY=true serves reads next to another instance that owns ./data:
+	// it skips the data directory lock, rejects writes and runs no background jobs.
+	// Only customers and merchants follow the other instance's writes; every other
+	// JSON file is read once at startup, so restart to see newer payments,
+	// invoices, disputes, schedules, batches, rewards or installment plans
+	readOnly := os.Getenv("READ_ONLY") == "true"
+	if !readOnly {
+		dataLock, err := utils.LockDir(dataDir)
+		if err != nil {
+			log.Fatalf("Failed to lock data directory, is another instance running? Start it with READ_ONLY=true to run alongside: %v", err)
+		}
+		defer dataLock.Unlock()
+	}
+
+	// DATA_ENCRYPTION_KEY_FILE or DATA_ENCRYPTION_KEYS keep utils.EncryptedFiles
+	// (customers.json and merchants.json), and their journals, encrypted at rest
+	// with AES-256-GCM
+	keyring, err := utils.LoadKeyring(os.Getenv("DATA_ENCRYPTION_KEY_FILE"), os.Getenv("DATA_ENCRYPTION_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to load data encryption keys: %v", err)
+	}
+	utils.SetEncryption(keyring, utils.EncryptedPaths(dataDir)...)
 
 	// Membuat router Gin
 	router := gin.Default()
@@ -55,32 +133,210 @@ func main() {
 			cors.Config{
 				AllowOrigins:     []string{"*"},
 				AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-				AllowHeaders:     []string{"Content-Type", "Authorization", "token"}, // Add the "token" header here
+				AllowHeaders:     []string{"Content-Type", "Authorization", "token", middleware.MerchantAPIKeyHeader, middleware.AdminAPIKeyHeader}, // Add the "token" header here
 				AllowCredentials: true,
 			},
 		),
 	)
 
-	customerhRepository, err := CustomerRepository.NewCustomerRepository(customerDataPath)
+	// STORAGE_BACKEND=sqlite keeps customers, merchants, histories and
+	// promotions in the SQLite database at SQLITE_PATH, seeded from the JSON
+	// files on first run
+	var customerhRepository CustomerRepository.CustomerRepository
+	var merchantRepository MerchantRepository.MerchantRepository
+	var historyRepository HistoryRepository.HistoryRepository
+	var promotionRepository PromotionRepository.PromotionRepository
+	var transactor TransactionRepository.Transactor
+	switch storageBackend := os.Getenv("STORAGE_BACKEND"); storageBackend {
+	case "", "json":
+		if readOnly {
+			customerhRepository, err = CustomerRepository.NewReadOnlyCustomerRepository(customerDataPath)
+		} else {
+			customerhRepository, err = CustomerRepository.NewCustomerRepository(customerDataPath)
+		}
+		if err != nil {
+			log.Fatalf("Failed to create customer repository: %v", err)
+		}
+		if readOnly {
+			merchantRepository, err = MerchantRepository.NewReadOnlyMerchantRepository(merhacntDataPath)
+		} else {
+			merchantRepository, err = MerchantRepository.NewMerchantRepository(merhacntDataPath)
+		}
+		if err != nil {
+			log.Fatalf("Failed to create merchant repository: %v", err)
+		}
+		historyRepository, err = HistoryRepository.NewHistoryRepository(historyDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create history repository: %v", err)
+		}
+		promotionRepository, err = PromotionRepository.NewPromotionRepository(promotionDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create promotion repository: %v", err)
+		}
+	case "sqlite":
+		sqlitePath := os.Getenv("SQLITE_PATH")
+		if sqlitePath == "" {
+			sqlitePath = defaultSQLitePath
+		}
+		open := database.Open
+		if readOnly {
+			open = database.OpenReadOnly
+		}
+		db, err := open(sqlitePath)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database: %v", err)
+		}
+		defer db.Close()
+
+		customerhRepository, err = CustomerRepository.NewSQLiteCustomerRepository(db, customerDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create customer repository: %v", err)
+		}
+		merchantRepository, err = MerchantRepository.NewSQLiteMerchantRepository(db, merhacntDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create merchant repository: %v", err)
+		}
+		historyRepository, err = HistoryRepository.NewSQLiteHistoryRepository(db, historyDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create history repository: %v", err)
+		}
+		promotionRepository, err = PromotionRepository.NewSQLitePromotionRepository(db, promotionDataPath)
+		if err != nil {
+			log.Fatalf("Failed to create promotion repository: %v", err)
+		}
+		transactor = TransactionRepository.NewSQLiteTransactor(db)
+	default:
+		log.Fatalf("Unknown storage backend %q", storageBackend)
+	}
+	paymentRepository, err := PaymentRepository.NewPaymentRepository(paymentDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create payment repository: %v", err)
+	}
+	accountRepository, err := AccountRepository.NewAccountRepository(accountDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create account repository: %v", err)
+	}
+	feeRepository, err := FeeRepository.NewFeeRepository(feeRuleDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create fee repository: %v", err)
+	}
+	limitRepository, err := LimitRepository.NewLimitRepository(spendingLimitDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create spending limit repository: %v", err)
+	}
+	fxRepository, err := FXRepository.NewFXRepository(exchangeRateDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create exchange rate repository: %v", err)
+	}
+	scheduleRepository, err := ScheduleRepository.NewScheduleRepository(scheduleDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create schedule repository: %v", err)
+	}
+	batchRepository, err := BatchRepository.NewBatchRepository(batchDataPath)
 	if err != nil {
-		log.Fatalf("Failed to create customer repository: %v", err)
+		log.Fatalf("Failed to create batch repository: %v", err)
 	}
-	merchantRepository, err := MerchantRepository.NewMerchantRepository(merhacntDataPath)
+	invoiceRepository, err := InvoiceRepository.NewInvoiceRepository(invoiceDataPath)
 	if err != nil {
-		log.Fatalf("Failed to create merchant repository: %v", err)
+		log.Fatalf("Failed to create invoice repository: %v", err)
 	}
-	historyRepository, err := HistoryRepository.NewHistoryRepository(historyDataPath)
+	loyaltyRepository, err := LoyaltyRepository.NewLoyaltyRepository(rewardRuleDataPath)
 	if err != nil {
-		log.Fatalf("Failed to create history repository: %v", err)
+		log.Fatalf("Failed to create loyalty repository: %v", err)
+	}
+	rewardRepository, err := RewardRepository.NewRewardRepository(rewardDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create reward repository: %v", err)
+	}
+	disputeRepository, err := DisputeRepository.NewDisputeRepository(disputeDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create dispute repository: %v", err)
+	}
+	installmentRepository, err := InstallmentRepository.NewInstallmentRepository(installmentPlanDataPath)
+	if err != nil {
+		log.Fatalf("Failed to create installment repository: %v", err)
 	}
 
 	authService := AuthService.NewAuthService(customerhRepository)
-	customerService := CustomerService.NewCustomerService(customerhRepository, merchantRepository)
+	feeService := FeeService.NewFeeService(feeRepository)
+	limitService := LimitService.NewLimitService(limitRepository, paymentRepository)
+	fxService := FXService.NewFXService(fxRepository)
+	loyaltyService := LoyaltyService.NewLoyaltyService(loyaltyRepository, rewardRepository, customerhRepository, merchantRepository, paymentRepository, accountRepository, transactor, time.Now)
+	customerService := CustomerService.NewCustomerService(customerhRepository, merchantRepository, paymentRepository, accountRepository, promotionRepository, feeService, limitService, fxService, loyaltyService, transactor)
+	scheduleService := ScheduleService.NewScheduleService(scheduleRepository, customerhRepository, merchantRepository, customerService, time.Now)
+	batchService := BatchService.NewBatchService(batchRepository, customerhRepository, merchantRepository, customerService)
+	invoiceService := InvoiceService.NewInvoiceService(invoiceRepository, customerService, time.Now)
+	qrService := QRService.NewQRService(merchantRepository)
+	disputeService := DisputeService.NewDisputeService(disputeRepository, customerhRepository, customerService, time.Now)
+	escrowService := EscrowService.NewEscrowService(paymentRepository, customerService, time.Now)
+	installmentService := InstallmentService.NewInstallmentService(installmentRepository, customerhRepository, merchantRepository, paymentRepository, accountRepository, feeService, limitService, transactor, time.Now)
 
 	authController := AuthController.NewAuthController(authService)
 	customerController := CustomerController.NewCustomerController(customerService)
+	scheduleController := ScheduleController.NewScheduleController(scheduleService)
+	batchController := BatchController.NewBatchController(batchService)
+	invoiceController := InvoiceController.NewInvoiceController(invoiceService)
+	loyaltyController := LoyaltyController.NewLoyaltyController(loyaltyService)
+	qrController := QRController.NewQRController(qrService)
+	disputeController := DisputeController.NewDisputeController(disputeService)
+	escrowController := EscrowController.NewEscrowController(escrowService)
+	installmentController := InstallmentController.NewInstallmentController(installmentService)
+
+	if !readOnly {
+		// Batches left unfinished by the last run continue in the background
+		if err := batchService.ResumeBatches(); err != nil {
+			log.Fatalf("Failed to resume batches: %v", err)
+		}
+		// Scheduled payments are checked every minute in the background
+		go scheduleService.Start(context.Background(), time.Minute)
+		// Rewards past their holding period are credited every hour
+		go loyaltyService.Start(context.Background(), time.Hour)
+		// Disputes past the merchant response deadline go to review every hour
+		go disputeService.Start(context.Background(), time.Hour)
+		// Escrow payments past their release time are paid out to merchants every hour
+		go escrowService.Start(context.Background(), time.Hour)
+		// Due installments are collected and late fees charged every hour
+		go installmentService.Start(context.Background(), time.Hour)
+
+		// The data directory is backed up into BACKUP_DIR every BACKUP_INTERVAL,
+		// keeping the newest BACKUP_KEEP archives; BACKUP_INTERVAL=0 disables it
+		backupDir := os.Getenv("BACKUP_DIR")
+		if backupDir == "" {
+			backupDir = "./backups"
+		}
+		backupInterval := time.Hour
+		if value := os.Getenv("BACKUP_INTERVAL"); value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				log.Fatalf("Invalid BACKUP_INTERVAL: %v", err)
+			}
+			backupInterval = interval
+		}
+		backupKeep := 24
+		if value := os.Getenv("BACKUP_KEEP"); value != "" {
+			keep, err := strconv.Atoi(value)
+			if err != nil {
+				log.Fatalf("Invalid BACKUP_KEEP: %v", err)
+			}
+			backupKeep = keep
+		}
+		if backupInterval > 0 {
+			archiver := backup.NewArchiver(dataDir, backupDir, backupKeep, time.Now)
+			go archiver.Start(context.Background(), backupInterval)
+		}
+
+		// Requests that write wait while a backup is taken, and the backup
+		// waits for those in flight, so it holds the data at one point in time
+		router.Use(middleware.WriteMiddleware())
+		router.Use(middleware.HistoryLoggerMiddleware(historyRepository))
+	}
+
+	// In read-only mode login still works and every other group only serves reads
+	var readOnlyGuard []gin.HandlerFunc
+	if readOnly {
+		readOnlyGuard = append(readOnlyGuard, middleware.ReadOnlyMiddleware())
+	}
 
-	router.Use(middleware.HistoryLoggerMiddleware(historyRepository))
 	noAuthGroup := router.Group("/user/v1")
 	noAuthGroup.Use()
 	{
@@ -90,14 +346,41 @@ func main() {
 	// Define the authGroup (authenticated routes)
 	authGroup := router.Group("/api/v1/")
 	authGroup.Use(middleware.JWTAuthMiddleware()) // Use authentication middleware here
+	authGroup.Use(readOnlyGuard...)
 	{
 
 		routes.SetupCustomerRoutes(authGroup, customerController)
+		routes.SetupScheduleRoutes(authGroup, scheduleController)
+		routes.SetupBatchRoutes(authGroup, batchController)
+		routes.SetupCustomerInvoiceRoutes(authGroup, invoiceController)
+		routes.SetupLoyaltyRoutes(authGroup, loyaltyController)
+		routes.SetupCustomerDisputeRoutes(authGroup, disputeController)
+		routes.SetupCustomerEscrowRoutes(authGroup, escrowController)
+		routes.SetupInstallmentRoutes(authGroup, installmentController)
 		// Add routes that require authentication (e.g., user profile, protected resources)
 		// Example:
 		// authGroup.GET("/user", userController.GetUser)
 	}
 
+	// Define the merchantGroup (routes authenticated with a merchant API key)
+	merchantGroup := router.Group("/api/v1/merchant")
+	merchantGroup.Use(middleware.MerchantAuthMiddleware(merchantRepository))
+	merchantGroup.Use(readOnlyGuard...)
+	{
+		routes.SetupMerchantInvoiceRoutes(merchantGroup, invoiceController)
+		routes.SetupMerchantQRRoutes(merchantGroup, qrController)
+		routes.SetupMerchantDisputeRoutes(merchantGroup, disputeController)
+		routes.SetupMerchantEscrowRoutes(merchantGroup, escrowController)
+	}
+
+	// Define the adminGroup (routes authenticated with the admin API key)
+	adminGroup := router.Group("/api/v1/admin")
+	adminGroup.Use(middleware.AdminAuthMiddleware(os.Getenv("ADMIN_API_KEY")))
+	adminGroup.Use(readOnlyGuard...)
+	{
+		routes.SetupAdminDisputeRoutes(adminGroup, disputeController)
+	}
+
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	port := "8080"
 	// Menjalankan server
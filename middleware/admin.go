@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAPIKeyHeader is the header admins send the admin API key in.
+const AdminAPIKeyHeader = "X-Admin-Key"
+
+// AdminAuthMiddleware only lets through requests carrying adminKey. Every
+// request is rejected when adminKey is empty, so admin endpoints stay closed
+// until a key is configured.
+func AdminAuthMiddleware(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(AdminAPIKeyHeader)
+		if apiKey == "" {
+			utils.ErrorResponse(c, 401, "Admin key header is missing")
+			c.Abort()
+			return
+		}
+
+		if adminKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(adminKey)) != 1 {
+			utils.ErrorResponse(c, 401, "Unauthorized: invalid admin key")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
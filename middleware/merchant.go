@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantAPIKeyHeader is the header merchants send their API key in.
+const MerchantAPIKeyHeader = "X-API-Key"
+
+// MerchantAuthMiddleware authenticates merchants by API key and stores the
+// merchant's ID in the context as "merchant_id".
+func MerchantAuthMiddleware(merchantRepository merchantRepo.MerchantRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(MerchantAPIKeyHeader)
+		if apiKey == "" {
+			utils.ErrorResponse(c, 401, "API key header is missing")
+			c.Abort()
+			return
+		}
+
+		merchant, err := merchantRepository.GetMerchantByAPIKey(apiKey)
+		if err != nil {
+			utils.ErrorResponse(c, 401, "Unauthorized: invalid API key")
+			c.Abort()
+			return
+		}
+
+		c.Set("merchant_id", merchant.ID)
+		c.Next()
+	}
+}
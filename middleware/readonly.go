@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyMiddleware rejects every request that could change data, so an
+// instance started in read-only mode only serves reads.
+func ReadOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+		default:
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Server is running in read-only mode")
+			c.Abort()
+		}
+	}
+}
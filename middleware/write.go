@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"simple-golang-tdd/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WriteMiddleware marks every request that could change data as a write, so
+// a backup waits for the requests in flight and captures the data between
+// them.
+func WriteMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+		default:
+			end := utils.BeginWrite()
+			defer end()
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+type AccountType string
+
+const (
+	AccountTypeRevenue AccountType = "revenue"
+	AccountTypeExpense AccountType = "expense"
+	AccountTypeEscrow  AccountType = "escrow"
+	AccountTypeCredit  AccountType = "credit"
+)
+
+// PlatformRevenueAccountID is the account that collects the platform's fees.
+const PlatformRevenueAccountID = "platform-revenue"
+
+// PlatformEscrowAccountID is the account that holds escrow payments until they
+// are released to the merchant or returned to the customer.
+const PlatformEscrowAccountID = "platform-escrow"
+
+// PlatformCreditAccountID is the credit line that pays merchants up front for
+// pay-later purchases. Its balance is what customers still have to repay.
+const PlatformCreditAccountID = "platform-credit"
+
+// Account is an internal platform account, as opposed to a customer or merchant wallet.
+type Account struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Type    AccountType `json:"type"`
+	Balance float64     `json:"balance"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type BatchStatus string
+
+const (
+	BatchStatusPending    BatchStatus = "pending"
+	BatchStatusProcessing BatchStatus = "processing"
+	BatchStatusCompleted  BatchStatus = "completed"
+)
+
+type BatchLineStatus string
+
+const (
+	BatchLineStatusPending BatchLineStatus = "pending"
+	// BatchLineStatusProcessing is saved before the line is paid, so a line
+	// found in it after a restart may or may not have been paid.
+	BatchLineStatusProcessing BatchLineStatus = "processing"
+	BatchLineStatusSucceeded  BatchLineStatus = "succeeded"
+	BatchLineStatusFailed     BatchLineStatus = "failed"
+)
+
+// BatchLine is one payment instruction of a batch and its outcome.
+type BatchLine struct {
+	Line       int             `json:"line"` // 1-based position in the submitted batch
+	MerchantID string          `json:"merchant_id"`
+	Amount     float64         `json:"amount"`
+	Currency   string          `json:"currency,omitempty"`
+	PromoCode  string          `json:"promo_code,omitempty"`
+	Escrow     bool            `json:"escrow,omitempty"`
+	Status     BatchLineStatus `json:"status"`
+	PaymentID  string          `json:"payment_id,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Batch is a set of payments submitted together and processed one line at a
+// time in the background.
+type Batch struct {
+	ID          string      `json:"id"`
+	CustomerID  string      `json:"customer_id"`
+	Username    string      `json:"username"`
+	Status      BatchStatus `json:"status"`
+	Total       int         `json:"total"`
+	Succeeded   int         `json:"succeeded"`
+	Failed      int         `json:"failed"`
+	Lines       []BatchLine `json:"lines"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+}
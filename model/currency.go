@@ -0,0 +1,42 @@
+package model
+
+import "math"
+
+// Currency describes how amounts in a currency may be expressed.
+type Currency struct {
+	Code      string  `json:"code"`
+	Numeric   string  `json:"numeric"`    // ISO 4217 numeric code
+	Precision int     `json:"precision"`  // number of decimal places allowed
+	MaxAmount float64 `json:"max_amount"` // largest amount accepted for a single payment
+}
+
+// DefaultCurrency is used when a payment does not name its currency.
+const DefaultCurrency = "IDR"
+
+var currencies = map[string]Currency{
+	"IDR": {Code: "IDR", Numeric: "360", Precision: 2, MaxAmount: 1000000000},
+	"USD": {Code: "USD", Numeric: "840", Precision: 2, MaxAmount: 100000},
+	"SGD": {Code: "SGD", Numeric: "702", Precision: 2, MaxAmount: 100000},
+}
+
+// GetCurrency looks up a supported currency by its ISO 4217 code.
+func GetCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[code]
+	return currency, ok
+}
+
+// GetCurrencyByNumeric looks up a supported currency by its ISO 4217 numeric code.
+func GetCurrencyByNumeric(numeric string) (Currency, bool) {
+	for _, currency := range currencies {
+		if currency.Numeric == numeric {
+			return currency, true
+		}
+	}
+	return Currency{}, false
+}
+
+// Round rounds amount to the number of decimal places the currency allows.
+func (c Currency) Round(amount float64) float64 {
+	scale := math.Pow10(c.Precision)
+	return math.Round(amount*scale) / scale
+}
@@ -1,9 +1,33 @@
 package model
 
 type Customer struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Username string  `json:"username"`
-	Password string  `json:"password"`
-	Balance  float64 `json:"balance"`
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Username string             `json:"username"`
+	Password string             `json:"password"`
+	Tier     string             `json:"tier,omitempty"`
+	Balance  float64            `json:"balance"`           // balance in DefaultCurrency
+	Wallets  map[string]float64 `json:"wallets,omitempty"` // balances in other currencies, keyed by currency code
+	Points   int64              `json:"points"`            // loyalty points, kept apart from the wallet balances
+	Version  int64              `json:"version"`           // incremented on every update, see ConflictError
+}
+
+// BalanceIn returns the customer's balance in currency.
+func (c Customer) BalanceIn(currency string) float64 {
+	if currency == "" || currency == DefaultCurrency {
+		return c.Balance
+	}
+	return c.Wallets[currency]
+}
+
+// SetBalanceIn sets the customer's balance in currency.
+func (c *Customer) SetBalanceIn(currency string, amount float64) {
+	if currency == "" || currency == DefaultCurrency {
+		c.Balance = amount
+		return
+	}
+	if c.Wallets == nil {
+		c.Wallets = map[string]float64{}
+	}
+	c.Wallets[currency] = amount
 }
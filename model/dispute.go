@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type DisputeStatus string
+
+const (
+	DisputeStatusOpen        DisputeStatus = "open"         // waiting for the merchant's response
+	DisputeStatusUnderReview DisputeStatus = "under_review" // waiting for an admin's resolution
+	DisputeStatusRefunded    DisputeStatus = "refunded"     // resolved for the customer, the payment was refunded
+	DisputeStatusUpheld      DisputeStatus = "upheld"       // resolved for the merchant, the payment stands
+)
+
+type DisputeReason string
+
+const (
+	DisputeReasonNotReceived    DisputeReason = "not_received"
+	DisputeReasonNotAsDescribed DisputeReason = "not_as_described"
+	DisputeReasonDuplicate      DisputeReason = "duplicate"
+	DisputeReasonUnauthorized   DisputeReason = "unauthorized"
+	DisputeReasonOther          DisputeReason = "other"
+)
+
+// ErrInvalidDisputeTransition is returned when a dispute is moved to a status
+// the transition table does not allow from its current status.
+var ErrInvalidDisputeTransition = errors.New("invalid dispute status transition")
+
+// disputeTransitions lists the statuses a dispute may move to from each status.
+// Resolved disputes are final.
+var disputeTransitions = map[DisputeStatus][]DisputeStatus{
+	DisputeStatusOpen:        {DisputeStatusUnderReview},
+	DisputeStatusUnderReview: {DisputeStatusRefunded, DisputeStatusUpheld},
+	DisputeStatusRefunded:    {},
+	DisputeStatusUpheld:      {},
+}
+
+// CanTransitionTo reports whether a dispute in status s may move to next.
+func (s DisputeStatus) CanTransitionTo(next DisputeStatus) bool {
+	for _, allowed := range disputeTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsResolved reports whether the dispute has reached a final status.
+func (s DisputeStatus) IsResolved() bool {
+	return s == DisputeStatusRefunded || s == DisputeStatusUpheld
+}
+
+type DisputeParty string
+
+const (
+	DisputePartyCustomer DisputeParty = "customer"
+	DisputePartyMerchant DisputeParty = "merchant"
+)
+
+// DisputeEvidence describes a file attached to a dispute. Only its metadata is
+// kept; the file itself lives wherever URL points.
+type DisputeEvidence struct {
+	Party       DisputeParty `json:"party"`
+	FileName    string       `json:"file_name"`
+	ContentType string       `json:"content_type"`
+	Size        int64        `json:"size"`
+	URL         string       `json:"url"`
+	UploadedAt  time.Time    `json:"uploaded_at"`
+}
+
+type DisputeTransition struct {
+	Status    DisputeStatus `json:"status"`
+	Reason    string        `json:"reason,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Dispute is a customer's claim against a payment. The merchant can respond
+// until RespondBy, after which an admin resolves it by refunding the customer
+// or upholding the payment.
+type Dispute struct {
+	ID               string              `json:"id"`
+	PaymentID        string              `json:"payment_id"`
+	CustomerID       string              `json:"customer_id"`
+	MerchantID       string              `json:"merchant_id"`
+	Amount           float64             `json:"amount"`
+	Currency         string              `json:"currency"`
+	Reason           DisputeReason       `json:"reason"`
+	Description      string              `json:"description"`
+	Evidence         []DisputeEvidence   `json:"evidence"`
+	MerchantResponse string              `json:"merchant_response,omitempty"`
+	RespondBy        time.Time           `json:"respond_by"`
+	Resolution       string              `json:"resolution,omitempty"`
+	Status           DisputeStatus       `json:"status"`
+	Transitions      []DisputeTransition `json:"transitions"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// TransitionTo moves the dispute to next and records the transition, or returns
+// ErrInvalidDisputeTransition if the move is not allowed.
+func (d *Dispute) TransitionTo(next DisputeStatus, reason string, at time.Time) error {
+	if !d.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidDisputeTransition, d.Status, next)
+	}
+	d.Status = next
+	d.Transitions = append(d.Transitions, DisputeTransition{Status: next, Reason: reason, Timestamp: at})
+	d.UpdatedAt = at
+	return nil
+}
@@ -0,0 +1,34 @@
+package model
+
+type FeeType string
+
+const (
+	FeeTypePercentage FeeType = "percentage"
+	FeeTypeFixed      FeeType = "fixed"
+	FeeTypeTiered     FeeType = "tiered"
+)
+
+// FeeTier applies to payments up to and including UpTo. A zero UpTo means no upper bound.
+type FeeTier struct {
+	UpTo       float64 `json:"up_to"`
+	Percentage float64 `json:"percentage"`
+	Fixed      float64 `json:"fixed"`
+}
+
+// FeeRule describes the fee charged on payments to a merchant. A rule with a
+// MerchantID applies to that merchant only, a rule with a Category applies to
+// every merchant in that category and a rule with neither is the default.
+type FeeRule struct {
+	ID         string    `json:"id"`
+	MerchantID string    `json:"merchant_id,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	Type       FeeType   `json:"type"`
+	Percentage float64   `json:"percentage,omitempty"`
+	Fixed      float64   `json:"fixed,omitempty"`
+	Tiers      []FeeTier `json:"tiers,omitempty"`
+}
+
+type Fee struct {
+	RuleID string  `json:"rule_id,omitempty"`
+	Amount float64 `json:"amount"`
+}
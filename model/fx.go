@@ -0,0 +1,27 @@
+package model
+
+// ExchangeRate is the mid-market rate for converting one unit of From into To.
+type ExchangeRate struct {
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Rate float64 `json:"rate"`
+}
+
+// ExchangeRateTable is the rate file: the known mid-market rates and the spread
+// the platform takes on every conversion.
+type ExchangeRateTable struct {
+	SpreadPercentage float64        `json:"spread_percentage"`
+	Rates            []ExchangeRate `json:"rates"`
+}
+
+// FXConversion records the exchange applied to a payment whose payer and
+// merchant currencies differ. Rate is MidRate less the spread.
+type FXConversion struct {
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	MidRate          float64 `json:"mid_rate"`
+	SpreadPercentage float64 `json:"spread_percentage"`
+	Rate             float64 `json:"rate"`
+	Amount           float64 `json:"amount"`           // amount converted, in From
+	ConvertedAmount  float64 `json:"converted_amount"` // amount received, in To
+}
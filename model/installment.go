@@ -0,0 +1,133 @@
+package model
+
+import "time"
+
+type InstallmentPlanStatus string
+
+const (
+	InstallmentPlanStatusActive    InstallmentPlanStatus = "active"
+	InstallmentPlanStatusCompleted InstallmentPlanStatus = "completed"
+	InstallmentPlanStatusCancelled InstallmentPlanStatus = "cancelled" // its payment failed, nothing is owed
+)
+
+type InstallmentStatus string
+
+const (
+	InstallmentStatusPending InstallmentStatus = "pending"
+	InstallmentStatusPaid    InstallmentStatus = "paid"
+)
+
+// Installment is one scheduled repayment of a plan. LateFee is added once the
+// installment stays unpaid past its grace period.
+type Installment struct {
+	Number  int               `json:"number"`
+	Amount  float64           `json:"amount"`
+	LateFee float64           `json:"late_fee,omitempty"`
+	DueAt   time.Time         `json:"due_at"`
+	Status  InstallmentStatus `json:"status"`
+	PaidAt  *time.Time        `json:"paid_at,omitempty"`
+}
+
+// AmountDue is what the customer has to pay to settle the installment.
+func (i Installment) AmountDue() float64 {
+	return i.Amount + i.LateFee
+}
+
+// IsOverdue reports whether the installment is still unpaid after its due time.
+func (i Installment) IsOverdue(at time.Time) bool {
+	return i.Status == InstallmentStatusPending && at.After(i.DueAt)
+}
+
+// InstallmentPlan is a pay-later purchase: the merchant was paid in full from
+// the platform credit line and the customer repays Principal in monthly
+// installments.
+type InstallmentPlan struct {
+	ID           string                `json:"id"`
+	PaymentID    string                `json:"payment_id"`
+	CustomerID   string                `json:"customer_id"`
+	MerchantID   string                `json:"merchant_id"`
+	Principal    float64               `json:"principal"`
+	Currency     string                `json:"currency"`
+	Installments []Installment         `json:"installments"`
+	Status       InstallmentPlanStatus `json:"status"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// NewInstallmentPlan splits principal into count monthly installments, the
+// first due a month after at and the others on the same day of later months,
+// or the last day of shorter months. Rounding is absorbed by the last
+// installment.
+func NewInstallmentPlan(id string, payment Payment, count int, at time.Time) InstallmentPlan {
+	currency, _ := GetCurrency(payment.Currency)
+	amount := currency.Round(payment.Amount / float64(count))
+
+	installments := make([]Installment, 0, count)
+	for number := 1; number <= count; number++ {
+		installment := Installment{Number: number, Amount: amount, DueAt: addMonths(at, number), Status: InstallmentStatusPending}
+		if number == count {
+			installment.Amount = currency.Round(payment.Amount - amount*float64(count-1))
+		}
+		installments = append(installments, installment)
+	}
+
+	return InstallmentPlan{
+		ID:           id,
+		PaymentID:    payment.ID,
+		CustomerID:   payment.CustomerID,
+		MerchantID:   payment.MerchantID,
+		Principal:    payment.Amount,
+		Currency:     payment.Currency,
+		Installments: installments,
+		Status:       InstallmentPlanStatusActive,
+		CreatedAt:    at,
+		UpdatedAt:    at,
+	}
+}
+
+// Outstanding is what the customer still owes on the plan, late fees included.
+func (p InstallmentPlan) Outstanding() float64 {
+	outstanding := 0.0
+	if p.Status == InstallmentPlanStatusCancelled {
+		return outstanding
+	}
+	for _, installment := range p.Installments {
+		if installment.Status == InstallmentStatusPending {
+			outstanding += installment.AmountDue()
+		}
+	}
+	return outstanding
+}
+
+// OverdueAmount is what the customer owes on installments past their due time.
+func (p InstallmentPlan) OverdueAmount(at time.Time) float64 {
+	overdue := 0.0
+	if p.Status == InstallmentPlanStatusCancelled {
+		return overdue
+	}
+	for _, installment := range p.Installments {
+		if installment.IsOverdue(at) {
+			overdue += installment.AmountDue()
+		}
+	}
+	return overdue
+}
+
+// NextUnpaid returns the index of the earliest unpaid installment, or -1 once
+// the plan is fully repaid.
+func (p InstallmentPlan) NextUnpaid() int {
+	for i, installment := range p.Installments {
+		if installment.Status == InstallmentStatusPending {
+			return i
+		}
+	}
+	return -1
+}
+
+// InstallmentSummary is a customer's pay-later debt across all plans.
+type InstallmentSummary struct {
+	Outstanding   float64           `json:"outstanding"`
+	OverdueAmount float64           `json:"overdue_amount"`
+	Overdue       bool              `json:"overdue"`
+	Plans         []InstallmentPlan `json:"plans"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type InvoiceStatus string
+
+const (
+	InvoiceStatusOpen InvoiceStatus = "open"
+	InvoiceStatusPaid InvoiceStatus = "paid"
+)
+
+var (
+	ErrInvoiceExpired = errors.New("invoice has expired")
+	ErrInvoicePaid    = errors.New("invoice has already been paid")
+)
+
+// Invoice is a merchant's request for money. Customers pay it through its
+// Token, which is what the shareable payment link carries. A single-use
+// invoice is paid once; otherwise it can be paid until it expires.
+type Invoice struct {
+	ID          string        `json:"id"`
+	MerchantID  string        `json:"merchant_id"`
+	Amount      float64       `json:"amount"`
+	Currency    string        `json:"currency"`
+	Description string        `json:"description"`
+	Token       string        `json:"token"`
+	SingleUse   bool          `json:"single_use"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	Status      InvoiceStatus `json:"status"`
+	PaymentIDs  []string      `json:"payment_ids"`
+	PaidAt      *time.Time    `json:"paid_at,omitempty"` // time of the latest payment
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// CheckPayable returns why the invoice cannot be paid at the given time, or nil.
+func (i Invoice) CheckPayable(at time.Time) error {
+	if i.Status == InvoiceStatusPaid {
+		return ErrInvoicePaid
+	}
+	if !at.Before(i.ExpiresAt) {
+		return ErrInvoiceExpired
+	}
+	return nil
+}
+
+// RecordPayment adds a payment of the invoice, closing single-use invoices.
+func (i *Invoice) RecordPayment(paymentID string, at time.Time) {
+	i.PaymentIDs = append(i.PaymentIDs, paymentID)
+	i.PaidAt = &at
+	i.UpdatedAt = at
+	if i.SingleUse {
+		i.Status = InvoiceStatusPaid
+	}
+}
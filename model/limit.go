@@ -0,0 +1,20 @@
+package model
+
+// LimitWindow caps the total amount and number of payments within a window.
+// A zero value means the window is not limited on that dimension.
+type LimitWindow struct {
+	MaxAmount float64 `json:"max_amount"`
+	MaxCount  int     `json:"max_count"`
+}
+
+// SpendingLimit holds the spending rules for every customer of a tier.
+// A zero PerTransactionMax means single payments are not capped.
+type SpendingLimit struct {
+	Tier              string      `json:"tier"`
+	PerTransactionMax float64     `json:"per_transaction_max"`
+	Daily             LimitWindow `json:"daily"`
+	Monthly           LimitWindow `json:"monthly"`
+}
+
+// DefaultLimitTier is used for customers without a tier, or whose tier has no limits configured.
+const DefaultLimitTier = "default"
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+type RewardType string
+
+const (
+	RewardTypePoints   RewardType = "points"
+	RewardTypeCashback RewardType = "cashback"
+)
+
+type RewardStatus string
+
+const (
+	RewardStatusPending   RewardStatus = "pending"
+	RewardStatusCredited  RewardStatus = "credited"
+	RewardStatusCancelled RewardStatus = "cancelled"
+)
+
+// PointValue is what one loyalty point is worth in DefaultCurrency when redeemed.
+const PointValue = 1.0
+
+// PlatformLoyaltyAccountID is the expense account that adds up the cashback
+// and redeemed points the platform pays out to customers.
+const PlatformLoyaltyAccountID = "platform-loyalty"
+
+// RewardRule describes what customers earn on payments to a merchant, as a
+// percentage of the amount paid in DefaultCurrency. Like FeeRule, a rule with
+// a MerchantID applies to that merchant only, a rule with a Category applies
+// to every merchant in that category and a rule with neither is the default.
+// A zero MaxReward means no cap.
+type RewardRule struct {
+	ID         string     `json:"id"`
+	MerchantID string     `json:"merchant_id,omitempty"`
+	Category   string     `json:"category,omitempty"`
+	Type       RewardType `json:"type"`
+	Percentage float64    `json:"percentage"`
+	MaxReward  float64    `json:"max_reward,omitempty"`
+}
+
+// Reward returns what the rule earns on amount: whole points, or cashback in
+// DefaultCurrency.
+func (r RewardRule) Reward(amount float64) float64 {
+	reward := amount * r.Percentage / 100
+	if r.MaxReward > 0 && reward > r.MaxReward {
+		reward = r.MaxReward
+	}
+
+	if r.Type == RewardTypePoints {
+		return math.Floor(reward / PointValue)
+	}
+	return math.Round(reward*100) / 100
+}
+
+// Reward is earned on a payment and held until AvailableAt, so that it can be
+// cancelled if the payment is refunded in the meantime.
+type Reward struct {
+	ID          string       `json:"id"`
+	CustomerID  string       `json:"customer_id"`
+	PaymentID   string       `json:"payment_id"`
+	MerchantID  string       `json:"merchant_id"`
+	RuleID      string       `json:"rule_id"`
+	Type        RewardType   `json:"type"`
+	Amount      float64      `json:"amount"` // points, or cashback in DefaultCurrency
+	Status      RewardStatus `json:"status"`
+	AvailableAt time.Time    `json:"available_at"`
+	CreditedAt  *time.Time   `json:"credited_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// LoyaltySummary is a customer's points and cashback, credited and still held.
+type LoyaltySummary struct {
+	Points          int64    `json:"points"`
+	Balance         float64  `json:"balance"`
+	PendingPoints   int64    `json:"pending_points"`
+	PendingCashback float64  `json:"pending_cashback"`
+	Rewards         []Reward `json:"rewards,omitempty"`
+}
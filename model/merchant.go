@@ -1,9 +1,42 @@
 package model
 
 type Merchant struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	BankAccount string  `json:"bank_account"`
-	BankName    string  `json:"bank_name"`
-	Balance     float64 `json:"balance"`
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	BankAccount string             `json:"bank_account"`
+	BankName    string             `json:"bank_name"`
+	Category    string             `json:"category,omitempty"`
+	Currency    string             `json:"currency,omitempty"`     // settlement currency, DefaultCurrency when empty
+	APIKeyHash  string             `json:"api_key_hash,omitempty"` // SHA-256 hex of the key the merchant authenticates with
+	Balance     float64            `json:"balance"`                // balance in DefaultCurrency
+	Wallets     map[string]float64 `json:"wallets,omitempty"`      // balances in other currencies, keyed by currency code
+	Version     int64              `json:"version"`                // incremented on every update, see ConflictError
+}
+
+// SettlementCurrency returns the currency the merchant is paid in.
+func (m Merchant) SettlementCurrency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// BalanceIn returns the merchant's balance in currency.
+func (m Merchant) BalanceIn(currency string) float64 {
+	if currency == "" || currency == DefaultCurrency {
+		return m.Balance
+	}
+	return m.Wallets[currency]
+}
+
+// SetBalanceIn sets the merchant's balance in currency.
+func (m *Merchant) SetBalanceIn(currency string, amount float64) {
+	if currency == "" || currency == DefaultCurrency {
+		m.Balance = amount
+		return
+	}
+	if m.Wallets == nil {
+		m.Wallets = map[string]float64{}
+	}
+	m.Wallets[currency] = amount
 }
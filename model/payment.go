@@ -0,0 +1,149 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusSucceeded         PaymentStatus = "succeeded"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusReversed          PaymentStatus = "reversed"
+	PaymentStatusEscrowed          PaymentStatus = "escrowed"  // charged to the customer, held in escrow for the merchant
+	PaymentStatusCancelled         PaymentStatus = "cancelled" // released from escrow back to the customer
+)
+
+// ErrInvalidPaymentTransition is returned when a payment is moved to a status
+// the transition table does not allow from its current status.
+var ErrInvalidPaymentTransition = errors.New("invalid payment status transition")
+
+// paymentTransitions lists the statuses a payment may move to from each status.
+// Failed, cancelled, refunded and reversed payments are final.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending:           {PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusEscrowed},
+	PaymentStatusEscrowed:          {PaymentStatusSucceeded, PaymentStatusCancelled},
+	PaymentStatusSucceeded:         {PaymentStatusRefunded, PaymentStatusPartiallyRefunded, PaymentStatusReversed},
+	PaymentStatusPartiallyRefunded: {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusFailed:            {},
+	PaymentStatusCancelled:         {},
+	PaymentStatusRefunded:          {},
+	PaymentStatusReversed:          {},
+}
+
+// CanTransitionTo reports whether a payment in status s may move to next.
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, allowed := range paymentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type PaymentTransition struct {
+	Status    PaymentStatus `json:"status"`
+	Reason    string        `json:"reason,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// PaymentLeg is the share of a split payment that goes to one merchant. Its
+// amounts are in the payment's currency.
+type PaymentLeg struct {
+	MerchantID string        `json:"merchant_id"`
+	Amount     float64       `json:"amount"`
+	Fee        float64       `json:"fee"`
+	FeeRuleID  string        `json:"fee_rule_id,omitempty"`
+	NetAmount  float64       `json:"net_amount"`
+	FX         *FXConversion `json:"fx,omitempty"`
+}
+
+type Payment struct {
+	ID          string              `json:"id"`
+	CustomerID  string              `json:"customer_id"`
+	MerchantID  string              `json:"merchant_id"`
+	Amount      float64             `json:"amount"`
+	Currency    string              `json:"currency"`    // currency of Amount, Fee and NetAmount
+	BaseAmount  float64             `json:"base_amount"` // Amount in DefaultCurrency at the mid-market rate
+	Fee         float64             `json:"fee"`
+	FeeRuleID   string              `json:"fee_rule_id,omitempty"`
+	NetAmount   float64             `json:"net_amount"`     // what the merchant receives after the fee
+	FX          *FXConversion       `json:"fx,omitempty"`   // set when the merchant settles in another currency
+	Legs        []PaymentLeg        `json:"legs,omitempty"` // set on split payments, which leave MerchantID empty
+	PromotionID string              `json:"promotion_id,omitempty"`
+	PromoCode   string              `json:"promo_code,omitempty"`
+	Discount    float64             `json:"discount,omitempty"`            // funded by the platform, the merchant still receives NetAmount
+	ReleaseAt   *time.Time          `json:"release_at,omitempty"`          // set on escrow payments, when the held funds go to the merchant
+	PlanID      string              `json:"installment_plan_id,omitempty"` // set on pay-later payments, which the platform credit line funds
+	Status      PaymentStatus       `json:"status"`
+	Transitions []PaymentTransition `json:"transitions"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// NewPayment creates a pending, fee-free payment in DefaultCurrency whose first
+// transition is recorded at the given time.
+func NewPayment(id, customerID, merchantID string, amount float64, at time.Time) Payment {
+	return Payment{
+		ID:          id,
+		CustomerID:  customerID,
+		MerchantID:  merchantID,
+		Amount:      amount,
+		Currency:    DefaultCurrency,
+		BaseAmount:  amount,
+		NetAmount:   amount,
+		Status:      PaymentStatusPending,
+		Transitions: []PaymentTransition{{Status: PaymentStatusPending, Timestamp: at}},
+		CreatedAt:   at,
+		UpdatedAt:   at,
+	}
+}
+
+// ApplyFee charges fee on the payment, reducing what the merchant receives.
+func (p *Payment) ApplyFee(fee Fee) {
+	p.Fee = fee.Amount
+	p.FeeRuleID = fee.RuleID
+	p.NetAmount = p.Amount - fee.Amount
+}
+
+// ApplyLegs turns the payment into a split payment over legs, whose fees add
+// up to the payment's fee.
+func (p *Payment) ApplyLegs(legs []PaymentLeg) {
+	p.MerchantID = ""
+	p.Legs = legs
+	p.Fee = 0
+	p.FeeRuleID = ""
+	for _, leg := range legs {
+		p.Fee += leg.Fee
+	}
+	p.NetAmount = p.Amount - p.Fee
+}
+
+// ApplyDiscount lowers what the customer is charged by the promotion's discount.
+func (p *Payment) ApplyDiscount(promotion Promotion, discount float64) {
+	p.PromotionID = promotion.ID
+	p.PromoCode = promotion.Code
+	p.Discount = discount
+}
+
+// ChargedAmount is what the customer pays after the discount.
+func (p Payment) ChargedAmount() float64 {
+	return p.Amount - p.Discount
+}
+
+// TransitionTo moves the payment to next and records the transition, or returns
+// ErrInvalidPaymentTransition if the move is not allowed.
+func (p *Payment) TransitionTo(next PaymentStatus, reason string, at time.Time) error {
+	if !p.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidPaymentTransition, p.Status, next)
+	}
+	p.Status = next
+	p.Transitions = append(p.Transitions, PaymentTransition{Status: next, Reason: reason, Timestamp: at})
+	p.UpdatedAt = at
+	return nil
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type DiscountType string
+
+const (
+	DiscountTypePercentage DiscountType = "percentage"
+	DiscountTypeFixed      DiscountType = "fixed"
+)
+
+// PlatformPromotionAccountID is the expense account that adds up the discounts
+// the platform has funded.
+const PlatformPromotionAccountID = "platform-promotions"
+
+// ErrPromotionNotApplicable is returned when a promotion cannot be used for a payment.
+var ErrPromotionNotApplicable = errors.New("promotion not applicable")
+
+// Promotion is a discount code. Amounts are in DefaultCurrency; a zero
+// UsageLimit, PerUserLimit, MaxDiscount or EndsAt means no limit and an empty
+// MerchantIDs means every merchant.
+type Promotion struct {
+	ID            string                `json:"id"`
+	Code          string                `json:"code"`
+	Description   string                `json:"description"`
+	DiscountType  DiscountType          `json:"discount_type"`
+	DiscountValue float64               `json:"discount_value"` // percentage, or a fixed amount
+	MaxDiscount   float64               `json:"max_discount,omitempty"`
+	MinSpend      float64               `json:"min_spend,omitempty"`
+	MerchantIDs   []string              `json:"merchant_ids,omitempty"`
+	UsageLimit    int                   `json:"usage_limit,omitempty"`
+	PerUserLimit  int                   `json:"per_user_limit,omitempty"`
+	StartsAt      time.Time             `json:"starts_at"`
+	EndsAt        time.Time             `json:"ends_at"`
+	Redemptions   []PromotionRedemption `json:"redemptions"`
+}
+
+// PromotionRedemption records one use of a promotion by a payment.
+type PromotionRedemption struct {
+	PaymentID  string    `json:"payment_id"`
+	CustomerID string    `json:"customer_id"`
+	MerchantID string    `json:"merchant_id"`
+	Amount     float64   `json:"amount"`
+	Discount   float64   `json:"discount"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+// Discount returns the discount on amount, which never exceeds amount itself.
+func (p Promotion) Discount(amount float64) float64 {
+	var discount float64
+	switch p.DiscountType {
+	case DiscountTypePercentage:
+		discount = amount * p.DiscountValue / 100
+		if p.MaxDiscount > 0 && discount > p.MaxDiscount {
+			discount = p.MaxDiscount
+		}
+	case DiscountTypeFixed:
+		discount = p.DiscountValue
+	}
+
+	if discount > amount {
+		return amount
+	}
+	return discount
+}
+
+// CheckRedeemable returns why the customer cannot use the promotion on a
+// payment of amount to the merchant at the given time, or nil.
+func (p Promotion) CheckRedeemable(customerID, merchantID string, amount float64, at time.Time) error {
+	if at.Before(p.StartsAt) || (!p.EndsAt.IsZero() && !at.Before(p.EndsAt)) {
+		return fmt.Errorf("%w: promotion is not active", ErrPromotionNotApplicable)
+	}
+
+	if len(p.MerchantIDs) > 0 && !slices.Contains(p.MerchantIDs, merchantID) {
+		return fmt.Errorf("%w: promotion is not valid for this merchant", ErrPromotionNotApplicable)
+	}
+
+	if amount < p.MinSpend {
+		return fmt.Errorf("%w: minimum spend is %.2f", ErrPromotionNotApplicable, p.MinSpend)
+	}
+
+	if p.UsageLimit > 0 && len(p.Redemptions) >= p.UsageLimit {
+		return fmt.Errorf("%w: promotion has been fully redeemed", ErrPromotionNotApplicable)
+	}
+
+	if p.PerUserLimit > 0 && p.RedemptionsBy(customerID) >= p.PerUserLimit {
+		return fmt.Errorf("%w: promotion already used the maximum number of times", ErrPromotionNotApplicable)
+	}
+
+	return nil
+}
+
+// RedemptionsBy counts the redemptions of a customer.
+func (p Promotion) RedemptionsBy(customerID string) int {
+	count := 0
+	for _, redemption := range p.Redemptions {
+		if redemption.CustomerID == customerID {
+			count++
+		}
+	}
+	return count
+}
+
+// Redeem records the redemption if the promotion still allows it.
+func (p *Promotion) Redeem(redemption PromotionRedemption) error {
+	if err := p.CheckRedeemable(redemption.CustomerID, redemption.MerchantID, redemption.Amount, redemption.RedeemedAt); err != nil {
+		return err
+	}
+	p.Redemptions = append(p.Redemptions, redemption)
+	return nil
+}
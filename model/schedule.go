@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+type ScheduleFrequency string
+
+const (
+	ScheduleFrequencyOnce    ScheduleFrequency = "once"
+	ScheduleFrequencyDaily   ScheduleFrequency = "daily"
+	ScheduleFrequencyWeekly  ScheduleFrequency = "weekly"
+	ScheduleFrequencyMonthly ScheduleFrequency = "monthly"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusFailed    ScheduleStatus = "failed"
+)
+
+// Schedule is a payment the platform makes on the customer's behalf, once at
+// StartAt or repeatedly from StartAt until EndAt.
+type Schedule struct {
+	ID          string            `json:"id"`
+	CustomerID  string            `json:"customer_id"`
+	Username    string            `json:"username"`
+	MerchantID  string            `json:"merchant_id"`
+	Amount      float64           `json:"amount"`
+	Currency    string            `json:"currency,omitempty"`
+	Frequency   ScheduleFrequency `json:"frequency"`
+	StartAt     time.Time         `json:"start_at"`
+	EndAt       *time.Time        `json:"end_at,omitempty"`
+	Status      ScheduleStatus    `json:"status"`
+	Occurrence  int               `json:"occurrence"`  // index of the occurrence NextRunAt belongs to
+	NextRunAt   time.Time         `json:"next_run_at"` // due time of the next attempt, including retries
+	Retries     int               `json:"retries"`     // failed attempts of the current occurrence
+	LastRunAt   *time.Time        `json:"last_run_at,omitempty"`
+	LastPayment string            `json:"last_payment_id,omitempty"`
+	LastError   string            `json:"last_error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// OccurrenceAt returns when the n-th occurrence (starting at zero) is due.
+// Monthly schedules stay on StartAt's day of month, or the last day of
+// shorter months.
+func (s Schedule) OccurrenceAt(n int) time.Time {
+	switch s.Frequency {
+	case ScheduleFrequencyDaily:
+		return s.StartAt.AddDate(0, 0, n)
+	case ScheduleFrequencyWeekly:
+		return s.StartAt.AddDate(0, 0, 7*n)
+	case ScheduleFrequencyMonthly:
+		return addMonths(s.StartAt, n)
+	}
+	return s.StartAt
+}
+
+// addMonths returns t moved months months ahead, on t's day of month or the
+// last day of shorter months, where time.AddDate would roll over into the
+// month after.
+func addMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// Advance moves the schedule to its first occurrence after now, completing it
+// when there is none left. Occurrences missed while the scheduler was not
+// running are skipped rather than paid one after another.
+func (s *Schedule) Advance(now time.Time) {
+	s.Retries = 0
+	if s.Frequency == ScheduleFrequencyOnce {
+		s.Status = ScheduleStatusCompleted
+		return
+	}
+
+	for {
+		s.Occurrence++
+		s.NextRunAt = s.OccurrenceAt(s.Occurrence)
+		if s.NextRunAt.After(now) {
+			break
+		}
+	}
+	if s.EndAt != nil && s.NextRunAt.After(*s.EndAt) {
+		s.Status = ScheduleStatusCompleted
+	}
+}
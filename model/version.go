@@ -0,0 +1,31 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is matched by every *ConflictError, for callers that only need
+// to know an update lost to a newer one.
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError is returned by an update that expected a record at a version
+// it is no longer at: another update changed it in the meantime.
+type ConflictError struct {
+	Record   string
+	ID       string
+	Expected int64
+	Actual   int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s was changed by another update: expected version %d, found %d", e.Record, e.ID, e.Expected, e.Actual)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ConflictRetries is how many times an update that lost to a newer one is
+// tried again on the record read afresh before its ConflictError is returned.
+const ConflictRetries = 5
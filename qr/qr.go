@@ -0,0 +1,277 @@
+// Package qr encodes and parses QRIS payloads, the Indonesian profile of the
+// EMVCo merchant-presented QR code (MPM) specification.
+//
+// A payload is a sequence of TLV data objects: a two digit ID, a two digit
+// length and the value. It ends with the CRC (ID 63) computed over everything
+// before the checksum value, including "6304" itself.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPayload   = errors.New("invalid QR payload")
+	ErrChecksumMismatch = errors.New("QR payload checksum mismatch")
+)
+
+// Data object IDs used in the payload.
+const (
+	idPayloadFormat       = "00"
+	idInitiationMethod    = "01"
+	idMerchantAccount     = "26"
+	idQRISMerchantAccount = "51"
+	idMerchantCategory    = "52"
+	idCurrency            = "53"
+	idAmount              = "54"
+	idCountryCode         = "58"
+	idMerchantName        = "59"
+	idMerchantCity        = "60"
+	idCRC                 = "63"
+)
+
+const (
+	payloadFormatIndicator = "01"
+	initiationStatic       = "11" // the customer enters the amount
+	initiationDynamic      = "12" // the amount is part of the payload
+
+	// GlobalUniqueIdentifier identifies this platform in the merchant account
+	// template, whose sub-tag 01 carries the merchant ID.
+	GlobalUniqueIdentifier = "ID.CO.SIMPLEGOLANGTDD.WWW"
+	qrisIdentifier         = "ID.CO.QRIS.WWW"
+
+	countryCode         = "ID"
+	DefaultMerchantCity = "JAKARTA"
+	defaultMCC          = "5999" // miscellaneous retail
+
+	maxMerchantNameLength = 25
+	maxMerchantCityLength = 15
+	maxPayloadLength      = 512
+	maxValueLength        = 99
+)
+
+// categoryMCC maps merchant categories to ISO 18245 merchant category codes.
+var categoryMCC = map[string]string{
+	"retail":      "5399",
+	"grocery":     "5411",
+	"electronics": "5732",
+}
+
+// Payload is what a scanned QR code tells the payer.
+type Payload struct {
+	MerchantID   string  `json:"merchant_id"`
+	MerchantName string  `json:"merchant_name"`
+	MerchantCity string  `json:"merchant_city"`
+	MCC          string  `json:"mcc"`
+	Currency     string  `json:"currency"`
+	Amount       float64 `json:"amount,omitempty"` // set on dynamic payloads only
+	Dynamic      bool    `json:"dynamic"`
+}
+
+// EncodeStatic returns the merchant's static payload, for which the customer
+// enters the amount when paying.
+func EncodeStatic(merchant model.Merchant) (string, error) {
+	return encode(merchant, 0, false)
+}
+
+// EncodeDynamic returns a payload for a single payment of amount to the merchant.
+func EncodeDynamic(merchant model.Merchant, amount float64) (string, error) {
+	return encode(merchant, amount, true)
+}
+
+func encode(merchant model.Merchant, amount float64, dynamic bool) (string, error) {
+	if merchant.ID == "" {
+		return "", fmt.Errorf("%w: merchant ID is required", ErrInvalidPayload)
+	}
+
+	currency, ok := model.GetCurrency(merchant.SettlementCurrency())
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported currency %s", ErrInvalidPayload, merchant.SettlementCurrency())
+	}
+
+	mcc, ok := categoryMCC[merchant.Category]
+	if !ok {
+		mcc = defaultMCC
+	}
+
+	initiation := initiationStatic
+	if dynamic {
+		initiation = initiationDynamic
+	}
+
+	// A value longer than a two digit length can hold, such as a long
+	// merchant ID, fails the whole payload instead of corrupting it.
+	var tlvErr error
+	add := func(id string, value string) string {
+		object, err := tlv(id, value)
+		if err != nil && tlvErr == nil {
+			tlvErr = err
+		}
+		return object
+	}
+
+	var b strings.Builder
+	b.WriteString(add(idPayloadFormat, payloadFormatIndicator))
+	b.WriteString(add(idInitiationMethod, initiation))
+	b.WriteString(add(idMerchantAccount, add("00", GlobalUniqueIdentifier)+add("01", merchant.ID)))
+	b.WriteString(add(idQRISMerchantAccount, add("00", qrisIdentifier)+add("02", merchant.ID)))
+	b.WriteString(add(idMerchantCategory, mcc))
+	b.WriteString(add(idCurrency, currency.Numeric))
+	if dynamic {
+		if amount <= 0 || currency.Round(amount) != amount {
+			return "", fmt.Errorf("%w: invalid amount %v", ErrInvalidPayload, amount)
+		}
+		b.WriteString(add(idAmount, strconv.FormatFloat(amount, 'f', -1, 64)))
+	}
+	b.WriteString(add(idCountryCode, countryCode))
+	b.WriteString(add(idMerchantName, truncate(asciiOnly(merchant.Name), maxMerchantNameLength)))
+	b.WriteString(add(idMerchantCity, DefaultMerchantCity))
+	b.WriteString(idCRC + "04")
+
+	if tlvErr != nil {
+		return "", tlvErr
+	}
+
+	payload := b.String()
+	return payload + fmt.Sprintf("%04X", CRC16(payload)), nil
+}
+
+// Parse checks the payload's checksum and reads the merchant and amount from it.
+func Parse(payload string) (Payload, error) {
+	payload = strings.TrimSpace(payload)
+	if len(payload) > maxPayloadLength || len(payload) < 8 {
+		return Payload{}, fmt.Errorf("%w: unexpected length %d", ErrInvalidPayload, len(payload))
+	}
+
+	body, checksum := payload[:len(payload)-4], payload[len(payload)-4:]
+	if !strings.HasSuffix(body, idCRC+"04") {
+		return Payload{}, fmt.Errorf("%w: payload must end with the CRC", ErrInvalidPayload)
+	}
+	if fmt.Sprintf("%04X", CRC16(body)) != strings.ToUpper(checksum) {
+		return Payload{}, ErrChecksumMismatch
+	}
+
+	objects, err := parseTLV(body[:len(body)-4])
+	if err != nil {
+		return Payload{}, err
+	}
+
+	if objects[idPayloadFormat] != payloadFormatIndicator {
+		return Payload{}, fmt.Errorf("%w: unsupported payload format", ErrInvalidPayload)
+	}
+
+	account, err := parseTLV(objects[idMerchantAccount])
+	if err != nil {
+		return Payload{}, err
+	}
+	if account["00"] != GlobalUniqueIdentifier || account["01"] == "" {
+		return Payload{}, fmt.Errorf("%w: not a merchant of this platform", ErrInvalidPayload)
+	}
+
+	currency, ok := model.GetCurrencyByNumeric(objects[idCurrency])
+	if !ok {
+		return Payload{}, fmt.Errorf("%w: unsupported currency %q", ErrInvalidPayload, objects[idCurrency])
+	}
+
+	result := Payload{
+		MerchantID:   account["01"],
+		MerchantName: objects[idMerchantName],
+		MerchantCity: objects[idMerchantCity],
+		MCC:          objects[idMerchantCategory],
+		Currency:     currency.Code,
+	}
+
+	switch objects[idInitiationMethod] {
+	case initiationStatic:
+	case initiationDynamic:
+		result.Dynamic = true
+		amount, err := strconv.ParseFloat(objects[idAmount], 64)
+		if err != nil || amount <= 0 {
+			return Payload{}, fmt.Errorf("%w: invalid amount %q", ErrInvalidPayload, objects[idAmount])
+		}
+		result.Amount = amount
+	default:
+		return Payload{}, fmt.Errorf("%w: unknown point of initiation %q", ErrInvalidPayload, objects[idInitiationMethod])
+	}
+
+	return result, nil
+}
+
+// CRC16 computes the CRC-16/CCITT-FALSE checksum (polynomial 0x1021, initial
+// value 0xFFFF) that EMVCo payloads end with.
+func CRC16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// tlv encodes a data object. Its length is two decimal digits, so the value
+// can be at most 99 characters long.
+func tlv(id string, value string) (string, error) {
+	if len(value) > maxValueLength {
+		return "", fmt.Errorf("%w: data object %s is %d characters long, the maximum is %d", ErrInvalidPayload, id, len(value), maxValueLength)
+	}
+	return fmt.Sprintf("%s%02d%s", id, len(value), value), nil
+}
+
+// parseTLV splits data into its data objects, keyed by ID.
+func parseTLV(data string) (map[string]string, error) {
+	objects := make(map[string]string)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("%w: truncated data object", ErrInvalidPayload)
+		}
+		id := data[:2]
+		length, ok := tlvLength(data[2:4])
+		if !ok || length > len(data)-4 {
+			return nil, fmt.Errorf("%w: bad length for data object %s", ErrInvalidPayload, id)
+		}
+		objects[id] = data[4 : 4+length]
+		data = data[4+length:]
+	}
+	return objects, nil
+}
+
+// tlvLength reads the length of a data object, which is always two decimal
+// digits. strconv.Atoi would also take a sign, such as "-1".
+func tlvLength(digits string) (int, bool) {
+	length := 0
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+		length = length*10 + int(digits[i]-'0')
+	}
+	return length, true
+}
+
+// asciiOnly drops characters outside printable ASCII, which is all the
+// payload's alphanumeric-special fields may hold.
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7E {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,165 @@
+package qr
+
+import (
+	"fmt"
+	"simple-golang-tdd/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeMerchant = model.Merchant{ID: "merchant-001", Name: "ABC Store", Category: "retail"}
+
+func TestCRC16(t *testing.T) {
+	// Standard check value of CRC-16/CCITT-FALSE.
+	assert.Equal(t, uint16(0x29B1), CRC16("123456789"))
+}
+
+func TestEncodeStatic(t *testing.T) {
+	payload, err := EncodeStatic(fakeMerchant)
+
+	require.NoError(t, err)
+	assert.Equal(t, "000201"+
+		"010211"+
+		"2645"+"0025ID.CO.SIMPLEGOLANGTDD.WWW"+"0112merchant-001"+
+		"5134"+"0014ID.CO.QRIS.WWW"+"0212merchant-001"+
+		"52045399"+
+		"5303360"+
+		"5802ID"+
+		"5909ABC Store"+
+		"6007JAKARTA"+
+		"6304", payload[:len(payload)-4])
+	assert.NotContains(t, payload, "54")
+}
+
+func TestEncodeAndParse_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		merchant model.Merchant
+		amount   float64
+		dynamic  bool
+		want     Payload
+	}{
+		{
+			name:     "static",
+			merchant: fakeMerchant,
+			want:     Payload{MerchantID: "merchant-001", MerchantName: "ABC Store", MerchantCity: "JAKARTA", MCC: "5399", Currency: "IDR"},
+		},
+		{
+			name:     "dynamic",
+			merchant: fakeMerchant,
+			amount:   150000,
+			dynamic:  true,
+			want:     Payload{MerchantID: "merchant-001", MerchantName: "ABC Store", MerchantCity: "JAKARTA", MCC: "5399", Currency: "IDR", Amount: 150000, Dynamic: true},
+		},
+		{
+			name:     "dynamic with decimals in settlement currency",
+			merchant: model.Merchant{ID: "merchant-003", Name: "Global Gadgets", Category: "electronics", Currency: "USD"},
+			amount:   25.5,
+			dynamic:  true,
+			want:     Payload{MerchantID: "merchant-003", MerchantName: "Global Gadgets", MerchantCity: "JAKARTA", MCC: "5732", Currency: "USD", Amount: 25.5, Dynamic: true},
+		},
+		{
+			name:     "long non-ASCII name is cleaned up",
+			merchant: model.Merchant{ID: "merchant-009", Name: "Toko Kue Enak Sekali Ñam Ñam Jaya"},
+			want:     Payload{MerchantID: "merchant-009", MerchantName: "Toko Kue Enak Sekali am a", MerchantCity: "JAKARTA", MCC: "5999", Currency: "IDR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload string
+			var err error
+			if tt.dynamic {
+				payload, err = EncodeDynamic(tt.merchant, tt.amount)
+			} else {
+				payload, err = EncodeStatic(tt.merchant)
+			}
+			require.NoError(t, err)
+
+			parsed, err := Parse(payload)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, parsed)
+		})
+	}
+}
+
+func TestEncodeDynamic_InvalidAmount(t *testing.T) {
+	for _, amount := range []float64{0, -10, 10.005} {
+		_, err := EncodeDynamic(fakeMerchant, amount)
+		assert.ErrorIs(t, err, ErrInvalidPayload, "amount %v", amount)
+	}
+}
+
+func TestEncode_ValueTooLong(t *testing.T) {
+	merchant := fakeMerchant
+	merchant.ID = strings.Repeat("m", 70)
+
+	_, err := EncodeStatic(merchant)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+	assert.ErrorContains(t, err, "data object 26 is 103 characters long")
+
+	_, err = EncodeDynamic(fakeMerchant, 1e120)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+	assert.ErrorContains(t, err, "data object 54")
+}
+
+func TestEncode_LongMerchantNameIsTruncated(t *testing.T) {
+	merchant := fakeMerchant
+	merchant.Name = strings.Repeat("Toko Serba Ada ", 10)
+
+	payload, err := EncodeStatic(merchant)
+	require.NoError(t, err)
+
+	parsed, err := Parse(payload)
+	require.NoError(t, err)
+	assert.Equal(t, merchant.Name[:25], parsed.MerchantName)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	valid, err := EncodeDynamic(fakeMerchant, 150000)
+	require.NoError(t, err)
+
+	tampered := strings.Replace(valid, "5406150000", "5406990000", 1)
+	otherPlatform := "000201010211" + "2619" + "0015ID.CO.OTHER.WWW" + "5303360" + "5802ID" + "6304"
+	otherPlatform += fmt.Sprintf("%04X", CRC16(otherPlatform))
+	noCRC := "000201010211"
+	negativeLength := "00-1AB" + "6304"
+	negativeLength += fmt.Sprintf("%04X", CRC16(negativeLength))
+	signedLength := "00+2AB" + "6304"
+	signedLength += fmt.Sprintf("%04X", CRC16(signedLength))
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr error
+	}{
+		{"tampered amount", tampered, ErrChecksumMismatch},
+		{"other platform", otherPlatform, ErrInvalidPayload},
+		{"missing CRC", noCRC, ErrInvalidPayload},
+		{"negative length", negativeLength, ErrInvalidPayload},
+		{"signed length", signedLength, ErrInvalidPayload},
+		{"empty", "", ErrInvalidPayload},
+		{"too long", strings.Repeat("0", maxPayloadLength+1), ErrInvalidPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.payload)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParse_LowercaseChecksum(t *testing.T) {
+	payload, err := EncodeStatic(fakeMerchant)
+	require.NoError(t, err)
+
+	parsed, err := Parse(payload[:len(payload)-4] + strings.ToLower(payload[len(payload)-4:]))
+
+	require.NoError(t, err)
+	assert.Equal(t, "merchant-001", parsed.MerchantID)
+}
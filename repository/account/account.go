@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type AccountRepository interface {
+	GetAccountBalance(id string) (float64, error)
+	UpdateAccountBalance(id string, amount float64) (model.Account, error)
+	// AdjustAccountBalance adds delta to the account's balance in one step, so
+	// concurrent adjustments cannot overwrite one another.
+	AdjustAccountBalance(id string, delta float64) (model.Account, error)
+}
+
+type accountRepositoryImpl struct {
+	dataSourcePath string
+	accounts       []model.Account
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewAccountRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewAccountRepository(dataSourcePath string) (AccountRepository, error) {
+	repo := &accountRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *accountRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.accounts)
+}
+
+func (r *accountRepositoryImpl) saveAccountsToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.accounts)
+}
+
+func (r *accountRepositoryImpl) GetAccountBalance(id string) (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, account := range r.accounts {
+		if account.ID == id {
+			return account.Balance, nil
+		}
+	}
+
+	return 0, errors.New("account not found for balance check")
+}
+
+func (r *accountRepositoryImpl) UpdateAccountBalance(id string, amount float64) (model.Account, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, account := range r.accounts {
+		if account.ID == id {
+			r.accounts[i].Balance = amount
+			err := r.saveAccountsToFile()
+			if err != nil {
+				r.accounts[i].Balance = account.Balance
+				return model.Account{}, fmt.Errorf("error while updating account balance: %v", err)
+			}
+			return r.accounts[i], nil
+		}
+	}
+
+	return model.Account{}, errors.New("error while updating account balance")
+}
+
+func (r *accountRepositoryImpl) AdjustAccountBalance(id string, delta float64) (model.Account, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, account := range r.accounts {
+		if account.ID == id {
+			r.accounts[i].Balance = account.Balance + delta
+			err := r.saveAccountsToFile()
+			if err != nil {
+				r.accounts[i].Balance = account.Balance
+				return model.Account{}, fmt.Errorf("error while adjusting account balance: %v", err)
+			}
+			return r.accounts[i], nil
+		}
+	}
+
+	return model.Account{}, errors.New("error while adjusting account balance")
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan salinan file data json
+func setupRepository(t *testing.T) AccountRepository {
+	data, err := os.ReadFile("../../data/accounts.json")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	repo, err := NewAccountRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestGetAccountBalance_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	balance, err := repo.GetAccountBalance("platform-revenue")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, balance)
+}
+
+func TestUpdateAccountBalance_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	updatedAccount, err := repo.UpdateAccountBalance("platform-revenue", 500.0)
+	require.NoError(t, err)
+	balance, err := repo.GetAccountBalance("platform-revenue")
+
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, balance)
+	assert.Equal(t, balance, updatedAccount.Balance)
+}
+
+func TestGetAccountBalance_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	balance, err := repo.GetAccountBalance("unknown_id")
+
+	require.Error(t, err)
+	assert.Equal(t, 0.0, balance)
+	assert.EqualError(t, err, "account not found for balance check")
+}
+
+func TestUpdateAccountBalance_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	account, err := repo.UpdateAccountBalance("invalid_id", 500.0)
+
+	require.Error(t, err)
+	assert.Empty(t, account)
+	assert.EqualError(t, err, "error while updating account balance")
+}
+
+func TestAdjustAccountBalance_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	_, err := repo.AdjustAccountBalance("platform-revenue", 500.0)
+	require.NoError(t, err)
+	updatedAccount, err := repo.AdjustAccountBalance("platform-revenue", -200.0)
+	require.NoError(t, err)
+	balance, err := repo.GetAccountBalance("platform-revenue")
+
+	require.NoError(t, err)
+	assert.Equal(t, 300.0, balance)
+	assert.Equal(t, balance, updatedAccount.Balance)
+}
+
+func TestAdjustAccountBalance_Concurrent(t *testing.T) {
+	repo := setupRepository(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.AdjustAccountBalance("platform-revenue", 10.0)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, err := repo.GetAccountBalance("platform-revenue")
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, balance)
+}
+
+func TestAdjustAccountBalance_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	account, err := repo.AdjustAccountBalance("invalid_id", 500.0)
+
+	require.Error(t, err)
+	assert.Empty(t, account)
+	assert.EqualError(t, err, "error while adjusting account balance")
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type BatchRepository interface {
+	CreateBatch(batch model.Batch) (model.Batch, error)
+	GetBatchByID(id string) (model.Batch, error)
+	GetUnfinishedBatches() ([]model.Batch, error)
+	UpdateBatch(batch model.Batch) (model.Batch, error)
+}
+
+type batchRepositoryImpl struct {
+	dataSourcePath string
+	batches        []model.Batch
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewBatchRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewBatchRepository(dataSourcePath string) (BatchRepository, error) {
+	repo := &batchRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *batchRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.batches)
+}
+
+func (r *batchRepositoryImpl) saveBatchesToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.batches)
+}
+
+func (r *batchRepositoryImpl) CreateBatch(batch model.Batch) (model.Batch, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.batches {
+		if existing.ID == batch.ID {
+			return model.Batch{}, errors.New("batch already exists")
+		}
+	}
+
+	r.batches = append(r.batches, batch)
+	err := r.saveBatchesToFile()
+	if err != nil {
+		r.batches = r.batches[:len(r.batches)-1]
+		return model.Batch{}, fmt.Errorf("error while creating batch: %v", err)
+	}
+
+	return batch, nil
+}
+
+func (r *batchRepositoryImpl) GetBatchByID(id string) (model.Batch, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, batch := range r.batches {
+		if batch.ID == id {
+			return batch, nil
+		}
+	}
+	return model.Batch{}, errors.New("batch not found by ID")
+}
+
+// GetUnfinishedBatches returns the batches that are not completed yet.
+func (r *batchRepositoryImpl) GetUnfinishedBatches() ([]model.Batch, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	batches := []model.Batch{}
+	for _, batch := range r.batches {
+		if batch.Status != model.BatchStatusCompleted {
+			batches = append(batches, batch)
+		}
+	}
+	return batches, nil
+}
+
+func (r *batchRepositoryImpl) UpdateBatch(batch model.Batch) (model.Batch, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.batches {
+		if existing.ID == batch.ID {
+			r.batches[i] = batch
+			err := r.saveBatchesToFile()
+			if err != nil {
+				r.batches[i] = existing
+				return model.Batch{}, fmt.Errorf("error while updating batch: %v", err)
+			}
+			return batch, nil
+		}
+	}
+
+	return model.Batch{}, errors.New("error while updating batch")
+}
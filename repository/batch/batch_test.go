@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) (BatchRepository, string) {
+	path := filepath.Join(t.TempDir(), "batches.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewBatchRepository(path)
+	require.NoError(t, err)
+	return repo, path
+}
+
+func fakeBatch(id string) model.Batch {
+	createdAt := time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+	return model.Batch{
+		ID:         id,
+		CustomerID: "cust-001",
+		Username:   "johndoe",
+		Status:     model.BatchStatusPending,
+		Total:      1,
+		Lines: []model.BatchLine{
+			{Line: 1, MerchantID: "merchant-001", Amount: 100.0, Status: model.BatchLineStatusPending},
+		},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreateBatch_Success(t *testing.T) {
+	repo, path := setupRepository(t)
+
+	batch, err := repo.CreateBatch(fakeBatch("batch-001"))
+	require.NoError(t, err)
+	assert.Equal(t, "batch-001", batch.ID)
+
+	// Batch yang dibuat harus tetap ada setelah repository dibuka ulang
+	reopened, err := NewBatchRepository(path)
+	require.NoError(t, err)
+	stored, err := reopened.GetBatchByID("batch-001")
+	require.NoError(t, err)
+	assert.Len(t, stored.Lines, 1)
+}
+
+func TestUpdateBatch_Success(t *testing.T) {
+	repo, _ := setupRepository(t)
+	batch, err := repo.CreateBatch(fakeBatch("batch-001"))
+	require.NoError(t, err)
+
+	batch.Status = model.BatchStatusCompleted
+	batch.Lines[0].Status = model.BatchLineStatusSucceeded
+	_, err = repo.UpdateBatch(batch)
+	require.NoError(t, err)
+	stored, err := repo.GetBatchByID("batch-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.BatchStatusCompleted, stored.Status)
+	assert.Equal(t, model.BatchLineStatusSucceeded, stored.Lines[0].Status)
+}
+
+func TestGetUnfinishedBatches_Success(t *testing.T) {
+	repo, _ := setupRepository(t)
+	for _, status := range []model.BatchStatus{model.BatchStatusPending, model.BatchStatusProcessing, model.BatchStatusCompleted} {
+		batch := fakeBatch("batch-" + string(status))
+		batch.Status = status
+		_, err := repo.CreateBatch(batch)
+		require.NoError(t, err)
+	}
+
+	batches, err := repo.GetUnfinishedBatches()
+
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "batch-pending", batches[0].ID)
+	assert.Equal(t, "batch-processing", batches[1].ID)
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreateBatch_DuplicateID(t *testing.T) {
+	repo, _ := setupRepository(t)
+	_, err := repo.CreateBatch(fakeBatch("batch-001"))
+	require.NoError(t, err)
+
+	batch, err := repo.CreateBatch(fakeBatch("batch-001"))
+
+	require.Error(t, err)
+	assert.Empty(t, batch)
+	assert.EqualError(t, err, "batch already exists")
+}
+
+func TestGetBatchByID_Error(t *testing.T) {
+	repo, _ := setupRepository(t)
+
+	batch, err := repo.GetBatchByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, batch)
+	assert.EqualError(t, err, "batch not found by ID")
+}
+
+func TestUpdateBatch_Error(t *testing.T) {
+	repo, _ := setupRepository(t)
+
+	batch, err := repo.UpdateBatch(fakeBatch("unknown_id"))
+
+	require.Error(t, err)
+	assert.Empty(t, batch)
+	assert.EqualError(t, err, "error while updating batch")
+}
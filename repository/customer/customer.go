@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"simple-golang-tdd/model"
 	"simple-golang-tdd/utils"
 	"sync"
@@ -12,83 +15,229 @@ type CustomerRepository interface {
 	GetUserByUsername(username string) (model.Customer, error)
 	GetUserByID(id string) (model.Customer, error)
 	GetUserBalance(id string) (float64, error)
-	UpdateUserBalance(id string, amount float64) (model.Customer, error)
+	// The updates below only save a customer still at expectedVersion, the
+	// version it was read at, and otherwise fail with a *model.ConflictError.
+	// The saved customer is at the next version.
+	UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error)
+	UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error)
+	UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error)
 }
 
 type customerRepositoryImpl struct {
 	dataSourcePath string
 	customers      []model.Customer
-	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+	index          customerIndex
+	journal        *utils.Journal
+	stamp          utils.SnapshotStamp // what a read-only repository last loaded
+	mutex          sync.RWMutex        // Untuk menghindari masalah concurrency jika diperlukan
 }
 
 // NewCustomerRepository membuat repository baru dan membaca file JSON sekali saja.
+// Perubahan yang belum masuk ke file JSON diputar ulang dari journal-nya.
 func NewCustomerRepository(dataSourcePath string) (CustomerRepository, error) {
 	repo := &customerRepositoryImpl{dataSourcePath: dataSourcePath}
 	err := repo.loadData()
 	if err != nil {
 		return nil, err
 	}
+	repo.journal, err = utils.OpenJournal(utils.JournalPath(dataSourcePath), repo.replay)
+	if err != nil {
+		return nil, err
+	}
 	return repo, nil
 }
 
+// NewReadOnlyCustomerRepository membaca file JSON dan journal-nya seperti
+// NewCustomerRepository tanpa pernah menulis keduanya. Keduanya dibaca ulang
+// setiap kali proses lain mengubahnya, dan setiap update gagal dengan
+// utils.ErrReadOnly.
+func NewReadOnlyCustomerRepository(dataSourcePath string) (CustomerRepository, error) {
+	repo := &customerRepositoryImpl{dataSourcePath: dataSourcePath}
+	if err := repo.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// loadSnapshot reads the JSON file and replays its journal without opening
+// it, recording the stamp of what it read.
+func (r *customerRepositoryImpl) loadSnapshot() error {
+	// Stamped first, so a write made while loading is picked up next time.
+	stamp, err := utils.StampSnapshot(r.dataSourcePath)
+	if err != nil {
+		return err
+	}
+	if err := r.loadData(); err != nil {
+		return err
+	}
+	if err := utils.ReplayJournal(utils.JournalPath(r.dataSourcePath), r.replay); err != nil {
+		return err
+	}
+	r.stamp = stamp
+	return nil
+}
+
+// refresh loads a read-only repository again once the process that owns the
+// files has written them. Until a reload succeeds the last one keeps being
+// served. A writable repository is the only writer and never reloads.
+func (r *customerRepositoryImpl) refresh() {
+	if r.journal != nil {
+		return
+	}
+	stamp, err := utils.StampSnapshot(r.dataSourcePath)
+	if err != nil {
+		log.Printf("failed to check customer data for changes: %v", err)
+		return
+	}
+	r.mutex.RLock()
+	unchanged := stamp == r.stamp
+	r.mutex.RUnlock()
+	if unchanged {
+		return
+	}
+
+	reloaded := &customerRepositoryImpl{dataSourcePath: r.dataSourcePath}
+	if err := reloaded.loadSnapshot(); err != nil {
+		log.Printf("failed to reload customer data: %v", err)
+		return
+	}
+	r.mutex.Lock()
+	r.customers, r.index, r.stamp = reloaded.customers, reloaded.index, reloaded.stamp
+	r.mutex.Unlock()
+}
+
 func (r *customerRepositoryImpl) loadData() error {
-	return utils.LoadJSONFile(r.dataSourcePath, &r.customers)
+	err := utils.LoadJSONFile(r.dataSourcePath, &r.customers)
+	if err != nil {
+		return err
+	}
+	r.index, err = newCustomerIndex(r.customers)
+	return err
 }
 
-func (r *customerRepositoryImpl) saveCustomersToFile() error {
-	return utils.SaveJSONFile(r.dataSourcePath, r.customers)
+// replay applies one journal record, the full customer as it was saved.
+func (r *customerRepositoryImpl) replay(record json.RawMessage) error {
+	var customer model.Customer
+	if err := json.Unmarshal(record, &customer); err != nil {
+		return err
+	}
+	i, ok := r.index.id(customer.ID)
+	if !ok {
+		return fmt.Errorf("unknown customer %s", customer.ID)
+	}
+	if err := r.index.rename(i, r.customers[i].Username, customer.Username); err != nil {
+		return err
+	}
+	r.customers[i] = customer
+	return nil
+}
+
+// saveCustomer appends the customer at index i to the journal. Once the journal
+// holds enough records it is compacted into the JSON file.
+// A read-only repository has no journal and refuses every update.
+func (r *customerRepositoryImpl) saveCustomer(i int) error {
+	if r.journal == nil {
+		return utils.ErrReadOnly
+	}
+	if err := r.journal.Append(r.customers[i]); err != nil {
+		return err
+	}
+	if r.journal.Len() >= utils.JournalCompactThreshold {
+		// The update is already durable in the journal, so a failed
+		// compaction is only retried on the next update.
+		if err := r.compactJournal(); err != nil {
+			log.Printf("failed to compact customer journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// compactJournal saves every customer to the JSON file and empties the journal.
+func (r *customerRepositoryImpl) compactJournal() error {
+	if err := utils.SaveJSONFile(r.dataSourcePath, r.customers); err != nil {
+		return err
+	}
+	return r.journal.Reset()
 }
 
 func (r *customerRepositoryImpl) GetUserByUsername(username string) (model.Customer, error) {
+	r.refresh()
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, user := range r.customers {
-		if user.Username == username {
-			return user, nil
-		}
+	if i, ok := r.index.username(username); ok {
+		return r.customers[i], nil
 	}
 	return model.Customer{}, errors.New("user not found")
 }
 
 func (r *customerRepositoryImpl) GetUserByID(id string) (model.Customer, error) {
+	r.refresh()
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, user := range r.customers {
-		if user.ID == id {
-			return user, nil
-		}
+	if i, ok := r.index.id(id); ok {
+		return r.customers[i], nil
 	}
 	return model.Customer{}, errors.New("user not found by ID")
 }
 
 func (r *customerRepositoryImpl) GetUserBalance(id string) (float64, error) {
+	r.refresh()
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, user := range r.customers {
-		if user.ID == id {
-			return user.Balance, nil
-		}
+	if i, ok := r.index.id(id); ok {
+		return r.customers[i].Balance, nil
 	}
 	return 0.0, errors.New("user not found for balance check")
 }
 
-func (r *customerRepositoryImpl) UpdateUserBalance(id string, amount float64) (model.Customer, error) {
+// update applies fn to the customer with id, as long as it is still at
+// expectedVersion, and saves it at the next version. failure is the error
+// message used when the customer does not exist or cannot be saved.
+func (r *customerRepositoryImpl) update(id string, expectedVersion int64, failure string, fn func(customer *model.Customer)) (model.Customer, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	for i, user := range r.customers {
-		if user.ID == id {
-			r.customers[i].Balance = amount
-			err := r.saveCustomersToFile()
-			if err != nil {
-				return model.Customer{}, fmt.Errorf("error while updating user balance: %v", err)
-			}
-			return r.customers[i], nil
-		}
+	i, ok := r.index.id(id)
+	if !ok {
+		return model.Customer{}, errors.New(failure)
 	}
+	if r.customers[i].Version != expectedVersion {
+		return model.Customer{}, &model.ConflictError{Record: "customer", ID: id, Expected: expectedVersion, Actual: r.customers[i].Version}
+	}
+
+	// Records handed out before share the wallets map, so it is replaced
+	// rather than changed in place.
+	previous := r.customers[i]
+	r.customers[i].Wallets = maps.Clone(previous.Wallets)
+	fn(&r.customers[i])
+	r.customers[i].Version++
+	err := r.saveCustomer(i)
+	if err != nil {
+		r.customers[i] = previous
+		return model.Customer{}, fmt.Errorf("%s: %v", failure, err)
+	}
+	return r.customers[i], nil
+}
+
+func (r *customerRepositoryImpl) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user balance", func(customer *model.Customer) {
+		customer.Balance = amount
+	})
+}
+
+// UpdateUserWalletBalance sets the customer's balance in currency. The default
+// currency is the same balance UpdateUserBalance sets.
+func (r *customerRepositoryImpl) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user wallet balance", func(customer *model.Customer) {
+		customer.SetBalanceIn(currency, amount)
+	})
+}
 
-	return model.Customer{}, errors.New("error while updating user balance")
+func (r *customerRepositoryImpl) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user points", func(customer *model.Customer) {
+		customer.Points = points
+	})
 }
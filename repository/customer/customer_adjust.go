@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+)
+
+// AdjustUserBalance adds delta to the balance in currency of customer, as read
+// from r, and saves it only while the customer is still at the version it was
+// read at. When another update saved the customer first, it is read again and
+// the update retried, up to model.ConflictRetries times. check, if set, vets
+// the customer before each attempt, so a debit can refuse a balance that is
+// no longer enough.
+func AdjustUserBalance(r CustomerRepository, customer model.Customer, currency string, delta float64, check func(customer model.Customer) error) (model.Customer, error) {
+	return retryOnConflict(r, customer, check, func(customer model.Customer) (model.Customer, error) {
+		balance := customer.BalanceIn(currency) + delta
+		if currency == "" || currency == model.DefaultCurrency {
+			return r.UpdateUserBalance(customer.ID, balance, customer.Version)
+		}
+		return r.UpdateUserWalletBalance(customer.ID, currency, balance, customer.Version)
+	})
+}
+
+// AdjustUserPoints adds delta to the points of customer, as read from r, the
+// way AdjustUserBalance adds to its balance.
+func AdjustUserPoints(r CustomerRepository, customer model.Customer, delta int64, check func(customer model.Customer) error) (model.Customer, error) {
+	return retryOnConflict(r, customer, check, func(customer model.Customer) (model.Customer, error) {
+		return r.UpdateUserPoints(customer.ID, customer.Points+delta, customer.Version)
+	})
+}
+
+func retryOnConflict(r CustomerRepository, customer model.Customer, check func(customer model.Customer) error, update func(customer model.Customer) (model.Customer, error)) (model.Customer, error) {
+	for attempt := 0; ; attempt++ {
+		if check != nil {
+			if err := check(customer); err != nil {
+				return model.Customer{}, err
+			}
+		}
+
+		updated, err := update(customer)
+		if !errors.Is(err, model.ErrConflict) || attempt == model.ConflictRetries {
+			return updated, err
+		}
+
+		customer, err = r.GetUserByID(customer.ID)
+		if err != nil {
+			return model.Customer{}, err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"simple-golang-tdd/model"
+	"testing"
+)
+
+var benchmarkSizes = []int{1_000, 1_000_000}
+
+// Helper function untuk membuat n customer dengan ID dan username unik
+func benchmarkCustomers(n int) []model.Customer {
+	customers := make([]model.Customer, n)
+	for i := range customers {
+		customers[i] = model.Customer{
+			ID:       fmt.Sprintf("cust-%07d", i),
+			Username: fmt.Sprintf("user%07d", i),
+			Balance:  float64(i),
+		}
+	}
+	return customers
+}
+
+// forEachBenchmarkRepository runs bench against the JSON and the in-memory
+// repository holding each of benchmarkSizes customers. The JSON repository is
+// built in memory, so only its lookups are measured.
+func forEachBenchmarkRepository(b *testing.B, bench func(b *testing.B, repo CustomerRepository, n int)) {
+	for _, n := range benchmarkSizes {
+		customers := benchmarkCustomers(n)
+		index, err := newCustomerIndex(customers)
+		if err != nil {
+			b.Fatal(err)
+		}
+		memory, err := NewInMemoryCustomerRepository(customers)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("json/%d", n), func(b *testing.B) {
+			bench(b, &customerRepositoryImpl{customers: customers, index: index}, n)
+		})
+		b.Run(fmt.Sprintf("memory/%d", n), func(b *testing.B) {
+			bench(b, memory, n)
+		})
+	}
+}
+
+func BenchmarkGetUserByID(b *testing.B) {
+	forEachBenchmarkRepository(b, func(b *testing.B, repo CustomerRepository, n int) {
+		// Look up the last customer, the worst case for a linear scan.
+		id := fmt.Sprintf("cust-%07d", n-1)
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetUserByID(id); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetUserByUsername(b *testing.B) {
+	forEachBenchmarkRepository(b, func(b *testing.B, repo CustomerRepository, n int) {
+		username := fmt.Sprintf("USER%07d", n-1)
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetUserByUsername(username); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetUserBalance(b *testing.B) {
+	forEachBenchmarkRepository(b, func(b *testing.B, repo CustomerRepository, n int) {
+		id := fmt.Sprintf("cust-%07d", n-1)
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetUserBalance(id); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+	"simple-golang-tdd/model"
+	"strings"
+)
+
+// customerIndex locates customers in a slice by ID and by username. Usernames
+// are unique regardless of case and are looked up case-insensitively.
+type customerIndex struct {
+	byID       map[string]int
+	byUsername map[string]int
+}
+
+func newCustomerIndex(customers []model.Customer) (customerIndex, error) {
+	index := customerIndex{
+		byID:       make(map[string]int, len(customers)),
+		byUsername: make(map[string]int, len(customers)),
+	}
+	for i, customer := range customers {
+		if _, ok := index.byID[customer.ID]; ok {
+			return customerIndex{}, fmt.Errorf("duplicate customer ID %s", customer.ID)
+		}
+		if _, ok := index.byUsername[usernameKey(customer.Username)]; ok {
+			return customerIndex{}, fmt.Errorf("duplicate username %s", customer.Username)
+		}
+		index.byID[customer.ID] = i
+		index.byUsername[usernameKey(customer.Username)] = i
+	}
+	return index, nil
+}
+
+// id returns the position of the customer with id.
+func (x customerIndex) id(id string) (int, bool) {
+	i, ok := x.byID[id]
+	return i, ok
+}
+
+// username returns the position of the customer with username, in any case.
+func (x customerIndex) username(username string) (int, bool) {
+	i, ok := x.byUsername[usernameKey(username)]
+	return i, ok
+}
+
+// rename moves the customer at i from one username to another.
+func (x customerIndex) rename(i int, from string, to string) error {
+	if usernameKey(from) == usernameKey(to) {
+		return nil
+	}
+	if _, ok := x.byUsername[usernameKey(to)]; ok {
+		return fmt.Errorf("duplicate username %s", to)
+	}
+	delete(x.byUsername, usernameKey(from))
+	x.byUsername[usernameKey(to)] = i
+	return nil
+}
+
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
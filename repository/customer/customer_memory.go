@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"errors"
+	"maps"
+	"simple-golang-tdd/model"
+	"sync"
+)
+
+type inMemoryCustomerRepository struct {
+	customers []model.Customer
+	index     customerIndex
+	mutex     sync.RWMutex
+}
+
+// NewInMemoryCustomerRepository returns a CustomerRepository that keeps
+// customers in memory only, seeded with a copy of customers. It is meant for
+// tests that must not touch the files under ./data.
+func NewInMemoryCustomerRepository(customers []model.Customer) (CustomerRepository, error) {
+	seeded := make([]model.Customer, len(customers))
+	for i, customer := range customers {
+		customer.Wallets = maps.Clone(customer.Wallets)
+		seeded[i] = customer
+	}
+	index, err := newCustomerIndex(seeded)
+	if err != nil {
+		return nil, err
+	}
+	return &inMemoryCustomerRepository{customers: seeded, index: index}, nil
+}
+
+func (r *inMemoryCustomerRepository) GetUserByUsername(username string) (model.Customer, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.index.username(username); ok {
+		return r.customers[i], nil
+	}
+	return model.Customer{}, errors.New("user not found")
+}
+
+func (r *inMemoryCustomerRepository) GetUserByID(id string) (model.Customer, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.index.id(id); ok {
+		return r.customers[i], nil
+	}
+	return model.Customer{}, errors.New("user not found by ID")
+}
+
+func (r *inMemoryCustomerRepository) GetUserBalance(id string) (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.index.id(id); ok {
+		return r.customers[i].Balance, nil
+	}
+	return 0.0, errors.New("user not found for balance check")
+}
+
+// update applies fn to the customer with id, as long as it is still at
+// expectedVersion. failure is the error message used when the customer does
+// not exist.
+func (r *inMemoryCustomerRepository) update(id string, expectedVersion int64, failure string, fn func(customer *model.Customer)) (model.Customer, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i, ok := r.index.id(id)
+	if !ok {
+		return model.Customer{}, errors.New(failure)
+	}
+	if r.customers[i].Version != expectedVersion {
+		return model.Customer{}, &model.ConflictError{Record: "customer", ID: id, Expected: expectedVersion, Actual: r.customers[i].Version}
+	}
+	// Records handed out before share the wallets map, so it is replaced
+	// rather than changed in place.
+	r.customers[i].Wallets = maps.Clone(r.customers[i].Wallets)
+	fn(&r.customers[i])
+	r.customers[i].Version++
+	return r.customers[i], nil
+}
+
+func (r *inMemoryCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user balance", func(customer *model.Customer) {
+		customer.Balance = amount
+	})
+}
+
+func (r *inMemoryCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user wallet balance", func(customer *model.Customer) {
+		customer.SetBalanceIn(currency, amount)
+	})
+}
+
+func (r *inMemoryCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	return r.update(id, expectedVersion, "error while updating user points", func(customer *model.Customer) {
+		customer.Points = points
+	})
+}
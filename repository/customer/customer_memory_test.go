@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"simple-golang-tdd/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInMemoryCustomerRepository_CopiesSeed(t *testing.T) {
+	customers := []model.Customer{{ID: "cust-001", Username: "johndoe", Balance: 100.0, Wallets: map[string]float64{"USD": 10.0}}}
+	repo, err := NewInMemoryCustomerRepository(customers)
+	require.NoError(t, err)
+
+	_, err = repo.UpdateUserBalance("cust-001", 50.0, 0)
+	require.NoError(t, err)
+	_, err = repo.UpdateUserWalletBalance("cust-001", "USD", 5.0, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100.0, customers[0].Balance)
+	assert.Equal(t, 10.0, customers[0].BalanceIn("USD"))
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+)
+
+const customerColumns = "id, name, username, password, tier, balance, wallets, points, version"
+
+type sqliteCustomerRepository struct {
+	db database.Querier
+}
+
+// NewSQLiteCustomerRepository returns a CustomerRepository stored in db. An
+// empty customers table is seeded once from the JSON file at seedPath.
+func NewSQLiteCustomerRepository(db *sql.DB, seedPath string) (CustomerRepository, error) {
+	if err := seedCustomers(db, seedPath); err != nil {
+		return nil, err
+	}
+	return &sqliteCustomerRepository{db: db}, nil
+}
+
+// NewSQLiteCustomerRepositoryTx returns a CustomerRepository that reads and
+// writes through tx, so its updates commit or roll back together.
+func NewSQLiteCustomerRepositoryTx(tx *sql.Tx) CustomerRepository {
+	return &sqliteCustomerRepository{db: tx}
+}
+
+func seedCustomers(db *sql.DB, seedPath string) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM customers").Scan(&count); err != nil {
+		return fmt.Errorf("error while counting customers: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var customers []model.Customer
+	if err := utils.LoadJSONFile(seedPath, &customers); err != nil {
+		return err
+	}
+
+	return database.WithTransaction(db, func(tx *sql.Tx) error {
+		for _, customer := range customers {
+			_, err := tx.Exec("INSERT INTO customers ("+customerColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				customer.ID, customer.Name, customer.Username, customer.Password, customer.Tier, customer.Balance, encodeWallets(customer.Wallets), customer.Points, customer.Version)
+			if err != nil {
+				return fmt.Errorf("error while seeding customer %s: %v", customer.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// encodeWallets stores a wallet map as a JSON object, empty when there is none.
+func encodeWallets(wallets map[string]float64) string {
+	if len(wallets) == 0 {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(wallets)
+	return string(encoded)
+}
+
+func scanCustomer(row *sql.Row) (model.Customer, error) {
+	var customer model.Customer
+	var wallets string
+	err := row.Scan(&customer.ID, &customer.Name, &customer.Username, &customer.Password, &customer.Tier, &customer.Balance, &wallets, &customer.Points, &customer.Version)
+	if err != nil {
+		return model.Customer{}, err
+	}
+
+	if wallets != "{}" {
+		if err := json.Unmarshal([]byte(wallets), &customer.Wallets); err != nil {
+			return model.Customer{}, err
+		}
+	}
+	return customer, nil
+}
+
+func (r *sqliteCustomerRepository) GetUserByUsername(username string) (model.Customer, error) {
+	customer, err := scanCustomer(r.db.QueryRow("SELECT "+customerColumns+" FROM customers WHERE username = ? COLLATE NOCASE", username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Customer{}, errors.New("user not found")
+	}
+	if err != nil {
+		return model.Customer{}, fmt.Errorf("error while getting user: %v", err)
+	}
+	return customer, nil
+}
+
+func (r *sqliteCustomerRepository) GetUserByID(id string) (model.Customer, error) {
+	customer, err := scanCustomer(r.db.QueryRow("SELECT "+customerColumns+" FROM customers WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Customer{}, errors.New("user not found by ID")
+	}
+	if err != nil {
+		return model.Customer{}, fmt.Errorf("error while getting user: %v", err)
+	}
+	return customer, nil
+}
+
+func (r *sqliteCustomerRepository) GetUserBalance(id string) (float64, error) {
+	var balance float64
+	err := r.db.QueryRow("SELECT balance FROM customers WHERE id = ?", id).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0.0, errors.New("user not found for balance check")
+	}
+	if err != nil {
+		return 0.0, fmt.Errorf("error while getting user balance: %v", err)
+	}
+	return balance, nil
+}
+
+// updateCustomer runs an UPDATE ... RETURNING statement on the customer with
+// id, as long as it is still at expectedVersion, and increments its version.
+// failure is the error message used when the customer does not exist.
+func (r *sqliteCustomerRepository) updateCustomer(id string, expectedVersion int64, failure string, set string, args ...any) (model.Customer, error) {
+	args = append(args, id, expectedVersion)
+	customer, err := scanCustomer(r.db.QueryRow("UPDATE customers SET "+set+", version = version + 1 WHERE id = ? AND version = ? RETURNING "+customerColumns, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := r.GetUserByID(id)
+		if err != nil {
+			return model.Customer{}, errors.New(failure)
+		}
+		return model.Customer{}, &model.ConflictError{Record: "customer", ID: id, Expected: expectedVersion, Actual: current.Version}
+	}
+	if err != nil {
+		return model.Customer{}, fmt.Errorf("%s: %v", failure, err)
+	}
+	return customer, nil
+}
+
+func (r *sqliteCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	return r.updateCustomer(id, expectedVersion, "error while updating user balance", "balance = ?", amount)
+}
+
+// UpdateUserWalletBalance sets the customer's balance in currency. The default
+// currency is the same balance UpdateUserBalance sets.
+func (r *sqliteCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	if currency == "" || currency == model.DefaultCurrency {
+		return r.updateCustomer(id, expectedVersion, "error while updating user wallet balance", "balance = ?", amount)
+	}
+	return r.updateCustomer(id, expectedVersion, "error while updating user wallet balance", "wallets = json_set(wallets, '$.' || ?, ?)", currency, amount)
+}
+
+func (r *sqliteCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	return r.updateCustomer(id, expectedVersion, "error while updating user points", "points = ?", points)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteCustomerRepository_SeedsOnlyEmptyTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := database.Open(path)
+	require.NoError(t, err)
+	repo, err := NewSQLiteCustomerRepository(db, "../../data/customers.json")
+	require.NoError(t, err)
+	_, err = repo.UpdateUserBalance("cust-001", 123.0, 0)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err = NewSQLiteCustomerRepository(db, "../../data/customers.json")
+	require.NoError(t, err)
+	balance, err := repo.GetUserBalance("cust-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, 123.0, balance)
+}
+
+func TestNewSQLiteCustomerRepositoryTx_RollsBackWithTransaction(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err := NewSQLiteCustomerRepository(db, "../../data/customers.json")
+	require.NoError(t, err)
+	before, err := repo.GetUserBalance("cust-001")
+	require.NoError(t, err)
+
+	err = database.WithTransaction(db, func(tx *sql.Tx) error {
+		txRepo := NewSQLiteCustomerRepositoryTx(tx)
+		if _, err := txRepo.UpdateUserBalance("cust-001", before-100.0, 0); err != nil {
+			return err
+		}
+		if _, err := txRepo.UpdateUserWalletBalance("cust-001", "USD", 5.0, 1); err != nil {
+			return err
+		}
+		return errors.New("failed to update merchant balance")
+	})
+	require.Error(t, err)
+	customer, err := repo.GetUserByID("cust-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, before, customer.Balance)
+	assert.Zero(t, customer.BalanceIn("USD"))
+}
+
+func TestNewSQLiteCustomerRepository_MissingSeedFile(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewSQLiteCustomerRepository(db, filepath.Join(t.TempDir(), "missing.json"))
+
+	require.Error(t, err)
+	assert.Nil(t, repo)
+}
+
+func TestNewSQLiteCustomerRepository_DuplicateUsername(t *testing.T) {
+	seedPath := filepath.Join(t.TempDir(), "customers.json")
+	require.NoError(t, utils.SaveJSONFile(seedPath, []model.Customer{
+		{ID: "cust-001", Username: "johndoe"},
+		{ID: "cust-002", Username: "JohnDoe"},
+	}))
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewSQLiteCustomerRepository(db, seedPath)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error while seeding customer cust-002")
+	assert.Nil(t, repo)
+}
@@ -1,96 +1,492 @@
 package repository
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// Helper function untuk inisialisasi repository dengan file data json
+// Helper function untuk inisialisasi repository dengan salinan file data json,
+// supaya test tidak mengubah fixture di ./data
 func setupRepository(t *testing.T) CustomerRepository {
-	repo, err := NewCustomerRepository("../../data/customers.json")
+	repo, err := NewCustomerRepository(copyDataFile(t))
 	require.NoError(t, err)
 	return repo
 }
 
+// Helper function untuk inisialisasi repository SQLite di database sementara,
+// diisi dari file data json yang sama
+func setupSQLiteRepository(t *testing.T) CustomerRepository {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewSQLiteCustomerRepository(db, "../../data/customers.json")
+	require.NoError(t, err)
+	return repo
+}
+
+// Helper function untuk menyalin file data json ke direktori sementara
+func copyDataFile(t *testing.T) string {
+	data, err := os.ReadFile("../../data/customers.json")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "customers.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// Helper function untuk menyalin fixture dari testdata sebagai customers.json
+// di direktori sementara
+func copyFixture(t *testing.T, name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "customers.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// Helper function untuk inisialisasi repository in-memory, diisi dari file
+// data json yang sama
+func setupInMemoryRepository(t *testing.T) CustomerRepository {
+	var customers []model.Customer
+	require.NoError(t, utils.LoadJSONFile("../../data/customers.json", &customers))
+	repo, err := NewInMemoryCustomerRepository(customers)
+	require.NoError(t, err)
+	return repo
+}
+
+// forEachBackend runs test against every CustomerRepository implementation, each
+// seeded with the same fixture. The tests run through it are the contract all
+// implementations must pass.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo CustomerRepository)) {
+	t.Run("json", func(t *testing.T) { test(t, setupRepository(t)) })
+	t.Run("sqlite", func(t *testing.T) { test(t, setupSQLiteRepository(t)) })
+	t.Run("memory", func(t *testing.T) { test(t, setupInMemoryRepository(t)) })
+}
+
 // ========== SUCCESS CASES ==========
 
 func TestGetUserByUsername_Success(t *testing.T) {
-	repo := setupRepository(t)
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByUsername("johndoe")
 
-	customer, err := repo.GetUserByUsername("johndoe")
+		require.NoError(t, err)
+		assert.Equal(t, "johndoe", customer.Username)
+	})
+}
 
-	require.NoError(t, err)
-	assert.Equal(t, "johndoe", customer.Username)
+func TestGetUserByUsername_IgnoresCase(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByUsername("JohnDoe")
+
+		require.NoError(t, err)
+		assert.Equal(t, "johndoe", customer.Username)
+	})
 }
 
 func TestGetUserByID_Success(t *testing.T) {
-	repo := setupRepository(t)
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByID("cust-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, "cust-001", customer.ID)
+	})
+}
+
+func TestGetUserBalance_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		balance, err := repo.GetUserBalance("cust-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, balance, balance)
+	})
+}
+
+func TestUpdateUserBalance_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		updatedCustomer, err := repo.UpdateUserBalance("cust-001", 500.0, 0)
+		balance, err := repo.GetUserBalance("cust-001")
 
-	customer, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		assert.Equal(t, balance, updatedCustomer.Balance)
+	})
+}
+
+func TestUpdateUserWalletBalance_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		updatedCustomer, err := repo.UpdateUserWalletBalance("cust-002", "USD", 250.0, 0)
+		require.NoError(t, err)
+		customer, err := repo.GetUserByID("cust-002")
+
+		require.NoError(t, err)
+		assert.Equal(t, 250.0, customer.BalanceIn("USD"))
+		assert.Equal(t, customer.Wallets, updatedCustomer.Wallets)
+	})
+}
+
+func TestUpdateUserPoints_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		updatedCustomer, err := repo.UpdateUserPoints("cust-001", 0, 0)
+		require.NoError(t, err)
+		customer, err := repo.GetUserByID("cust-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), customer.Points)
+		assert.Equal(t, customer.Points, updatedCustomer.Points)
+	})
+}
+
+func TestUpdateUserBalance_OnlyChangesThatCustomer(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		before, err := repo.GetUserByID("cust-002")
+		require.NoError(t, err)
+
+		_, err = repo.UpdateUserBalance("cust-001", 42.0, 0)
+		require.NoError(t, err)
+
+		updated, err := repo.GetUserByUsername("johndoe")
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, updated.Balance)
+		after, err := repo.GetUserByID("cust-002")
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
+
+func TestUpdateUserBalance_IncrementsVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByID("cust-002")
+		require.NoError(t, err)
+
+		_, err = repo.UpdateUserBalance("cust-002", 1.0, customer.Version)
+		require.NoError(t, err)
+		_, err = repo.UpdateUserWalletBalance("cust-002", "USD", 2.0, customer.Version+1)
+		require.NoError(t, err)
+		updated, err := repo.UpdateUserPoints("cust-002", 3, customer.Version+2)
+		require.NoError(t, err)
+
+		assert.Equal(t, customer.Version+3, updated.Version)
+	})
+}
+
+func TestAdjustUserBalance_RetriesOnConflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		stale, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		_, err = repo.UpdateUserBalance("cust-001", stale.Balance+50.0, stale.Version)
+		require.NoError(t, err)
+
+		updated, err := AdjustUserBalance(repo, stale, model.DefaultCurrency, -100.0, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, stale.Balance-50.0, updated.Balance)
+		assert.Equal(t, stale.Version+2, updated.Version)
+		stored, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		assert.Equal(t, updated, stored)
+	})
+}
+
+func TestAdjustUserPoints_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+
+		updated, err := AdjustUserPoints(repo, customer, 25, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, customer.Points+25, updated.Points)
+	})
+}
+
+func TestNewCustomerRepository_ReplaysJournal(t *testing.T) {
+	path := copyDataFile(t)
+	repo, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	_, err = repo.UpdateUserBalance("cust-001", 123.0, 0)
+	require.NoError(t, err)
+	_, err = repo.UpdateUserWalletBalance("cust-001", "USD", 45.0, 1)
+	require.NoError(t, err)
+
+	reopened, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	customer, err := reopened.GetUserByID("cust-001")
 
 	require.NoError(t, err)
-	assert.Equal(t, "cust-001", customer.ID)
+	assert.Equal(t, 123.0, customer.Balance)
+	assert.Equal(t, 45.0, customer.BalanceIn("USD"))
 }
 
-func TestGetUserBalance_Success(t *testing.T) {
-	repo := setupRepository(t)
+func TestUpdateUserBalance_CompactsJournal(t *testing.T) {
+	path := copyDataFile(t)
+	repo, err := NewCustomerRepository(path)
+	require.NoError(t, err)
 
-	balance, err := repo.GetUserBalance("cust-001")
+	for i := 1; i <= utils.JournalCompactThreshold; i++ {
+		_, err := repo.UpdateUserBalance("cust-001", float64(i), int64(i-1))
+		require.NoError(t, err)
+	}
 
+	var customers []model.Customer
+	require.NoError(t, utils.LoadJSONFile(path, &customers))
+	assert.Equal(t, float64(utils.JournalCompactThreshold), customers[0].Balance)
+	info, err := os.Stat(utils.JournalPath(path))
 	require.NoError(t, err)
-	assert.Equal(t, balance, balance)
+	assert.Zero(t, info.Size())
 }
 
-func TestUpdateUserBalance_Success(t *testing.T) {
-	repo := setupRepository(t)
+func TestNewReadOnlyCustomerRepository_ReadsButDoesNotWrite(t *testing.T) {
+	path := copyDataFile(t)
+	repo, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	_, err = repo.UpdateUserBalance("cust-001", 123.0, 0)
+	require.NoError(t, err)
+	journal, err := os.ReadFile(utils.JournalPath(path))
+	require.NoError(t, err)
 
-	updatedCustomer, err := repo.UpdateUserBalance("cust-001", 500.0)
-	balance, err := repo.GetUserBalance("cust-001")
+	readOnly, err := NewReadOnlyCustomerRepository(path)
+	require.NoError(t, err)
+	balance, err := readOnly.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, 123.0, balance)
 
+	_, err = readOnly.UpdateUserBalance("cust-001", 1.0, 1)
+	require.EqualError(t, err, "error while updating user balance: data is read-only")
+	balance, err = readOnly.GetUserBalance("cust-001")
 	require.NoError(t, err)
-	assert.Equal(t, balance, updatedCustomer.Balance)
+	assert.Equal(t, 123.0, balance)
+	unchanged, err := os.ReadFile(utils.JournalPath(path))
+	require.NoError(t, err)
+	assert.Equal(t, journal, unchanged)
+}
+
+func TestNewReadOnlyCustomerRepository_SeesLaterPrimaryWrites(t *testing.T) {
+	path := copyDataFile(t)
+	primary, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	readOnly, err := NewReadOnlyCustomerRepository(path)
+	require.NoError(t, err)
+
+	_, err = primary.UpdateUserBalance("cust-001", 123.0, 0)
+	require.NoError(t, err)
+
+	balance, err := readOnly.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, 123.0, balance)
+
+	// Enough updates to compact the journal into the JSON file.
+	for i := 1; i <= utils.JournalCompactThreshold; i++ {
+		_, err = primary.UpdateUserBalance("cust-001", float64(i), int64(i))
+		require.NoError(t, err)
+	}
+
+	customer, err := readOnly.GetUserByID("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, float64(utils.JournalCompactThreshold), customer.Balance)
+	assert.Equal(t, int64(utils.JournalCompactThreshold+1), customer.Version)
+}
+
+func TestUpdateUserBalance_EncryptsAtRest(t *testing.T) {
+	path := copyDataFile(t)
+	keyring, err := utils.ParseKeyring("k1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	require.NoError(t, err)
+	utils.SetEncryption(keyring, path)
+	t.Cleanup(func() { utils.SetEncryption(nil) })
+
+	repo, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	for i := 1; i <= utils.JournalCompactThreshold+1; i++ {
+		_, err := repo.UpdateUserBalance("cust-001", float64(i), int64(i-1))
+		require.NoError(t, err)
+	}
+
+	for _, file := range []string{path, utils.JournalPath(path)} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), `"key_id":`)
+		assert.NotContains(t, string(content), "cust-001")
+	}
+	reopened, err := NewCustomerRepository(path)
+	require.NoError(t, err)
+	balance, err := reopened.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, float64(utils.JournalCompactThreshold+1), balance)
+}
+
+func TestNewCustomerRepository_LoadsEveryHistoricalVersion(t *testing.T) {
+	janeSmith := model.Customer{ID: "cust-002", Name: "Jane Smith", Username: "janesmith", Password: "mypassword", Balance: 500000}
+	withTier := janeSmith
+	withTier.Tier = "premium"
+	withWallets := withTier
+	withWallets.Wallets = map[string]float64{"USD": 250}
+	fixtures := map[string]model.Customer{
+		"customers.v0-baseline.json": janeSmith,
+		"customers.v0-tier.json":     withTier,
+		"customers.v0-wallets.json":  withWallets,
+		"customers.v1.json":          withWallets,
+	}
+
+	for fixture, expected := range fixtures {
+		path := copyFixture(t, fixture)
+		repo, err := NewCustomerRepository(path)
+		require.NoError(t, err, fixture)
+		customer, err := repo.GetUserByID("cust-002")
+		require.NoError(t, err, fixture)
+		assert.Equal(t, expected, customer, fixture)
+
+		require.NoError(t, utils.MigrateFile(path), fixture)
+		plan, err := utils.PlanMigration(path)
+		require.NoError(t, err, fixture)
+		assert.Equal(t, 1, plan.FromVersion, fixture)
+		assert.Empty(t, plan.Migrations, fixture)
+		migrated, err := NewCustomerRepository(path)
+		require.NoError(t, err, fixture)
+		customer, err = migrated.GetUserByID("cust-002")
+		require.NoError(t, err, fixture)
+		assert.Equal(t, expected, customer, fixture)
+	}
 }
 
 // ========== ERROR CASES ==========
 
 func TestGetUserByUsername_Error(t *testing.T) {
-	repo := setupRepository(t)
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByUsername("unknownuser")
 
-	customer, err := repo.GetUserByUsername("unknownuser")
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "user not found")
+	})
+}
 
-	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "user not found")
+func TestUpdateUserBalance_Conflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		stale, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		_, err = repo.UpdateUserPoints("cust-001", 7, stale.Version)
+		require.NoError(t, err)
+
+		updated, err := repo.UpdateUserBalance("cust-001", 42.0, stale.Version)
+
+		require.ErrorIs(t, err, model.ErrConflict)
+		assert.Equal(t, &model.ConflictError{Record: "customer", ID: "cust-001", Expected: stale.Version, Actual: stale.Version + 1}, err)
+		assert.Empty(t, updated)
+		stored, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		assert.Equal(t, stale.Balance, stored.Balance)
+		assert.Equal(t, int64(7), stored.Points)
+	})
 }
 
-func TestGetUserByID_Error(t *testing.T) {
-	repo := setupRepository(t)
+func TestAdjustUserBalance_CheckRejectsFreshRead(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		stale, err := repo.GetUserByID("cust-001")
+		require.NoError(t, err)
+		_, err = repo.UpdateUserBalance("cust-001", 10.0, stale.Version)
+		require.NoError(t, err)
+		insufficient := errors.New("insufficient balance")
 
-	customer, err := repo.GetUserByID("unknown_id")
+		updated, err := AdjustUserBalance(repo, stale, model.DefaultCurrency, -100.0, func(customer model.Customer) error {
+			if customer.Balance < 100.0 {
+				return insufficient
+			}
+			return nil
+		})
 
-	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "user not found by ID")
+		require.ErrorIs(t, err, insufficient)
+		assert.Empty(t, updated)
+		balance, err := repo.GetUserBalance("cust-001")
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, balance)
+	})
 }
 
-func TestGetUserBalance_Error(t *testing.T) {
-	repo := setupRepository(t)
+func TestGetUserByID_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.GetUserByID("unknown_id")
 
-	balance, err := repo.GetUserBalance("unknown_id")
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "user not found by ID")
+	})
+}
 
-	require.Error(t, err)
-	assert.Equal(t, 0.0, balance)
-	assert.EqualError(t, err, "user not found for balance check")
+func TestGetUserBalance_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		balance, err := repo.GetUserBalance("unknown_id")
+
+		require.Error(t, err)
+		assert.Equal(t, 0.0, balance)
+		assert.EqualError(t, err, "user not found for balance check")
+	})
 }
 
 func TestUpdateUserBalance_Error(t *testing.T) {
-	repo := setupRepository(t)
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.UpdateUserBalance("invalid_id", 500.0, 0)
+
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "error while updating user balance")
+	})
+}
+
+func TestUpdateUserWalletBalance_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.UpdateUserWalletBalance("invalid_id", "USD", 500.0, 0)
+
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "error while updating user wallet balance")
+	})
+}
+
+func TestNewCustomerRepository_UnknownCustomerInJournal(t *testing.T) {
+	path := copyDataFile(t)
+	require.NoError(t, os.WriteFile(utils.JournalPath(path), []byte("{\"id\":\"cust-404\"}\n"), 0644))
+
+	repo, err := NewCustomerRepository(path)
+
+	require.EqualError(t, err, "error while replaying journal record 1: unknown customer cust-404")
+	assert.Nil(t, repo)
+}
+
+func TestNewCustomerRepository_DuplicateUsername(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "customers.json")
+	require.NoError(t, utils.SaveJSONFile(path, []model.Customer{
+		{ID: "cust-001", Username: "johndoe"},
+		{ID: "cust-002", Username: "JohnDoe"},
+	}))
+
+	repo, err := NewCustomerRepository(path)
+
+	require.EqualError(t, err, "duplicate username JohnDoe")
+	assert.Nil(t, repo)
+}
+
+func TestNewInMemoryCustomerRepository_DuplicateID(t *testing.T) {
+	repo, err := NewInMemoryCustomerRepository([]model.Customer{
+		{ID: "cust-001", Username: "johndoe"},
+		{ID: "cust-001", Username: "janesmith"},
+	})
+
+	require.EqualError(t, err, "duplicate customer ID cust-001")
+	assert.Nil(t, repo)
+}
 
-	customer, err := repo.UpdateUserBalance("invalid_id", 500.0)
+func TestUpdateUserPoints_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo CustomerRepository) {
+		customer, err := repo.UpdateUserPoints("invalid_id", 100, 0)
 
-	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "error while updating user balance")
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "error while updating user points")
+	})
 }
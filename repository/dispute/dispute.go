@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+)
+
+type DisputeRepository interface {
+	CreateDispute(dispute model.Dispute) (model.Dispute, error)
+	GetDisputeByID(id string) (model.Dispute, error)
+	GetDisputesByCustomerID(customerID string) ([]model.Dispute, error)
+	GetDisputesByMerchantID(merchantID string) ([]model.Dispute, error)
+	GetOverdueDisputes(at time.Time) ([]model.Dispute, error)
+	UpdateDispute(dispute model.Dispute) (model.Dispute, error)
+}
+
+type disputeRepositoryImpl struct {
+	dataSourcePath string
+	disputes       []model.Dispute
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewDisputeRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewDisputeRepository(dataSourcePath string) (DisputeRepository, error) {
+	repo := &disputeRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *disputeRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.disputes)
+}
+
+func (r *disputeRepositoryImpl) saveDisputesToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.disputes)
+}
+
+// CreateDispute stores a new dispute. A payment can only be disputed once.
+func (r *disputeRepositoryImpl) CreateDispute(dispute model.Dispute) (model.Dispute, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.disputes {
+		if existing.ID == dispute.ID || existing.PaymentID == dispute.PaymentID {
+			return model.Dispute{}, errors.New("dispute already exists")
+		}
+	}
+
+	r.disputes = append(r.disputes, dispute)
+	err := r.saveDisputesToFile()
+	if err != nil {
+		r.disputes = r.disputes[:len(r.disputes)-1]
+		return model.Dispute{}, fmt.Errorf("error while creating dispute: %v", err)
+	}
+
+	return dispute, nil
+}
+
+func (r *disputeRepositoryImpl) GetDisputeByID(id string) (model.Dispute, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, dispute := range r.disputes {
+		if dispute.ID == id {
+			return dispute, nil
+		}
+	}
+	return model.Dispute{}, errors.New("dispute not found by ID")
+}
+
+func (r *disputeRepositoryImpl) GetDisputesByCustomerID(customerID string) ([]model.Dispute, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	disputes := []model.Dispute{}
+	for _, dispute := range r.disputes {
+		if dispute.CustomerID == customerID {
+			disputes = append(disputes, dispute)
+		}
+	}
+	return disputes, nil
+}
+
+func (r *disputeRepositoryImpl) GetDisputesByMerchantID(merchantID string) ([]model.Dispute, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	disputes := []model.Dispute{}
+	for _, dispute := range r.disputes {
+		if dispute.MerchantID == merchantID {
+			disputes = append(disputes, dispute)
+		}
+	}
+	return disputes, nil
+}
+
+// GetOverdueDisputes returns the open disputes whose merchant response deadline is at or before at.
+func (r *disputeRepositoryImpl) GetOverdueDisputes(at time.Time) ([]model.Dispute, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	disputes := []model.Dispute{}
+	for _, dispute := range r.disputes {
+		if dispute.Status == model.DisputeStatusOpen && !dispute.RespondBy.After(at) {
+			disputes = append(disputes, dispute)
+		}
+	}
+	return disputes, nil
+}
+
+func (r *disputeRepositoryImpl) UpdateDispute(dispute model.Dispute) (model.Dispute, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.disputes {
+		if existing.ID == dispute.ID {
+			r.disputes[i] = dispute
+			err := r.saveDisputesToFile()
+			if err != nil {
+				r.disputes[i] = existing
+				return model.Dispute{}, fmt.Errorf("error while updating dispute: %v", err)
+			}
+			return dispute, nil
+		}
+	}
+
+	return model.Dispute{}, errors.New("error while updating dispute")
+}
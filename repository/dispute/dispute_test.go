@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) DisputeRepository {
+	path := filepath.Join(t.TempDir(), "disputes.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewDisputeRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+var fakeCreatedAt = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+func fakeDispute(id string, paymentID string, respondBy time.Time) model.Dispute {
+	return model.Dispute{
+		ID:          id,
+		PaymentID:   paymentID,
+		CustomerID:  "cust-001",
+		MerchantID:  "merchant-001",
+		Amount:      150.0,
+		Currency:    model.DefaultCurrency,
+		Reason:      model.DisputeReasonNotReceived,
+		Description: "Order never arrived",
+		RespondBy:   respondBy,
+		Status:      model.DisputeStatusOpen,
+		Transitions: []model.DisputeTransition{{Status: model.DisputeStatusOpen, Timestamp: fakeCreatedAt}},
+		CreatedAt:   fakeCreatedAt,
+		UpdatedAt:   fakeCreatedAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreateDispute_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	dispute, err := repo.CreateDispute(fakeDispute("dsp-001", "pay-001", fakeCreatedAt))
+
+	require.NoError(t, err)
+	assert.Equal(t, "dsp-001", dispute.ID)
+}
+
+func TestGetDisputesByCustomerAndMerchant_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateDispute(fakeDispute("dsp-001", "pay-001", fakeCreatedAt))
+	require.NoError(t, err)
+	other := fakeDispute("dsp-002", "pay-002", fakeCreatedAt)
+	other.CustomerID, other.MerchantID = "cust-002", "merchant-002"
+	_, err = repo.CreateDispute(other)
+	require.NoError(t, err)
+
+	byCustomer, err := repo.GetDisputesByCustomerID("cust-002")
+	require.NoError(t, err)
+	byMerchant, err := repo.GetDisputesByMerchantID("merchant-001")
+	require.NoError(t, err)
+
+	require.Len(t, byCustomer, 1)
+	assert.Equal(t, "dsp-002", byCustomer[0].ID)
+	require.Len(t, byMerchant, 1)
+	assert.Equal(t, "dsp-001", byMerchant[0].ID)
+}
+
+func TestGetOverdueDisputes_Success(t *testing.T) {
+	repo := setupRepository(t)
+	now := fakeCreatedAt.Add(7 * 24 * time.Hour)
+	_, err := repo.CreateDispute(fakeDispute("dsp-overdue", "pay-001", now))
+	require.NoError(t, err)
+	_, err = repo.CreateDispute(fakeDispute("dsp-pending", "pay-002", now.Add(time.Hour)))
+	require.NoError(t, err)
+	reviewed := fakeDispute("dsp-reviewed", "pay-003", now.Add(-time.Hour))
+	reviewed.Status = model.DisputeStatusUnderReview
+	_, err = repo.CreateDispute(reviewed)
+	require.NoError(t, err)
+
+	disputes, err := repo.GetOverdueDisputes(now)
+
+	require.NoError(t, err)
+	require.Len(t, disputes, 1)
+	assert.Equal(t, "dsp-overdue", disputes[0].ID)
+}
+
+func TestUpdateDispute_Success(t *testing.T) {
+	repo := setupRepository(t)
+	dispute, err := repo.CreateDispute(fakeDispute("dsp-001", "pay-001", fakeCreatedAt))
+	require.NoError(t, err)
+
+	require.NoError(t, dispute.TransitionTo(model.DisputeStatusUnderReview, "merchant responded", fakeCreatedAt.Add(time.Hour)))
+	_, err = repo.UpdateDispute(dispute)
+	require.NoError(t, err)
+	stored, err := repo.GetDisputeByID("dsp-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusUnderReview, stored.Status)
+	assert.Len(t, stored.Transitions, 2)
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreateDispute_PaymentAlreadyDisputed(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateDispute(fakeDispute("dsp-001", "pay-001", fakeCreatedAt))
+	require.NoError(t, err)
+
+	dispute, err := repo.CreateDispute(fakeDispute("dsp-002", "pay-001", fakeCreatedAt))
+
+	require.Error(t, err)
+	assert.Empty(t, dispute)
+	assert.EqualError(t, err, "dispute already exists")
+}
+
+func TestGetDisputeByID_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	dispute, err := repo.GetDisputeByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, dispute)
+	assert.EqualError(t, err, "dispute not found by ID")
+}
+
+func TestUpdateDispute_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	dispute, err := repo.UpdateDispute(fakeDispute("unknown_id", "pay-001", fakeCreatedAt))
+
+	require.Error(t, err)
+	assert.Empty(t, dispute)
+	assert.EqualError(t, err, "error while updating dispute")
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type FeeRepository interface {
+	GetFeeRules() ([]model.FeeRule, error)
+}
+
+type feeRepositoryImpl struct {
+	dataSourcePath string
+	rules          []model.FeeRule
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewFeeRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewFeeRepository(dataSourcePath string) (FeeRepository, error) {
+	repo := &feeRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *feeRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.rules)
+}
+
+func (r *feeRepositoryImpl) GetFeeRules() ([]model.FeeRule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rules := make([]model.FeeRule, len(r.rules))
+	copy(rules, r.rules)
+	return rules, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json
+func setupRepository(t *testing.T) FeeRepository {
+	repo, err := NewFeeRepository("../../data/fee_rules.json")
+	require.NoError(t, err)
+	return repo
+}
+
+func TestGetFeeRules_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	rules, err := repo.GetFeeRules()
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, rules)
+}
+
+func TestNewFeeRepository_Error(t *testing.T) {
+	repo, err := NewFeeRepository("../../data/unknown.json")
+
+	require.Error(t, err)
+	assert.Nil(t, repo)
+}
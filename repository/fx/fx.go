@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type FXRepository interface {
+	GetRate(from, to string) (model.ExchangeRate, error)
+	GetSpreadPercentage() (float64, error)
+}
+
+type fxRepositoryImpl struct {
+	dataSourcePath string
+	table          model.ExchangeRateTable
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewFXRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewFXRepository(dataSourcePath string) (FXRepository, error) {
+	repo := &fxRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *fxRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.table)
+}
+
+// GetRate returns the rate listed for converting from into to. Only rates
+// present in the file are returned; deriving inverse or cross rates is up to
+// the caller.
+func (r *fxRepositoryImpl) GetRate(from, to string) (model.ExchangeRate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, rate := range r.table.Rates {
+		if rate.From == from && rate.To == to {
+			return rate, nil
+		}
+	}
+	return model.ExchangeRate{}, errors.New("exchange rate not found")
+}
+
+func (r *fxRepositoryImpl) GetSpreadPercentage() (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.table.SpreadPercentage, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json
+func setupRepository(t *testing.T) FXRepository {
+	repo, err := NewFXRepository("../../data/exchange_rates.json")
+	require.NoError(t, err)
+	return repo
+}
+
+func TestGetRate_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	rate, err := repo.GetRate("USD", "IDR")
+
+	require.NoError(t, err)
+	assert.Equal(t, 16000.0, rate.Rate)
+}
+
+func TestGetSpreadPercentage_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	spread, err := repo.GetSpreadPercentage()
+
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, spread)
+}
+
+func TestGetRate_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	rate, err := repo.GetRate("IDR", "USD")
+
+	require.Error(t, err)
+	assert.Empty(t, rate)
+	assert.EqualError(t, err, "exchange rate not found")
+}
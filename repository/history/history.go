@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"fmt"
 	"simple-golang-tdd/model"
 	"simple-golang-tdd/utils"
 	"sync"
@@ -44,7 +45,8 @@ func (r *historyRepositoryImpl) UpdateHistory(history model.History) (model.Hist
 	// Now, save the updated slice to the JSON file
 	err := r.saveHistoriesToFile()
 	if err != nil {
-		return model.History{}, err
+		r.histories = r.histories[:len(r.histories)-1]
+		return model.History{}, fmt.Errorf("error while saving history to file: %v", err)
 	}
 
 	return history, nil
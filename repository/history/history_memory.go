@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"simple-golang-tdd/model"
+	"sync"
+)
+
+type inMemoryHistoryRepository struct {
+	histories []model.History
+	mutex     sync.Mutex
+}
+
+// NewInMemoryHistoryRepository returns a HistoryRepository that keeps
+// histories in memory only, seeded with a copy of histories. It is meant for
+// tests that must not touch the files under ./data.
+func NewInMemoryHistoryRepository(histories []model.History) HistoryRepository {
+	return &inMemoryHistoryRepository{histories: append([]model.History(nil), histories...)}
+}
+
+func (r *inMemoryHistoryRepository) UpdateHistory(history model.History) (model.History, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.histories = append(r.histories, history)
+	return history, nil
+}
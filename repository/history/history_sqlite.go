@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+)
+
+type sqliteHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteHistoryRepository returns a HistoryRepository stored in db. An
+// empty histories table is seeded once from the JSON file at seedPath.
+func NewSQLiteHistoryRepository(db *sql.DB, seedPath string) (HistoryRepository, error) {
+	repo := &sqliteHistoryRepository{db: db}
+	if err := repo.seed(seedPath); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *sqliteHistoryRepository) seed(seedPath string) error {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM histories").Scan(&count); err != nil {
+		return fmt.Errorf("error while counting histories: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var histories []model.History
+	if err := utils.LoadJSONFile(seedPath, &histories); err != nil {
+		return err
+	}
+
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		for _, history := range histories {
+			if err := insertHistory(tx, history); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func insertHistory(db database.Querier, history model.History) error {
+	details, err := json.Marshal(history.Details)
+	if err != nil {
+		return fmt.Errorf("error while encoding history details: %v", err)
+	}
+
+	_, err = db.Exec("INSERT INTO histories (id, customer_id, status, action, details, timestamp) VALUES (?, ?, ?, ?, ?, ?)",
+		history.ID, history.CustomerID, history.Status, history.Action, string(details), history.Timestamp)
+	if err != nil {
+		return fmt.Errorf("error while saving history: %v", err)
+	}
+	return nil
+}
+
+func (r *sqliteHistoryRepository) UpdateHistory(history model.History) (model.History, error) {
+	if err := insertHistory(r.db, history); err != nil {
+		return model.History{}, err
+	}
+	return history, nil
+}
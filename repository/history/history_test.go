@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
 	"simple-golang-tdd/dto"
 	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
 	"testing"
 	"time"
 
@@ -10,77 +14,101 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// Helper function untuk inisialisasi repository dengan file data json
+// Helper function untuk inisialisasi repository dengan salinan file data json,
+// supaya test tidak mengubah fixture di ./data
 func setupRepository(t *testing.T) HistoryRepository {
-	repo, err := NewHistoryRepository("../../data/histories.json")
+	repo, err := NewHistoryRepository(copyDataFile(t))
 	require.NoError(t, err)
 	return repo
 }
 
-func TestUpdateHistory_Success(t *testing.T) {
-	repo := setupRepository(t)
-
-	fakeRequest := dto.PaymentRequest{
-		MerchantID: "merchant-002",
-		Amount:     500.0,
-	}
-
-	fakeModel := model.History{
-		ID:         "invalid_id",
-		CustomerID: "cust-001",
-		Action:     "update",
-		Details:    fakeRequest,
-		Timestamp:  time.Now().UTC().Format(time.RFC3339),
-	}
+// Helper function untuk inisialisasi repository SQLite di database sementara,
+// diisi dari file data json yang sama
+func setupSQLiteRepository(t *testing.T) HistoryRepository {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
 
-	history, err := repo.UpdateHistory(fakeModel)
+	repo, err := NewSQLiteHistoryRepository(db, "../../data/histories.json")
+	require.NoError(t, err)
+	return repo
+}
 
+// Helper function untuk menyalin file data json ke direktori sementara
+func copyDataFile(t *testing.T) string {
+	data, err := os.ReadFile("../../data/histories.json")
 	require.NoError(t, err)
-	assert.Equal(t, history, fakeModel)
+	path := filepath.Join(t.TempDir(), "histories.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
 }
 
-func TestUpdateHistory_Error(t *testing.T) {
-	repo := setupRepository(t)
+// Helper function untuk inisialisasi repository in-memory, diisi dari file
+// data json yang sama
+func setupInMemoryRepository(t *testing.T) HistoryRepository {
+	var histories []model.History
+	require.NoError(t, utils.LoadJSONFile("../../data/histories.json", &histories))
+	return NewInMemoryHistoryRepository(histories)
+}
 
-	fakeRequest := dto.PaymentRequest{
-		MerchantID: "merchant-002",
-		Amount:     500.0,
-	}
+// forEachBackend runs test against every HistoryRepository implementation, each
+// seeded with the same fixture. The tests run through it are the contract all
+// implementations must pass.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo HistoryRepository)) {
+	t.Run("json", func(t *testing.T) { test(t, setupRepository(t)) })
+	t.Run("sqlite", func(t *testing.T) { test(t, setupSQLiteRepository(t)) })
+	t.Run("memory", func(t *testing.T) { test(t, setupInMemoryRepository(t)) })
+}
 
-	fakeModel := model.History{
+// Helper function untuk membuat history pembayaran palsu
+func fakeHistory() model.History {
+	return model.History{
 		ID:         "invalid_id",
 		CustomerID: "cust-001",
 		Action:     "update",
-		Details:    fakeRequest,
-		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Details: dto.PaymentRequest{
+			MerchantID: "merchant-002",
+			Amount:     500.0,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
+}
 
-	customer, err := repo.UpdateHistory(fakeModel)
+func TestUpdateHistory_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo HistoryRepository) {
+		fakeModel := fakeHistory()
 
-	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "error while updating logging history")
+		history, err := repo.UpdateHistory(fakeModel)
+
+		require.NoError(t, err)
+		assert.Equal(t, history, fakeModel)
+	})
 }
 
-func TestUpdateHistory_ErrorSavingFile(t *testing.T) {
-	repo := setupRepository(t)
+func TestUpdateHistory_Error(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	repo, err := NewSQLiteHistoryRepository(db, "../../data/histories.json")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
 
-	fakeRequest := dto.PaymentRequest{
-		MerchantID: "merchant-002",
-		Amount:     500.0,
-	}
+	history, err := repo.UpdateHistory(fakeHistory())
 
-	fakeModel := model.History{
-		ID:         "invalid_id",
-		CustomerID: "cust-001",
-		Action:     "update",
-		Details:    fakeRequest,
-		Timestamp:  time.Now().UTC().Format(time.RFC3339),
-	}
+	require.Error(t, err)
+	assert.Empty(t, history)
+	assert.EqualError(t, err, "error while saving history: sql: database is closed")
+}
+
+func TestUpdateHistory_ErrorSavingFile(t *testing.T) {
+	path := copyDataFile(t)
+	repo, err := NewHistoryRepository(path)
+	require.NoError(t, err)
+	// Folder data dihapus supaya file JSON tidak bisa ditulis lagi
+	require.NoError(t, os.RemoveAll(filepath.Dir(path)))
 
-	customer, err := repo.UpdateHistory(fakeModel)
+	history, err := repo.UpdateHistory(fakeHistory())
 
 	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "error while saving history to file")
+	assert.Empty(t, history)
+	assert.ErrorContains(t, err, "error while saving history to file")
 }
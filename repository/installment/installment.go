@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type InstallmentRepository interface {
+	CreatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error)
+	GetPlanByID(id string) (model.InstallmentPlan, error)
+	GetPlansByCustomerID(customerID string) ([]model.InstallmentPlan, error)
+	GetActivePlans() ([]model.InstallmentPlan, error)
+	UpdatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error)
+}
+
+type installmentRepositoryImpl struct {
+	dataSourcePath string
+	plans          []model.InstallmentPlan
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewInstallmentRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewInstallmentRepository(dataSourcePath string) (InstallmentRepository, error) {
+	repo := &installmentRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *installmentRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.plans)
+}
+
+func (r *installmentRepositoryImpl) savePlansToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.plans)
+}
+
+func (r *installmentRepositoryImpl) CreatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.plans {
+		if existing.ID == plan.ID {
+			return model.InstallmentPlan{}, errors.New("installment plan already exists")
+		}
+	}
+
+	r.plans = append(r.plans, plan)
+	err := r.savePlansToFile()
+	if err != nil {
+		r.plans = r.plans[:len(r.plans)-1]
+		return model.InstallmentPlan{}, fmt.Errorf("error while creating installment plan: %v", err)
+	}
+
+	return plan, nil
+}
+
+func (r *installmentRepositoryImpl) GetPlanByID(id string) (model.InstallmentPlan, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, plan := range r.plans {
+		if plan.ID == id {
+			return plan, nil
+		}
+	}
+	return model.InstallmentPlan{}, errors.New("installment plan not found by ID")
+}
+
+func (r *installmentRepositoryImpl) GetPlansByCustomerID(customerID string) ([]model.InstallmentPlan, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	plans := []model.InstallmentPlan{}
+	for _, plan := range r.plans {
+		if plan.CustomerID == customerID {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+// GetActivePlans returns the plans that still have installments to collect.
+func (r *installmentRepositoryImpl) GetActivePlans() ([]model.InstallmentPlan, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	plans := []model.InstallmentPlan{}
+	for _, plan := range r.plans {
+		if plan.Status == model.InstallmentPlanStatusActive {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+func (r *installmentRepositoryImpl) UpdatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.plans {
+		if existing.ID == plan.ID {
+			r.plans[i] = plan
+			err := r.savePlansToFile()
+			if err != nil {
+				r.plans[i] = existing
+				return model.InstallmentPlan{}, fmt.Errorf("error while updating installment plan: %v", err)
+			}
+			return plan, nil
+		}
+	}
+
+	return model.InstallmentPlan{}, errors.New("error while updating installment plan")
+}
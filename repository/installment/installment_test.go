@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) InstallmentRepository {
+	path := filepath.Join(t.TempDir(), "installment_plans.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewInstallmentRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+var fakeCreatedAt = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+func fakePlan(id string, customerID string) model.InstallmentPlan {
+	payment := model.NewPayment("pay-"+id, customerID, "merchant-001", 300.0, fakeCreatedAt)
+	return model.NewInstallmentPlan(id, payment, 3, fakeCreatedAt)
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreatePlan_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	plan, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "plan-001", plan.ID)
+	assert.Len(t, plan.Installments, 3)
+}
+
+func TestGetPlansByCustomerID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+	require.NoError(t, err)
+	_, err = repo.CreatePlan(fakePlan("plan-002", "cust-002"))
+	require.NoError(t, err)
+
+	plans, err := repo.GetPlansByCustomerID("cust-002")
+
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	assert.Equal(t, "plan-002", plans[0].ID)
+}
+
+func TestGetActivePlans_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+	require.NoError(t, err)
+	completed := fakePlan("plan-002", "cust-001")
+	completed.Status = model.InstallmentPlanStatusCompleted
+	_, err = repo.CreatePlan(completed)
+	require.NoError(t, err)
+
+	plans, err := repo.GetActivePlans()
+
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	assert.Equal(t, "plan-001", plans[0].ID)
+}
+
+func TestUpdatePlan_Success(t *testing.T) {
+	repo := setupRepository(t)
+	plan, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+	require.NoError(t, err)
+
+	paidAt := fakeCreatedAt.AddDate(0, 1, 0)
+	plan.Installments[0].Status = model.InstallmentStatusPaid
+	plan.Installments[0].PaidAt = &paidAt
+	_, err = repo.UpdatePlan(plan)
+	require.NoError(t, err)
+	stored, err := repo.GetPlanByID("plan-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.InstallmentStatusPaid, stored.Installments[0].Status)
+	assert.Equal(t, 200.0, stored.Outstanding())
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreatePlan_DuplicateID(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+	require.NoError(t, err)
+
+	plan, err := repo.CreatePlan(fakePlan("plan-001", "cust-001"))
+
+	require.Error(t, err)
+	assert.Empty(t, plan)
+	assert.EqualError(t, err, "installment plan already exists")
+}
+
+func TestGetPlanByID_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	plan, err := repo.GetPlanByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, plan)
+	assert.EqualError(t, err, "installment plan not found by ID")
+}
+
+func TestUpdatePlan_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	plan, err := repo.UpdatePlan(fakePlan("unknown_id", "cust-001"))
+
+	require.Error(t, err)
+	assert.Empty(t, plan)
+	assert.EqualError(t, err, "error while updating installment plan")
+}
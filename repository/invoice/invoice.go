@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+)
+
+type InvoiceRepository interface {
+	CreateInvoice(invoice model.Invoice) (model.Invoice, error)
+	GetInvoiceByID(id string) (model.Invoice, error)
+	GetInvoiceByToken(token string) (model.Invoice, error)
+	UpdateInvoice(invoice model.Invoice) (model.Invoice, error)
+	MarkInvoicePaid(id string, at time.Time) (model.Invoice, error)
+}
+
+type invoiceRepositoryImpl struct {
+	dataSourcePath string
+	invoices       []model.Invoice
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewInvoiceRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewInvoiceRepository(dataSourcePath string) (InvoiceRepository, error) {
+	repo := &invoiceRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *invoiceRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.invoices)
+}
+
+func (r *invoiceRepositoryImpl) saveInvoicesToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.invoices)
+}
+
+func (r *invoiceRepositoryImpl) CreateInvoice(invoice model.Invoice) (model.Invoice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.invoices {
+		if existing.ID == invoice.ID || existing.Token == invoice.Token {
+			return model.Invoice{}, errors.New("invoice already exists")
+		}
+	}
+
+	r.invoices = append(r.invoices, invoice)
+	err := r.saveInvoicesToFile()
+	if err != nil {
+		r.invoices = r.invoices[:len(r.invoices)-1]
+		return model.Invoice{}, fmt.Errorf("error while creating invoice: %v", err)
+	}
+
+	return invoice, nil
+}
+
+func (r *invoiceRepositoryImpl) GetInvoiceByID(id string) (model.Invoice, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, invoice := range r.invoices {
+		if invoice.ID == id {
+			return invoice, nil
+		}
+	}
+	return model.Invoice{}, errors.New("invoice not found by ID")
+}
+
+func (r *invoiceRepositoryImpl) GetInvoiceByToken(token string) (model.Invoice, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, invoice := range r.invoices {
+		if invoice.Token == token {
+			return invoice, nil
+		}
+	}
+	return model.Invoice{}, errors.New("invoice not found by token")
+}
+
+func (r *invoiceRepositoryImpl) UpdateInvoice(invoice model.Invoice) (model.Invoice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.invoices {
+		if existing.ID == invoice.ID {
+			r.invoices[i] = invoice
+			err := r.saveInvoicesToFile()
+			if err != nil {
+				r.invoices[i] = existing
+				return model.Invoice{}, fmt.Errorf("error while updating invoice: %v", err)
+			}
+			return invoice, nil
+		}
+	}
+
+	return model.Invoice{}, errors.New("error while updating invoice")
+}
+
+// MarkInvoicePaid marks the invoice paid at at if it is still open. A
+// single-use invoice is marked before it is charged, so two payments of it
+// cannot both go ahead. It fails with model.ErrInvoicePaid when the invoice
+// is already paid.
+func (r *invoiceRepositoryImpl) MarkInvoicePaid(id string, at time.Time) (model.Invoice, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.invoices {
+		if existing.ID == id {
+			if existing.Status == model.InvoiceStatusPaid {
+				return model.Invoice{}, model.ErrInvoicePaid
+			}
+			invoice := existing
+			invoice.Status = model.InvoiceStatusPaid
+			invoice.UpdatedAt = at
+			r.invoices[i] = invoice
+			err := r.saveInvoicesToFile()
+			if err != nil {
+				r.invoices[i] = existing
+				return model.Invoice{}, fmt.Errorf("error while updating invoice: %v", err)
+			}
+			return invoice, nil
+		}
+	}
+
+	return model.Invoice{}, errors.New("error while updating invoice")
+}
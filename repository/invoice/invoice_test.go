@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) InvoiceRepository {
+	path := filepath.Join(t.TempDir(), "invoices.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewInvoiceRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+func fakeInvoice(id string, token string) model.Invoice {
+	createdAt := time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+	return model.Invoice{
+		ID:          id,
+		MerchantID:  "merchant-001",
+		Amount:      150.0,
+		Currency:    model.DefaultCurrency,
+		Description: "Order #1001",
+		Token:       token,
+		SingleUse:   true,
+		ExpiresAt:   createdAt.Add(24 * time.Hour),
+		Status:      model.InvoiceStatusOpen,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreateInvoice_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	invoice, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "inv-001", invoice.ID)
+}
+
+func TestGetInvoiceByToken_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+	require.NoError(t, err)
+	_, err = repo.CreateInvoice(fakeInvoice("inv-002", "tok-002"))
+	require.NoError(t, err)
+
+	invoice, err := repo.GetInvoiceByToken("tok-002")
+
+	require.NoError(t, err)
+	assert.Equal(t, "inv-002", invoice.ID)
+}
+
+func TestUpdateInvoice_Success(t *testing.T) {
+	repo := setupRepository(t)
+	invoice, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+	require.NoError(t, err)
+
+	invoice.RecordPayment("pay-001", invoice.CreatedAt.Add(time.Hour))
+	_, err = repo.UpdateInvoice(invoice)
+	require.NoError(t, err)
+	stored, err := repo.GetInvoiceByID("inv-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.InvoiceStatusPaid, stored.Status)
+	assert.Equal(t, []string{"pay-001"}, stored.PaymentIDs)
+}
+
+func TestMarkInvoicePaid_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+	require.NoError(t, err)
+	paidAt := time.Date(2024, 4, 27, 10, 0, 0, 0, time.UTC)
+
+	invoice, err := repo.MarkInvoicePaid("inv-001", paidAt)
+
+	require.NoError(t, err)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, paidAt, invoice.UpdatedAt)
+	stored, err := repo.GetInvoiceByID("inv-001")
+	require.NoError(t, err)
+	assert.Equal(t, model.InvoiceStatusPaid, stored.Status)
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreateInvoice_DuplicateToken(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+	require.NoError(t, err)
+
+	invoice, err := repo.CreateInvoice(fakeInvoice("inv-002", "tok-001"))
+
+	require.Error(t, err)
+	assert.Empty(t, invoice)
+	assert.EqualError(t, err, "invoice already exists")
+}
+
+func TestGetInvoiceByID_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	invoice, err := repo.GetInvoiceByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, invoice)
+	assert.EqualError(t, err, "invoice not found by ID")
+}
+
+func TestGetInvoiceByToken_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	invoice, err := repo.GetInvoiceByToken("unknown_token")
+
+	require.Error(t, err)
+	assert.Empty(t, invoice)
+	assert.EqualError(t, err, "invoice not found by token")
+}
+
+func TestUpdateInvoice_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	invoice, err := repo.UpdateInvoice(fakeInvoice("unknown_id", "tok-001"))
+
+	require.Error(t, err)
+	assert.Empty(t, invoice)
+	assert.EqualError(t, err, "error while updating invoice")
+}
+
+func TestMarkInvoicePaid_AlreadyPaid(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateInvoice(fakeInvoice("inv-001", "tok-001"))
+	require.NoError(t, err)
+	_, err = repo.MarkInvoicePaid("inv-001", time.Now())
+	require.NoError(t, err)
+
+	_, err = repo.MarkInvoicePaid("inv-001", time.Now())
+
+	assert.ErrorIs(t, err, model.ErrInvoicePaid)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type LimitRepository interface {
+	GetLimitByTier(tier string) (model.SpendingLimit, error)
+}
+
+type limitRepositoryImpl struct {
+	dataSourcePath string
+	limits         []model.SpendingLimit
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewLimitRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewLimitRepository(dataSourcePath string) (LimitRepository, error) {
+	repo := &limitRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *limitRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.limits)
+}
+
+func (r *limitRepositoryImpl) GetLimitByTier(tier string) (model.SpendingLimit, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, limit := range r.limits {
+		if limit.Tier == tier {
+			return limit, nil
+		}
+	}
+	return model.SpendingLimit{}, errors.New("spending limit not found for tier")
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json
+func setupRepository(t *testing.T) LimitRepository {
+	repo, err := NewLimitRepository("../../data/spending_limits.json")
+	require.NoError(t, err)
+	return repo
+}
+
+func TestGetLimitByTier_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	limit, err := repo.GetLimitByTier("premium")
+
+	require.NoError(t, err)
+	assert.Equal(t, "premium", limit.Tier)
+}
+
+func TestGetLimitByTier_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	limit, err := repo.GetLimitByTier("unknown_tier")
+
+	require.Error(t, err)
+	assert.Empty(t, limit)
+	assert.EqualError(t, err, "spending limit not found for tier")
+}
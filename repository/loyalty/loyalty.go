@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type LoyaltyRepository interface {
+	GetRewardRules() ([]model.RewardRule, error)
+}
+
+type loyaltyRepositoryImpl struct {
+	dataSourcePath string
+	rules          []model.RewardRule
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewLoyaltyRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewLoyaltyRepository(dataSourcePath string) (LoyaltyRepository, error) {
+	repo := &loyaltyRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *loyaltyRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.rules)
+}
+
+func (r *loyaltyRepositoryImpl) GetRewardRules() ([]model.RewardRule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rules := make([]model.RewardRule, len(r.rules))
+	copy(rules, r.rules)
+	return rules, nil
+}
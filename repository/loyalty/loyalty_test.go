@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json
+func setupRepository(t *testing.T) LoyaltyRepository {
+	repo, err := NewLoyaltyRepository("../../data/reward_rules.json")
+	require.NoError(t, err)
+	return repo
+}
+
+func TestGetRewardRules_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	rules, err := repo.GetRewardRules()
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, rules)
+}
+
+func TestNewLoyaltyRepository_Error(t *testing.T) {
+	repo, err := NewLoyaltyRepository("../../data/unknown.json")
+
+	require.Error(t, err)
+	assert.Nil(t, repo)
+}
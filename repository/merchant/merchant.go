@@ -1,67 +1,252 @@
 package repository
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"maps"
 	"simple-golang-tdd/model"
 	"simple-golang-tdd/utils"
 	"sync"
 )
 
 type MerchantRepository interface {
-	UpdateMerchantBalance(id string, amount float64) (model.Merchant, error)
+	GetMerchantByID(id string) (model.Merchant, error)
+	GetMerchantByAPIKey(apiKey string) (model.Merchant, error)
+	// The updates below only save a merchant still at expectedVersion, the
+	// version it was read at, and otherwise fail with a *model.ConflictError.
+	// The saved merchant is at the next version.
+	UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error)
+	UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error)
 	GetMerchantBalance(id string) (float64, error)
 }
 
 type merchantRepositoryImpl struct {
 	dataSourcePath string
 	merchants      []model.Merchant
-	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+	byID           map[string]int // position of each merchant in merchants
+	journal        *utils.Journal
+	stamp          utils.SnapshotStamp // what a read-only repository last loaded
+	mutex          sync.RWMutex        // Untuk menghindari masalah concurrency jika diperlukan
 }
 
 // NewMerchantRepository membuat repository baru dan membaca file JSON sekali saja.
+// Perubahan yang belum masuk ke file JSON diputar ulang dari journal-nya.
 func NewMerchantRepository(dataSourcePath string) (MerchantRepository, error) {
 	repo := &merchantRepositoryImpl{dataSourcePath: dataSourcePath}
 	err := repo.loadData()
 	if err != nil {
 		return nil, err
 	}
+	repo.journal, err = utils.OpenJournal(utils.JournalPath(dataSourcePath), repo.replay)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// NewReadOnlyMerchantRepository membaca file JSON dan journal-nya seperti
+// NewMerchantRepository tanpa pernah menulis keduanya. Keduanya dibaca ulang
+// setiap kali proses lain mengubahnya, dan setiap update gagal dengan
+// utils.ErrReadOnly.
+func NewReadOnlyMerchantRepository(dataSourcePath string) (MerchantRepository, error) {
+	repo := &merchantRepositoryImpl{dataSourcePath: dataSourcePath}
+	if err := repo.loadSnapshot(); err != nil {
+		return nil, err
+	}
 	return repo, nil
 }
 
+// loadSnapshot reads the JSON file and replays its journal without opening
+// it, recording the stamp of what it read.
+func (r *merchantRepositoryImpl) loadSnapshot() error {
+	// Stamped first, so a write made while loading is picked up next time.
+	stamp, err := utils.StampSnapshot(r.dataSourcePath)
+	if err != nil {
+		return err
+	}
+	if err := r.loadData(); err != nil {
+		return err
+	}
+	if err := utils.ReplayJournal(utils.JournalPath(r.dataSourcePath), r.replay); err != nil {
+		return err
+	}
+	r.stamp = stamp
+	return nil
+}
+
+// refresh loads a read-only repository again once the process that owns the
+// files has written them. Until a reload succeeds the last one keeps being
+// served. A writable repository is the only writer and never reloads.
+func (r *merchantRepositoryImpl) refresh() {
+	if r.journal != nil {
+		return
+	}
+	stamp, err := utils.StampSnapshot(r.dataSourcePath)
+	if err != nil {
+		log.Printf("failed to check merchant data for changes: %v", err)
+		return
+	}
+	r.mutex.RLock()
+	unchanged := stamp == r.stamp
+	r.mutex.RUnlock()
+	if unchanged {
+		return
+	}
+
+	reloaded := &merchantRepositoryImpl{dataSourcePath: r.dataSourcePath}
+	if err := reloaded.loadSnapshot(); err != nil {
+		log.Printf("failed to reload merchant data: %v", err)
+		return
+	}
+	r.mutex.Lock()
+	r.merchants, r.byID, r.stamp = reloaded.merchants, reloaded.byID, reloaded.stamp
+	r.mutex.Unlock()
+}
+
 func (r *merchantRepositoryImpl) loadData() error {
-	return utils.LoadJSONFile(r.dataSourcePath, &r.merchants)
+	err := utils.LoadJSONFile(r.dataSourcePath, &r.merchants)
+	if err != nil {
+		return err
+	}
+	r.byID, err = indexMerchants(r.merchants)
+	return err
+}
+
+// replay applies one journal record, the full merchant as it was saved.
+func (r *merchantRepositoryImpl) replay(record json.RawMessage) error {
+	var merchant model.Merchant
+	if err := json.Unmarshal(record, &merchant); err != nil {
+		return err
+	}
+	i, ok := r.byID[merchant.ID]
+	if !ok {
+		return fmt.Errorf("unknown merchant %s", merchant.ID)
+	}
+	r.merchants[i] = merchant
+	return nil
+}
+
+// indexMerchants maps each merchant ID to its position in merchants.
+func indexMerchants(merchants []model.Merchant) (map[string]int, error) {
+	byID := make(map[string]int, len(merchants))
+	for i, merchant := range merchants {
+		if _, ok := byID[merchant.ID]; ok {
+			return nil, fmt.Errorf("duplicate merchant ID %s", merchant.ID)
+		}
+		byID[merchant.ID] = i
+	}
+	return byID, nil
 }
 
-func (r *merchantRepositoryImpl) saveMerchantsToFile() error {
-	return utils.SaveJSONFile(r.dataSourcePath, r.merchants)
+// saveMerchant appends the merchant at index i to the journal. Once the journal
+// holds enough records it is compacted into the JSON file.
+// A read-only repository has no journal and refuses every update.
+func (r *merchantRepositoryImpl) saveMerchant(i int) error {
+	if r.journal == nil {
+		return utils.ErrReadOnly
+	}
+	if err := r.journal.Append(r.merchants[i]); err != nil {
+		return err
+	}
+	if r.journal.Len() >= utils.JournalCompactThreshold {
+		// The update is already durable in the journal, so a failed
+		// compaction is only retried on the next update.
+		if err := r.compactJournal(); err != nil {
+			log.Printf("failed to compact merchant journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// compactJournal saves every merchant to the JSON file and empties the journal.
+func (r *merchantRepositoryImpl) compactJournal() error {
+	if err := utils.SaveJSONFile(r.dataSourcePath, r.merchants); err != nil {
+		return err
+	}
+	return r.journal.Reset()
 }
 
-func (r *merchantRepositoryImpl) UpdateMerchantBalance(id string, amount float64) (model.Merchant, error) {
+func (r *merchantRepositoryImpl) GetMerchantByID(id string) (model.Merchant, error) {
+	r.refresh()
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.byID[id]; ok {
+		return r.merchants[i], nil
+	}
+
+	return model.Merchant{}, errors.New("merchant not found by ID")
+}
+
+// GetMerchantByAPIKey returns the merchant whose stored key hash matches apiKey.
+func (r *merchantRepositoryImpl) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	r.refresh()
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	hash := []byte(utils.HashAPIKey(apiKey))
+	for _, merchant := range r.merchants {
+		if merchant.APIKeyHash != "" && subtle.ConstantTimeCompare([]byte(merchant.APIKeyHash), hash) == 1 {
+			return merchant, nil
+		}
+	}
+
+	return model.Merchant{}, errors.New("merchant not found by API key")
+}
+
+// update applies fn to the merchant with id, as long as it is still at
+// expectedVersion, and saves it at the next version. failure is the error
+// message used when the merchant does not exist or cannot be saved.
+func (r *merchantRepositoryImpl) update(id string, expectedVersion int64, failure string, fn func(merchant *model.Merchant)) (model.Merchant, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	for i, user := range r.merchants {
-		if user.ID == id {
-			r.merchants[i].Balance = amount
-			err := r.saveMerchantsToFile()
-			if err != nil {
-				return model.Merchant{}, err
-			}
-			return r.merchants[i], nil
-		}
+	i, ok := r.byID[id]
+	if !ok {
+		return model.Merchant{}, errors.New(failure)
 	}
+	if r.merchants[i].Version != expectedVersion {
+		return model.Merchant{}, &model.ConflictError{Record: "merchant", ID: id, Expected: expectedVersion, Actual: r.merchants[i].Version}
+	}
+
+	// Records handed out before share the wallets map, so it is replaced
+	// rather than changed in place.
+	previous := r.merchants[i]
+	r.merchants[i].Wallets = maps.Clone(previous.Wallets)
+	fn(&r.merchants[i])
+	r.merchants[i].Version++
+	err := r.saveMerchant(i)
+	if err != nil {
+		r.merchants[i] = previous
+		return model.Merchant{}, fmt.Errorf("%s: %v", failure, err)
+	}
+	return r.merchants[i], nil
+}
+
+func (r *merchantRepositoryImpl) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	return r.update(id, expectedVersion, "error while updating merchant balance", func(merchant *model.Merchant) {
+		merchant.Balance = amount
+	})
+}
 
-	return model.Merchant{}, errors.New("error while updating merchant balance")
+// UpdateMerchantWalletBalance sets the merchant's balance in currency. The
+// default currency is the same balance UpdateMerchantBalance sets.
+func (r *merchantRepositoryImpl) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	return r.update(id, expectedVersion, "error while updating merchant wallet balance", func(merchant *model.Merchant) {
+		merchant.SetBalanceIn(currency, amount)
+	})
 }
 
 func (r *merchantRepositoryImpl) GetMerchantBalance(id string) (float64, error) {
+	r.refresh()
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, user := range r.merchants {
-		if user.ID == id {
-			return user.Balance, nil
-		}
+	if i, ok := r.byID[id]; ok {
+		return r.merchants[i].Balance, nil
 	}
 
 	return 0, errors.New("merchant not found for balance check")
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+)
+
+// AdjustMerchantBalance adds delta to the balance in currency of merchant, as
+// read from r, and saves it only while the merchant is still at the version it
+// was read at. When another update saved the merchant first, it is read again
+// and the update retried, up to model.ConflictRetries times.
+func AdjustMerchantBalance(r MerchantRepository, merchant model.Merchant, currency string, delta float64) (model.Merchant, error) {
+	for attempt := 0; ; attempt++ {
+		balance := merchant.BalanceIn(currency) + delta
+		var updated model.Merchant
+		var err error
+		if currency == "" || currency == model.DefaultCurrency {
+			updated, err = r.UpdateMerchantBalance(merchant.ID, balance, merchant.Version)
+		} else {
+			updated, err = r.UpdateMerchantWalletBalance(merchant.ID, currency, balance, merchant.Version)
+		}
+		if !errors.Is(err, model.ErrConflict) || attempt == model.ConflictRetries {
+			return updated, err
+		}
+
+		merchant, err = r.GetMerchantByID(merchant.ID)
+		if err != nil {
+			return model.Merchant{}, err
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"simple-golang-tdd/model"
+	"testing"
+)
+
+func BenchmarkGetMerchantBalance(b *testing.B) {
+	for _, n := range []int{1_000, 1_000_000} {
+		merchants := make([]model.Merchant, n)
+		for i := range merchants {
+			merchants[i] = model.Merchant{ID: fmt.Sprintf("merchant-%07d", i), Balance: float64(i)}
+		}
+		byID, err := indexMerchants(merchants)
+		if err != nil {
+			b.Fatal(err)
+		}
+		memory, err := NewInMemoryMerchantRepository(merchants)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		// Look up the last merchant, the worst case for a linear scan.
+		id := fmt.Sprintf("merchant-%07d", n-1)
+		repos := []struct {
+			name string
+			repo MerchantRepository
+		}{
+			{"json", &merchantRepositoryImpl{merchants: merchants, byID: byID}},
+			{"memory", memory},
+		}
+		for _, r := range repos {
+			b.Run(fmt.Sprintf("%s/%d", r.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := r.repo.GetMerchantBalance(id); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"crypto/subtle"
+	"errors"
+	"maps"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+)
+
+type inMemoryMerchantRepository struct {
+	merchants []model.Merchant
+	byID      map[string]int
+	mutex     sync.RWMutex
+}
+
+// NewInMemoryMerchantRepository returns a MerchantRepository that keeps
+// merchants in memory only, seeded with a copy of merchants. It is meant for
+// tests that must not touch the files under ./data.
+func NewInMemoryMerchantRepository(merchants []model.Merchant) (MerchantRepository, error) {
+	seeded := make([]model.Merchant, len(merchants))
+	for i, merchant := range merchants {
+		merchant.Wallets = maps.Clone(merchant.Wallets)
+		seeded[i] = merchant
+	}
+	byID, err := indexMerchants(seeded)
+	if err != nil {
+		return nil, err
+	}
+	return &inMemoryMerchantRepository{merchants: seeded, byID: byID}, nil
+}
+
+func (r *inMemoryMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.byID[id]; ok {
+		return r.merchants[i], nil
+	}
+	return model.Merchant{}, errors.New("merchant not found by ID")
+}
+
+// GetMerchantByAPIKey returns the merchant whose stored key hash matches apiKey.
+func (r *inMemoryMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	hash := []byte(utils.HashAPIKey(apiKey))
+	for _, merchant := range r.merchants {
+		if merchant.APIKeyHash != "" && subtle.ConstantTimeCompare([]byte(merchant.APIKeyHash), hash) == 1 {
+			return merchant, nil
+		}
+	}
+	return model.Merchant{}, errors.New("merchant not found by API key")
+}
+
+// update applies fn to the merchant with id, as long as it is still at
+// expectedVersion. failure is the error message used when the merchant does
+// not exist.
+func (r *inMemoryMerchantRepository) update(id string, expectedVersion int64, failure string, fn func(merchant *model.Merchant)) (model.Merchant, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i, ok := r.byID[id]
+	if !ok {
+		return model.Merchant{}, errors.New(failure)
+	}
+	if r.merchants[i].Version != expectedVersion {
+		return model.Merchant{}, &model.ConflictError{Record: "merchant", ID: id, Expected: expectedVersion, Actual: r.merchants[i].Version}
+	}
+	// Records handed out before share the wallets map, so it is replaced
+	// rather than changed in place.
+	r.merchants[i].Wallets = maps.Clone(r.merchants[i].Wallets)
+	fn(&r.merchants[i])
+	r.merchants[i].Version++
+	return r.merchants[i], nil
+}
+
+func (r *inMemoryMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	return r.update(id, expectedVersion, "error while updating merchant balance", func(merchant *model.Merchant) {
+		merchant.Balance = amount
+	})
+}
+
+func (r *inMemoryMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	return r.update(id, expectedVersion, "error while updating merchant wallet balance", func(merchant *model.Merchant) {
+		merchant.SetBalanceIn(currency, amount)
+	})
+}
+
+func (r *inMemoryMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.byID[id]; ok {
+		return r.merchants[i].Balance, nil
+	}
+	return 0, errors.New("merchant not found for balance check")
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"simple-golang-tdd/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInMemoryMerchantRepository_CopiesSeed(t *testing.T) {
+	merchants := []model.Merchant{{ID: "merchant-001", Balance: 100.0, Wallets: map[string]float64{"USD": 10.0}}}
+	repo, err := NewInMemoryMerchantRepository(merchants)
+	require.NoError(t, err)
+
+	_, err = repo.UpdateMerchantBalance("merchant-001", 50.0, 0)
+	require.NoError(t, err)
+	_, err = repo.UpdateMerchantWalletBalance("merchant-001", "USD", 5.0, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100.0, merchants[0].Balance)
+	assert.Equal(t, 10.0, merchants[0].BalanceIn("USD"))
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+)
+
+const merchantColumns = "id, name, bank_account, bank_name, category, currency, api_key_hash, balance, wallets, version"
+
+type sqliteMerchantRepository struct {
+	db database.Querier
+}
+
+// NewSQLiteMerchantRepository returns a MerchantRepository stored in db. An
+// empty merchants table is seeded once from the JSON file at seedPath.
+func NewSQLiteMerchantRepository(db *sql.DB, seedPath string) (MerchantRepository, error) {
+	if err := seedMerchants(db, seedPath); err != nil {
+		return nil, err
+	}
+	return &sqliteMerchantRepository{db: db}, nil
+}
+
+// NewSQLiteMerchantRepositoryTx returns a MerchantRepository that reads and
+// writes through tx, so its updates commit or roll back together.
+func NewSQLiteMerchantRepositoryTx(tx *sql.Tx) MerchantRepository {
+	return &sqliteMerchantRepository{db: tx}
+}
+
+func seedMerchants(db *sql.DB, seedPath string) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM merchants").Scan(&count); err != nil {
+		return fmt.Errorf("error while counting merchants: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var merchants []model.Merchant
+	if err := utils.LoadJSONFile(seedPath, &merchants); err != nil {
+		return err
+	}
+
+	return database.WithTransaction(db, func(tx *sql.Tx) error {
+		for _, merchant := range merchants {
+			_, err := tx.Exec("INSERT INTO merchants ("+merchantColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				merchant.ID, merchant.Name, merchant.BankAccount, merchant.BankName, merchant.Category, merchant.Currency, merchant.APIKeyHash, merchant.Balance, encodeWallets(merchant.Wallets), merchant.Version)
+			if err != nil {
+				return fmt.Errorf("error while seeding merchant %s: %v", merchant.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// encodeWallets stores a wallet map as a JSON object, empty when there is none.
+func encodeWallets(wallets map[string]float64) string {
+	if len(wallets) == 0 {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(wallets)
+	return string(encoded)
+}
+
+func scanMerchant(row *sql.Row) (model.Merchant, error) {
+	var merchant model.Merchant
+	var wallets string
+	err := row.Scan(&merchant.ID, &merchant.Name, &merchant.BankAccount, &merchant.BankName, &merchant.Category, &merchant.Currency, &merchant.APIKeyHash, &merchant.Balance, &wallets, &merchant.Version)
+	if err != nil {
+		return model.Merchant{}, err
+	}
+
+	if wallets != "{}" {
+		if err := json.Unmarshal([]byte(wallets), &merchant.Wallets); err != nil {
+			return model.Merchant{}, err
+		}
+	}
+	return merchant, nil
+}
+
+func (r *sqliteMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	merchant, err := scanMerchant(r.db.QueryRow("SELECT "+merchantColumns+" FROM merchants WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Merchant{}, errors.New("merchant not found by ID")
+	}
+	if err != nil {
+		return model.Merchant{}, fmt.Errorf("error while getting merchant: %v", err)
+	}
+	return merchant, nil
+}
+
+// GetMerchantByAPIKey returns the merchant whose stored key hash matches apiKey.
+func (r *sqliteMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	merchant, err := scanMerchant(r.db.QueryRow("SELECT "+merchantColumns+" FROM merchants WHERE api_key_hash <> '' AND api_key_hash = ?", utils.HashAPIKey(apiKey)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Merchant{}, errors.New("merchant not found by API key")
+	}
+	if err != nil {
+		return model.Merchant{}, fmt.Errorf("error while getting merchant: %v", err)
+	}
+	return merchant, nil
+}
+
+// updateMerchant runs an UPDATE ... RETURNING statement on the merchant with
+// id, as long as it is still at expectedVersion, and increments its version.
+// failure is the error message used when the merchant does not exist.
+func (r *sqliteMerchantRepository) updateMerchant(id string, expectedVersion int64, failure string, set string, args ...any) (model.Merchant, error) {
+	args = append(args, id, expectedVersion)
+	merchant, err := scanMerchant(r.db.QueryRow("UPDATE merchants SET "+set+", version = version + 1 WHERE id = ? AND version = ? RETURNING "+merchantColumns, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := r.GetMerchantByID(id)
+		if err != nil {
+			return model.Merchant{}, errors.New(failure)
+		}
+		return model.Merchant{}, &model.ConflictError{Record: "merchant", ID: id, Expected: expectedVersion, Actual: current.Version}
+	}
+	if err != nil {
+		return model.Merchant{}, fmt.Errorf("%s: %v", failure, err)
+	}
+	return merchant, nil
+}
+
+func (r *sqliteMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	return r.updateMerchant(id, expectedVersion, "error while updating merchant balance", "balance = ?", amount)
+}
+
+// UpdateMerchantWalletBalance sets the merchant's balance in currency. The
+// default currency is the same balance UpdateMerchantBalance sets.
+func (r *sqliteMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	if currency == "" || currency == model.DefaultCurrency {
+		return r.updateMerchant(id, expectedVersion, "error while updating merchant wallet balance", "balance = ?", amount)
+	}
+	return r.updateMerchant(id, expectedVersion, "error while updating merchant wallet balance", "wallets = json_set(wallets, '$.' || ?, ?)", currency, amount)
+}
+
+func (r *sqliteMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	var balance float64
+	err := r.db.QueryRow("SELECT balance FROM merchants WHERE id = ?", id).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("merchant not found for balance check")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error while getting merchant balance: %v", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteMerchantRepository_SeedsOnlyEmptyTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := database.Open(path)
+	require.NoError(t, err)
+	repo, err := NewSQLiteMerchantRepository(db, "../../data/merchants.json")
+	require.NoError(t, err)
+	_, err = repo.UpdateMerchantWalletBalance("merchant-001", "USD", 42.0, 0)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	repo, err = NewSQLiteMerchantRepository(db, "../../data/merchants.json")
+	require.NoError(t, err)
+	merchant, err := repo.GetMerchantByID("merchant-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, merchant.BalanceIn("USD"))
+}
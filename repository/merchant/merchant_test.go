@@ -1,54 +1,306 @@
 package repository
 
 import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// Helper function untuk inisialisasi repository dengan file data json
+// Helper function untuk inisialisasi repository dengan salinan file data json,
+// supaya test tidak mengubah fixture di ./data
 func setupRepository(t *testing.T) MerchantRepository {
-	repo, err := NewMerchantRepository("../../data/merchants.json")
+	repo, err := NewMerchantRepository(copyDataFile(t))
 	require.NoError(t, err)
 	return repo
 }
 
-func TestGetMerchantBalance_Success(t *testing.T) {
-	repo := setupRepository(t)
+// Helper function untuk inisialisasi repository SQLite di database sementara,
+// diisi dari file data json yang sama
+func setupSQLiteRepository(t *testing.T) MerchantRepository {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewSQLiteMerchantRepository(db, "../../data/merchants.json")
+	require.NoError(t, err)
+	return repo
+}
 
-	balance, err := repo.GetMerchantBalance("merchant-001")
+// Helper function untuk menyalin fixture dari testdata sebagai merchants.json
+// di direktori sementara
+func copyFixture(t *testing.T, name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "merchants.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
 
+// Helper function untuk menyalin file data json ke direktori sementara
+func copyDataFile(t *testing.T) string {
+	data, err := os.ReadFile("../../data/merchants.json")
 	require.NoError(t, err)
-	assert.Equal(t, balance, balance)
+	path := filepath.Join(t.TempDir(), "merchants.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// Helper function untuk inisialisasi repository in-memory, diisi dari file
+// data json yang sama
+func setupInMemoryRepository(t *testing.T) MerchantRepository {
+	var merchants []model.Merchant
+	require.NoError(t, utils.LoadJSONFile("../../data/merchants.json", &merchants))
+	repo, err := NewInMemoryMerchantRepository(merchants)
+	require.NoError(t, err)
+	return repo
+}
+
+// forEachBackend runs test against every MerchantRepository implementation, each
+// seeded with the same fixture. The tests run through it are the contract all
+// implementations must pass.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo MerchantRepository)) {
+	t.Run("json", func(t *testing.T) { test(t, setupRepository(t)) })
+	t.Run("sqlite", func(t *testing.T) { test(t, setupSQLiteRepository(t)) })
+	t.Run("memory", func(t *testing.T) { test(t, setupInMemoryRepository(t)) })
+}
+
+func TestGetMerchantByID_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.GetMerchantByID("merchant-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, "merchant-001", merchant.ID)
+		assert.Equal(t, "retail", merchant.Category)
+	})
+}
+
+func TestGetMerchantByAPIKey_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.GetMerchantByAPIKey("mk_test_merchant002")
+
+		require.NoError(t, err)
+		assert.Equal(t, "merchant-002", merchant.ID)
+	})
+}
+
+func TestGetMerchantBalance_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		balance, err := repo.GetMerchantBalance("merchant-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, balance, balance)
+	})
 }
 
 func TestUpdateMerchantBalance_Success(t *testing.T) {
-	repo := setupRepository(t)
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		updatedCustomer, err := repo.UpdateMerchantBalance("merchant-001", 500.0, 0)
+		balance, err := repo.GetMerchantBalance("merchant-001")
+
+		require.NoError(t, err)
+		assert.Equal(t, balance, updatedCustomer.Balance)
+	})
+}
+
+func TestUpdateMerchantBalance_OnlyChangesThatMerchant(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		before, err := repo.GetMerchantByID("merchant-002")
+		require.NoError(t, err)
+
+		_, err = repo.UpdateMerchantBalance("merchant-001", 42.0, 0)
+		require.NoError(t, err)
 
-	updatedCustomer, err := repo.UpdateMerchantBalance("merchant-001", 500.0)
-	balance, err := repo.GetMerchantBalance("merchant-001")
+		updated, err := repo.GetMerchantByID("merchant-001")
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, updated.Balance)
+		after, err := repo.GetMerchantByID("merchant-002")
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
+
+func TestAdjustMerchantBalance_RetriesOnConflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		stale, err := repo.GetMerchantByID("merchant-003")
+		require.NoError(t, err)
+		_, err = repo.UpdateMerchantWalletBalance("merchant-003", "USD", stale.BalanceIn("USD")+50.0, stale.Version)
+		require.NoError(t, err)
+
+		updated, err := AdjustMerchantBalance(repo, stale, "USD", 100.0)
+
+		require.NoError(t, err)
+		assert.Equal(t, stale.BalanceIn("USD")+150.0, updated.BalanceIn("USD"))
+		assert.Equal(t, stale.Version+2, updated.Version)
+	})
+}
 
+func TestNewMerchantRepository_ReplaysJournal(t *testing.T) {
+	path := copyDataFile(t)
+	repo, err := NewMerchantRepository(path)
 	require.NoError(t, err)
-	assert.Equal(t, balance, updatedCustomer.Balance)
+	_, err = repo.UpdateMerchantBalance("merchant-001", 321.0, 0)
+	require.NoError(t, err)
+
+	reopened, err := NewMerchantRepository(path)
+	require.NoError(t, err)
+	balance, err := reopened.GetMerchantBalance("merchant-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, 321.0, balance)
 }
 
-func TestGetMerchantBalance_Error(t *testing.T) {
-	repo := setupRepository(t)
+func TestNewReadOnlyMerchantRepository_SeesLaterPrimaryWrites(t *testing.T) {
+	path := copyDataFile(t)
+	primary, err := NewMerchantRepository(path)
+	require.NoError(t, err)
+	readOnly, err := NewReadOnlyMerchantRepository(path)
+	require.NoError(t, err)
+	balance, err := readOnly.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+	require.NotEqual(t, 321.0, balance)
 
-	balance, err := repo.GetMerchantBalance("unknown_id")
+	_, err = primary.UpdateMerchantBalance("merchant-001", 321.0, 0)
+	require.NoError(t, err)
 
-	require.Error(t, err)
-	assert.Equal(t, 0.0, balance)
-	assert.EqualError(t, err, "merchant not found for balance check")
+	balance, err = readOnly.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+	assert.Equal(t, 321.0, balance)
+	_, err = readOnly.UpdateMerchantBalance("merchant-001", 1.0, 1)
+	assert.EqualError(t, err, "error while updating merchant balance: data is read-only")
 }
 
-func TestUpdateMerchantBalance_Error(t *testing.T) {
-	repo := setupRepository(t)
+func TestNewMerchantRepository_LoadsEveryHistoricalVersion(t *testing.T) {
+	globalGadgets := model.Merchant{ID: "merchant-003", Name: "Global Gadgets", BankAccount: "5566778899", BankName: "Bank Global", Category: "electronics", Currency: "USD", Wallets: map[string]float64{"USD": 1500}}
+	withAPIKey := globalGadgets
+	withAPIKey.APIKeyHash = "65fdc205d38f7a776ec2e70064c68c52688908941c5a67aad44e203595e1a2c8"
+	abcStore := model.Merchant{ID: "merchant-001", Name: "ABC Store", BankAccount: "1234567890", BankName: "Bank ABC", Balance: 600}
+	withCategory := abcStore
+	withCategory.Category = "retail"
+	fixtures := map[string]model.Merchant{
+		"merchants.v0-baseline.json": abcStore,
+		"merchants.v0-category.json": withCategory,
+		"merchants.v0-currency.json": globalGadgets,
+		"merchants.v0-api-key.json":  withAPIKey,
+		"merchants.v1.json":          withAPIKey,
+	}
+
+	for fixture, expected := range fixtures {
+		path := copyFixture(t, fixture)
+		repo, err := NewMerchantRepository(path)
+		require.NoError(t, err, fixture)
+		merchant, err := repo.GetMerchantByID(expected.ID)
+		require.NoError(t, err, fixture)
+		assert.Equal(t, expected, merchant, fixture)
+
+		require.NoError(t, utils.MigrateFile(path), fixture)
+		plan, err := utils.PlanMigration(path)
+		require.NoError(t, err, fixture)
+		assert.Equal(t, 1, plan.FromVersion, fixture)
+		assert.Empty(t, plan.Migrations, fixture)
+		migrated, err := NewMerchantRepository(path)
+		require.NoError(t, err, fixture)
+		merchant, err = migrated.GetMerchantByID(expected.ID)
+		require.NoError(t, err, fixture)
+		assert.Equal(t, expected, merchant, fixture)
+	}
+}
+
+func TestUpdateMerchantBalance_IncrementsVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.GetMerchantByID("merchant-003")
+		require.NoError(t, err)
+
+		_, err = repo.UpdateMerchantBalance("merchant-003", 1.0, merchant.Version)
+		require.NoError(t, err)
+		updated, err := repo.UpdateMerchantWalletBalance("merchant-003", "USD", 2.0, merchant.Version+1)
+		require.NoError(t, err)
+
+		assert.Equal(t, merchant.Version+2, updated.Version)
+	})
+}
+
+func TestUpdateMerchantBalance_Conflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		stale, err := repo.GetMerchantByID("merchant-001")
+		require.NoError(t, err)
+		_, err = repo.UpdateMerchantBalance("merchant-001", 42.0, stale.Version)
+		require.NoError(t, err)
+
+		updated, err := repo.UpdateMerchantBalance("merchant-001", stale.Balance+10.0, stale.Version)
+
+		require.ErrorIs(t, err, model.ErrConflict)
+		assert.Equal(t, &model.ConflictError{Record: "merchant", ID: "merchant-001", Expected: stale.Version, Actual: stale.Version + 1}, err)
+		assert.Empty(t, updated)
+		balance, err := repo.GetMerchantBalance("merchant-001")
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, balance)
+	})
+}
+
+func TestGetMerchantByID_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.GetMerchantByID("unknown_id")
+
+		require.Error(t, err)
+		assert.Empty(t, merchant)
+		assert.EqualError(t, err, "merchant not found by ID")
+	})
+}
+
+func TestGetMerchantByAPIKey_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.GetMerchantByAPIKey("mk_unknown")
+
+		require.Error(t, err)
+		assert.Empty(t, merchant)
+		assert.EqualError(t, err, "merchant not found by API key")
+	})
+}
+
+func TestUpdateMerchantWalletBalance_Success(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		updatedMerchant, err := repo.UpdateMerchantWalletBalance("merchant-003", "USD", 75.0, 0)
+		require.NoError(t, err)
+		merchant, err := repo.GetMerchantByID("merchant-003")
+
+		require.NoError(t, err)
+		assert.Equal(t, 75.0, merchant.BalanceIn("USD"))
+		assert.Equal(t, merchant.Wallets, updatedMerchant.Wallets)
+	})
+}
+
+func TestUpdateMerchantWalletBalance_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		merchant, err := repo.UpdateMerchantWalletBalance("invalid_id", "USD", 75.0, 0)
 
-	customer, err := repo.UpdateMerchantBalance("invalid_id", 500.0)
+		require.Error(t, err)
+		assert.Empty(t, merchant)
+		assert.EqualError(t, err, "error while updating merchant wallet balance")
+	})
+}
+
+func TestGetMerchantBalance_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		balance, err := repo.GetMerchantBalance("unknown_id")
+
+		require.Error(t, err)
+		assert.Equal(t, 0.0, balance)
+		assert.EqualError(t, err, "merchant not found for balance check")
+	})
+}
+
+func TestUpdateMerchantBalance_Error(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo MerchantRepository) {
+		customer, err := repo.UpdateMerchantBalance("invalid_id", 500.0, 0)
 
-	require.Error(t, err)
-	assert.Empty(t, customer)
-	assert.EqualError(t, err, "error while updating merchant balance")
+		require.Error(t, err)
+		assert.Empty(t, customer)
+		assert.EqualError(t, err, "error while updating merchant balance")
+	})
 }
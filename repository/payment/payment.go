@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+)
+
+type PaymentRepository interface {
+	CreatePayment(payment model.Payment) (model.Payment, error)
+	GetPaymentByID(id string) (model.Payment, error)
+	GetPaymentsByCustomerID(customerID string, since time.Time) ([]model.Payment, error)
+	GetDueEscrowPayments(at time.Time) ([]model.Payment, error)
+	UpdatePayment(payment model.Payment) (model.Payment, error)
+}
+
+type paymentRepositoryImpl struct {
+	dataSourcePath string
+	payments       []model.Payment
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewPaymentRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewPaymentRepository(dataSourcePath string) (PaymentRepository, error) {
+	repo := &paymentRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *paymentRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.payments)
+}
+
+func (r *paymentRepositoryImpl) savePaymentsToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.payments)
+}
+
+func (r *paymentRepositoryImpl) CreatePayment(payment model.Payment) (model.Payment, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.payments {
+		if existing.ID == payment.ID {
+			return model.Payment{}, errors.New("payment already exists")
+		}
+	}
+
+	r.payments = append(r.payments, payment)
+	err := r.savePaymentsToFile()
+	if err != nil {
+		r.payments = r.payments[:len(r.payments)-1]
+		return model.Payment{}, fmt.Errorf("error while creating payment: %v", err)
+	}
+
+	return payment, nil
+}
+
+func (r *paymentRepositoryImpl) GetPaymentByID(id string) (model.Payment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.ID == id {
+			return payment, nil
+		}
+	}
+	return model.Payment{}, errors.New("payment not found by ID")
+}
+
+// GetPaymentsByCustomerID returns the customer's payments created at or after since.
+func (r *paymentRepositoryImpl) GetPaymentsByCustomerID(customerID string, since time.Time) ([]model.Payment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	payments := []model.Payment{}
+	for _, payment := range r.payments {
+		if payment.CustomerID == customerID && !payment.CreatedAt.Before(since) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+// GetDueEscrowPayments returns the payments still held in escrow whose release
+// time is at or before at.
+func (r *paymentRepositoryImpl) GetDueEscrowPayments(at time.Time) ([]model.Payment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	payments := []model.Payment{}
+	for _, payment := range r.payments {
+		if payment.Status == model.PaymentStatusEscrowed && payment.ReleaseAt != nil && !payment.ReleaseAt.After(at) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (r *paymentRepositoryImpl) UpdatePayment(payment model.Payment) (model.Payment, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.payments {
+		if existing.ID == payment.ID {
+			r.payments[i] = payment
+			err := r.savePaymentsToFile()
+			if err != nil {
+				r.payments[i] = existing
+				return model.Payment{}, fmt.Errorf("error while updating payment: %v", err)
+			}
+			return payment, nil
+		}
+	}
+
+	return model.Payment{}, errors.New("error while updating payment")
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) PaymentRepository {
+	path := filepath.Join(t.TempDir(), "payments.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewPaymentRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+func fakePayment() model.Payment {
+	return model.NewPayment("pay-001", "cust-001", "merchant-001", 100.0, time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC))
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreatePayment_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	payment, err := repo.CreatePayment(fakePayment())
+
+	require.NoError(t, err)
+	assert.Equal(t, fakePayment(), payment)
+}
+
+func TestGetPaymentByID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreatePayment(fakePayment())
+	require.NoError(t, err)
+
+	payment, err := repo.GetPaymentByID("pay-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusPending, payment.Status)
+}
+
+func TestUpdatePayment_Success(t *testing.T) {
+	repo := setupRepository(t)
+	payment, err := repo.CreatePayment(fakePayment())
+	require.NoError(t, err)
+
+	require.NoError(t, payment.TransitionTo(model.PaymentStatusSucceeded, "", payment.CreatedAt.Add(time.Second)))
+	_, err = repo.UpdatePayment(payment)
+	require.NoError(t, err)
+
+	stored, err := repo.GetPaymentByID("pay-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, stored.Status)
+	assert.Len(t, stored.Transitions, 2)
+}
+
+func TestGetPaymentsByCustomerID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	createdAt := time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC)
+	for _, payment := range []model.Payment{
+		model.NewPayment("pay-001", "cust-001", "merchant-001", 100.0, createdAt.Add(-time.Hour)),
+		model.NewPayment("pay-002", "cust-001", "merchant-001", 100.0, createdAt),
+		model.NewPayment("pay-003", "cust-002", "merchant-001", 100.0, createdAt),
+	} {
+		_, err := repo.CreatePayment(payment)
+		require.NoError(t, err)
+	}
+
+	payments, err := repo.GetPaymentsByCustomerID("cust-001", createdAt)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, "pay-002", payments[0].ID)
+}
+
+func TestGetDueEscrowPayments_Success(t *testing.T) {
+	repo := setupRepository(t)
+	now := time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	for _, tt := range []struct {
+		id        string
+		status    model.PaymentStatus
+		releaseAt *time.Time
+	}{
+		{"pay-due", model.PaymentStatusEscrowed, &now},
+		{"pay-later", model.PaymentStatusEscrowed, &later},
+		{"pay-released", model.PaymentStatusSucceeded, &now},
+		{"pay-regular", model.PaymentStatusSucceeded, nil},
+	} {
+		payment := model.NewPayment(tt.id, "cust-001", "merchant-001", 100.0, now.AddDate(0, 0, -7))
+		payment.Status = tt.status
+		payment.ReleaseAt = tt.releaseAt
+		_, err := repo.CreatePayment(payment)
+		require.NoError(t, err)
+	}
+
+	payments, err := repo.GetDueEscrowPayments(now)
+
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, "pay-due", payments[0].ID)
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreatePayment_DuplicateID(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreatePayment(fakePayment())
+	require.NoError(t, err)
+
+	payment, err := repo.CreatePayment(fakePayment())
+
+	require.Error(t, err)
+	assert.Empty(t, payment)
+	assert.EqualError(t, err, "payment already exists")
+}
+
+func TestGetPaymentByID_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	payment, err := repo.GetPaymentByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, payment)
+	assert.EqualError(t, err, "payment not found by ID")
+}
+
+func TestUpdatePayment_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	payment, err := repo.UpdatePayment(fakePayment())
+
+	require.Error(t, err)
+	assert.Empty(t, payment)
+	assert.EqualError(t, err, "error while updating payment")
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"strings"
+	"sync"
+)
+
+type PromotionRepository interface {
+	GetPromotionByCode(code string) (model.Promotion, error)
+	RedeemPromotion(id string, redemption model.PromotionRedemption) (model.Promotion, error)
+	CancelRedemption(id string, paymentID string) (model.Promotion, error)
+}
+
+type promotionRepositoryImpl struct {
+	dataSourcePath string
+	promotions     []model.Promotion
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewPromotionRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewPromotionRepository(dataSourcePath string) (PromotionRepository, error) {
+	repo := &promotionRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *promotionRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.promotions)
+}
+
+func (r *promotionRepositoryImpl) savePromotionsToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.promotions)
+}
+
+// GetPromotionByCode finds a promotion by its code, ignoring case.
+func (r *promotionRepositoryImpl) GetPromotionByCode(code string) (model.Promotion, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, promotion := range r.promotions {
+		if strings.EqualFold(promotion.Code, code) {
+			return promotion, nil
+		}
+	}
+	return model.Promotion{}, errors.New("promotion not found by code")
+}
+
+// RedeemPromotion records the redemption. The usage caps are checked again
+// under the lock so concurrent payments cannot exceed them.
+func (r *promotionRepositoryImpl) RedeemPromotion(id string, redemption model.PromotionRedemption) (model.Promotion, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.promotions {
+		if existing.ID == id {
+			promotion := existing
+			promotion.Redemptions = append([]model.PromotionRedemption(nil), existing.Redemptions...)
+			if err := promotion.Redeem(redemption); err != nil {
+				return model.Promotion{}, err
+			}
+
+			r.promotions[i] = promotion
+			err := r.savePromotionsToFile()
+			if err != nil {
+				r.promotions[i] = existing
+				return model.Promotion{}, fmt.Errorf("error while redeeming promotion: %v", err)
+			}
+			return promotion, nil
+		}
+	}
+
+	return model.Promotion{}, errors.New("promotion not found by ID")
+}
+
+// CancelRedemption removes the redemption made by a payment, freeing its usage.
+func (r *promotionRepositoryImpl) CancelRedemption(id string, paymentID string) (model.Promotion, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.promotions {
+		if existing.ID == id {
+			promotion := existing
+			promotion.Redemptions = make([]model.PromotionRedemption, 0, len(existing.Redemptions))
+			for _, redemption := range existing.Redemptions {
+				if redemption.PaymentID != paymentID {
+					promotion.Redemptions = append(promotion.Redemptions, redemption)
+				}
+			}
+
+			r.promotions[i] = promotion
+			err := r.savePromotionsToFile()
+			if err != nil {
+				r.promotions[i] = existing
+				return model.Promotion{}, fmt.Errorf("error while cancelling redemption: %v", err)
+			}
+			return promotion, nil
+		}
+	}
+
+	return model.Promotion{}, errors.New("promotion not found by ID")
+}
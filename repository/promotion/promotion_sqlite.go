@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"time"
+)
+
+const promotionColumns = "id, code, description, discount_type, discount_value, max_discount, min_spend, merchant_ids, usage_limit, per_user_limit, starts_at, ends_at"
+
+const redemptionColumns = "payment_id, customer_id, merchant_id, amount, discount, redeemed_at"
+
+type sqlitePromotionRepository struct {
+	db database.Querier
+	// conn is the database a redemption starts its own transaction on. It is
+	// nil when db is already a transaction.
+	conn *sql.DB
+}
+
+// NewSQLitePromotionRepository returns a PromotionRepository stored in db. An
+// empty promotions table is seeded once from the JSON file at seedPath.
+func NewSQLitePromotionRepository(db *sql.DB, seedPath string) (PromotionRepository, error) {
+	if err := seedPromotions(db, seedPath); err != nil {
+		return nil, err
+	}
+	return &sqlitePromotionRepository{db: db, conn: db}, nil
+}
+
+// NewSQLitePromotionRepositoryTx returns a PromotionRepository that reads and
+// writes through tx, so its redemptions commit or roll back with the balance
+// updates made in the same transaction.
+func NewSQLitePromotionRepositoryTx(tx *sql.Tx) PromotionRepository {
+	return &sqlitePromotionRepository{db: tx}
+}
+
+func seedPromotions(db *sql.DB, seedPath string) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM promotions").Scan(&count); err != nil {
+		return fmt.Errorf("error while counting promotions: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var promotions []model.Promotion
+	if err := utils.LoadJSONFile(seedPath, &promotions); err != nil {
+		return err
+	}
+
+	return database.WithTransaction(db, func(tx *sql.Tx) error {
+		for _, promotion := range promotions {
+			merchantIDs, err := json.Marshal(promotion.MerchantIDs)
+			if err != nil {
+				return fmt.Errorf("error while encoding promotion %s merchants: %v", promotion.ID, err)
+			}
+			_, err = tx.Exec("INSERT INTO promotions ("+promotionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				promotion.ID, promotion.Code, promotion.Description, promotion.DiscountType, promotion.DiscountValue, promotion.MaxDiscount, promotion.MinSpend,
+				string(merchantIDs), promotion.UsageLimit, promotion.PerUserLimit, formatTime(promotion.StartsAt), formatTime(promotion.EndsAt))
+			if err != nil {
+				return fmt.Errorf("error while seeding promotion %s: %v", promotion.ID, err)
+			}
+			for _, redemption := range promotion.Redemptions {
+				if err := insertRedemption(tx, promotion.ID, redemption); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func insertRedemption(db database.Querier, promotionID string, redemption model.PromotionRedemption) error {
+	_, err := db.Exec("INSERT INTO promotion_redemptions (promotion_id, "+redemptionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		promotionID, redemption.PaymentID, redemption.CustomerID, redemption.MerchantID, redemption.Amount, redemption.Discount, formatTime(redemption.RedeemedAt))
+	if err != nil {
+		return fmt.Errorf("error while redeeming promotion: %v", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// loadPromotion reads the promotion matching where, with its redemptions in
+// the order they were made.
+func loadPromotion(db database.Querier, where string, arg string) (model.Promotion, error) {
+	var promotion model.Promotion
+	var merchantIDs, startsAt, endsAt string
+	err := db.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE "+where, arg).Scan(
+		&promotion.ID, &promotion.Code, &promotion.Description, &promotion.DiscountType, &promotion.DiscountValue, &promotion.MaxDiscount, &promotion.MinSpend,
+		&merchantIDs, &promotion.UsageLimit, &promotion.PerUserLimit, &startsAt, &endsAt)
+	if err != nil {
+		return model.Promotion{}, err
+	}
+
+	if err := json.Unmarshal([]byte(merchantIDs), &promotion.MerchantIDs); err != nil {
+		return model.Promotion{}, err
+	}
+	if promotion.StartsAt, err = parseTime(startsAt); err != nil {
+		return model.Promotion{}, err
+	}
+	if promotion.EndsAt, err = parseTime(endsAt); err != nil {
+		return model.Promotion{}, err
+	}
+
+	rows, err := db.Query("SELECT "+redemptionColumns+" FROM promotion_redemptions WHERE promotion_id = ? ORDER BY rowid", promotion.ID)
+	if err != nil {
+		return model.Promotion{}, err
+	}
+	defer rows.Close()
+
+	promotion.Redemptions = []model.PromotionRedemption{}
+	for rows.Next() {
+		var redemption model.PromotionRedemption
+		var redeemedAt string
+		if err := rows.Scan(&redemption.PaymentID, &redemption.CustomerID, &redemption.MerchantID, &redemption.Amount, &redemption.Discount, &redeemedAt); err != nil {
+			return model.Promotion{}, err
+		}
+		if redemption.RedeemedAt, err = parseTime(redeemedAt); err != nil {
+			return model.Promotion{}, err
+		}
+		promotion.Redemptions = append(promotion.Redemptions, redemption)
+	}
+	return promotion, rows.Err()
+}
+
+// GetPromotionByCode finds a promotion by its code, ignoring case.
+func (r *sqlitePromotionRepository) GetPromotionByCode(code string) (model.Promotion, error) {
+	promotion, err := loadPromotion(r.db, "code = ? COLLATE NOCASE", code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Promotion{}, errors.New("promotion not found by code")
+	}
+	if err != nil {
+		return model.Promotion{}, fmt.Errorf("error while getting promotion: %v", err)
+	}
+	return promotion, nil
+}
+
+// inTransaction runs fn in a transaction of its own, or directly when the
+// repository is already bound to one. Transactions take the write lock when
+// they begin, so the usage caps read by fn cannot change before it writes.
+func (r *sqlitePromotionRepository) inTransaction(fn func(db database.Querier) error) error {
+	if r.conn == nil {
+		return fn(r.db)
+	}
+	return database.WithTransaction(r.conn, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
+
+// RedeemPromotion records the redemption. The usage caps are checked again
+// in the same transaction so concurrent payments cannot exceed them.
+func (r *sqlitePromotionRepository) RedeemPromotion(id string, redemption model.PromotionRedemption) (model.Promotion, error) {
+	var redeemed model.Promotion
+	err := r.inTransaction(func(db database.Querier) error {
+		promotion, err := loadPromotion(db, "id = ?", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("promotion not found by ID")
+		}
+		if err != nil {
+			return fmt.Errorf("error while getting promotion: %v", err)
+		}
+
+		if err := promotion.Redeem(redemption); err != nil {
+			return err
+		}
+		if err := insertRedemption(db, id, redemption); err != nil {
+			return err
+		}
+		redeemed = promotion
+		return nil
+	})
+	if err != nil {
+		return model.Promotion{}, err
+	}
+	return redeemed, nil
+}
+
+// CancelRedemption removes the redemption made by a payment, freeing its usage.
+func (r *sqlitePromotionRepository) CancelRedemption(id string, paymentID string) (model.Promotion, error) {
+	var cancelled model.Promotion
+	err := r.inTransaction(func(db database.Querier) error {
+		if _, err := db.Exec("DELETE FROM promotion_redemptions WHERE promotion_id = ? AND payment_id = ?", id, paymentID); err != nil {
+			return fmt.Errorf("error while cancelling redemption: %v", err)
+		}
+
+		promotion, err := loadPromotion(db, "id = ?", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("promotion not found by ID")
+		}
+		if err != nil {
+			return fmt.Errorf("error while getting promotion: %v", err)
+		}
+		cancelled = promotion
+		return nil
+	})
+	if err != nil {
+		return model.Promotion{}, err
+	}
+	return cancelled, nil
+}
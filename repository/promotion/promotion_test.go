@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var redeemedAt = time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC)
+
+// Helper function untuk inisialisasi repository dengan satu promo
+func setupRepository(t *testing.T, promotion model.Promotion) PromotionRepository {
+	repo, err := NewPromotionRepository(writePromotionsFile(t, promotion))
+	require.NoError(t, err)
+	return repo
+}
+
+// Helper function untuk inisialisasi repository SQLite di database sementara,
+// diisi dari file json dengan satu promo
+func setupSQLiteRepository(t *testing.T, promotion model.Promotion) PromotionRepository {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewSQLitePromotionRepository(db, writePromotionsFile(t, promotion))
+	require.NoError(t, err)
+	return repo
+}
+
+// Helper function untuk menulis file promotions.json berisi satu promo di
+// direktori sementara
+func writePromotionsFile(t *testing.T, promotion model.Promotion) string {
+	path := filepath.Join(t.TempDir(), "promotions.json")
+	data, err := json.Marshal([]model.Promotion{promotion})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// forEachBackend runs test against every PromotionRepository implementation,
+// each seeded with the same promotion. The tests run through it are the
+// contract all implementations must pass.
+func forEachBackend(t *testing.T, promotion model.Promotion, test func(t *testing.T, repo PromotionRepository)) {
+	t.Run("json", func(t *testing.T) { test(t, setupRepository(t, promotion)) })
+	t.Run("sqlite", func(t *testing.T) { test(t, setupSQLiteRepository(t, promotion)) })
+}
+
+func fakePromotion() model.Promotion {
+	return model.Promotion{
+		ID:            "promo-001",
+		Code:          "HEMAT10",
+		DiscountType:  model.DiscountTypePercentage,
+		DiscountValue: 10,
+		UsageLimit:    5,
+		PerUserLimit:  1,
+		StartsAt:      redeemedAt.Add(-24 * time.Hour),
+		EndsAt:        redeemedAt.Add(24 * time.Hour),
+	}
+}
+
+func fakeRedemption(paymentID string, customerID string) model.PromotionRedemption {
+	return model.PromotionRedemption{
+		PaymentID:  paymentID,
+		CustomerID: customerID,
+		MerchantID: "merchant-001",
+		Amount:     100.0,
+		Discount:   10.0,
+		RedeemedAt: redeemedAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestGetPromotionByCode_IgnoresCase(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		promotion, err := repo.GetPromotionByCode("hemat10")
+
+		require.NoError(t, err)
+		assert.Equal(t, "promo-001", promotion.ID)
+	})
+}
+
+func TestRedeemPromotion_Success(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		promotion, err := repo.RedeemPromotion("promo-001", fakeRedemption("pay-001", "1"))
+
+		require.NoError(t, err)
+		assert.Len(t, promotion.Redemptions, 1)
+		stored, err := repo.GetPromotionByCode("HEMAT10")
+		require.NoError(t, err)
+		assert.Equal(t, "pay-001", stored.Redemptions[0].PaymentID)
+	})
+}
+
+func TestRedeemPromotion_ConcurrentRedemptionsRespectUsageLimit(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _ = repo.RedeemPromotion("promo-001", fakeRedemption(fmt.Sprintf("pay-%03d", i), fmt.Sprintf("customer-%d", i)))
+			}(i)
+		}
+		wg.Wait()
+
+		stored, err := repo.GetPromotionByCode("HEMAT10")
+		require.NoError(t, err)
+		assert.Len(t, stored.Redemptions, 5)
+	})
+}
+
+func TestCancelRedemption_Success(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		_, err := repo.RedeemPromotion("promo-001", fakeRedemption("pay-001", "1"))
+		require.NoError(t, err)
+
+		promotion, err := repo.CancelRedemption("promo-001", "pay-001")
+
+		require.NoError(t, err)
+		assert.Empty(t, promotion.Redemptions)
+		_, err = repo.RedeemPromotion("promo-001", fakeRedemption("pay-002", "1"))
+		assert.NoError(t, err)
+	})
+}
+
+// ========== ERROR CASES ==========
+
+func TestGetPromotionByCode_Error(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		promotion, err := repo.GetPromotionByCode("UNKNOWN")
+
+		require.Error(t, err)
+		assert.Empty(t, promotion)
+		assert.EqualError(t, err, "promotion not found by code")
+	})
+}
+
+func TestRedeemPromotion_PerUserLimitReached(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		_, err := repo.RedeemPromotion("promo-001", fakeRedemption("pay-001", "1"))
+		require.NoError(t, err)
+
+		promotion, err := repo.RedeemPromotion("promo-001", fakeRedemption("pay-002", "1"))
+
+		assert.ErrorIs(t, err, model.ErrPromotionNotApplicable)
+		assert.Empty(t, promotion)
+		stored, err := repo.GetPromotionByCode("HEMAT10")
+		require.NoError(t, err)
+		assert.Len(t, stored.Redemptions, 1)
+	})
+}
+
+func TestRedeemPromotion_NotFound(t *testing.T) {
+	forEachBackend(t, fakePromotion(), func(t *testing.T, repo PromotionRepository) {
+		promotion, err := repo.RedeemPromotion("unknown_id", fakeRedemption("pay-001", "1"))
+
+		require.Error(t, err)
+		assert.Empty(t, promotion)
+		assert.EqualError(t, err, "promotion not found by ID")
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+)
+
+type RewardRepository interface {
+	CreateReward(reward model.Reward) (model.Reward, error)
+	GetRewardsByCustomerID(customerID string) ([]model.Reward, error)
+	GetDueRewards(at time.Time) ([]model.Reward, error)
+	UpdateReward(reward model.Reward) (model.Reward, error)
+}
+
+type rewardRepositoryImpl struct {
+	dataSourcePath string
+	rewards        []model.Reward
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewRewardRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewRewardRepository(dataSourcePath string) (RewardRepository, error) {
+	repo := &rewardRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *rewardRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.rewards)
+}
+
+func (r *rewardRepositoryImpl) saveRewardsToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.rewards)
+}
+
+func (r *rewardRepositoryImpl) CreateReward(reward model.Reward) (model.Reward, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.rewards {
+		if existing.ID == reward.ID {
+			return model.Reward{}, errors.New("reward already exists")
+		}
+	}
+
+	r.rewards = append(r.rewards, reward)
+	err := r.saveRewardsToFile()
+	if err != nil {
+		r.rewards = r.rewards[:len(r.rewards)-1]
+		return model.Reward{}, fmt.Errorf("error while creating reward: %v", err)
+	}
+
+	return reward, nil
+}
+
+func (r *rewardRepositoryImpl) GetRewardsByCustomerID(customerID string) ([]model.Reward, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rewards := []model.Reward{}
+	for _, reward := range r.rewards {
+		if reward.CustomerID == customerID {
+			rewards = append(rewards, reward)
+		}
+	}
+	return rewards, nil
+}
+
+// GetDueRewards returns the pending rewards whose holding period ended at or before at.
+func (r *rewardRepositoryImpl) GetDueRewards(at time.Time) ([]model.Reward, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rewards := []model.Reward{}
+	for _, reward := range r.rewards {
+		if reward.Status == model.RewardStatusPending && !reward.AvailableAt.After(at) {
+			rewards = append(rewards, reward)
+		}
+	}
+	return rewards, nil
+}
+
+func (r *rewardRepositoryImpl) UpdateReward(reward model.Reward) (model.Reward, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.rewards {
+		if existing.ID == reward.ID {
+			r.rewards[i] = reward
+			err := r.saveRewardsToFile()
+			if err != nil {
+				r.rewards[i] = existing
+				return model.Reward{}, fmt.Errorf("error while updating reward: %v", err)
+			}
+			return reward, nil
+		}
+	}
+
+	return model.Reward{}, errors.New("error while updating reward")
+}
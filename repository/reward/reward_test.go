@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) RewardRepository {
+	path := filepath.Join(t.TempDir(), "rewards.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewRewardRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+var fakeCreatedAt = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+func fakeReward(id string, customerID string, availableAt time.Time) model.Reward {
+	return model.Reward{
+		ID:          id,
+		CustomerID:  customerID,
+		PaymentID:   "pay-" + id,
+		MerchantID:  "merchant-001",
+		RuleID:      "reward-default",
+		Type:        model.RewardTypePoints,
+		Amount:      10,
+		Status:      model.RewardStatusPending,
+		AvailableAt: availableAt,
+		CreatedAt:   fakeCreatedAt,
+		UpdatedAt:   fakeCreatedAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreateReward_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	reward, err := repo.CreateReward(fakeReward("rwd-001", "cust-001", fakeCreatedAt))
+
+	require.NoError(t, err)
+	assert.Equal(t, "rwd-001", reward.ID)
+}
+
+func TestGetRewardsByCustomerID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateReward(fakeReward("rwd-001", "cust-001", fakeCreatedAt))
+	require.NoError(t, err)
+	_, err = repo.CreateReward(fakeReward("rwd-002", "cust-002", fakeCreatedAt))
+	require.NoError(t, err)
+
+	rewards, err := repo.GetRewardsByCustomerID("cust-001")
+
+	require.NoError(t, err)
+	require.Len(t, rewards, 1)
+	assert.Equal(t, "rwd-001", rewards[0].ID)
+}
+
+func TestGetDueRewards_Success(t *testing.T) {
+	repo := setupRepository(t)
+	now := fakeCreatedAt.Add(7 * 24 * time.Hour)
+	_, err := repo.CreateReward(fakeReward("rwd-due", "cust-001", now))
+	require.NoError(t, err)
+	_, err = repo.CreateReward(fakeReward("rwd-held", "cust-001", now.Add(time.Hour)))
+	require.NoError(t, err)
+	credited := fakeReward("rwd-credited", "cust-001", now.Add(-time.Hour))
+	credited.Status = model.RewardStatusCredited
+	_, err = repo.CreateReward(credited)
+	require.NoError(t, err)
+
+	rewards, err := repo.GetDueRewards(now)
+
+	require.NoError(t, err)
+	require.Len(t, rewards, 1)
+	assert.Equal(t, "rwd-due", rewards[0].ID)
+}
+
+func TestUpdateReward_Success(t *testing.T) {
+	repo := setupRepository(t)
+	reward, err := repo.CreateReward(fakeReward("rwd-001", "cust-001", fakeCreatedAt))
+	require.NoError(t, err)
+
+	reward.Status = model.RewardStatusCancelled
+	_, err = repo.UpdateReward(reward)
+	require.NoError(t, err)
+	rewards, err := repo.GetRewardsByCustomerID("cust-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.RewardStatusCancelled, rewards[0].Status)
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreateReward_Duplicate(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateReward(fakeReward("rwd-001", "cust-001", fakeCreatedAt))
+	require.NoError(t, err)
+
+	reward, err := repo.CreateReward(fakeReward("rwd-001", "cust-001", fakeCreatedAt))
+
+	require.Error(t, err)
+	assert.Empty(t, reward)
+	assert.EqualError(t, err, "reward already exists")
+}
+
+func TestUpdateReward_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	reward, err := repo.UpdateReward(fakeReward("unknown_id", "cust-001", fakeCreatedAt))
+
+	require.Error(t, err)
+	assert.Empty(t, reward)
+	assert.EqualError(t, err, "error while updating reward")
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+)
+
+type ScheduleRepository interface {
+	CreateSchedule(schedule model.Schedule) (model.Schedule, error)
+	GetScheduleByID(id string) (model.Schedule, error)
+	GetSchedulesByCustomerID(customerID string) ([]model.Schedule, error)
+	GetDueSchedules(at time.Time) ([]model.Schedule, error)
+	UpdateSchedule(schedule model.Schedule) (model.Schedule, error)
+	DeleteSchedule(id string) error
+}
+
+type scheduleRepositoryImpl struct {
+	dataSourcePath string
+	schedules      []model.Schedule
+	mutex          sync.RWMutex // Untuk menghindari masalah concurrency jika diperlukan
+}
+
+// NewScheduleRepository membuat repository baru dan membaca file JSON sekali saja.
+func NewScheduleRepository(dataSourcePath string) (ScheduleRepository, error) {
+	repo := &scheduleRepositoryImpl{dataSourcePath: dataSourcePath}
+	err := repo.loadData()
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *scheduleRepositoryImpl) loadData() error {
+	return utils.LoadJSONFile(r.dataSourcePath, &r.schedules)
+}
+
+func (r *scheduleRepositoryImpl) saveSchedulesToFile() error {
+	return utils.SaveJSONFile(r.dataSourcePath, r.schedules)
+}
+
+func (r *scheduleRepositoryImpl) CreateSchedule(schedule model.Schedule) (model.Schedule, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.schedules {
+		if existing.ID == schedule.ID {
+			return model.Schedule{}, errors.New("schedule already exists")
+		}
+	}
+
+	r.schedules = append(r.schedules, schedule)
+	err := r.saveSchedulesToFile()
+	if err != nil {
+		r.schedules = r.schedules[:len(r.schedules)-1]
+		return model.Schedule{}, fmt.Errorf("error while creating schedule: %v", err)
+	}
+
+	return schedule, nil
+}
+
+func (r *scheduleRepositoryImpl) GetScheduleByID(id string) (model.Schedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, schedule := range r.schedules {
+		if schedule.ID == id {
+			return schedule, nil
+		}
+	}
+	return model.Schedule{}, errors.New("schedule not found by ID")
+}
+
+func (r *scheduleRepositoryImpl) GetSchedulesByCustomerID(customerID string) ([]model.Schedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schedules := []model.Schedule{}
+	for _, schedule := range r.schedules {
+		if schedule.CustomerID == customerID {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+// GetDueSchedules returns the active schedules whose next run is at or before at.
+func (r *scheduleRepositoryImpl) GetDueSchedules(at time.Time) ([]model.Schedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schedules := []model.Schedule{}
+	for _, schedule := range r.schedules {
+		if schedule.Status == model.ScheduleStatusActive && !schedule.NextRunAt.After(at) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (r *scheduleRepositoryImpl) UpdateSchedule(schedule model.Schedule) (model.Schedule, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.schedules {
+		if existing.ID == schedule.ID {
+			r.schedules[i] = schedule
+			err := r.saveSchedulesToFile()
+			if err != nil {
+				r.schedules[i] = existing
+				return model.Schedule{}, fmt.Errorf("error while updating schedule: %v", err)
+			}
+			return schedule, nil
+		}
+	}
+
+	return model.Schedule{}, errors.New("error while updating schedule")
+}
+
+func (r *scheduleRepositoryImpl) DeleteSchedule(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.schedules {
+		if existing.ID == id {
+			schedules := append(append([]model.Schedule{}, r.schedules[:i]...), r.schedules[i+1:]...)
+			previous := r.schedules
+			r.schedules = schedules
+			err := r.saveSchedulesToFile()
+			if err != nil {
+				r.schedules = previous
+				return fmt.Errorf("error while deleting schedule: %v", err)
+			}
+			return nil
+		}
+	}
+
+	return errors.New("error while deleting schedule")
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi repository dengan file data json kosong
+func setupRepository(t *testing.T) ScheduleRepository {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+
+	repo, err := NewScheduleRepository(path)
+	require.NoError(t, err)
+	return repo
+}
+
+var fakeStartAt = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+func fakeSchedule(id string, customerID string, nextRunAt time.Time) model.Schedule {
+	return model.Schedule{
+		ID:         id,
+		CustomerID: customerID,
+		Username:   "johndoe",
+		MerchantID: "merchant-001",
+		Amount:     100.0,
+		Frequency:  model.ScheduleFrequencyDaily,
+		StartAt:    fakeStartAt,
+		Status:     model.ScheduleStatusActive,
+		NextRunAt:  nextRunAt,
+		CreatedAt:  fakeStartAt,
+		UpdatedAt:  fakeStartAt,
+	}
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestCreateSchedule_Success(t *testing.T) {
+	repo := setupRepository(t)
+
+	schedule, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+
+	require.NoError(t, err)
+	assert.Equal(t, "sched-001", schedule.ID)
+}
+
+func TestGetScheduleByID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+	require.NoError(t, err)
+
+	schedule, err := repo.GetScheduleByID("sched-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, "cust-001", schedule.CustomerID)
+}
+
+func TestGetSchedulesByCustomerID_Success(t *testing.T) {
+	repo := setupRepository(t)
+	for _, schedule := range []model.Schedule{
+		fakeSchedule("sched-001", "cust-001", fakeStartAt),
+		fakeSchedule("sched-002", "cust-002", fakeStartAt),
+		fakeSchedule("sched-003", "cust-001", fakeStartAt),
+	} {
+		_, err := repo.CreateSchedule(schedule)
+		require.NoError(t, err)
+	}
+
+	schedules, err := repo.GetSchedulesByCustomerID("cust-001")
+
+	require.NoError(t, err)
+	assert.Len(t, schedules, 2)
+}
+
+func TestGetDueSchedules_Success(t *testing.T) {
+	repo := setupRepository(t)
+	completed := fakeSchedule("sched-003", "cust-001", fakeStartAt)
+	completed.Status = model.ScheduleStatusCompleted
+	for _, schedule := range []model.Schedule{
+		fakeSchedule("sched-001", "cust-001", fakeStartAt),
+		fakeSchedule("sched-002", "cust-001", fakeStartAt.Add(time.Hour)),
+		completed,
+	} {
+		_, err := repo.CreateSchedule(schedule)
+		require.NoError(t, err)
+	}
+
+	schedules, err := repo.GetDueSchedules(fakeStartAt)
+
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, "sched-001", schedules[0].ID)
+}
+
+func TestUpdateSchedule_Success(t *testing.T) {
+	repo := setupRepository(t)
+	schedule, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+	require.NoError(t, err)
+
+	schedule.Amount = 250.0
+	_, err = repo.UpdateSchedule(schedule)
+	require.NoError(t, err)
+	stored, err := repo.GetScheduleByID("sched-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, 250.0, stored.Amount)
+}
+
+func TestDeleteSchedule_Success(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+	require.NoError(t, err)
+
+	err = repo.DeleteSchedule("sched-001")
+	require.NoError(t, err)
+	_, err = repo.GetScheduleByID("sched-001")
+
+	assert.EqualError(t, err, "schedule not found by ID")
+}
+
+// ========== ERROR CASES ==========
+
+func TestCreateSchedule_DuplicateID(t *testing.T) {
+	repo := setupRepository(t)
+	_, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+	require.NoError(t, err)
+
+	schedule, err := repo.CreateSchedule(fakeSchedule("sched-001", "cust-001", fakeStartAt))
+
+	require.Error(t, err)
+	assert.Empty(t, schedule)
+	assert.EqualError(t, err, "schedule already exists")
+}
+
+func TestGetScheduleByID_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	schedule, err := repo.GetScheduleByID("unknown_id")
+
+	require.Error(t, err)
+	assert.Empty(t, schedule)
+	assert.EqualError(t, err, "schedule not found by ID")
+}
+
+func TestUpdateSchedule_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	schedule, err := repo.UpdateSchedule(fakeSchedule("unknown_id", "cust-001", fakeStartAt))
+
+	require.Error(t, err)
+	assert.Empty(t, schedule)
+	assert.EqualError(t, err, "error while updating schedule")
+}
+
+func TestDeleteSchedule_Error(t *testing.T) {
+	repo := setupRepository(t)
+
+	err := repo.DeleteSchedule("unknown_id")
+
+	assert.EqualError(t, err, "error while deleting schedule")
+}
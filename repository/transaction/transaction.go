@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"database/sql"
+	"simple-golang-tdd/database"
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+)
+
+// Transactor runs fn in one storage transaction, passing it customer,
+// merchant and promotion repositories bound to that transaction. Their updates
+// commit together when fn returns nil and roll back together otherwise.
+type Transactor interface {
+	InTransaction(fn func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, promotionRepository promotionRepo.PromotionRepository) error) error
+}
+
+type sqliteTransactor struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactor returns a Transactor over the SQLite repositories in db.
+func NewSQLiteTransactor(db *sql.DB) Transactor {
+	return &sqliteTransactor{db: db}
+}
+
+func (t *sqliteTransactor) InTransaction(fn func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, promotionRepository promotionRepo.PromotionRepository) error) error {
+	return database.WithTransaction(t.db, func(tx *sql.Tx) error {
+		return fn(customerRepo.NewSQLiteCustomerRepositoryTx(tx), merchantRepo.NewSQLiteMerchantRepositoryTx(tx), promotionRepo.NewSQLitePromotionRepositoryTx(tx))
+	})
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function untuk inisialisasi transactor dan repository SQLite di database sementara
+func setupTransactor(t *testing.T) (Transactor, customerRepo.CustomerRepository, merchantRepo.MerchantRepository) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	customers, err := customerRepo.NewSQLiteCustomerRepository(db, "../../data/customers.json")
+	require.NoError(t, err)
+	merchants, err := merchantRepo.NewSQLiteMerchantRepository(db, "../../data/merchants.json")
+	require.NoError(t, err)
+	return NewSQLiteTransactor(db), customers, merchants
+}
+
+// transfer debits the customer and credits the merchant, then returns fail.
+func transfer(amount float64, fail error) func(customerRepo.CustomerRepository, merchantRepo.MerchantRepository, promotionRepo.PromotionRepository) error {
+	return func(customers customerRepo.CustomerRepository, merchants merchantRepo.MerchantRepository, _ promotionRepo.PromotionRepository) error {
+		customer, err := customers.GetUserByID("cust-001")
+		if err != nil {
+			return err
+		}
+		if _, err := customers.UpdateUserBalance(customer.ID, customer.Balance-amount, customer.Version); err != nil {
+			return err
+		}
+		merchant, err := merchants.GetMerchantByID("merchant-001")
+		if err != nil {
+			return err
+		}
+		if _, err := merchants.UpdateMerchantBalance(merchant.ID, merchant.Balance+amount, merchant.Version); err != nil {
+			return err
+		}
+		return fail
+	}
+}
+
+func TestInTransaction_Commit(t *testing.T) {
+	transactor, customers, merchants := setupTransactor(t)
+	customerBalance, err := customers.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	merchantBalance, err := merchants.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+
+	err = transactor.InTransaction(transfer(100.0, nil))
+
+	require.NoError(t, err)
+	balance, err := customers.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, customerBalance-100.0, balance)
+	balance, err = merchants.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+	assert.Equal(t, merchantBalance+100.0, balance)
+}
+
+func TestInTransaction_Rollback(t *testing.T) {
+	transactor, customers, merchants := setupTransactor(t)
+	customerBalance, err := customers.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	merchantBalance, err := merchants.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+
+	err = transactor.InTransaction(transfer(100.0, errors.New("failed to update platform revenue balance")))
+
+	require.EqualError(t, err, "failed to update platform revenue balance")
+	balance, err := customers.GetUserBalance("cust-001")
+	require.NoError(t, err)
+	assert.Equal(t, customerBalance, balance)
+	balance, err = merchants.GetMerchantBalance("merchant-001")
+	require.NoError(t, err)
+	assert.Equal(t, merchantBalance, balance)
+}
+
+func TestInTransaction_RollsBackPromotionRedemption(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	promotions, err := promotionRepo.NewSQLitePromotionRepository(db, "../../data/promotions.json")
+	require.NoError(t, err)
+	transactor := NewSQLiteTransactor(db)
+	redemption := model.PromotionRedemption{PaymentID: "pay-001", CustomerID: "cust-001", Amount: 100000.0, Discount: 10000.0, RedeemedAt: time.Now().UTC()}
+
+	err = transactor.InTransaction(func(_ customerRepo.CustomerRepository, _ merchantRepo.MerchantRepository, promotions promotionRepo.PromotionRepository) error {
+		if _, err := promotions.RedeemPromotion("promo-001", redemption); err != nil {
+			return err
+		}
+		return errors.New("failed to update merchant balance")
+	})
+
+	require.EqualError(t, err, "failed to update merchant balance")
+	promotion, err := promotions.GetPromotionByCode("HEMAT10")
+	require.NoError(t, err)
+	assert.Empty(t, promotion.Redemptions)
+}
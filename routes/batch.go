@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupBatchRoutes(router *gin.RouterGroup, batchController *controller.BatchController) {
+	batchGroup := router.Group("/customer/batches")
+	{
+		batchGroup.POST("", batchController.SubmitBatch)
+		batchGroup.GET("/:id", batchController.GetBatch)
+	}
+}
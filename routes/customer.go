@@ -10,5 +10,8 @@ func SetupCustomerRoutes(router *gin.RouterGroup, customerController *controller
 	customerGroup := router.Group("/customer")
 	{
 		customerGroup.POST("/payment", customerController.Payment)
+		customerGroup.POST("/payment/split", customerController.SplitPayment)
+		customerGroup.POST("/payment/qr", customerController.QRPayment)
+		customerGroup.GET("/payments/:id", customerController.GetPayment)
 	}
 }
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/dispute"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCustomerDisputeRoutes registers the routes customers use to dispute their payments.
+func SetupCustomerDisputeRoutes(router *gin.RouterGroup, disputeController *controller.DisputeController) {
+	disputeGroup := router.Group("/customer/disputes")
+	{
+		disputeGroup.POST("", disputeController.OpenDispute)
+		disputeGroup.GET("", disputeController.GetDisputes)
+		disputeGroup.GET("/:id", disputeController.GetDispute)
+		disputeGroup.POST("/:id/evidence", disputeController.AddEvidence)
+	}
+}
+
+// SetupMerchantDisputeRoutes registers the dispute routes merchants use with their API key.
+func SetupMerchantDisputeRoutes(router *gin.RouterGroup, disputeController *controller.DisputeController) {
+	disputeGroup := router.Group("/disputes")
+	{
+		disputeGroup.GET("", disputeController.GetMerchantDisputes)
+		disputeGroup.GET("/:id", disputeController.GetMerchantDispute)
+		disputeGroup.POST("/:id/respond", disputeController.RespondToDispute)
+	}
+}
+
+// SetupAdminDisputeRoutes registers the dispute routes admins use with the admin key.
+func SetupAdminDisputeRoutes(router *gin.RouterGroup, disputeController *controller.DisputeController) {
+	disputeGroup := router.Group("/disputes")
+	{
+		disputeGroup.POST("/:id/resolve", disputeController.ResolveDispute)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/escrow"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCustomerEscrowRoutes registers the route customers use to confirm delivery of an escrow payment.
+func SetupCustomerEscrowRoutes(router *gin.RouterGroup, escrowController *controller.EscrowController) {
+	router.POST("/customer/payments/:id/confirm", escrowController.ConfirmDelivery)
+}
+
+// SetupMerchantEscrowRoutes registers the route merchants use to cancel an escrow payment.
+func SetupMerchantEscrowRoutes(router *gin.RouterGroup, escrowController *controller.EscrowController) {
+	router.POST("/payments/:id/cancel", escrowController.CancelPayment)
+}
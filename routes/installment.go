@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/installment"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupInstallmentRoutes registers the routes customers use to buy on pay-later and repay their installments.
+func SetupInstallmentRoutes(router *gin.RouterGroup, installmentController *controller.InstallmentController) {
+	router.POST("/customer/payment/installments", installmentController.CreatePlan)
+
+	installmentGroup := router.Group("/customer/installments")
+	{
+		installmentGroup.GET("", installmentController.GetInstallments)
+		installmentGroup.GET("/:id", installmentController.GetPlan)
+		installmentGroup.POST("/:id/pay", installmentController.PayInstallment)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/invoice"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupMerchantInvoiceRoutes registers the invoice routes merchants use with their API key.
+func SetupMerchantInvoiceRoutes(router *gin.RouterGroup, invoiceController *controller.InvoiceController) {
+	invoiceGroup := router.Group("/invoices")
+	{
+		invoiceGroup.POST("", invoiceController.CreateInvoice)
+		invoiceGroup.GET("/:id", invoiceController.GetInvoice)
+	}
+}
+
+// SetupCustomerInvoiceRoutes registers the payment-link routes customers use.
+func SetupCustomerInvoiceRoutes(router *gin.RouterGroup, invoiceController *controller.InvoiceController) {
+	invoiceGroup := router.Group("/customer/invoices")
+	{
+		invoiceGroup.GET("/:token", invoiceController.GetInvoiceByToken)
+		invoiceGroup.POST("/:token/pay", invoiceController.PayInvoice)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/loyalty"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupLoyaltyRoutes(router *gin.RouterGroup, loyaltyController *controller.LoyaltyController) {
+	loyaltyGroup := router.Group("/customer/loyalty")
+	{
+		loyaltyGroup.GET("", loyaltyController.GetLoyalty)
+		loyaltyGroup.POST("/redeem", loyaltyController.RedeemPoints)
+	}
+}
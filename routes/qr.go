@@ -0,0 +1,12 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/qr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupMerchantQRRoutes registers the QRIS routes merchants use with their API key.
+func SetupMerchantQRRoutes(router *gin.RouterGroup, qrController *controller.QRController) {
+	router.GET("/qr", qrController.GenerateQR)
+}
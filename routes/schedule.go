@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "simple-golang-tdd/controller/schedule"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupScheduleRoutes(router *gin.RouterGroup, scheduleController *controller.ScheduleController) {
+	scheduleGroup := router.Group("/customer/schedules")
+	{
+		scheduleGroup.POST("", scheduleController.CreateSchedule)
+		scheduleGroup.GET("", scheduleController.GetSchedules)
+		scheduleGroup.GET("/:id", scheduleController.GetSchedule)
+		scheduleGroup.PUT("/:id", scheduleController.UpdateSchedule)
+		scheduleGroup.DELETE("/:id", scheduleController.DeleteSchedule)
+	}
+}
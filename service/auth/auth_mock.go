@@ -26,7 +26,17 @@ func (m *MockCustomerRepository) GetUserBalance(id string) (float64, error) {
 	return args.Get(0).(float64), args.Error(1)
 }
 
-func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64) (model.Customer, error) {
-	args := m.Called(id, amount)
+func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, points, expectedVersion)
 	return args.Get(0).(model.Customer), args.Error(1)
 }
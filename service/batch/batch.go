@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"strings"
+	"time"
+
+	batchRepo "simple-golang-tdd/repository/batch"
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBatchNotFound = errors.New("batch not found")
+	ErrInvalidBatch  = errors.New("invalid batch")
+)
+
+// MaxBatchLines is the largest number of payment instructions a batch may hold.
+const MaxBatchLines = 1000
+
+// interruptedLineError is recorded on a line that was being paid when the
+// server stopped. It is not paid again since its payment may have been made.
+const interruptedLineError = "interrupted while being paid, check the payments before submitting it again"
+
+type BatchService interface {
+	SubmitBatch(requests []dto.PaymentRequest, username string) (model.Batch, error)
+	GetBatch(id string, username string) (model.Batch, error)
+	ResumeBatches() error
+}
+
+type batchServiceImpl struct {
+	batchRepository    batchRepo.BatchRepository
+	customerRepository customerRepo.CustomerRepository
+	merchantRepository merchantRepo.MerchantRepository
+	customerService    customerService.CustomerService
+	dispatch           func(process func()) // runs a submitted batch in the background
+}
+
+func NewBatchService(batchRepository batchRepo.BatchRepository, customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, customerService customerService.CustomerService) BatchService {
+	return &batchServiceImpl{
+		batchRepository:    batchRepository,
+		customerRepository: customerRepository,
+		merchantRepository: merchantRepository,
+		customerService:    customerService,
+		dispatch:           func(process func()) { go process() }}
+}
+
+// SubmitBatch validates every line up front and stores the batch as pending.
+// The payments are then made one line at a time in the background; the batch
+// returned here only reflects the submission.
+func (s *batchServiceImpl) SubmitBatch(requests []dto.PaymentRequest, username string) (model.Batch, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Batch{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	if err := s.validateBatch(requests); err != nil {
+		return model.Batch{}, err
+	}
+
+	now := time.Now().UTC()
+	batch := model.Batch{
+		ID:         uuid.New().String(),
+		CustomerID: customer.ID,
+		Username:   customer.Username,
+		Status:     model.BatchStatusPending,
+		Total:      len(requests),
+		Lines:      make([]model.BatchLine, 0, len(requests)),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	for i, request := range requests {
+		batch.Lines = append(batch.Lines, model.BatchLine{
+			Line:       i + 1,
+			MerchantID: request.MerchantID,
+			Amount:     request.Amount,
+			Currency:   request.Currency,
+			PromoCode:  request.PromoCode,
+			Escrow:     request.Escrow,
+			Status:     model.BatchLineStatusPending,
+		})
+	}
+
+	created, err := s.batchRepository.CreateBatch(batch)
+	if err != nil {
+		return model.Batch{}, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	s.dispatch(func() { s.processBatch(batch) })
+
+	return created, nil
+}
+
+func (s *batchServiceImpl) GetBatch(id string, username string) (model.Batch, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Batch{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	batch, err := s.batchRepository.GetBatchByID(id)
+	if err != nil || batch.CustomerID != customer.ID {
+		return model.Batch{}, ErrBatchNotFound
+	}
+
+	return batch, nil
+}
+
+// ResumeBatches processes again the batches left pending or processing when
+// the server stopped. Lines with an outcome keep it and the rest are paid.
+func (s *batchServiceImpl) ResumeBatches() error {
+	batches, err := s.batchRepository.GetUnfinishedBatches()
+	if err != nil {
+		return fmt.Errorf("failed to get unfinished batches: %w", err)
+	}
+
+	for _, batch := range batches {
+		s.dispatch(func() { s.processBatch(batch) })
+	}
+	return nil
+}
+
+// validateBatch checks every line and reports all invalid ones at once, so
+// nothing is paid unless the whole batch is acceptable.
+func (s *batchServiceImpl) validateBatch(requests []dto.PaymentRequest) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("%w: batch has no lines", ErrInvalidBatch)
+	}
+	if len(requests) > MaxBatchLines {
+		return fmt.Errorf("%w: batch has %d lines, the maximum is %d", ErrInvalidBatch, len(requests), MaxBatchLines)
+	}
+
+	knownMerchants := make(map[string]bool)
+	var lineErrors []string
+	for i, request := range requests {
+		if err := s.validateLine(request, knownMerchants); err != nil {
+			lineErrors = append(lineErrors, fmt.Sprintf("line %d: %v", i+1, err))
+		}
+	}
+
+	if len(lineErrors) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidBatch, strings.Join(lineErrors, "; "))
+	}
+	return nil
+}
+
+func (s *batchServiceImpl) validateLine(request dto.PaymentRequest, knownMerchants map[string]bool) error {
+	if request.MerchantID == "" {
+		return errors.New("merchant_id is required")
+	}
+
+	currency := request.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if err := customerService.ValidateAmount(request.Amount, currency); err != nil {
+		return err
+	}
+	if request.PromoCode != "" && currency != model.DefaultCurrency {
+		return fmt.Errorf("promo codes only apply to %s payments", model.DefaultCurrency)
+	}
+	if request.Escrow && currency != model.DefaultCurrency {
+		return fmt.Errorf("escrow is only available for %s payments", model.DefaultCurrency)
+	}
+
+	if !knownMerchants[request.MerchantID] {
+		if _, err := s.merchantRepository.GetMerchantByID(request.MerchantID); err != nil {
+			return fmt.Errorf("merchant %s not found", request.MerchantID)
+		}
+		knownMerchants[request.MerchantID] = true
+	}
+
+	return nil
+}
+
+// processBatch pays each pending line through CustomerService.Payment,
+// recording the outcome after every line so the batch can be followed while it
+// runs. A failed line does not stop the rest of the batch. Each line is saved as
+// processing before it is paid, so a restart never pays it twice.
+func (s *batchServiceImpl) processBatch(batch model.Batch) {
+	// The lines are updated in place, so work on a copy of the submitted ones.
+	batch.Lines = append([]model.BatchLine(nil), batch.Lines...)
+	batch.Status = model.BatchStatusProcessing
+	s.saveProgress(batch)
+
+	for i := range batch.Lines {
+		end := utils.BeginWrite()
+		s.processLine(&batch, &batch.Lines[i])
+		end()
+	}
+
+	completedAt := time.Now().UTC()
+	batch.Status = model.BatchStatusCompleted
+	batch.CompletedAt = &completedAt
+	batch.UpdatedAt = completedAt
+	s.saveProgress(batch)
+}
+
+// processLine pays line if it is pending and saves its outcome in batch.
+func (s *batchServiceImpl) processLine(batch *model.Batch, line *model.BatchLine) {
+	switch line.Status {
+	case model.BatchLineStatusPending:
+	case model.BatchLineStatusProcessing:
+		line.Status = model.BatchLineStatusFailed
+		line.Error = interruptedLineError
+		batch.Failed++
+		batch.UpdatedAt = time.Now().UTC()
+		s.saveProgress(*batch)
+		return
+	default:
+		return
+	}
+
+	line.Status = model.BatchLineStatusProcessing
+	if err := s.saveProgress(*batch); err != nil {
+		line.Status = model.BatchLineStatusFailed
+		line.Error = err.Error()
+		batch.Failed++
+		return
+	}
+
+	request := dto.PaymentRequest{MerchantID: line.MerchantID, Amount: line.Amount, Currency: line.Currency, PromoCode: line.PromoCode, Escrow: line.Escrow}
+	payment, err := s.customerService.Payment(request, batch.Username)
+	if err != nil {
+		line.Status = model.BatchLineStatusFailed
+		line.Error = err.Error()
+		batch.Failed++
+	} else {
+		line.Status = model.BatchLineStatusSucceeded
+		line.PaymentID = payment.ID
+		batch.Succeeded++
+	}
+
+	batch.UpdatedAt = time.Now().UTC()
+	s.saveProgress(*batch)
+}
+
+// saveProgress stores a snapshot of the batch, so the lines being processed are
+// not shared with the repository. Failures are logged since no caller is
+// waiting for the background processing.
+func (s *batchServiceImpl) saveProgress(batch model.Batch) error {
+	batch.Lines = append([]model.BatchLine(nil), batch.Lines...)
+	if _, err := s.batchRepository.UpdateBatch(batch); err != nil {
+		log.Printf("Error saving batch %s: %v", batch.ID, err)
+		return fmt.Errorf("failed to save batch progress: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type batchServiceMocks struct {
+	batchRepo       *MockBatchRepository
+	customerRepo    *MockCustomerRepository
+	merchantRepo    *MockMerchantRepository
+	customerService *MockCustomerService
+	dispatched      []func()
+}
+
+// setupBatchService returns a service whose background processing is held
+// back until the test runs the dispatched functions itself.
+func setupBatchService() (BatchService, *batchServiceMocks) {
+	mocks := &batchServiceMocks{
+		batchRepo:       new(MockBatchRepository),
+		customerRepo:    new(MockCustomerRepository),
+		merchantRepo:    new(MockMerchantRepository),
+		customerService: new(MockCustomerService),
+	}
+	service := NewBatchService(mocks.batchRepo, mocks.customerRepo, mocks.merchantRepo, mocks.customerService)
+	service.(*batchServiceImpl).dispatch = func(process func()) {
+		mocks.dispatched = append(mocks.dispatched, process)
+	}
+	return service, mocks
+}
+
+var fakeCustomer = model.Customer{ID: "cust-001", Username: "payroll", Balance: 10000}
+
+func TestSubmitBatch_ProcessesEveryLine(t *testing.T) {
+	service, mocks := setupBatchService()
+	requests := []dto.PaymentRequest{
+		{MerchantID: "merchant-001", Amount: 100},
+		{MerchantID: "merchant-002", Amount: 200},
+		{MerchantID: "merchant-001", Amount: 300},
+	}
+
+	mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001"}, nil).Once()
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-002").Return(model.Merchant{ID: "merchant-002"}, nil).Once()
+	mocks.batchRepo.On("CreateBatch", mock.AnythingOfType("model.Batch")).Return(model.Batch{ID: "batch-001", Status: model.BatchStatusPending}, nil)
+
+	var saved []model.Batch
+	mocks.batchRepo.On("UpdateBatch", mock.AnythingOfType("model.Batch")).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(0).(model.Batch))
+	}).Return(model.Batch{}, nil)
+	mocks.customerService.On("Payment", requests[0], "payroll").Return(model.Payment{ID: "pay-001"}, nil)
+	mocks.customerService.On("Payment", requests[1], "payroll").Return(model.Payment{}, customerService.ErrInsufficientBalance)
+	mocks.customerService.On("Payment", requests[2], "payroll").Return(model.Payment{ID: "pay-003"}, nil)
+
+	batch, err := service.SubmitBatch(requests, "payroll")
+	require.NoError(t, err)
+	assert.Equal(t, "batch-001", batch.ID)
+	assert.Equal(t, model.BatchStatusPending, batch.Status)
+
+	created := mocks.batchRepo.Calls[0].Arguments.Get(0).(model.Batch)
+	assert.Equal(t, model.BatchStatusPending, created.Status)
+	assert.Equal(t, 3, created.Total)
+	assert.Equal(t, 2, created.Lines[1].Line)
+
+	// Nothing is paid until the background processing runs.
+	mocks.customerService.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+	require.Len(t, mocks.dispatched, 1)
+	mocks.dispatched[0]()
+
+	require.Len(t, saved, 8) // processing, two per line, completed
+	assert.Equal(t, model.BatchStatusProcessing, saved[0].Status)
+	assert.Equal(t, model.BatchLineStatusProcessing, saved[1].Lines[0].Status)
+	assert.Equal(t, model.BatchLineStatusSucceeded, saved[2].Lines[0].Status)
+	assert.Equal(t, model.BatchLineStatusPending, saved[2].Lines[1].Status)
+
+	final := saved[7]
+	assert.Equal(t, model.BatchStatusCompleted, final.Status)
+	assert.NotNil(t, final.CompletedAt)
+	assert.Equal(t, 2, final.Succeeded)
+	assert.Equal(t, 1, final.Failed)
+	assert.Equal(t, "pay-001", final.Lines[0].PaymentID)
+	assert.Equal(t, model.BatchLineStatusFailed, final.Lines[1].Status)
+	assert.Equal(t, "insufficient balance", final.Lines[1].Error)
+	assert.Equal(t, "pay-003", final.Lines[2].PaymentID)
+	mocks.merchantRepo.AssertExpectations(t)
+}
+
+func TestSubmitBatch_PassesPromoCodeAndEscrow(t *testing.T) {
+	service, mocks := setupBatchService()
+	requests := []dto.PaymentRequest{
+		{MerchantID: "merchant-001", Amount: 100, PromoCode: "HEMAT10", Escrow: true},
+	}
+
+	mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001"}, nil)
+	mocks.batchRepo.On("CreateBatch", mock.AnythingOfType("model.Batch")).Return(model.Batch{ID: "batch-001"}, nil)
+	mocks.batchRepo.On("UpdateBatch", mock.AnythingOfType("model.Batch")).Return(model.Batch{}, nil)
+	mocks.customerService.On("Payment", requests[0], "payroll").Return(model.Payment{ID: "pay-001"}, nil)
+
+	_, err := service.SubmitBatch(requests, "payroll")
+	require.NoError(t, err)
+	mocks.dispatched[0]()
+
+	created := mocks.batchRepo.Calls[0].Arguments.Get(0).(model.Batch)
+	assert.Equal(t, "HEMAT10", created.Lines[0].PromoCode)
+	assert.True(t, created.Lines[0].Escrow)
+	mocks.customerService.AssertExpectations(t)
+}
+
+func TestResumeBatches_PaysOnlyPendingLines(t *testing.T) {
+	service, mocks := setupBatchService()
+	unfinished := model.Batch{
+		ID:        "batch-001",
+		Username:  "payroll",
+		Status:    model.BatchStatusProcessing,
+		Total:     3,
+		Succeeded: 1,
+		Lines: []model.BatchLine{
+			{Line: 1, MerchantID: "merchant-001", Amount: 100, Status: model.BatchLineStatusSucceeded, PaymentID: "pay-001"},
+			{Line: 2, MerchantID: "merchant-001", Amount: 200, Status: model.BatchLineStatusProcessing},
+			{Line: 3, MerchantID: "merchant-001", Amount: 300, Status: model.BatchLineStatusPending},
+		},
+	}
+
+	var saved []model.Batch
+	mocks.batchRepo.On("GetUnfinishedBatches").Return([]model.Batch{unfinished}, nil)
+	mocks.batchRepo.On("UpdateBatch", mock.AnythingOfType("model.Batch")).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(0).(model.Batch))
+	}).Return(model.Batch{}, nil)
+	mocks.customerService.On("Payment", dto.PaymentRequest{MerchantID: "merchant-001", Amount: 300}, "payroll").Return(model.Payment{ID: "pay-003"}, nil)
+
+	require.NoError(t, service.ResumeBatches())
+	require.Len(t, mocks.dispatched, 1)
+	mocks.dispatched[0]()
+
+	// Baris yang sedang dibayar saat server berhenti tidak dibayar ulang.
+	mocks.customerService.AssertNumberOfCalls(t, "Payment", 1)
+	final := saved[len(saved)-1]
+	assert.Equal(t, model.BatchStatusCompleted, final.Status)
+	assert.Equal(t, 2, final.Succeeded)
+	assert.Equal(t, 1, final.Failed)
+	assert.Equal(t, model.BatchLineStatusFailed, final.Lines[1].Status)
+	assert.Equal(t, interruptedLineError, final.Lines[1].Error)
+	assert.Equal(t, "pay-003", final.Lines[2].PaymentID)
+}
+
+func TestSubmitBatch_InvalidLinesRejectWholeBatch(t *testing.T) {
+	service, mocks := setupBatchService()
+	requests := []dto.PaymentRequest{
+		{MerchantID: "merchant-001", Amount: 100},
+		{MerchantID: "", Amount: 100},
+		{MerchantID: "merchant-001", Amount: 10.001},
+		{MerchantID: "merchant-404", Amount: 100},
+		{MerchantID: "merchant-001", Amount: 100, Currency: "USD", PromoCode: "HEMAT10"},
+		{MerchantID: "merchant-001", Amount: 100, Currency: "USD", Escrow: true},
+	}
+
+	mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001"}, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-404").Return(model.Merchant{}, errors.New("merchant not found by ID"))
+
+	_, err := service.SubmitBatch(requests, "payroll")
+
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	assert.Contains(t, err.Error(), "line 2: merchant_id is required")
+	assert.Contains(t, err.Error(), "line 3: invalid amount")
+	assert.Contains(t, err.Error(), "line 4: merchant merchant-404 not found")
+	assert.Contains(t, err.Error(), "line 5: promo codes only apply to IDR payments")
+	assert.Contains(t, err.Error(), "line 6: escrow is only available for IDR payments")
+	assert.NotContains(t, err.Error(), "line 1")
+	mocks.batchRepo.AssertNotCalled(t, "CreateBatch", mock.Anything)
+	assert.Empty(t, mocks.dispatched)
+}
+
+func TestSubmitBatch_Size(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+	}{
+		{"empty", 0},
+		{"too many lines", MaxBatchLines + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mocks := setupBatchService()
+			mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+
+			_, err := service.SubmitBatch(make([]dto.PaymentRequest, tt.lines), "payroll")
+
+			assert.ErrorIs(t, err, ErrInvalidBatch)
+			mocks.batchRepo.AssertNotCalled(t, "CreateBatch", mock.Anything)
+		})
+	}
+}
+
+func TestGetBatch_OtherCustomer(t *testing.T) {
+	service, mocks := setupBatchService()
+
+	mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+	mocks.batchRepo.On("GetBatchByID", "batch-001").Return(model.Batch{ID: "batch-001", CustomerID: "cust-002"}, nil)
+
+	_, err := service.GetBatch("batch-001", "payroll")
+
+	assert.ErrorIs(t, err, ErrBatchNotFound)
+}
+
+func TestGetBatch_Success(t *testing.T) {
+	service, mocks := setupBatchService()
+	stored := model.Batch{ID: "batch-001", CustomerID: fakeCustomer.ID, Status: model.BatchStatusProcessing}
+
+	mocks.customerRepo.On("GetUserByUsername", "payroll").Return(fakeCustomer, nil)
+	mocks.batchRepo.On("GetBatchByID", "batch-001").Return(stored, nil)
+
+	batch, err := service.GetBatch("batch-001", "payroll")
+
+	assert.NoError(t, err)
+	assert.Equal(t, stored, batch)
+}
@@ -1,57 +1,877 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"simple-golang-tdd/model"
+	"simple-golang-tdd/qr"
+	"sync"
+	"time"
 
 	"simple-golang-tdd/dto"
+	accountRepo "simple-golang-tdd/repository/account"
 	customerRepo "simple-golang-tdd/repository/customer"
 	merchantRepo "simple-golang-tdd/repository/merchant"
+	paymentRepo "simple-golang-tdd/repository/payment"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+	transactionRepo "simple-golang-tdd/repository/transaction"
+	feeService "simple-golang-tdd/service/fee"
+	fxService "simple-golang-tdd/service/fx"
+	limitService "simple-golang-tdd/service/limit"
+	loyaltyService "simple-golang-tdd/service/loyalty"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrPaymentNotFound     = errors.New("payment not found")
+	ErrInvalidSplit        = errors.New("invalid split payment")
+	ErrInvalidQR           = errors.New("invalid QR payment")
+	ErrInvalidPromoCode    = errors.New("invalid promo code")
+	ErrInvalidEscrow       = errors.New("invalid escrow payment")
 )
 
+// EscrowReleasePeriod is how long an escrow payment is held before it is
+// released to the merchant without the customer's confirmation.
+const EscrowReleasePeriod = 7 * 24 * time.Hour
+
 type CustomerService interface {
-	Payment(request dto.PaymentRequest, username string) (model.Customer, error)
+	Payment(request dto.PaymentRequest, username string) (model.Payment, error)
+	SplitPayment(request dto.SplitPaymentRequest, username string) (model.Payment, error)
+	QRPayment(request dto.QRPaymentRequest, username string) (model.Payment, error)
+	GetPayment(id string, username string) (model.Payment, error)
+	RefundPayment(id string, reason string) (model.Payment, error)
+	ReleaseEscrow(id string, reason string) (model.Payment, error)
+	CancelEscrow(id string, reason string) (model.Payment, error)
 }
 
 type customerServiceImpl struct {
-	customerRepository customerRepo.CustomerRepository
-	merchantRepository merchantRepo.MerchantRepository
+	customerRepository  customerRepo.CustomerRepository
+	merchantRepository  merchantRepo.MerchantRepository
+	paymentRepository   paymentRepo.PaymentRepository
+	accountRepository   accountRepo.AccountRepository
+	promotionRepository promotionRepo.PromotionRepository
+	feeService          feeService.FeeService
+	limitService        limitService.LimitService
+	fxService           fxService.FXService
+	loyaltyService      loyaltyService.LoyaltyService
+	transactor          transactionRepo.Transactor // nil when the storage has no transactions
+	outside             *customerServiceImpl       // set on the copy made by inTransaction to the service it was made from
+	paymentMutexes      *sync.Map                  // payment ID to *sync.Mutex, so a refund or escrow release of one payment runs one at a time
 }
 
-func NewCustomerService(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository) CustomerService {
+func NewCustomerService(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, paymentRepository paymentRepo.PaymentRepository, accountRepository accountRepo.AccountRepository, promotionRepository promotionRepo.PromotionRepository, feeService feeService.FeeService, limitService limitService.LimitService, fxService fxService.FXService, loyaltyService loyaltyService.LoyaltyService, transactor transactionRepo.Transactor) CustomerService {
 	return &customerServiceImpl{
-		customerRepository: customerRepository,
-		merchantRepository: merchantRepository}
+		customerRepository:  customerRepository,
+		merchantRepository:  merchantRepository,
+		paymentRepository:   paymentRepository,
+		accountRepository:   accountRepository,
+		promotionRepository: promotionRepository,
+		feeService:          feeService,
+		limitService:        limitService,
+		fxService:           fxService,
+		loyaltyService:      loyaltyService,
+		transactor:          transactor,
+		paymentMutexes:      &sync.Map{}}
 }
 
-func (s *customerServiceImpl) Payment(request dto.PaymentRequest, username string) (model.Customer, error) {
+func (s *customerServiceImpl) Payment(request dto.PaymentRequest, username string) (model.Payment, error) {
+	currency := request.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+
+	if err := ValidateAmount(request.Amount, currency); err != nil {
+		return model.Payment{}, err
+	}
+
 	var customer model.Customer
 	customer, err := s.customerRepository.GetUserByUsername(username)
 	if err != nil {
-		return model.Customer{}, fmt.Errorf("failed to get user by username: %w", err)
+		return model.Payment{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	merchant, err := s.merchantRepository.GetMerchantByID(request.MerchantID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get merchant: %w", err)
+	}
+
+	// Spending limits and platform revenue are kept in the default currency.
+	baseAmount := request.Amount
+	if currency != model.DefaultCurrency {
+		conversion, err := s.fxService.Convert(request.Amount, currency, model.DefaultCurrency)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to convert amount: %w", err)
+		}
+		baseAmount = roundIn(model.DefaultCurrency, request.Amount*conversion.MidRate)
+	}
+
+	fee, err := s.calculateFee(merchant, request.Amount, baseAmount, currency)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+
+	payment := model.NewPayment(uuid.New().String(), customer.ID, merchant.ID, request.Amount, time.Now().UTC())
+	payment.Currency = currency
+	payment.BaseAmount = baseAmount
+	payment.ApplyFee(fee)
+
+	if request.PromoCode != "" {
+		promotion, err := s.promotionFor(request.PromoCode, customer.ID, merchant.ID, request.Amount, currency)
+		if err != nil {
+			return model.Payment{}, err
+		}
+		payment.ApplyDiscount(promotion, roundIn(currency, promotion.Discount(request.Amount)))
+	}
+
+	if settlementCurrency := merchant.SettlementCurrency(); settlementCurrency != currency {
+		conversion, err := s.fxService.Convert(payment.NetAmount, currency, settlementCurrency)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to convert amount: %w", err)
+		}
+		payment.FX = &conversion
+	}
+
+	if request.Escrow {
+		// The escrow account is kept in the default currency only.
+		if payment.Currency != model.DefaultCurrency || payment.FX != nil {
+			return model.Payment{}, fmt.Errorf("%w: escrow is only available for %s payments", ErrInvalidEscrow, model.DefaultCurrency)
+		}
+		releaseAt := payment.CreatedAt.Add(EscrowReleasePeriod)
+		payment.ReleaseAt = &releaseAt
+	}
+
+	payment, err = s.createPayment(customer, payment)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	if customer.BalanceIn(currency) < payment.ChargedAmount() {
+		return model.Payment{}, s.failPayment(payment, ErrInsufficientBalance)
+	}
+
+	if request.Escrow {
+		st, err := s.holdPayment(customer, payment)
+		if err != nil {
+			return model.Payment{}, s.failPayment(payment, err)
+		}
+		escrowed, err := s.transitionPayment(payment, model.PaymentStatusEscrowed, "")
+		if err != nil {
+			return model.Payment{}, s.failPayment(payment, st.rollback(err))
+		}
+		return escrowed, nil
+	}
+
+	st, err := s.settlePayment(customer, map[string]model.Merchant{merchant.ID: merchant}, payment)
+	if err != nil {
+		return model.Payment{}, s.failPayment(payment, err)
+	}
+
+	return s.completePayment(st, payment)
+}
+
+// QRPayment pays the merchant in a scanned QR payload. A dynamic payload fixes
+// the amount; a static one needs the amount from the request.
+func (s *customerServiceImpl) QRPayment(request dto.QRPaymentRequest, username string) (model.Payment, error) {
+	payload, err := qr.Parse(request.Payload)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("%w: %v", ErrInvalidQR, err)
+	}
+
+	amount := request.Amount
+	if payload.Dynamic {
+		if amount != 0 && amount != payload.Amount {
+			return model.Payment{}, fmt.Errorf("%w: amount does not match the QR code", ErrInvalidQR)
+		}
+		amount = payload.Amount
+	} else if amount == 0 {
+		return model.Payment{}, fmt.Errorf("%w: amount is required for a static QR code", ErrInvalidQR)
+	}
+
+	return s.Payment(dto.PaymentRequest{
+		MerchantID: payload.MerchantID,
+		Amount:     amount,
+		Currency:   payload.Currency,
+		PromoCode:  request.PromoCode,
+	}, username)
+}
+
+// promotionFor finds the promotion of a promo code and checks the customer can
+// use it on a payment of amount to the merchant.
+func (s *customerServiceImpl) promotionFor(code string, customerID, merchantID string, amount float64, currency string) (model.Promotion, error) {
+	if currency != model.DefaultCurrency {
+		return model.Promotion{}, fmt.Errorf("%w: promo codes only apply to %s payments", ErrInvalidPromoCode, model.DefaultCurrency)
+	}
+
+	promotion, err := s.promotionRepository.GetPromotionByCode(code)
+	if err != nil {
+		return model.Promotion{}, fmt.Errorf("%w: %s", ErrInvalidPromoCode, code)
+	}
+
+	if err := promotion.CheckRedeemable(customerID, merchantID, amount, time.Now().UTC()); err != nil {
+		return model.Promotion{}, err
+	}
+
+	return promotion, nil
+}
+
+// SplitPayment debits the customer once and distributes the amount over
+// several merchants. The legs are recorded on a single parent payment and
+// either every merchant is credited or none is.
+func (s *customerServiceImpl) SplitPayment(request dto.SplitPaymentRequest, username string) (model.Payment, error) {
+	currency := request.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+
+	if err := ValidateAmount(request.Amount, currency); err != nil {
+		return model.Payment{}, err
+	}
+
+	if err := validateLegs(request, currency); err != nil {
+		return model.Payment{}, err
+	}
+
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	merchants := make(map[string]model.Merchant, len(request.Legs))
+	for _, leg := range request.Legs {
+		merchant, err := s.merchantRepository.GetMerchantByID(leg.MerchantID)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to get merchant: %w", err)
+		}
+		merchants[merchant.ID] = merchant
+	}
+
+	baseAmount := request.Amount
+	if currency != model.DefaultCurrency {
+		conversion, err := s.fxService.Convert(request.Amount, currency, model.DefaultCurrency)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to convert amount: %w", err)
+		}
+		baseAmount = roundIn(model.DefaultCurrency, request.Amount*conversion.MidRate)
+	}
+
+	legs := make([]model.PaymentLeg, 0, len(request.Legs))
+	for _, requestLeg := range request.Legs {
+		merchant := merchants[requestLeg.MerchantID]
+
+		legBaseAmount := requestLeg.Amount
+		if currency != model.DefaultCurrency {
+			legBaseAmount = roundIn(model.DefaultCurrency, requestLeg.Amount*baseAmount/request.Amount)
+		}
+
+		fee, err := s.calculateFee(merchant, requestLeg.Amount, legBaseAmount, currency)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to calculate fee: %w", err)
+		}
+
+		leg := model.PaymentLeg{
+			MerchantID: merchant.ID,
+			Amount:     requestLeg.Amount,
+			Fee:        fee.Amount,
+			FeeRuleID:  fee.RuleID,
+			NetAmount:  requestLeg.Amount - fee.Amount,
+		}
+
+		if settlementCurrency := merchant.SettlementCurrency(); settlementCurrency != currency {
+			conversion, err := s.fxService.Convert(leg.NetAmount, currency, settlementCurrency)
+			if err != nil {
+				return model.Payment{}, fmt.Errorf("failed to convert amount: %w", err)
+			}
+			leg.FX = &conversion
+		}
+
+		legs = append(legs, leg)
+	}
+
+	payment := model.NewPayment(uuid.New().String(), customer.ID, "", request.Amount, time.Now().UTC())
+	payment.Currency = currency
+	payment.BaseAmount = baseAmount
+	payment.ApplyLegs(legs)
+
+	payment, err = s.createPayment(customer, payment)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	if customer.BalanceIn(currency) < request.Amount {
+		return model.Payment{}, s.failPayment(payment, ErrInsufficientBalance)
+	}
+
+	st, err := s.settlePayment(customer, merchants, payment)
+	if err != nil {
+		return model.Payment{}, s.failPayment(payment, err)
+	}
+
+	return s.completePayment(st, payment)
+}
+
+// createPayment stores the payment once the customer's spending limits allow
+// it. The check and the store happen together, so the stored payment counts
+// towards the limits of the customer's next payment before any balance is
+// touched.
+func (s *customerServiceImpl) createPayment(customer model.Customer, payment model.Payment) (model.Payment, error) {
+	var created model.Payment
+	err := s.limitService.ReserveLimit(customer, payment.BaseAmount, func() error {
+		var err error
+		created, err = s.paymentRepository.CreatePayment(payment)
+		if err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Payment{}, err
+	}
+	return created, nil
+}
+
+// validateLegs checks that every leg is a valid amount for its own merchant
+// and that together they add up to the payment's amount.
+func validateLegs(request dto.SplitPaymentRequest, currency string) error {
+	if len(request.Legs) < 2 {
+		return fmt.Errorf("%w: at least two legs are required", ErrInvalidSplit)
+	}
+
+	seen := make(map[string]bool, len(request.Legs))
+	total := 0.0
+	for _, leg := range request.Legs {
+		if seen[leg.MerchantID] {
+			return fmt.Errorf("%w: merchant %s appears in more than one leg", ErrInvalidSplit, leg.MerchantID)
+		}
+		seen[leg.MerchantID] = true
+
+		if err := ValidateAmount(leg.Amount, currency); err != nil {
+			return err
+		}
+		total += leg.Amount
+	}
+
+	if math.Abs(roundIn(currency, total)-request.Amount) > 1e-9 {
+		return fmt.Errorf("%w: leg amounts add up to %.2f instead of %.2f", ErrInvalidSplit, total, request.Amount)
+	}
+
+	return nil
+}
+
+// ValidateAmount rejects amounts that are not a positive, finite number, that
+// have more decimals than the currency allows or that exceed its maximum.
+func ValidateAmount(amount float64, currencyCode string) error {
+	currency, ok := model.GetCurrency(currencyCode)
+	if !ok {
+		return fmt.Errorf("%w: unsupported currency %s", ErrInvalidAmount, currencyCode)
+	}
+
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount <= 0 {
+		return fmt.Errorf("%w: amount must be a positive number", ErrInvalidAmount)
+	}
+
+	if math.Abs(currency.Round(amount)-amount) > 1e-9 {
+		return fmt.Errorf("%w: %s amounts allow at most %d decimal places", ErrInvalidAmount, currency.Code, currency.Precision)
+	}
+
+	if amount > currency.MaxAmount {
+		return fmt.Errorf("%w: amount exceeds the maximum of %.2f %s", ErrInvalidAmount, currency.MaxAmount, currency.Code)
+	}
+
+	return nil
+}
+
+// merchantCredit is what one merchant receives from a payment, in the currency it settles in.
+type merchantCredit struct {
+	merchantID string
+	currency   string
+	amount     float64
+}
+
+// merchantCredits lists the credits of a payment: one per leg of a split
+// payment, or a single one for the payment's merchant.
+func merchantCredits(payment model.Payment) []merchantCredit {
+	if len(payment.Legs) == 0 {
+		credit := merchantCredit{merchantID: payment.MerchantID, currency: payment.Currency, amount: payment.NetAmount}
+		if payment.FX != nil {
+			credit.currency, credit.amount = payment.FX.To, payment.FX.ConvertedAmount
+		}
+		return []merchantCredit{credit}
+	}
+
+	credits := make([]merchantCredit, 0, len(payment.Legs))
+	for _, leg := range payment.Legs {
+		credit := merchantCredit{merchantID: leg.MerchantID, currency: payment.Currency, amount: leg.NetAmount}
+		if leg.FX != nil {
+			credit.currency, credit.amount = leg.FX.To, leg.FX.ConvertedAmount
+		}
+		credits = append(credits, credit)
+	}
+	return credits
+}
+
+// settlePayment redeems the payment's promotion, debits the customer, credits
+// each merchant with its net amount, books the discount to the promotion
+// expense account and the fee to the platform revenue account. A failed
+// payment must not move money or use up a promotion, so any update already
+// made is rolled back when a later one fails. On success it returns the
+// settlement so the caller can still roll the payment back.
+func (s *customerServiceImpl) settlePayment(customer model.Customer, merchants map[string]model.Merchant, payment model.Payment) (*settlement, error) {
+	st := &settlement{}
+
+	err := s.inTransaction(func(tx *customerServiceImpl) error {
+		if err := tx.chargeCustomer(st, customer, payment); err != nil {
+			return err
+		}
+		return tx.payOut(st, merchants, payment)
+	})
+	if err != nil {
+		// Only a failed commit leaves updates on st to roll back.
+		return nil, st.rollback(err)
+	}
+
+	st.commit()
+	return st, nil
+}
+
+// holdPayment redeems the payment's promotion and moves what the customer is
+// charged into the escrow account, where it stays until the payment is
+// released or cancelled. Like settlePayment, it returns the settlement so the
+// caller can still roll the hold back.
+func (s *customerServiceImpl) holdPayment(customer model.Customer, payment model.Payment) (*settlement, error) {
+	st := &settlement{}
+
+	err := s.inTransaction(func(tx *customerServiceImpl) error {
+		if err := tx.chargeCustomer(st, customer, payment); err != nil {
+			return err
+		}
+		if _, err := tx.updateAccountBalance(st, model.PlatformEscrowAccountID, payment.ChargedAmount()); err != nil {
+			return st.rollback(err)
+		}
+		return nil
+	})
+	if err != nil {
+		// Only a failed commit leaves updates on st to roll back.
+		return nil, st.rollback(err)
+	}
+
+	st.commit()
+	return st, nil
+}
+
+// inTransaction runs fn on a copy of the service whose customer, merchant and
+// promotion repositories are bound to one storage transaction, so a failed
+// settlement cannot leave half of their updates behind. Without a transactor
+// fn runs on the service itself. Either way, updates to the other
+// repositories are only undone by the settlement rollbacks.
+func (s *customerServiceImpl) inTransaction(fn func(tx *customerServiceImpl) error) error {
+	if s.transactor == nil {
+		return fn(s)
+	}
+
+	return s.transactor.InTransaction(func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, promotionRepository promotionRepo.PromotionRepository) error {
+		tx := *s
+		tx.customerRepository = customerRepository
+		tx.merchantRepository = merchantRepository
+		tx.promotionRepository = promotionRepository
+		tx.outside = s
+		return fn(&tx)
+	})
+}
+
+// onTransactionalRollback registers fn to undo a customer, merchant or
+// promotion update. Inside a storage transaction the update rolls back with
+// it, so fn only runs once the transaction has committed, on the repositories
+// outside it.
+func (s *customerServiceImpl) onTransactionalRollback(st *settlement, fn func(s *customerServiceImpl) error) {
+	if s.outside == nil {
+		st.onRollback(func() error { return fn(s) })
+		return
+	}
+	outside := s.outside
+	st.onCommittedRollback(func() error { return fn(outside) })
+}
+
+// chargeCustomer redeems the payment's promotion and debits the customer with
+// the charged amount. Every update already made is rolled back when one fails.
+func (s *customerServiceImpl) chargeCustomer(st *settlement, customer model.Customer, payment model.Payment) error {
+	if payment.PromotionID != "" {
+		_, err := s.promotionRepository.RedeemPromotion(payment.PromotionID, model.PromotionRedemption{
+			PaymentID:  payment.ID,
+			CustomerID: customer.ID,
+			MerchantID: payment.MerchantID,
+			Amount:     payment.Amount,
+			Discount:   payment.Discount,
+			RedeemedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to redeem promotion: %w", err)
+		}
+		s.onTransactionalRollback(st, func(s *customerServiceImpl) error {
+			_, err := s.promotionRepository.CancelRedemption(payment.PromotionID, payment.ID)
+			return err
+		})
+	}
+
+	charged := payment.ChargedAmount()
+	debited, err := customerRepo.AdjustUserBalance(s.customerRepository, customer, payment.Currency, -charged, func(customer model.Customer) error {
+		if customer.BalanceIn(payment.Currency) < charged {
+			return ErrInsufficientBalance
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInsufficientBalance) {
+		return st.rollback(err)
+	}
+	if err != nil {
+		return st.rollback(fmt.Errorf("failed to update user balance: %w", err))
+	}
+	s.onTransactionalRollback(st, func(s *customerServiceImpl) error {
+		_, err := customerRepo.AdjustUserBalance(s.customerRepository, debited, payment.Currency, charged, nil)
+		return err
+	})
+
+	return nil
+}
+
+// payOut credits each merchant with its net amount, books the discount to the
+// promotion expense account and the fee to the platform revenue account. Every
+// update already made, including those registered on st before, is rolled back
+// when one fails.
+func (s *customerServiceImpl) payOut(st *settlement, merchants map[string]model.Merchant, payment model.Payment) error {
+	for _, credit := range merchantCredits(payment) {
+		credited, err := merchantRepo.AdjustMerchantBalance(s.merchantRepository, merchants[credit.merchantID], credit.currency, credit.amount)
+		if err != nil {
+			return st.rollback(fmt.Errorf("failed to update merchant balance: %w", err))
+		}
+		s.onTransactionalRollback(st, func(s *customerServiceImpl) error {
+			_, err := merchantRepo.AdjustMerchantBalance(s.merchantRepository, credited, credit.currency, -credit.amount)
+			return err
+		})
+	}
+
+	if payment.Discount > 0 {
+		_, err := s.accountRepository.AdjustAccountBalance(model.PlatformPromotionAccountID, payment.Discount)
+		if err != nil {
+			return st.rollback(fmt.Errorf("failed to update platform promotion balance: %w", err))
+		}
+		st.onRollback(func() error {
+			_, err := s.accountRepository.AdjustAccountBalance(model.PlatformPromotionAccountID, -payment.Discount)
+			return err
+		})
+	}
+
+	if payment.Fee > 0 {
+		fee := revenueFee(payment)
+
+		_, err := s.accountRepository.AdjustAccountBalance(model.PlatformRevenueAccountID, fee)
+		if err != nil {
+			return st.rollback(fmt.Errorf("failed to update platform revenue balance: %w", err))
+		}
+		st.onRollback(func() error {
+			_, err := s.accountRepository.AdjustAccountBalance(model.PlatformRevenueAccountID, -fee)
+			return err
+		})
+	}
+
+	return nil
+}
+
+// updateAccountBalance adds amount to a platform account and registers how to
+// undo it on st.
+func (s *customerServiceImpl) updateAccountBalance(st *settlement, id string, amount float64) (model.Account, error) {
+	account, err := s.accountRepository.AdjustAccountBalance(id, amount)
+	if err != nil {
+		return model.Account{}, fmt.Errorf("failed to update %s balance: %w", id, err)
+	}
+	st.onRollback(func() error {
+		_, err := s.accountRepository.AdjustAccountBalance(id, -amount)
+		return err
+	})
+
+	return account, nil
+}
+
+// ReleaseEscrow pays an escrow payment out of the escrow account to the
+// merchant, booking its discount and fee as a direct payment would, and marks
+// it as succeeded.
+func (s *customerServiceImpl) ReleaseEscrow(id string, reason string) (model.Payment, error) {
+	defer s.lockPayment(id)()
+
+	payment, err := s.escrowPayment(id)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	merchant, err := s.merchantRepository.GetMerchantByID(payment.MerchantID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get merchant: %w", err)
+	}
+
+	var st settlement
+	if _, err := s.updateAccountBalance(&st, model.PlatformEscrowAccountID, -payment.ChargedAmount()); err != nil {
+		return model.Payment{}, err
+	}
+	if err := s.payOut(&st, map[string]model.Merchant{merchant.ID: merchant}, payment); err != nil {
+		return model.Payment{}, err
+	}
+
+	released, err := s.succeedPayment(payment, reason)
+	if err != nil {
+		return model.Payment{}, st.rollback(err)
+	}
+
+	return released, nil
+}
+
+// CancelEscrow returns an escrow payment from the escrow account to the
+// customer, frees its promotion and marks it as cancelled.
+func (s *customerServiceImpl) CancelEscrow(id string, reason string) (model.Payment, error) {
+	defer s.lockPayment(id)()
+
+	payment, err := s.escrowPayment(id)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	customer, err := s.customerRepository.GetUserByID(payment.CustomerID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var st settlement
+	if _, err := s.updateAccountBalance(&st, model.PlatformEscrowAccountID, -payment.ChargedAmount()); err != nil {
+		return model.Payment{}, err
+	}
+
+	credited, err := customerRepo.AdjustUserBalance(s.customerRepository, customer, payment.Currency, payment.ChargedAmount(), nil)
+	if err != nil {
+		return model.Payment{}, st.rollback(fmt.Errorf("failed to update user balance: %w", err))
+	}
+	st.onRollback(func() error {
+		_, err := customerRepo.AdjustUserBalance(s.customerRepository, credited, payment.Currency, -payment.ChargedAmount(), nil)
+		return err
+	})
+
+	cancelled, err := s.transitionPayment(payment, model.PaymentStatusCancelled, reason)
+	if err != nil {
+		return model.Payment{}, st.rollback(err)
+	}
+
+	// The money is back with the customer, so a promotion that cannot be freed
+	// only costs them one use of it.
+	if cancelled.PromotionID != "" {
+		if _, err := s.promotionRepository.CancelRedemption(cancelled.PromotionID, cancelled.ID); err != nil {
+			log.Printf("Error cancelling promotion redemption for payment %s: %v", cancelled.ID, err)
+		}
+	}
+
+	return cancelled, nil
+}
+
+// escrowPayment returns the payment if it is still held in escrow.
+func (s *customerServiceImpl) escrowPayment(id string) (model.Payment, error) {
+	payment, err := s.paymentRepository.GetPaymentByID(id)
+	if err != nil {
+		return model.Payment{}, ErrPaymentNotFound
+	}
+
+	if payment.Status != model.PaymentStatusEscrowed {
+		return model.Payment{}, fmt.Errorf("%w: payment is %s, not held in escrow", ErrInvalidEscrow, payment.Status)
+	}
+
+	return payment, nil
+}
+
+// calculateFee returns the fee on amount in currency, which is worth
+// baseAmount in DefaultCurrency. Fee rules are kept in DefaultCurrency, so the
+// fee is worked out on baseAmount and converted back at the same rate.
+func (s *customerServiceImpl) calculateFee(merchant model.Merchant, amount float64, baseAmount float64, currency string) (model.Fee, error) {
+	fee, err := s.feeService.CalculateFee(merchant, baseAmount)
+	if err != nil {
+		return model.Fee{}, err
+	}
+	if currency != model.DefaultCurrency && baseAmount > 0 {
+		fee.Amount = math.Min(roundIn(currency, fee.Amount*amount/baseAmount), amount)
+	}
+	return fee, nil
+}
+
+// revenueFee is the payment's fee in DefaultCurrency. The fee is taken in the
+// payer's currency but revenue is booked in the default one.
+func revenueFee(payment model.Payment) float64 {
+	if payment.Currency == model.DefaultCurrency {
+		return payment.Fee
+	}
+	return roundIn(model.DefaultCurrency, payment.Fee*payment.BaseAmount/payment.Amount)
+}
+
+// RefundPayment gives a succeeded payment back to the customer: the charged
+// amount is credited to the customer, every merchant is debited what it
+// received and the discount and fee are taken off the platform accounts. A
+// merchant balance may go negative, as with a chargeback. Rewards still held
+// for the payment are cancelled when they fall due.
+func (s *customerServiceImpl) RefundPayment(id string, reason string) (model.Payment, error) {
+	defer s.lockPayment(id)()
+
+	payment, err := s.paymentRepository.GetPaymentByID(id)
+	if err != nil {
+		return model.Payment{}, ErrPaymentNotFound
+	}
+
+	if !payment.Status.CanTransitionTo(model.PaymentStatusRefunded) {
+		return model.Payment{}, fmt.Errorf("%w: %s to %s", model.ErrInvalidPaymentTransition, payment.Status, model.PaymentStatusRefunded)
 	}
 
-	merchantBalance, err := s.merchantRepository.GetMerchantBalance(request.MerchantID)
+	customer, err := s.customerRepository.GetUserByID(payment.CustomerID)
 	if err != nil {
-		return model.Customer{}, fmt.Errorf("failed to get merchant balance: %w", err)
+		return model.Payment{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	if customer.Balance < request.Amount {
-		return model.Customer{}, fmt.Errorf("insufficient balance")
+	merchants := map[string]model.Merchant{}
+	for _, credit := range merchantCredits(payment) {
+		merchant, err := s.merchantRepository.GetMerchantByID(credit.merchantID)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to get merchant: %w", err)
+		}
+		merchants[merchant.ID] = merchant
 	}
 
-	merchantBalance += request.Amount
-	customer.Balance -= request.Amount
+	st, err := s.reverseSettlement(customer, merchants, payment)
+	if err != nil {
+		return model.Payment{}, err
+	}
 
-	updatedCustomer, err := s.customerRepository.UpdateUserBalance(customer.ID, customer.Balance)
+	refunded, err := s.transitionPayment(payment, model.PaymentStatusRefunded, reason)
 	if err != nil {
-		return model.Customer{}, fmt.Errorf("failed to update user balance: %w", err)
+		return model.Payment{}, st.rollback(err)
 	}
 
-	_, err = s.merchantRepository.UpdateMerchantBalance(request.MerchantID, merchantBalance)
+	return refunded, nil
+}
+
+// reverseSettlement undoes the balance updates of settlePayment. On success it
+// returns the settlement so the caller can still roll the refund back; on
+// failure every update already made has been rolled back.
+func (s *customerServiceImpl) reverseSettlement(customer model.Customer, merchants map[string]model.Merchant, payment model.Payment) (*settlement, error) {
+	st := &settlement{}
+
+	credited, err := customerRepo.AdjustUserBalance(s.customerRepository, customer, payment.Currency, payment.ChargedAmount(), nil)
 	if err != nil {
-		return model.Customer{}, fmt.Errorf("failed to update merchant balance: %w", err)
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
+	}
+	st.onRollback(func() error {
+		_, err := customerRepo.AdjustUserBalance(s.customerRepository, credited, payment.Currency, -payment.ChargedAmount(), nil)
+		return err
+	})
+
+	for _, credit := range merchantCredits(payment) {
+		debited, err := merchantRepo.AdjustMerchantBalance(s.merchantRepository, merchants[credit.merchantID], credit.currency, -credit.amount)
+		if err != nil {
+			return nil, st.rollback(fmt.Errorf("failed to update merchant balance: %w", err))
+		}
+		st.onRollback(func() error {
+			_, err := merchantRepo.AdjustMerchantBalance(s.merchantRepository, debited, credit.currency, credit.amount)
+			return err
+		})
+	}
+
+	accounts := []struct {
+		id     string
+		amount float64
+	}{
+		{model.PlatformPromotionAccountID, payment.Discount},
+		{model.PlatformRevenueAccountID, revenueFee(payment)},
+	}
+	for _, account := range accounts {
+		if account.amount <= 0 {
+			continue
+		}
+		if _, err := s.updateAccountBalance(st, account.id, -account.amount); err != nil {
+			return nil, st.rollback(err)
+		}
 	}
 
-	return updatedCustomer, nil
+	return st, nil
+}
+
+// roundIn rounds amount to the precision of a supported currency.
+func roundIn(currencyCode string, amount float64) float64 {
+	currency, _ := model.GetCurrency(currencyCode)
+	return currency.Round(amount)
+}
+
+func (s *customerServiceImpl) GetPayment(id string, username string) (model.Payment, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	payment, err := s.paymentRepository.GetPaymentByID(id)
+	if err != nil || payment.CustomerID != customer.ID {
+		return model.Payment{}, ErrPaymentNotFound
+	}
+
+	return payment, nil
+}
+
+// lockPayment takes the lock of the payment with id, held while the payment
+// is refunded or released from escrow so that it is only settled once.
+func (s *customerServiceImpl) lockPayment(id string) (unlock func()) {
+	value, _ := s.paymentMutexes.LoadOrStore(id, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// transitionPayment moves the payment to the next status and persists it.
+func (s *customerServiceImpl) transitionPayment(payment model.Payment, next model.PaymentStatus, reason string) (model.Payment, error) {
+	if err := payment.TransitionTo(next, reason, time.Now().UTC()); err != nil {
+		return model.Payment{}, err
+	}
+
+	updatedPayment, err := s.paymentRepository.UpdatePayment(payment)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	return updatedPayment, nil
+}
+
+// completePayment marks a payment settled on st as succeeded. A payment whose
+// status cannot be saved would stay pending with its money moved, and be paid
+// again when the client retries, so the settlement is rolled back instead.
+func (s *customerServiceImpl) completePayment(st *settlement, payment model.Payment) (model.Payment, error) {
+	succeeded, err := s.succeedPayment(payment, "")
+	if err != nil {
+		return model.Payment{}, s.failPayment(payment, st.rollback(err))
+	}
+	return succeeded, nil
+}
+
+// succeedPayment marks the settled payment as succeeded and accrues its
+// loyalty rewards. The payment stands even if the rewards cannot be recorded.
+func (s *customerServiceImpl) succeedPayment(payment model.Payment, reason string) (model.Payment, error) {
+	succeeded, err := s.transitionPayment(payment, model.PaymentStatusSucceeded, reason)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	if _, err := s.loyaltyService.AccrueRewards(succeeded); err != nil {
+		log.Printf("Error accruing rewards for payment %s: %v", succeeded.ID, err)
+	}
+
+	return succeeded, nil
+}
+
+// failPayment marks the payment as failed with cause as the reason and returns cause.
+func (s *customerServiceImpl) failPayment(payment model.Payment, cause error) error {
+	if _, err := s.transitionPayment(payment, model.PaymentStatusFailed, cause.Error()); err != nil {
+		return fmt.Errorf("%w (%v)", cause, err)
+	}
+	return cause
 }
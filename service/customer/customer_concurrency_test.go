@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"simple-golang-tdd/database"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"testing"
+	"time"
+
+	customerRepo "simple-golang-tdd/repository/customer"
+	limitRepo "simple-golang-tdd/repository/limit"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	paymentRepo "simple-golang-tdd/repository/payment"
+	transactionRepo "simple-golang-tdd/repository/transaction"
+	limitService "simple-golang-tdd/service/limit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	concurrentCustomer = model.Customer{ID: "cust-001", Username: "testuser", Balance: 1000.0}
+	concurrentMerchant = model.Merchant{ID: "merchant-001", Balance: 500.0}
+)
+
+// barrierCustomerRepository menahan setiap GetUserByUsername sampai semua
+// pembayaran sudah membaca customer, sehingga semuanya melihat saldo yang sama.
+type barrierCustomerRepository struct {
+	customerRepo.CustomerRepository
+	barrier *sync.WaitGroup
+}
+
+func (r barrierCustomerRepository) GetUserByUsername(username string) (model.Customer, error) {
+	customer, err := r.CustomerRepository.GetUserByUsername(username)
+	r.barrier.Done()
+	r.barrier.Wait()
+	return customer, err
+}
+
+// barrierPaymentRepository menahan setiap GetPaymentByID sampai semua refund
+// sudah membaca payment, sehingga semuanya melihat status yang sama. Refund
+// yang dijalankan satu per satu hanya ditahan selama barrierTimeout.
+type barrierPaymentRepository struct {
+	paymentRepo.PaymentRepository
+	barrier *sync.WaitGroup
+}
+
+const barrierTimeout = 50 * time.Millisecond
+
+func (r barrierPaymentRepository) GetPaymentByID(id string) (model.Payment, error) {
+	payment, err := r.PaymentRepository.GetPaymentByID(id)
+	r.barrier.Done()
+	arrived := make(chan struct{})
+	go func() {
+		r.barrier.Wait()
+		close(arrived)
+	}()
+	select {
+	case <-arrived:
+	case <-time.After(barrierTimeout):
+	}
+	return payment, err
+}
+
+// Helper function untuk inisialisasi customer service dengan repository customer
+// dan merchant sungguhan, di memory atau di SQLite dengan transactor. Tanpa
+// limits, setiap pembayaran lolos pengecekan limit.
+func setupConcurrentCustomerService(t *testing.T, backend string, payments int, limitTiers []model.SpendingLimit) (CustomerService, customerRepo.CustomerRepository, merchantRepo.MerchantRepository, paymentRepo.PaymentRepository) {
+	var customers customerRepo.CustomerRepository
+	var merchants merchantRepo.MerchantRepository
+	var transactor transactionRepo.Transactor
+
+	switch backend {
+	case "memory":
+		var err error
+		customers, err = customerRepo.NewInMemoryCustomerRepository([]model.Customer{concurrentCustomer})
+		require.NoError(t, err)
+		merchants, err = merchantRepo.NewInMemoryMerchantRepository([]model.Merchant{concurrentMerchant})
+		require.NoError(t, err)
+	case "sqlite":
+		dir := t.TempDir()
+		require.NoError(t, utils.SaveJSONFile(filepath.Join(dir, "customers.json"), []model.Customer{concurrentCustomer}))
+		require.NoError(t, utils.SaveJSONFile(filepath.Join(dir, "merchants.json"), []model.Merchant{concurrentMerchant}))
+
+		db, err := database.Open(filepath.Join(dir, "app.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		customers, err = customerRepo.NewSQLiteCustomerRepository(db, filepath.Join(dir, "customers.json"))
+		require.NoError(t, err)
+		merchants, err = merchantRepo.NewSQLiteMerchantRepository(db, filepath.Join(dir, "merchants.json"))
+		require.NoError(t, err)
+		transactor = transactionRepo.NewSQLiteTransactor(db)
+	}
+
+	paymentsPath := filepath.Join(t.TempDir(), "payments.json")
+	require.NoError(t, utils.SaveJSONFile(paymentsPath, []model.Payment{}))
+	paymentRepository, err := paymentRepo.NewPaymentRepository(paymentsPath)
+	require.NoError(t, err)
+
+	feeService := new(MockFeeService)
+	feeService.On("CalculateFee", mock.Anything, mock.Anything).Return(model.Fee{}, nil)
+	var limits limitService.LimitService
+	if limitTiers == nil {
+		mockLimits := new(MockLimitService)
+		mockLimits.On("ReserveLimit", mock.Anything, mock.Anything).Return(nil)
+		limits = mockLimits
+	} else {
+		limitsPath := filepath.Join(t.TempDir(), "limits.json")
+		require.NoError(t, utils.SaveJSONFile(limitsPath, limitTiers))
+		limitRepository, err := limitRepo.NewLimitRepository(limitsPath)
+		require.NoError(t, err)
+		limits = limitService.NewLimitService(limitRepository, paymentRepository)
+	}
+	loyaltyService := new(MockLoyaltyService)
+	loyaltyService.On("AccrueRewards", mock.Anything).Return([]model.Reward{}, nil)
+
+	barrier := &sync.WaitGroup{}
+	barrier.Add(payments)
+	customerService := NewCustomerService(barrierCustomerRepository{customers, barrier}, merchants, paymentRepository, new(MockAccountRepository), new(MockPromotionRepository), feeService, limits, new(MockFXService), loyaltyService, transactor)
+	return customerService, customers, merchants, paymentRepository
+}
+
+func TestCustomerService_Payment_ConcurrentPaymentsCannotOverspend(t *testing.T) {
+	for _, backend := range []string{"memory", "sqlite"} {
+		t.Run(backend, func(t *testing.T) {
+			const payments = 20
+			customerService, customers, merchants, _ := setupConcurrentCustomerService(t, backend, payments, nil)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, payments)
+			for i := 0; i < payments; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := customerService.Payment(dto.PaymentRequest{MerchantID: "merchant-001", Amount: 100.0}, "testuser")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			succeeded := 0
+			for err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				if !errors.Is(err, ErrInsufficientBalance) && !errors.Is(err, model.ErrConflict) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+
+			customer, err := customers.GetUserByID("cust-001")
+			require.NoError(t, err)
+			merchant, err := merchants.GetMerchantByID("merchant-001")
+			require.NoError(t, err)
+
+			assert.LessOrEqual(t, succeeded, 10)
+			assert.GreaterOrEqual(t, customer.Balance, 0.0)
+			assert.Equal(t, 1000.0-float64(succeeded)*100.0, customer.Balance)
+			assert.Equal(t, 500.0+float64(succeeded)*100.0, merchant.Balance)
+		})
+	}
+}
+
+func TestCustomerService_Payment_ConcurrentPaymentsCannotExceedLimits(t *testing.T) {
+	for _, backend := range []string{"memory", "sqlite"} {
+		t.Run(backend, func(t *testing.T) {
+			const payments = 20
+			limits := []model.SpendingLimit{{Tier: model.DefaultLimitTier, Daily: model.LimitWindow{MaxCount: 3}}}
+			customerService, customers, _, paymentRepository := setupConcurrentCustomerService(t, backend, payments, limits)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, payments)
+			for i := 0; i < payments; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := customerService.Payment(dto.PaymentRequest{MerchantID: "merchant-001", Amount: 10.0}, "testuser")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			succeeded := 0
+			for err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				var limitErr *limitService.LimitError
+				if !errors.As(err, &limitErr) && !errors.Is(err, model.ErrConflict) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+
+			stored, err := paymentRepository.GetPaymentsByCustomerID("cust-001", time.Time{})
+			require.NoError(t, err)
+			counted := 0
+			for _, payment := range stored {
+				if payment.Status != model.PaymentStatusFailed {
+					counted++
+				}
+			}
+			customer, err := customers.GetUserByID("cust-001")
+			require.NoError(t, err)
+
+			assert.LessOrEqual(t, succeeded, 3)
+			assert.LessOrEqual(t, counted, 3)
+			assert.Equal(t, 1000.0-float64(succeeded)*10.0, customer.Balance)
+		})
+	}
+}
+
+func TestCustomerService_RefundPayment_ConcurrentRefundsRefundOnce(t *testing.T) {
+	for _, backend := range []string{"memory", "sqlite"} {
+		t.Run(backend, func(t *testing.T) {
+			const refunds = 5
+			customerService, customers, merchants, paymentRepository := setupConcurrentCustomerService(t, backend, 1, nil)
+			payment, err := customerService.Payment(dto.PaymentRequest{MerchantID: "merchant-001", Amount: 100.0}, "testuser")
+			require.NoError(t, err)
+			barrier := &sync.WaitGroup{}
+			barrier.Add(refunds)
+			refundService := NewCustomerService(customers, merchants, barrierPaymentRepository{paymentRepository, barrier}, new(MockAccountRepository), new(MockPromotionRepository), new(MockFeeService), new(MockLimitService), new(MockFXService), new(MockLoyaltyService), nil)
+
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			errs := make(chan error, refunds)
+			for i := 0; i < refunds; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					_, err := refundService.RefundPayment(payment.ID, "duplicate order")
+					errs <- err
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+
+			refunded := 0
+			for err := range errs {
+				if err == nil {
+					refunded++
+					continue
+				}
+				if !errors.Is(err, model.ErrInvalidPaymentTransition) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+
+			customer, err := customers.GetUserByID("cust-001")
+			require.NoError(t, err)
+			merchant, err := merchants.GetMerchantByID("merchant-001")
+			require.NoError(t, err)
+
+			assert.Equal(t, 1, refunded)
+			assert.Equal(t, 1000.0, customer.Balance)
+			assert.Equal(t, 500.0, merchant.Balance)
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package service
 
 import (
+	"context"
+	"simple-golang-tdd/dto"
 	"simple-golang-tdd/model"
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -26,8 +32,18 @@ func (m *MockCustomerRepository) GetUserBalance(id string) (float64, error) {
 	return args.Get(0).(float64), args.Error(1)
 }
 
-func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64) (model.Customer, error) {
-	args := m.Called(id, amount)
+func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, points, expectedVersion)
 	return args.Get(0).(model.Customer), args.Error(1)
 }
 
@@ -35,8 +51,23 @@ type MockMerchantRepository struct {
 	mock.Mock
 }
 
-func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64) (model.Merchant, error) {
-	args := m.Called(id, amount)
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
 	return args.Get(0).(model.Merchant), args.Error(1)
 }
 
@@ -44,3 +75,147 @@ func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error)
 	args := m.Called(id)
 	return args.Get(0).(float64), args.Error(1)
 }
+
+type MockPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) CreatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentByID(id string) (model.Payment, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentsByCustomerID(customerID string, since time.Time) ([]model.Payment, error) {
+	args := m.Called(customerID, since)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetDueEscrowPayments(at time.Time) ([]model.Payment, error) {
+	args := m.Called(at)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) GetAccountBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockAccountRepository) UpdateAccountBalance(id string, amount float64) (model.Account, error) {
+	args := m.Called(id, amount)
+	return args.Get(0).(model.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) AdjustAccountBalance(id string, delta float64) (model.Account, error) {
+	args := m.Called(id, delta)
+	return args.Get(0).(model.Account), args.Error(1)
+}
+
+type MockPromotionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPromotionRepository) GetPromotionByCode(code string) (model.Promotion, error) {
+	args := m.Called(code)
+	return args.Get(0).(model.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) RedeemPromotion(id string, redemption model.PromotionRedemption) (model.Promotion, error) {
+	args := m.Called(id, redemption)
+	return args.Get(0).(model.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) CancelRedemption(id string, paymentID string) (model.Promotion, error) {
+	args := m.Called(id, paymentID)
+	return args.Get(0).(model.Promotion), args.Error(1)
+}
+
+type MockFeeService struct {
+	mock.Mock
+}
+
+func (m *MockFeeService) CalculateFee(merchant model.Merchant, amount float64) (model.Fee, error) {
+	args := m.Called(merchant, amount)
+	return args.Get(0).(model.Fee), args.Error(1)
+}
+
+type MockLimitService struct {
+	mock.Mock
+}
+
+// ReserveLimit mocks the ReserveLimit method of LimitService, calling record
+// when the mocked limit check passes
+func (m *MockLimitService) ReserveLimit(customer model.Customer, amount float64, record func() error) error {
+	args := m.Called(customer, amount)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return record()
+}
+
+type MockFXService struct {
+	mock.Mock
+}
+
+func (m *MockFXService) Convert(amount float64, from, to string) (model.FXConversion, error) {
+	args := m.Called(amount, from, to)
+	return args.Get(0).(model.FXConversion), args.Error(1)
+}
+
+type MockLoyaltyService struct {
+	mock.Mock
+}
+
+func (m *MockLoyaltyService) AccrueRewards(payment model.Payment) ([]model.Reward, error) {
+	args := m.Called(payment)
+	return args.Get(0).([]model.Reward), args.Error(1)
+}
+
+func (m *MockLoyaltyService) GetLoyalty(username string) (model.LoyaltySummary, error) {
+	args := m.Called(username)
+	return args.Get(0).(model.LoyaltySummary), args.Error(1)
+}
+
+func (m *MockLoyaltyService) RedeemPoints(request dto.RedeemPointsRequest, username string) (model.LoyaltySummary, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.LoyaltySummary), args.Error(1)
+}
+
+func (m *MockLoyaltyService) CreditDueRewards() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockLoyaltyService) Start(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+// MockTransactor runs fn on the repositories it was set up with, standing in
+// for one storage transaction.
+type MockTransactor struct {
+	mock.Mock
+	customerRepository  *MockCustomerRepository
+	merchantRepository  *MockMerchantRepository
+	promotionRepository *MockPromotionRepository
+}
+
+func (m *MockTransactor) InTransaction(fn func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, promotionRepository promotionRepo.PromotionRepository) error) error {
+	args := m.Called()
+	if err := fn(m.customerRepository, m.merchantRepository, m.promotionRepository); err != nil {
+		return err
+	}
+	return args.Error(0)
+}
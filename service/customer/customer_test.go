@@ -2,17 +2,71 @@ package service
 
 import (
 	"errors"
+	"math"
 	"simple-golang-tdd/dto"
 	"simple-golang-tdd/model"
+	"simple-golang-tdd/qr"
+	limitService "simple-golang-tdd/service/limit"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+type customerServiceMocks struct {
+	customerRepository  *MockCustomerRepository
+	merchantRepository  *MockMerchantRepository
+	paymentRepository   *MockPaymentRepository
+	accountRepository   *MockAccountRepository
+	promotionRepository *MockPromotionRepository
+	feeService          *MockFeeService
+	limitService        *MockLimitService
+	fxService           *MockFXService
+	loyaltyService      *MockLoyaltyService
+}
+
+func (m customerServiceMocks) assertExpectations(t *testing.T) {
+	m.customerRepository.AssertExpectations(t)
+	m.merchantRepository.AssertExpectations(t)
+	m.paymentRepository.AssertExpectations(t)
+	m.accountRepository.AssertExpectations(t)
+	m.promotionRepository.AssertExpectations(t)
+	m.feeService.AssertExpectations(t)
+	m.limitService.AssertExpectations(t)
+	m.fxService.AssertExpectations(t)
+	m.loyaltyService.AssertExpectations(t)
+}
+
+func setupCustomerService() (CustomerService, customerServiceMocks) {
+	mocks := customerServiceMocks{
+		customerRepository:  new(MockCustomerRepository),
+		merchantRepository:  new(MockMerchantRepository),
+		paymentRepository:   new(MockPaymentRepository),
+		accountRepository:   new(MockAccountRepository),
+		promotionRepository: new(MockPromotionRepository),
+		feeService:          new(MockFeeService),
+		limitService:        new(MockLimitService),
+		fxService:           new(MockFXService),
+		loyaltyService:      new(MockLoyaltyService),
+	}
+	customerService := NewCustomerService(mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, mocks.promotionRepository, mocks.feeService, mocks.limitService, mocks.fxService, mocks.loyaltyService, nil)
+	return customerService, mocks
+}
+
+// paymentWithStatus matches a payment argument by its current status.
+func paymentWithStatus(status model.PaymentStatus) interface{} {
+	return mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.Status == status
+	})
+}
+
+func fakePendingPayment(request dto.PaymentRequest, customerID string) model.Payment {
+	return model.NewPayment("pay-001", customerID, request.MerchantID, request.Amount, time.Now().UTC())
+}
+
 func TestCustomerService_Payment_Success(t *testing.T) {
-	mockCustomerRepository := new(MockCustomerRepository)
-	mockMerchantRepository := new(MockMerchantRepository)
-	customerService := NewCustomerService(mockCustomerRepository, mockMerchantRepository)
+	customerService, mocks := setupCustomerService()
 
 	fakePayment := dto.PaymentRequest{
 		MerchantID: "merchant123",
@@ -32,24 +86,35 @@ func TestCustomerService_Payment_Success(t *testing.T) {
 	expectedMerchant := model.Merchant{
 		ID:      "merchant123",
 		Name:    "Merchant 123",
-		Balance: 600.0, // saldo awal merchant
+		Balance: 500.0, // saldo awal merchant
 	}
 
-	mockCustomerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
-	mockMerchantRepository.On("GetMerchantBalance", fakePayment.MerchantID).Return(500.0, nil)
-	mockCustomerRepository.On("UpdateUserBalance", expectedCustomer.ID, expectedCustomer.Balance-100.0).Return(expectedCustomer, nil)
-	mockMerchantRepository.On("UpdateMerchantBalance", fakePayment.MerchantID, 500.0+100.0).Return(expectedMerchant, nil)
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", expectedCustomer.ID, expectedCustomer.Balance-100.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", fakePayment.MerchantID, 500.0+100.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(func() model.Payment {
+		succeeded := pendingPayment
+		_ = succeeded.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+		return succeeded
+	}(), nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
 	resp, err := customerService.Payment(fakePayment, fakeUsername)
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, resp)
-	mockCustomerRepository.AssertExpectations(t)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	assert.Equal(t, "pay-001", resp.ID)
+	mocks.assertExpectations(t)
 }
 
 func TestCustomerService_Payment_UserNotFound(t *testing.T) {
-	mockCustomerRepository := new(MockCustomerRepository)
-	mockMerchantRepository := new(MockMerchantRepository)
-	customerService := NewCustomerService(mockCustomerRepository, mockMerchantRepository)
+	customerService, mocks := setupCustomerService()
 
 	fakePayment := dto.PaymentRequest{
 		MerchantID: "merchant123",
@@ -58,20 +123,17 @@ func TestCustomerService_Payment_UserNotFound(t *testing.T) {
 
 	fakeUsername := "testuser"
 
-	mockCustomerRepository.On("GetUserByUsername", fakeUsername).Return(model.Customer{}, errors.New("user not found"))
+	mocks.customerRepository.On("GetUserByUsername", fakeUsername).Return(model.Customer{}, errors.New("user not found"))
 
 	resp, err := customerService.Payment(fakePayment, fakeUsername)
 
 	assert.Error(t, err)
 	assert.Empty(t, resp.ID)
-	assert.Empty(t, resp.Username)
-	mockCustomerRepository.AssertExpectations(t)
+	mocks.assertExpectations(t)
 }
 
 func TestCustomerService_Payment_MerchantNotFound(t *testing.T) {
-	mockCustomerRepository := new(MockCustomerRepository)
-	mockMerchantRepository := new(MockMerchantRepository)
-	customerService := NewCustomerService(mockCustomerRepository, mockMerchantRepository)
+	customerService, mocks := setupCustomerService()
 
 	fakePayment := dto.PaymentRequest{
 		MerchantID: "merchant123",
@@ -88,22 +150,18 @@ func TestCustomerService_Payment_MerchantNotFound(t *testing.T) {
 		Balance:  1000.0,
 	}
 
-	mockCustomerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
-	mockMerchantRepository.On("GetMerchantBalance", fakePayment.MerchantID).Return(0.0, errors.New("merchant not found"))
+	mocks.customerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(model.Merchant{}, errors.New("merchant not found by ID"))
 
 	resp, err := customerService.Payment(fakePayment, fakeUsername)
 
 	assert.Error(t, err)
 	assert.Empty(t, resp.ID)
-	assert.Empty(t, resp.Username)
-	mockCustomerRepository.AssertExpectations(t)
-	mockMerchantRepository.AssertExpectations(t)
+	mocks.assertExpectations(t)
 }
 
 func TestCustomerService_Payment_InsufficientBalance(t *testing.T) {
-	mockCustomerRepository := new(MockCustomerRepository)
-	mockMerchantRepository := new(MockMerchantRepository)
-	customerService := NewCustomerService(mockCustomerRepository, mockMerchantRepository)
+	customerService, mocks := setupCustomerService()
 
 	fakePayment := dto.PaymentRequest{
 		MerchantID: "merchant123",
@@ -120,13 +178,1242 @@ func TestCustomerService_Payment_InsufficientBalance(t *testing.T) {
 		Balance:  1000.0,     // saldo awal
 	}
 
-	mockCustomerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
-	mockMerchantRepository.On("GetMerchantBalance", fakePayment.MerchantID).Return(500.0, nil)
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
 
 	resp, err := customerService.Payment(fakePayment, fakeUsername)
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_MerchantUpdateFailedRollsBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     100.0,
+	}
+
+	fakeUsername := "testuser"
+
+	expectedCustomer := model.Customer{
+		ID:       "1",
+		Username: "testuser",
+		Balance:  1000.0,
+	}
+
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", fakeUsername).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", expectedCustomer.ID, 900.0, int64(0)).Return(model.Customer{ID: "1", Balance: 900.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", fakePayment.MerchantID, 600.0, int64(0)).Return(model.Merchant{}, errors.New("disk full"))
+	mocks.customerRepository.On("UpdateUserBalance", expectedCustomer.ID, 1000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, fakeUsername)
+
+	assert.EqualError(t, err, "failed to update merchant balance: disk full")
 	assert.Empty(t, resp.ID)
-	assert.Empty(t, resp.Username)
-	mockCustomerRepository.AssertExpectations(t)
+	mocks.assertExpectations(t)
+}
+
+// Helper function untuk membuat CustomerService yang menyelesaikan pembayaran
+// di dalam transaksi storage
+func setupTransactionalCustomerService() (CustomerService, customerServiceMocks, *MockTransactor) {
+	_, mocks := setupCustomerService()
+	transactor := &MockTransactor{
+		customerRepository:  new(MockCustomerRepository),
+		merchantRepository:  new(MockMerchantRepository),
+		promotionRepository: new(MockPromotionRepository),
+	}
+	customerService := NewCustomerService(mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, mocks.promotionRepository, mocks.feeService, mocks.limitService, mocks.fxService, mocks.loyaltyService, transactor)
+	return customerService, mocks, transactor
+}
+
+func TestCustomerService_Payment_SettlesInTransaction(t *testing.T) {
+	customerService, mocks, transactor := setupTransactionalCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant123", Amount: 100.0}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	transactor.On("InTransaction").Return(nil)
+	transactor.customerRepository.On("UpdateUserBalance", expectedCustomer.ID, 900.0, int64(0)).Return(expectedCustomer, nil)
+	transactor.merchantRepository.On("UpdateMerchantBalance", fakePayment.MerchantID, 600.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(func() model.Payment {
+		succeeded := pendingPayment
+		_ = succeeded.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+		return succeeded
+	}(), nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	mocks.assertExpectations(t)
+	transactor.AssertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+	transactor.merchantRepository.AssertExpectations(t)
+}
+
+func TestCustomerService_Payment_FailedTransactionNeedsNoBalanceRollback(t *testing.T) {
+	customerService, mocks, transactor := setupTransactionalCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant123", Amount: 100.0}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	transactor.On("InTransaction").Return(nil)
+	transactor.customerRepository.On("UpdateUserBalance", expectedCustomer.ID, 900.0, int64(0)).Return(expectedCustomer, nil)
+	transactor.merchantRepository.On("UpdateMerchantBalance", fakePayment.MerchantID, 600.0, int64(0)).Return(model.Merchant{}, errors.New("disk full"))
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	// The customer debit rolls back with the transaction, so it is not undone by hand.
+	assert.EqualError(t, err, "failed to update merchant balance: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+	transactor.customerRepository.AssertNumberOfCalls(t, "UpdateUserBalance", 1)
+}
+
+func TestCustomerService_Payment_StatusUpdateFailedRollsBackSettlement(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant123", Amount: 100.0}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 900.0, int64(0)).Return(model.Customer{ID: "1", Balance: 900.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 600.0, int64(0)).Return(model.Merchant{ID: "merchant123", Balance: 600.0, Version: 1}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{}, errors.New("disk full"))
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(1)).Return(expectedMerchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 1000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.EqualError(t, err, "failed to update payment: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+	mocks.loyaltyService.AssertNotCalled(t, "AccrueRewards", mock.Anything)
+}
+
+func TestCustomerService_Payment_StatusUpdateFailedRollsBackCommittedTransaction(t *testing.T) {
+	customerService, mocks, transactor := setupTransactionalCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant123", Amount: 100.0}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	transactor.On("InTransaction").Return(nil)
+	transactor.customerRepository.On("UpdateUserBalance", "1", 900.0, int64(0)).Return(model.Customer{ID: "1", Balance: 900.0, Version: 1}, nil)
+	transactor.merchantRepository.On("UpdateMerchantBalance", "merchant123", 600.0, int64(0)).Return(model.Merchant{ID: "merchant123", Balance: 600.0, Version: 1}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{}, errors.New("disk full"))
+	// The transaction has committed, so its updates are undone outside it.
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(1)).Return(expectedMerchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 1000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.EqualError(t, err, "failed to update payment: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+	transactor.merchantRepository.AssertExpectations(t)
+}
+
+func TestCustomerService_Payment_WithFee(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     1000.0,
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Category: "grocery", Balance: 500.0}
+	fee := model.Fee{RuleID: "fee-grocery", Amount: 25.0}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(fee, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.Fee == 25.0 && payment.FeeRuleID == "fee-grocery" && payment.NetAmount == 975.0
+	})).Return(func() model.Payment {
+		payment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+		payment.ApplyFee(fee)
+		return payment
+	}(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1475.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 25.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{ID: "pay-001", Fee: 25.0, NetAmount: 975.0, Status: model.PaymentStatusSucceeded}, nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 25.0, resp.Fee)
+	assert.Equal(t, 975.0, resp.NetAmount)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_RevenueUpdateFailedRollsBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     1000.0,
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	fee := model.Fee{RuleID: "fee-default", Amount: 10.0}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(fee, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(func() model.Payment {
+		payment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+		payment.ApplyFee(fee)
+		return payment
+	}(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000.0, int64(0)).Return(model.Customer{ID: "1", Balance: 4000.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1490.0, int64(0)).Return(model.Merchant{ID: "merchant123", Balance: 1490.0, Version: 1}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 10.0).Return(model.Account{}, errors.New("disk full"))
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(1)).Return(expectedMerchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 5000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.EqualError(t, err, "failed to update platform revenue balance: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_FeeError(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     1000.0,
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, errors.New("failed to get fee rules: disk error"))
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.EqualError(t, err, "failed to calculate fee: failed to get fee rules: disk error")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_LimitExceeded(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     1000.0,
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Tier: "basic", Balance: 5000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	limitErr := &limitService.LimitError{Code: limitService.CodeDailyAmountLimitExceeded, Message: "payment exceeds the daily spending limit of 500.00"}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(limitErr)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.ErrorIs(t, err, limitErr)
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+	mocks.customerRepository.AssertNotCalled(t, "UpdateUserBalance", mock.Anything, mock.Anything, mock.Anything)
+	mocks.paymentRepository.AssertNotCalled(t, "CreatePayment", mock.Anything)
+}
+
+func TestCustomerService_Payment_InvalidAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		expected string
+	}{
+		{"negative", -100.0, "invalid amount: amount must be a positive number"},
+		{"zero", 0, "invalid amount: amount must be a positive number"},
+		{"NaN", math.NaN(), "invalid amount: amount must be a positive number"},
+		{"positive infinity", math.Inf(1), "invalid amount: amount must be a positive number"},
+		{"negative infinity", math.Inf(-1), "invalid amount: amount must be a positive number"},
+		{"too many decimals", 10.001, "invalid amount: IDR amounts allow at most 2 decimal places"},
+		{"above maximum", 1000000000.01, "invalid amount: amount exceeds the maximum of 1000000000.00 IDR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerService, mocks := setupCustomerService()
+
+			fakePayment := dto.PaymentRequest{
+				MerchantID: "merchant123",
+				Amount:     tt.amount,
+			}
+
+			resp, err := customerService.Payment(fakePayment, "testuser")
+
+			assert.ErrorIs(t, err, ErrInvalidAmount)
+			assert.EqualError(t, err, tt.expected)
+			assert.Empty(t, resp.ID)
+			// Invalid amounts are rejected before any data is read or written.
+			mocks.customerRepository.AssertNotCalled(t, "GetUserByUsername", mock.Anything)
+			mocks.paymentRepository.AssertNotCalled(t, "CreatePayment", mock.Anything)
+		})
+	}
+}
+
+func TestCustomerService_Payment_AmountWithinPrecision(t *testing.T) {
+	assert.NoError(t, ValidateAmount(0.01, model.DefaultCurrency))
+	assert.NoError(t, ValidateAmount(100.1, model.DefaultCurrency))
+	assert.NoError(t, ValidateAmount(1000000000, model.DefaultCurrency))
+	assert.ErrorIs(t, ValidateAmount(10, "XXX"), ErrInvalidAmount)
+}
+
+func TestCustomerService_Payment_CrossCurrency(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant-003",
+		Amount:     1000000.0,
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000000.0}
+	expectedMerchant := model.Merchant{ID: "merchant-003", Currency: "USD", Wallets: map[string]float64{"USD": 100.0}}
+	fee := model.Fee{RuleID: "fee-default", Amount: 10000.0}
+	conversion := model.FXConversion{From: "IDR", To: "USD", MidRate: 1.0 / 16000, SpreadPercentage: 1, Rate: 0.99 / 16000, Amount: 990000.0, ConvertedAmount: 61.26}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 1000000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(fee, nil)
+	mocks.fxService.On("Convert", 990000.0, "IDR", "USD").Return(conversion, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.FX != nil && payment.FX.Rate == conversion.Rate && payment.Currency == "IDR"
+	})).Return(func() model.Payment {
+		payment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+		payment.ApplyFee(fee)
+		payment.FX = &conversion
+		return payment
+	}(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000000.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantWalletBalance", "merchant-003", "USD", 161.26, int64(0)).Return(expectedMerchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 10000.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{ID: "pay-001", FX: &conversion, Status: model.PaymentStatusSucceeded}, nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, conversion.Rate, resp.FX.Rate)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_FromForeignWallet(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant123",
+		Amount:     10.0,
+		Currency:   "USD",
+	}
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 0, Wallets: map[string]float64{"USD": 50.0}}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	fee := model.Fee{RuleID: "fee-default", Amount: 0.1}
+	toBase := model.FXConversion{From: "USD", To: "IDR", MidRate: 16000, SpreadPercentage: 1, Rate: 15840, Amount: 10.0, ConvertedAmount: 158400.0}
+	toMerchant := model.FXConversion{From: "USD", To: "IDR", MidRate: 16000, SpreadPercentage: 1, Rate: 15840, Amount: 9.9, ConvertedAmount: 156816.0}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.fxService.On("Convert", 10.0, "USD", "IDR").Return(toBase, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 160000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 160000.0).Return(model.Fee{RuleID: "fee-default", Amount: 1600.0}, nil)
+	mocks.fxService.On("Convert", 9.9, "USD", "IDR").Return(toMerchant, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.Currency == "USD" && payment.BaseAmount == 160000.0 && payment.FX != nil
+	})).Return(func() model.Payment {
+		payment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+		payment.Currency = "USD"
+		payment.BaseAmount = 160000.0
+		payment.ApplyFee(fee)
+		payment.FX = &toMerchant
+		return payment
+	}(), nil)
+	mocks.customerRepository.On("UpdateUserWalletBalance", "1", "USD", 40.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 157316.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 1600.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusSucceeded}, nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pay-001", resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_InsufficientForeignBalance(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{
+		MerchantID: "merchant-003",
+		Amount:     100.0,
+		Currency:   "USD",
+	}
+
+	// Plenty of IDR does not pay for a USD payment.
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000000.0, Wallets: map[string]float64{"USD": 50.0}}
+	expectedMerchant := model.Merchant{ID: "merchant-003", Currency: "USD"}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.fxService.On("Convert", 100.0, "USD", "IDR").Return(model.FXConversion{MidRate: 16000}, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 1600000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 1600000.0).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(fakePendingPayment(fakePayment, expectedCustomer.ID), nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_GetPayment_Success(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	customer := model.Customer{ID: "1", Username: "testuser"}
+	payment := model.NewPayment("pay-001", "1", "merchant123", 100.0, time.Now().UTC())
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(customer, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(payment, nil)
+
+	resp, err := customerService.GetPayment("pay-001", "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, payment, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_GetPayment_NotFound(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	customer := model.Customer{ID: "1", Username: "testuser"}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(customer, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-404").Return(model.Payment{}, errors.New("payment not found by ID"))
+
+	resp, err := customerService.GetPayment("pay-404", "testuser")
+
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_GetPayment_OtherCustomer(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	customer := model.Customer{ID: "1", Username: "testuser"}
+	payment := model.NewPayment("pay-002", "2", "merchant123", 100.0, time.Now().UTC())
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(customer, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-002").Return(payment, nil)
+
+	resp, err := customerService.GetPayment("pay-002", "testuser")
+
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+// fakeSucceededPayment is a 1000 payment to merchant123 with a 25 fee and a 50 discount.
+func fakeSucceededPayment() model.Payment {
+	payment := model.NewPayment("pay-001", "1", "merchant123", 1000.0, time.Now().UTC())
+	payment.ApplyFee(model.Fee{RuleID: "fee-grocery", Amount: 25.0})
+	payment.ApplyDiscount(model.Promotion{ID: "promo-001", Code: "HEMAT"}, 50.0)
+	_ = payment.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+	return payment
+}
+
+func TestCustomerService_RefundPayment_Success(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	payment := fakeSucceededPayment()
+	customer := model.Customer{ID: "1", Username: "testuser", Balance: 4050.0}
+	merchant := model.Merchant{ID: "merchant123", Balance: 1475.0}
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(payment, nil)
+	mocks.customerRepository.On("GetUserByID", "1").Return(customer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(merchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 5000.0, int64(0)).Return(customer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(0)).Return(merchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformPromotionAccountID, -50.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, -25.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		last := payment.Transitions[len(payment.Transitions)-1]
+		return payment.Status == model.PaymentStatusRefunded && last.Reason == "dispute resolved"
+	})).Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusRefunded}, nil)
+
+	resp, err := customerService.RefundPayment("pay-001", "dispute resolved")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusRefunded, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_RefundPayment_NotSucceeded(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	payment := model.NewPayment("pay-001", "1", "merchant123", 100.0, time.Now().UTC())
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(payment, nil)
+
+	resp, err := customerService.RefundPayment("pay-001", "dispute resolved")
+
+	assert.ErrorIs(t, err, model.ErrInvalidPaymentTransition)
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_RefundPayment_NotFound(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-404").Return(model.Payment{}, errors.New("payment not found by ID"))
+
+	resp, err := customerService.RefundPayment("pay-404", "dispute resolved")
+
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_RefundPayment_UpdateFailedRollsBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	payment := fakeSucceededPayment()
+	customer := model.Customer{ID: "1", Username: "testuser", Balance: 4050.0}
+	merchant := model.Merchant{ID: "merchant123", Balance: 1475.0}
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(payment, nil)
+	mocks.customerRepository.On("GetUserByID", "1").Return(customer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(merchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 5000.0, int64(0)).Return(model.Customer{ID: "1", Balance: 5000.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(0)).Return(model.Merchant{ID: "merchant123", Balance: 500.0, Version: 1}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformPromotionAccountID, -50.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, -25.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusRefunded)).Return(model.Payment{}, errors.New("disk full"))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 25.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformPromotionAccountID, 50.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1475.0, int64(1)).Return(merchant, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4050.0, int64(1)).Return(customer, nil)
+
+	resp, err := customerService.RefundPayment("pay-001", "dispute resolved")
+
+	assert.EqualError(t, err, "failed to update payment: disk full")
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestPaymentStatus_Transitions(t *testing.T) {
+	tests := []struct {
+		from    model.PaymentStatus
+		to      model.PaymentStatus
+		allowed bool
+	}{
+		{model.PaymentStatusPending, model.PaymentStatusSucceeded, true},
+		{model.PaymentStatusPending, model.PaymentStatusFailed, true},
+		{model.PaymentStatusPending, model.PaymentStatusRefunded, false},
+		{model.PaymentStatusPending, model.PaymentStatusEscrowed, true},
+		{model.PaymentStatusEscrowed, model.PaymentStatusSucceeded, true},
+		{model.PaymentStatusEscrowed, model.PaymentStatusCancelled, true},
+		{model.PaymentStatusEscrowed, model.PaymentStatusRefunded, false},
+		{model.PaymentStatusCancelled, model.PaymentStatusSucceeded, false},
+		{model.PaymentStatusSucceeded, model.PaymentStatusRefunded, true},
+		{model.PaymentStatusSucceeded, model.PaymentStatusPartiallyRefunded, true},
+		{model.PaymentStatusSucceeded, model.PaymentStatusReversed, true},
+		{model.PaymentStatusSucceeded, model.PaymentStatusFailed, false},
+		{model.PaymentStatusPartiallyRefunded, model.PaymentStatusPartiallyRefunded, true},
+		{model.PaymentStatusPartiallyRefunded, model.PaymentStatusRefunded, true},
+		{model.PaymentStatusFailed, model.PaymentStatusSucceeded, false},
+		{model.PaymentStatusRefunded, model.PaymentStatusSucceeded, false},
+		{model.PaymentStatusReversed, model.PaymentStatusRefunded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"_to_"+string(tt.to), func(t *testing.T) {
+			payment := model.NewPayment("pay-001", "1", "merchant123", 100.0, time.Now().UTC())
+			payment.Status = tt.from
+
+			err := payment.TransitionTo(tt.to, "", time.Now().UTC())
+
+			if tt.allowed {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.to, payment.Status)
+				assert.Len(t, payment.Transitions, 2)
+			} else {
+				assert.ErrorIs(t, err, model.ErrInvalidPaymentTransition)
+				assert.Equal(t, tt.from, payment.Status)
+				assert.Len(t, payment.Transitions, 1)
+			}
+		})
+	}
+}
+
+func fakeSplitPaymentRequest() dto.SplitPaymentRequest {
+	return dto.SplitPaymentRequest{
+		Amount: 1000.0,
+		Legs: []dto.SplitPaymentLeg{
+			{MerchantID: "seller", Amount: 900.0},
+			{MerchantID: "logistics", Amount: 100.0},
+		},
+	}
+}
+
+func fakePendingSplitPayment(customerID string, legs []model.PaymentLeg) model.Payment {
+	payment := model.NewPayment("pay-001", customerID, "", 1000.0, time.Now().UTC())
+	payment.ApplyLegs(legs)
+	return payment
+}
+
+func TestCustomerService_SplitPayment_Success(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	seller := model.Merchant{ID: "seller", Category: "retail", Balance: 100.0}
+	logistics := model.Merchant{ID: "logistics", Balance: 50.0}
+	legs := []model.PaymentLeg{
+		{MerchantID: "seller", Amount: 900.0, Fee: 9.0, FeeRuleID: "fee-default", NetAmount: 891.0},
+		{MerchantID: "logistics", Amount: 100.0, NetAmount: 100.0},
+	}
+	pendingPayment := fakePendingSplitPayment(expectedCustomer.ID, legs)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "seller").Return(seller, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "logistics").Return(logistics, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 1000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", seller, 900.0).Return(model.Fee{RuleID: "fee-default", Amount: 9.0}, nil)
+	mocks.feeService.On("CalculateFee", logistics, 100.0).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.MerchantID == "" && len(payment.Legs) == 2 && payment.Fee == 9.0 && payment.NetAmount == 991.0
+	})).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "seller", 991.0, int64(0)).Return(seller, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "logistics", 150.0, int64(0)).Return(logistics, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 9.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(func() model.Payment {
+		succeeded := pendingPayment
+		_ = succeeded.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+		return succeeded
+	}(), nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.SplitPayment(fakeSplitPaymentRequest(), "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	assert.Len(t, resp.Legs, 2)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_SplitPayment_FeeOnBaseAmount(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	request := fakeSplitPaymentRequest()
+	request.Amount, request.Currency = 10.0, "USD"
+	request.Legs[0].Amount, request.Legs[1].Amount = 9.0, 1.0
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Wallets: map[string]float64{"USD": 50.0}}
+	seller := model.Merchant{ID: "seller", Currency: "USD"}
+	logistics := model.Merchant{ID: "logistics", Currency: "USD"}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "seller").Return(seller, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "logistics").Return(logistics, nil)
+	mocks.fxService.On("Convert", 10.0, "USD", model.DefaultCurrency).Return(model.FXConversion{MidRate: 16000}, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 160000.0).Return(nil)
+	// The fixed IDR 500 fee is worth 0.03 USD, not 500 USD.
+	mocks.feeService.On("CalculateFee", seller, 144000.0).Return(model.Fee{RuleID: "fee-fixed", Amount: 500.0}, nil)
+	mocks.feeService.On("CalculateFee", logistics, 16000.0).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.Legs[0].Fee == 0.03 && payment.Legs[0].NetAmount == 8.97 && payment.Fee == 0.03
+	})).Return(model.Payment{}, errors.New("disk full"))
+
+	_, err := customerService.SplitPayment(request, "testuser")
+
+	assert.EqualError(t, err, "failed to create payment: disk full")
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_SplitPayment_InvalidLegs(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.SplitPaymentRequest
+		wantErr error
+	}{
+		{
+			name:    "single leg",
+			request: dto.SplitPaymentRequest{Amount: 100.0, Legs: []dto.SplitPaymentLeg{{MerchantID: "seller", Amount: 100.0}}},
+			wantErr: ErrInvalidSplit,
+		},
+		{
+			name: "legs do not add up",
+			request: dto.SplitPaymentRequest{Amount: 1000.0, Legs: []dto.SplitPaymentLeg{
+				{MerchantID: "seller", Amount: 900.0},
+				{MerchantID: "logistics", Amount: 99.99},
+			}},
+			wantErr: ErrInvalidSplit,
+		},
+		{
+			name: "duplicate merchant",
+			request: dto.SplitPaymentRequest{Amount: 1000.0, Legs: []dto.SplitPaymentLeg{
+				{MerchantID: "seller", Amount: 500.0},
+				{MerchantID: "seller", Amount: 500.0},
+			}},
+			wantErr: ErrInvalidSplit,
+		},
+		{
+			name: "over-precise leg",
+			request: dto.SplitPaymentRequest{Amount: 1000.0, Legs: []dto.SplitPaymentLeg{
+				{MerchantID: "seller", Amount: 899.999},
+				{MerchantID: "logistics", Amount: 100.001},
+			}},
+			wantErr: ErrInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerService, mocks := setupCustomerService()
+
+			resp, err := customerService.SplitPayment(tt.request, "testuser")
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, resp.ID)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func TestCustomerService_SplitPayment_LegCreditFailedRollsBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	seller := model.Merchant{ID: "seller", Balance: 100.0}
+	logistics := model.Merchant{ID: "logistics", Balance: 50.0}
+	legs := []model.PaymentLeg{
+		{MerchantID: "seller", Amount: 900.0, NetAmount: 900.0},
+		{MerchantID: "logistics", Amount: 100.0, NetAmount: 100.0},
+	}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "seller").Return(seller, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "logistics").Return(logistics, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 1000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", mock.Anything, mock.Anything).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(fakePendingSplitPayment(expectedCustomer.ID, legs), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000.0, int64(0)).Return(model.Customer{ID: "1", Balance: 4000.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "seller", 1000.0, int64(0)).Return(model.Merchant{ID: "seller", Balance: 1000.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "logistics", 150.0, int64(0)).Return(model.Merchant{}, errors.New("disk full"))
+	mocks.merchantRepository.On("UpdateMerchantBalance", "seller", 100.0, int64(1)).Return(seller, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 5000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.SplitPayment(fakeSplitPaymentRequest(), "testuser")
+
+	assert.EqualError(t, err, "failed to update merchant balance: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_QRPayment_Dynamic(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant-001", Name: "Toko Budi", Balance: 500.0}
+	payload, err := qr.EncodeDynamic(expectedMerchant, 150.0)
+	assert.NoError(t, err)
+
+	paymentRequest := dto.PaymentRequest{MerchantID: "merchant-001", Amount: 150.0, Currency: model.DefaultCurrency}
+	pendingPayment := fakePendingPayment(paymentRequest, expectedCustomer.ID)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 150.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 150.0).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 850.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 650.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(func() model.Payment {
+		succeeded := pendingPayment
+		_ = succeeded.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+		return succeeded
+	}(), nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.QRPayment(dto.QRPaymentRequest{Payload: payload}, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	assert.Equal(t, 150.0, resp.Amount)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_QRPayment_Invalid(t *testing.T) {
+	merchant := model.Merchant{ID: "merchant-001", Name: "Toko Budi"}
+	static, err := qr.EncodeStatic(merchant)
+	assert.NoError(t, err)
+	dynamic, err := qr.EncodeDynamic(merchant, 150.0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		request dto.QRPaymentRequest
+	}{
+		{"malformed payload", dto.QRPaymentRequest{Payload: "not a qr code", Amount: 100.0}},
+		{"tampered payload", dto.QRPaymentRequest{Payload: static[:len(static)-4] + "0000", Amount: 100.0}},
+		{"static without amount", dto.QRPaymentRequest{Payload: static}},
+		{"dynamic with another amount", dto.QRPaymentRequest{Payload: dynamic, Amount: 100.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerService, mocks := setupCustomerService()
+
+			resp, err := customerService.QRPayment(tt.request, "testuser")
+
+			assert.ErrorIs(t, err, ErrInvalidQR)
+			assert.Empty(t, resp.ID)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func fakePromotion() model.Promotion {
+	return model.Promotion{
+		ID:            "promo-001",
+		Code:          "HEMAT10",
+		DiscountType:  model.DiscountTypePercentage,
+		DiscountValue: 10,
+		MaxDiscount:   50.0,
+		MinSpend:      100.0,
+		StartsAt:      time.Now().Add(-time.Hour),
+	}
+}
+
+func TestCustomerService_Payment_WithPromoCode(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	request := dto.PaymentRequest{MerchantID: "merchant123", Amount: 1000.0, PromoCode: "hemat10"}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(request, expectedCustomer.ID)
+	pendingPayment.ApplyFee(model.Fee{RuleID: "fee-default", Amount: 10.0})
+	pendingPayment.ApplyDiscount(fakePromotion(), 50.0)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 1000.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 1000.0).Return(model.Fee{RuleID: "fee-default", Amount: 10.0}, nil)
+	mocks.promotionRepository.On("GetPromotionByCode", "hemat10").Return(fakePromotion(), nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		// 10% of 1000 is capped at the promotion's maximum discount.
+		return payment.PromotionID == "promo-001" && payment.Discount == 50.0 && payment.NetAmount == 990.0
+	})).Return(pendingPayment, nil)
+	mocks.promotionRepository.On("RedeemPromotion", "promo-001", mock.MatchedBy(func(redemption model.PromotionRedemption) bool {
+		return redemption.PaymentID == pendingPayment.ID && redemption.CustomerID == "1" && redemption.Discount == 50.0
+	})).Return(fakePromotion(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 50.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1490.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformPromotionAccountID, 50.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 10.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(func() model.Payment {
+		succeeded := pendingPayment
+		_ = succeeded.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+		return succeeded
+	}(), nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.Payment(request, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	assert.Equal(t, 950.0, resp.ChargedAmount())
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_PromoCodeRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   dto.PaymentRequest
+		promotion model.Promotion
+		lookupErr error
+		wantErr   error
+	}{
+		{
+			name:      "unknown code",
+			request:   dto.PaymentRequest{MerchantID: "merchant123", Amount: 1000.0, PromoCode: "NOPE"},
+			lookupErr: errors.New("promotion not found by code"),
+			wantErr:   ErrInvalidPromoCode,
+		},
+		{
+			name:      "below minimum spend",
+			request:   dto.PaymentRequest{MerchantID: "merchant123", Amount: 50.0, PromoCode: "HEMAT10"},
+			promotion: fakePromotion(),
+			wantErr:   model.ErrPromotionNotApplicable,
+		},
+		{
+			name:    "other merchant",
+			request: dto.PaymentRequest{MerchantID: "merchant123", Amount: 1000.0, PromoCode: "HEMAT10"},
+			promotion: func() model.Promotion {
+				promotion := fakePromotion()
+				promotion.MerchantIDs = []string{"merchant-002"}
+				return promotion
+			}(),
+			wantErr: model.ErrPromotionNotApplicable,
+		},
+		{
+			name:    "expired",
+			request: dto.PaymentRequest{MerchantID: "merchant123", Amount: 1000.0, PromoCode: "HEMAT10"},
+			promotion: func() model.Promotion {
+				promotion := fakePromotion()
+				promotion.EndsAt = time.Now().Add(-time.Minute)
+				return promotion
+			}(),
+			wantErr: model.ErrPromotionNotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerService, mocks := setupCustomerService()
+
+			expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+			expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+			mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+			mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(expectedMerchant, nil)
+			mocks.feeService.On("CalculateFee", expectedMerchant, tt.request.Amount).Return(model.Fee{}, nil)
+			mocks.promotionRepository.On("GetPromotionByCode", tt.request.PromoCode).Return(tt.promotion, tt.lookupErr)
+
+			resp, err := customerService.Payment(tt.request, "testuser")
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, resp.ID)
+			mocks.paymentRepository.AssertNotCalled(t, "CreatePayment", mock.Anything)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func TestCustomerService_Payment_PromoCodeForeignCurrency(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	request := dto.PaymentRequest{MerchantID: "merchant-003", Amount: 100.0, Currency: "USD", PromoCode: "HEMAT10"}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser"}
+	expectedMerchant := model.Merchant{ID: "merchant-003", Currency: "USD"}
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-003").Return(expectedMerchant, nil)
+	mocks.fxService.On("Convert", 100.0, "USD", model.DefaultCurrency).Return(model.FXConversion{MidRate: 15000}, nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 1500000.0).Return(model.Fee{}, nil)
+
+	_, err := customerService.Payment(request, "testuser")
+
+	assert.ErrorIs(t, err, ErrInvalidPromoCode)
+	mocks.promotionRepository.AssertNotCalled(t, "GetPromotionByCode", mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_PromoRedemptionRolledBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	request := dto.PaymentRequest{MerchantID: "merchant123", Amount: 200.0, PromoCode: "HEMAT10"}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(request, expectedCustomer.ID)
+	pendingPayment.ApplyDiscount(fakePromotion(), 20.0)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 200.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 200.0).Return(model.Fee{}, nil)
+	mocks.promotionRepository.On("GetPromotionByCode", "HEMAT10").Return(fakePromotion(), nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.promotionRepository.On("RedeemPromotion", "promo-001", mock.Anything).Return(fakePromotion(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 820.0, int64(0)).Return(model.Customer{ID: "1", Balance: 820.0, Version: 1}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 700.0, int64(0)).Return(model.Merchant{}, errors.New("disk full"))
+	mocks.customerRepository.On("UpdateUserBalance", "1", 1000.0, int64(1)).Return(expectedCustomer, nil)
+	mocks.promotionRepository.On("CancelRedemption", "promo-001", pendingPayment.ID).Return(fakePromotion(), nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(request, "testuser")
+
+	assert.EqualError(t, err, "failed to update merchant balance: disk full")
+	assert.Empty(t, resp.ID)
+	mocks.accountRepository.AssertNotCalled(t, "AdjustAccountBalance", mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_PromoRedemptionRolledBackWithTransaction(t *testing.T) {
+	customerService, mocks, transactor := setupTransactionalCustomerService()
+
+	request := dto.PaymentRequest{MerchantID: "merchant123", Amount: 200.0, PromoCode: "HEMAT10"}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(request, expectedCustomer.ID)
+	pendingPayment.ApplyDiscount(fakePromotion(), 20.0)
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 200.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 200.0).Return(model.Fee{}, nil)
+	mocks.promotionRepository.On("GetPromotionByCode", "HEMAT10").Return(fakePromotion(), nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	transactor.On("InTransaction").Return(nil)
+	transactor.promotionRepository.On("RedeemPromotion", "promo-001", mock.Anything).Return(fakePromotion(), nil)
+	transactor.customerRepository.On("UpdateUserBalance", "1", 820.0, int64(0)).Return(model.Customer{ID: "1", Balance: 820.0, Version: 1}, nil)
+	transactor.merchantRepository.On("UpdateMerchantBalance", "merchant123", 700.0, int64(0)).Return(model.Merchant{}, errors.New("disk full"))
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	resp, err := customerService.Payment(request, "testuser")
+
+	assert.EqualError(t, err, "failed to update merchant balance: disk full")
+	assert.Empty(t, resp.ID)
+	// The redemption was written in the failed transaction, so it rolls back
+	// with it instead of being cancelled afterwards.
+	mocks.promotionRepository.AssertNotCalled(t, "RedeemPromotion", mock.Anything, mock.Anything)
+	mocks.promotionRepository.AssertNotCalled(t, "CancelRedemption", mock.Anything, mock.Anything)
+	transactor.promotionRepository.AssertNotCalled(t, "CancelRedemption", mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
+	transactor.promotionRepository.AssertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+	transactor.merchantRepository.AssertExpectations(t)
+}
+
+func TestCustomerService_Payment_RewardAccrualFailureKeepsPayment(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	request := dto.PaymentRequest{MerchantID: "merchant123", Amount: 100.0}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 1000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	pendingPayment := fakePendingPayment(request, expectedCustomer.ID)
+	succeededPayment := pendingPayment
+	_ = succeededPayment.TransitionTo(model.PaymentStatusSucceeded, "", time.Now().UTC())
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, 100.0).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, 100.0).Return(model.Fee{}, nil)
+	mocks.paymentRepository.On("CreatePayment", paymentWithStatus(model.PaymentStatusPending)).Return(pendingPayment, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 900.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 600.0, int64(0)).Return(expectedMerchant, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(succeededPayment, nil)
+	mocks.loyaltyService.On("AccrueRewards", succeededPayment).Return([]model.Reward{}, errors.New("disk full"))
+
+	resp, err := customerService.Payment(request, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+// fakeEscrowPayment is a 1000 escrow payment to merchant123 with a 25 fee and a 50 discount.
+func fakeEscrowPayment() model.Payment {
+	payment := model.NewPayment("pay-001", "1", "merchant123", 1000.0, time.Now().UTC())
+	payment.ApplyFee(model.Fee{RuleID: "fee-grocery", Amount: 25.0})
+	payment.ApplyDiscount(model.Promotion{ID: "promo-001", Code: "HEMAT"}, 50.0)
+	releaseAt := payment.CreatedAt.Add(EscrowReleasePeriod)
+	payment.ReleaseAt = &releaseAt
+	_ = payment.TransitionTo(model.PaymentStatusEscrowed, "", time.Now().UTC())
+	return payment
+}
+
+func TestCustomerService_Payment_Escrow(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant123", Amount: 1000.0, Escrow: true}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 5000.0}
+	expectedMerchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+	fee := model.Fee{RuleID: "fee-grocery", Amount: 25.0}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.limitService.On("ReserveLimit", expectedCustomer, fakePayment.Amount).Return(nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(fee, nil)
+	mocks.paymentRepository.On("CreatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.ReleaseAt != nil && payment.ReleaseAt.Equal(payment.CreatedAt.Add(EscrowReleasePeriod))
+	})).Return(func() model.Payment {
+		payment := fakePendingPayment(fakePayment, expectedCustomer.ID)
+		payment.ApplyFee(fee)
+		return payment
+	}(), nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 4000.0, int64(0)).Return(expectedCustomer, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformEscrowAccountID, 1000.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusEscrowed)).Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusEscrowed}, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusEscrowed, resp.Status)
+	mocks.merchantRepository.AssertNotCalled(t, "UpdateMerchantBalance", mock.Anything, mock.Anything, mock.Anything)
+	mocks.loyaltyService.AssertNotCalled(t, "AccrueRewards", mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_Payment_EscrowForeignCurrency(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	fakePayment := dto.PaymentRequest{MerchantID: "merchant-003", Amount: 100000.0, Escrow: true}
+	expectedCustomer := model.Customer{ID: "1", Username: "testuser", Balance: 500000.0}
+	expectedMerchant := model.Merchant{ID: "merchant-003", Currency: "USD"}
+	conversion := model.FXConversion{From: "IDR", To: "USD", ConvertedAmount: 6.19}
+
+	mocks.customerRepository.On("GetUserByUsername", "testuser").Return(expectedCustomer, nil)
+	mocks.merchantRepository.On("GetMerchantByID", fakePayment.MerchantID).Return(expectedMerchant, nil)
+	mocks.feeService.On("CalculateFee", expectedMerchant, fakePayment.Amount).Return(model.Fee{}, nil)
+	mocks.fxService.On("Convert", 100000.0, "IDR", "USD").Return(conversion, nil)
+
+	resp, err := customerService.Payment(fakePayment, "testuser")
+
+	assert.ErrorIs(t, err, ErrInvalidEscrow)
+	assert.Empty(t, resp.ID)
+	mocks.paymentRepository.AssertNotCalled(t, "CreatePayment", mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_ReleaseEscrow_Success(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	merchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(fakeEscrowPayment(), nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(merchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformEscrowAccountID, -950.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1475.0, int64(0)).Return(merchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformPromotionAccountID, 50.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 25.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", mock.MatchedBy(func(payment model.Payment) bool {
+		last := payment.Transitions[len(payment.Transitions)-1]
+		return payment.Status == model.PaymentStatusSucceeded && last.Reason == "delivery confirmed"
+	})).Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusSucceeded}, nil)
+	mocks.loyaltyService.On("AccrueRewards", paymentWithStatus(model.PaymentStatusSucceeded)).Return([]model.Reward{}, nil)
+
+	resp, err := customerService.ReleaseEscrow("pay-001", "delivery confirmed")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_ReleaseEscrow_UpdateFailedRollsBack(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	payment := fakeEscrowPayment()
+	payment.Discount, payment.PromotionID = 0, ""
+	merchant := model.Merchant{ID: "merchant123", Balance: 500.0}
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(payment, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant123").Return(merchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformEscrowAccountID, -1000.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 1475.0, int64(0)).Return(model.Merchant{ID: "merchant123", Balance: 1475.0, Version: 1}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 25.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{}, errors.New("disk full"))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, -25.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant123", 500.0, int64(1)).Return(merchant, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformEscrowAccountID, 1000.0).Return(model.Account{}, nil)
+
+	resp, err := customerService.ReleaseEscrow("pay-001", "delivery confirmed")
+
+	assert.EqualError(t, err, "failed to update payment: disk full")
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_ReleaseEscrow_NotEscrowed(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(fakeSucceededPayment(), nil)
+
+	resp, err := customerService.ReleaseEscrow("pay-001", "delivery confirmed")
+
+	assert.EqualError(t, err, "invalid escrow payment: payment is succeeded, not held in escrow")
+	assert.Empty(t, resp)
+	mocks.assertExpectations(t)
+}
+
+func TestCustomerService_CancelEscrow_Success(t *testing.T) {
+	customerService, mocks := setupCustomerService()
+
+	customer := model.Customer{ID: "1", Username: "testuser", Balance: 4050.0}
+
+	mocks.paymentRepository.On("GetPaymentByID", "pay-001").Return(fakeEscrowPayment(), nil)
+	mocks.customerRepository.On("GetUserByID", "1").Return(customer, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformEscrowAccountID, -950.0).Return(model.Account{}, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "1", 5000.0, int64(0)).Return(customer, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusCancelled)).Return(model.Payment{ID: "pay-001", PromotionID: "promo-001", Status: model.PaymentStatusCancelled}, nil)
+	mocks.promotionRepository.On("CancelRedemption", "promo-001", "pay-001").Return(model.Promotion{}, nil)
+
+	resp, err := customerService.CancelEscrow("pay-001", "out of stock")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusCancelled, resp.Status)
+	mocks.merchantRepository.AssertNotCalled(t, "UpdateMerchantBalance", mock.Anything, mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
 }
@@ -0,0 +1,49 @@
+package service
+
+import "fmt"
+
+// settlement tracks the balance updates made while moving a payment's money so
+// they can be undone in reverse order when a later update fails.
+type settlement struct {
+	rollbacks []rollbackStep
+	committed bool
+}
+
+type rollbackStep struct {
+	undo          func() error
+	transactional bool
+}
+
+// onRollback registers fn to undo the update that just succeeded.
+func (st *settlement) onRollback(fn func() error) {
+	st.rollbacks = append(st.rollbacks, rollbackStep{undo: fn})
+}
+
+// onCommittedRollback registers fn to undo an update made inside a storage
+// transaction. Until the transaction commits, the update rolls back with it,
+// so fn only runs once commit has been called.
+func (st *settlement) onCommittedRollback(fn func() error) {
+	st.rollbacks = append(st.rollbacks, rollbackStep{undo: fn, transactional: true})
+}
+
+// commit records that the storage transaction the updates were made in has
+// committed, so undoing them now takes their own rollbacks.
+func (st *settlement) commit() {
+	st.committed = true
+}
+
+// rollback undoes every registered update and returns cause, annotated with
+// any rollback that failed as well.
+func (st *settlement) rollback(cause error) error {
+	for i := len(st.rollbacks) - 1; i >= 0; i-- {
+		step := st.rollbacks[i]
+		if step.transactional && !st.committed {
+			continue
+		}
+		if err := step.undo(); err != nil {
+			cause = fmt.Errorf("%w (rollback failed: %v)", cause, err)
+		}
+	}
+	st.rollbacks = nil
+	return cause
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+
+	customerRepo "simple-golang-tdd/repository/customer"
+	disputeRepo "simple-golang-tdd/repository/dispute"
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDisputeNotFound = errors.New("dispute not found")
+	ErrInvalidDispute  = errors.New("invalid dispute")
+)
+
+// MerchantResponseWindow is how long the merchant has to respond to a new
+// dispute before it goes to review without a response.
+const MerchantResponseWindow = 7 * 24 * time.Hour
+
+// MaxDisputeEvidence caps the evidence files attached to one dispute.
+const MaxDisputeEvidence = 20
+
+const (
+	DisputeOutcomeRefund = "refund"
+	DisputeOutcomeUphold = "uphold"
+)
+
+type DisputeService interface {
+	OpenDispute(request dto.DisputeRequest, username string) (model.Dispute, error)
+	GetDisputes(username string) ([]model.Dispute, error)
+	GetDispute(id string, username string) (model.Dispute, error)
+	AddEvidence(id string, request dto.EvidenceListRequest, username string) (model.Dispute, error)
+	GetMerchantDisputes(merchantID string) ([]model.Dispute, error)
+	GetMerchantDispute(id string, merchantID string) (model.Dispute, error)
+	RespondToDispute(id string, request dto.DisputeResponseRequest, merchantID string) (model.Dispute, error)
+	ResolveDispute(id string, request dto.DisputeResolutionRequest) (model.Dispute, error)
+	EscalateOverdueDisputes() error
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type disputeServiceImpl struct {
+	disputeRepository  disputeRepo.DisputeRepository
+	customerRepository customerRepo.CustomerRepository
+	customerService    customerService.CustomerService
+	now                func() time.Time
+	mutex              sync.Mutex // serialises status changes so a dispute is resolved at most once
+}
+
+func NewDisputeService(disputeRepository disputeRepo.DisputeRepository, customerRepository customerRepo.CustomerRepository, customerService customerService.CustomerService, now func() time.Time) DisputeService {
+	return &disputeServiceImpl{
+		disputeRepository:  disputeRepository,
+		customerRepository: customerRepository,
+		customerService:    customerService,
+		now:                now,
+	}
+}
+
+// OpenDispute disputes one of the customer's succeeded payments for the full
+// amount charged. A payment can only be disputed once. The merchant then has MerchantResponseWindow to respond.
+func (s *disputeServiceImpl) OpenDispute(request dto.DisputeRequest, username string) (model.Dispute, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, err := s.customerService.GetPayment(request.PaymentID, username)
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	if payment.Status != model.PaymentStatusSucceeded {
+		return model.Dispute{}, fmt.Errorf("%w: only succeeded payments can be disputed", ErrInvalidDispute)
+	}
+	if len(payment.Legs) > 0 {
+		return model.Dispute{}, fmt.Errorf("%w: split payments cannot be disputed", ErrInvalidDispute)
+	}
+	// A refund would credit the customer for a purchase the credit line paid.
+	if payment.PlanID != "" {
+		return model.Dispute{}, fmt.Errorf("%w: pay-later payments cannot be disputed", ErrInvalidDispute)
+	}
+
+	disputes, err := s.disputeRepository.GetDisputesByCustomerID(payment.CustomerID)
+	if err != nil {
+		return model.Dispute{}, fmt.Errorf("failed to get disputes: %w", err)
+	}
+	for _, dispute := range disputes {
+		if dispute.PaymentID == payment.ID {
+			return model.Dispute{}, fmt.Errorf("%w: payment has already been disputed", ErrInvalidDispute)
+		}
+	}
+
+	now := s.now().UTC()
+	dispute := model.Dispute{
+		ID:          uuid.New().String(),
+		PaymentID:   payment.ID,
+		CustomerID:  payment.CustomerID,
+		MerchantID:  payment.MerchantID,
+		Amount:      payment.ChargedAmount(),
+		Currency:    payment.Currency,
+		Reason:      model.DisputeReason(request.Reason),
+		Description: request.Description,
+		Evidence:    evidenceFrom(request.Evidence, model.DisputePartyCustomer, now),
+		RespondBy:   now.Add(MerchantResponseWindow),
+		Status:      model.DisputeStatusOpen,
+		Transitions: []model.DisputeTransition{{Status: model.DisputeStatusOpen, Timestamp: now}},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	dispute, err = s.disputeRepository.CreateDispute(dispute)
+	if err != nil {
+		return model.Dispute{}, fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	return dispute, nil
+}
+
+func (s *disputeServiceImpl) GetDisputes(username string) ([]model.Dispute, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	disputes, err := s.disputeRepository.GetDisputesByCustomerID(customer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disputes: %w", err)
+	}
+
+	return disputes, nil
+}
+
+// GetDispute returns one of the customer's own disputes.
+func (s *disputeServiceImpl) GetDispute(id string, username string) (model.Dispute, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Dispute{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	dispute, err := s.disputeRepository.GetDisputeByID(id)
+	if err != nil || dispute.CustomerID != customer.ID {
+		return model.Dispute{}, ErrDisputeNotFound
+	}
+
+	return dispute, nil
+}
+
+// AddEvidence attaches more of the customer's evidence to a dispute that has
+// not been resolved yet.
+func (s *disputeServiceImpl) AddEvidence(id string, request dto.EvidenceListRequest, username string) (model.Dispute, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dispute, err := s.GetDispute(id, username)
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	if dispute.Status.IsResolved() {
+		return model.Dispute{}, fmt.Errorf("%w: dispute is already resolved", ErrInvalidDispute)
+	}
+
+	now := s.now().UTC()
+	if err := attachEvidence(&dispute, request.Evidence, model.DisputePartyCustomer, now); err != nil {
+		return model.Dispute{}, err
+	}
+	dispute.UpdatedAt = now
+
+	return s.updateDispute(dispute)
+}
+
+func (s *disputeServiceImpl) GetMerchantDisputes(merchantID string) ([]model.Dispute, error) {
+	disputes, err := s.disputeRepository.GetDisputesByMerchantID(merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disputes: %w", err)
+	}
+
+	return disputes, nil
+}
+
+// GetMerchantDispute returns a dispute against one of the merchant's payments.
+func (s *disputeServiceImpl) GetMerchantDispute(id string, merchantID string) (model.Dispute, error) {
+	dispute, err := s.disputeRepository.GetDisputeByID(id)
+	if err != nil || dispute.MerchantID != merchantID {
+		return model.Dispute{}, ErrDisputeNotFound
+	}
+
+	return dispute, nil
+}
+
+// RespondToDispute records the merchant's response and evidence and sends the
+// dispute to review. The merchant can respond once, before RespondBy.
+func (s *disputeServiceImpl) RespondToDispute(id string, request dto.DisputeResponseRequest, merchantID string) (model.Dispute, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dispute, err := s.GetMerchantDispute(id, merchantID)
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	now := s.now().UTC()
+	if dispute.Status == model.DisputeStatusOpen && !now.Before(dispute.RespondBy) {
+		return model.Dispute{}, fmt.Errorf("%w: the response deadline has passed", ErrInvalidDispute)
+	}
+
+	if err := dispute.TransitionTo(model.DisputeStatusUnderReview, "merchant responded", now); err != nil {
+		return model.Dispute{}, err
+	}
+	dispute.MerchantResponse = request.Response
+	if err := attachEvidence(&dispute, request.Evidence, model.DisputePartyMerchant, now); err != nil {
+		return model.Dispute{}, err
+	}
+
+	return s.updateDispute(dispute)
+}
+
+// ResolveDispute settles a dispute under review. A refund gives the payment
+// back to the customer through the customer service; upholding it leaves the
+// payment as it is. No money moves before this point. The resolution is saved
+// before the refund, and the dispute goes back to review when the refund
+// fails, so it can be resolved again.
+func (s *disputeServiceImpl) ResolveDispute(id string, request dto.DisputeResolutionRequest) (model.Dispute, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dispute, err := s.disputeRepository.GetDisputeByID(id)
+	if err != nil {
+		return model.Dispute{}, ErrDisputeNotFound
+	}
+
+	var next model.DisputeStatus
+	switch request.Outcome {
+	case DisputeOutcomeRefund:
+		next = model.DisputeStatusRefunded
+	case DisputeOutcomeUphold:
+		next = model.DisputeStatusUpheld
+	default:
+		return model.Dispute{}, fmt.Errorf("%w: unknown outcome %q", ErrInvalidDispute, request.Outcome)
+	}
+
+	underReview := dispute
+	if err := dispute.TransitionTo(next, request.Note, s.now().UTC()); err != nil {
+		return model.Dispute{}, err
+	}
+	dispute.Resolution = request.Note
+
+	resolved, err := s.updateDispute(dispute)
+	if err != nil {
+		return model.Dispute{}, err
+	}
+
+	if next == model.DisputeStatusRefunded {
+		if err := s.refundPayment(dispute, request.Note); err != nil {
+			err = fmt.Errorf("failed to refund payment: %w", err)
+			if _, updateErr := s.disputeRepository.UpdateDispute(underReview); updateErr != nil {
+				return model.Dispute{}, fmt.Errorf("%w (dispute %s stays refunded: %v)", err, dispute.ID, updateErr)
+			}
+			return model.Dispute{}, err
+		}
+	}
+
+	return resolved, nil
+}
+
+// refundPayment refunds the disputed payment. A payment that is already
+// refunded, by an earlier attempt at resolving the dispute, is not refunded
+// again.
+func (s *disputeServiceImpl) refundPayment(dispute model.Dispute, note string) error {
+	_, err := s.customerService.RefundPayment(dispute.PaymentID, fmt.Sprintf("dispute %s: %s", dispute.ID, note))
+	if !errors.Is(err, model.ErrInvalidPaymentTransition) {
+		return err
+	}
+
+	customer, getErr := s.customerRepository.GetUserByID(dispute.CustomerID)
+	if getErr != nil {
+		return err
+	}
+	payment, getErr := s.customerService.GetPayment(dispute.PaymentID, customer.Username)
+	if getErr != nil || payment.Status != model.PaymentStatusRefunded {
+		return err
+	}
+	return nil
+}
+
+// EscalateOverdueDisputes sends every open dispute whose merchant did not
+// respond in time to review.
+func (s *disputeServiceImpl) EscalateOverdueDisputes() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now().UTC()
+
+	disputes, err := s.disputeRepository.GetOverdueDisputes(now)
+	if err != nil {
+		return fmt.Errorf("failed to get overdue disputes: %w", err)
+	}
+
+	var errs []error
+	for _, dispute := range disputes {
+		if err := dispute.TransitionTo(model.DisputeStatusUnderReview, "merchant did not respond", now); err != nil {
+			errs = append(errs, fmt.Errorf("dispute %s: %w", dispute.ID, err))
+			continue
+		}
+		end := utils.BeginWrite()
+		if _, err := s.disputeRepository.UpdateDispute(dispute); err != nil {
+			errs = append(errs, fmt.Errorf("dispute %s: failed to update dispute: %w", dispute.ID, err))
+		}
+		end()
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *disputeServiceImpl) updateDispute(dispute model.Dispute) (model.Dispute, error) {
+	updated, err := s.disputeRepository.UpdateDispute(dispute)
+	if err != nil {
+		return model.Dispute{}, fmt.Errorf("failed to update dispute: %w", err)
+	}
+	return updated, nil
+}
+
+// attachEvidence adds the party's evidence to the dispute, keeping it within
+// MaxDisputeEvidence files.
+func attachEvidence(dispute *model.Dispute, requests []dto.EvidenceRequest, party model.DisputeParty, at time.Time) error {
+	if len(dispute.Evidence)+len(requests) > MaxDisputeEvidence {
+		return fmt.Errorf("%w: a dispute can have at most %d evidence files", ErrInvalidDispute, MaxDisputeEvidence)
+	}
+	dispute.Evidence = append(dispute.Evidence, evidenceFrom(requests, party, at)...)
+	return nil
+}
+
+func evidenceFrom(requests []dto.EvidenceRequest, party model.DisputeParty, at time.Time) []model.DisputeEvidence {
+	evidence := make([]model.DisputeEvidence, 0, len(requests))
+	for _, request := range requests {
+		evidence = append(evidence, model.DisputeEvidence{
+			Party:       party,
+			FileName:    request.FileName,
+			ContentType: request.ContentType,
+			Size:        request.Size,
+			URL:         request.URL,
+			UploadedAt:  at,
+		})
+	}
+	return evidence
+}
+
+// Start sends overdue disputes to review every interval until ctx is cancelled.
+func (s *disputeServiceImpl) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EscalateOverdueDisputes(); err != nil {
+				log.Printf("Error escalating disputes: %v", err)
+			}
+		}
+	}
+}
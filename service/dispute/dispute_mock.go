@@ -0,0 +1,118 @@
+package service
+
+import (
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDisputeRepository struct {
+	mock.Mock
+}
+
+func (m *MockDisputeRepository) CreateDispute(dispute model.Dispute) (model.Dispute, error) {
+	args := m.Called(dispute)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) GetDisputeByID(id string) (model.Dispute, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) GetDisputesByCustomerID(customerID string) ([]model.Dispute, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) GetDisputesByMerchantID(merchantID string) ([]model.Dispute, error) {
+	args := m.Called(merchantID)
+	return args.Get(0).([]model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) GetOverdueDisputes(at time.Time) ([]model.Dispute, error) {
+	args := m.Called(at)
+	return args.Get(0).([]model.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) UpdateDispute(dispute model.Dispute) (model.Dispute, error) {
+	args := m.Called(dispute)
+	return args.Get(0).(model.Dispute), args.Error(1)
+}
+
+// MockCustomerRepository is a mock of the CustomerRepository interface
+type MockCustomerRepository struct {
+	mock.Mock
+}
+
+func (m *MockCustomerRepository) GetUserByUsername(username string) (model.Customer, error) {
+	args := m.Called(username)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) GetUserByID(id string) (model.Customer, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) GetUserBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, points, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+type MockCustomerService struct {
+	mock.Mock
+}
+
+func (m *MockCustomerService) Payment(request dto.PaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) SplitPayment(request dto.SplitPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// QRPayment mocks the QRPayment method of CustomerService
+func (m *MockCustomerService) QRPayment(request dto.QRPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) GetPayment(id string, username string) (model.Payment, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) RefundPayment(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) ReleaseEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) CancelEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
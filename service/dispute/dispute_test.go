@@ -0,0 +1,333 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+type disputeServiceMocks struct {
+	disputeRepository  *MockDisputeRepository
+	customerRepository *MockCustomerRepository
+	customerService    *MockCustomerService
+}
+
+func (m disputeServiceMocks) assertExpectations(t *testing.T) {
+	m.disputeRepository.AssertExpectations(t)
+	m.customerRepository.AssertExpectations(t)
+	m.customerService.AssertExpectations(t)
+}
+
+func setupDisputeService() (DisputeService, disputeServiceMocks) {
+	mocks := disputeServiceMocks{
+		disputeRepository:  new(MockDisputeRepository),
+		customerRepository: new(MockCustomerRepository),
+		customerService:    new(MockCustomerService),
+	}
+	service := NewDisputeService(mocks.disputeRepository, mocks.customerRepository, mocks.customerService, func() time.Time { return fakeNow })
+	return service, mocks
+}
+
+func fakePayment() model.Payment {
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 150.0, fakeNow.Add(-24*time.Hour))
+	payment.ApplyDiscount(model.Promotion{ID: "promo-001"}, 15.0)
+	_ = payment.TransitionTo(model.PaymentStatusSucceeded, "", fakeNow.Add(-24*time.Hour))
+	return payment
+}
+
+func fakeDispute(status model.DisputeStatus) model.Dispute {
+	return model.Dispute{
+		ID:          "dsp-001",
+		PaymentID:   "pay-001",
+		CustomerID:  "cust-001",
+		MerchantID:  "merchant-001",
+		Amount:      135.0,
+		Currency:    model.DefaultCurrency,
+		Reason:      model.DisputeReasonNotReceived,
+		Description: "Order never arrived",
+		Evidence:    []model.DisputeEvidence{},
+		RespondBy:   fakeNow.Add(time.Hour),
+		Status:      status,
+		Transitions: []model.DisputeTransition{{Status: status, Timestamp: fakeNow.Add(-time.Hour)}},
+	}
+}
+
+func fakeEvidence(fileName string) dto.EvidenceRequest {
+	return dto.EvidenceRequest{FileName: fileName, ContentType: "image/png", Size: 2048, URL: "https://files.example.com/" + fileName}
+}
+
+// disputeWithStatus matches a dispute argument by its current status.
+func disputeWithStatus(status model.DisputeStatus) interface{} {
+	return mock.MatchedBy(func(dispute model.Dispute) bool {
+		return dispute.Status == status
+	})
+}
+
+func TestOpenDispute_Success(t *testing.T) {
+	service, mocks := setupDisputeService()
+	request := dto.DisputeRequest{PaymentID: "pay-001", Reason: "not_received", Description: "Order never arrived", Evidence: []dto.EvidenceRequest{fakeEvidence("receipt.png")}}
+
+	mocks.customerService.On("GetPayment", "pay-001", "user").Return(fakePayment(), nil)
+	mocks.disputeRepository.On("GetDisputesByCustomerID", "cust-001").Return([]model.Dispute{}, nil)
+	mocks.disputeRepository.On("CreateDispute", mock.AnythingOfType("model.Dispute")).Return(model.Dispute{ID: "dsp-001"}, nil)
+
+	_, err := service.OpenDispute(request, "user")
+
+	require.NoError(t, err)
+	created := mocks.disputeRepository.Calls[1].Arguments.Get(0).(model.Dispute)
+	assert.Equal(t, "cust-001", created.CustomerID)
+	assert.Equal(t, "merchant-001", created.MerchantID)
+	assert.Equal(t, 135.0, created.Amount)
+	assert.Equal(t, model.DisputeStatusOpen, created.Status)
+	assert.Equal(t, fakeNow.Add(MerchantResponseWindow), created.RespondBy)
+	require.Len(t, created.Evidence, 1)
+	assert.Equal(t, model.DisputePartyCustomer, created.Evidence[0].Party)
+	mocks.assertExpectations(t)
+}
+
+func TestOpenDispute_PaymentNotDisputable(t *testing.T) {
+	pending := model.NewPayment("pay-001", "cust-001", "merchant-001", 150.0, fakeNow)
+	split := fakePayment()
+	split.ApplyLegs([]model.PaymentLeg{{MerchantID: "merchant-001", Amount: 100.0}, {MerchantID: "merchant-002", Amount: 50.0}})
+	payLater := fakePayment()
+	payLater.PlanID = "plan-001"
+
+	for name, payment := range map[string]model.Payment{"pending": pending, "split": split, "pay later": payLater} {
+		t.Run(name, func(t *testing.T) {
+			service, mocks := setupDisputeService()
+			mocks.customerService.On("GetPayment", "pay-001", "user").Return(payment, nil)
+
+			_, err := service.OpenDispute(dto.DisputeRequest{PaymentID: "pay-001", Reason: "other", Description: "Wrong"}, "user")
+
+			assert.ErrorIs(t, err, ErrInvalidDispute)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func TestOpenDispute_AlreadyDisputed(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.customerService.On("GetPayment", "pay-001", "user").Return(fakePayment(), nil)
+	mocks.disputeRepository.On("GetDisputesByCustomerID", "cust-001").Return([]model.Dispute{fakeDispute(model.DisputeStatusUpheld)}, nil)
+
+	_, err := service.OpenDispute(dto.DisputeRequest{PaymentID: "pay-001", Reason: "other", Description: "Again"}, "user")
+
+	assert.EqualError(t, err, "invalid dispute: payment has already been disputed")
+	mocks.assertExpectations(t)
+}
+
+func TestOpenDispute_PaymentNotFound(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.customerService.On("GetPayment", "pay-404", "user").Return(model.Payment{}, customerService.ErrPaymentNotFound)
+
+	_, err := service.OpenDispute(dto.DisputeRequest{PaymentID: "pay-404", Reason: "other", Description: "Wrong"}, "user")
+
+	assert.ErrorIs(t, err, customerService.ErrPaymentNotFound)
+	mocks.assertExpectations(t)
+}
+
+func TestGetDispute_OtherCustomer(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(model.Customer{ID: "cust-002"}, nil)
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusOpen), nil)
+
+	_, err := service.GetDispute("dsp-001", "user")
+
+	assert.ErrorIs(t, err, ErrDisputeNotFound)
+	mocks.assertExpectations(t)
+}
+
+func TestAddEvidence_Success(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(model.Customer{ID: "cust-001"}, nil)
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", mock.MatchedBy(func(dispute model.Dispute) bool {
+		return len(dispute.Evidence) == 1 && dispute.Evidence[0].FileName == "chat.png"
+	})).Return(model.Dispute{ID: "dsp-001"}, nil)
+
+	_, err := service.AddEvidence("dsp-001", dto.EvidenceListRequest{Evidence: []dto.EvidenceRequest{fakeEvidence("chat.png")}}, "user")
+
+	assert.NoError(t, err)
+	mocks.assertExpectations(t)
+}
+
+func TestAddEvidence_Resolved(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(model.Customer{ID: "cust-001"}, nil)
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUpheld), nil)
+
+	_, err := service.AddEvidence("dsp-001", dto.EvidenceListRequest{Evidence: []dto.EvidenceRequest{fakeEvidence("chat.png")}}, "user")
+
+	assert.ErrorIs(t, err, ErrInvalidDispute)
+	mocks.assertExpectations(t)
+}
+
+func TestRespondToDispute_Success(t *testing.T) {
+	service, mocks := setupDisputeService()
+	request := dto.DisputeResponseRequest{Response: "Delivered on 25 April", Evidence: []dto.EvidenceRequest{fakeEvidence("proof.pdf")}}
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusOpen), nil)
+	mocks.disputeRepository.On("UpdateDispute", mock.MatchedBy(func(dispute model.Dispute) bool {
+		return dispute.Status == model.DisputeStatusUnderReview &&
+			dispute.MerchantResponse == "Delivered on 25 April" &&
+			dispute.Evidence[0].Party == model.DisputePartyMerchant
+	})).Return(model.Dispute{ID: "dsp-001", Status: model.DisputeStatusUnderReview}, nil)
+
+	resp, err := service.RespondToDispute("dsp-001", request, "merchant-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusUnderReview, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+func TestRespondToDispute_Rejected(t *testing.T) {
+	overdue := fakeDispute(model.DisputeStatusOpen)
+	overdue.RespondBy = fakeNow
+
+	tests := []struct {
+		name       string
+		dispute    model.Dispute
+		merchantID string
+		wantErr    error
+	}{
+		{"other merchant", fakeDispute(model.DisputeStatusOpen), "merchant-002", ErrDisputeNotFound},
+		{"deadline passed", overdue, "merchant-001", ErrInvalidDispute},
+		{"already responded", fakeDispute(model.DisputeStatusUnderReview), "merchant-001", model.ErrInvalidDisputeTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mocks := setupDisputeService()
+			mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(tt.dispute, nil)
+
+			_, err := service.RespondToDispute("dsp-001", dto.DisputeResponseRequest{Response: "Delivered"}, tt.merchantID)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func TestResolveDispute_Refund(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", disputeWithStatus(model.DisputeStatusRefunded)).Return(model.Dispute{ID: "dsp-001", Status: model.DisputeStatusRefunded}, nil)
+	mocks.customerService.On("RefundPayment", "pay-001", "dispute dsp-001: Courier confirmed loss").Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusRefunded}, nil).Run(func(args mock.Arguments) {
+		mocks.disputeRepository.AssertCalled(t, "UpdateDispute", disputeWithStatus(model.DisputeStatusRefunded))
+	})
+
+	resp, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "refund", Note: "Courier confirmed loss"})
+
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusRefunded, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+func TestResolveDispute_PaymentAlreadyRefunded(t *testing.T) {
+	service, mocks := setupDisputeService()
+	refunded := fakePayment()
+	_ = refunded.TransitionTo(model.PaymentStatusRefunded, "dispute dsp-001: Courier confirmed loss", fakeNow)
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", disputeWithStatus(model.DisputeStatusRefunded)).Return(model.Dispute{ID: "dsp-001", Status: model.DisputeStatusRefunded}, nil)
+	mocks.customerService.On("RefundPayment", "pay-001", mock.Anything).Return(model.Payment{}, fmt.Errorf("%w: refunded to refunded", model.ErrInvalidPaymentTransition))
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(model.Customer{ID: "cust-001", Username: "user"}, nil)
+	mocks.customerService.On("GetPayment", "pay-001", "user").Return(refunded, nil)
+
+	resp, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "refund", Note: "Courier confirmed loss"})
+
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusRefunded, resp.Status)
+	mocks.assertExpectations(t)
+}
+
+func TestResolveDispute_Uphold(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", mock.MatchedBy(func(dispute model.Dispute) bool {
+		return dispute.Status == model.DisputeStatusUpheld && dispute.Resolution == "Proof of delivery provided"
+	})).Return(model.Dispute{ID: "dsp-001", Status: model.DisputeStatusUpheld}, nil)
+
+	resp, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "uphold", Note: "Proof of delivery provided"})
+
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusUpheld, resp.Status)
+	mocks.customerService.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestResolveDispute_NotUnderReview(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusOpen), nil)
+
+	_, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "refund", Note: "Too early"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidDisputeTransition)
+	mocks.assertExpectations(t)
+}
+
+func TestResolveDispute_RefundFailed(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", disputeWithStatus(model.DisputeStatusRefunded)).Return(model.Dispute{ID: "dsp-001", Status: model.DisputeStatusRefunded}, nil)
+	mocks.customerService.On("RefundPayment", "pay-001", mock.Anything).Return(model.Payment{}, errors.New("disk full"))
+	mocks.disputeRepository.On("UpdateDispute", disputeWithStatus(model.DisputeStatusUnderReview)).Return(model.Dispute{}, nil)
+
+	_, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "refund", Note: "Courier confirmed loss"})
+
+	assert.EqualError(t, err, "failed to refund payment: disk full")
+	mocks.assertExpectations(t)
+}
+
+func TestResolveDispute_UpdateFailed(t *testing.T) {
+	service, mocks := setupDisputeService()
+
+	mocks.disputeRepository.On("GetDisputeByID", "dsp-001").Return(fakeDispute(model.DisputeStatusUnderReview), nil)
+	mocks.disputeRepository.On("UpdateDispute", disputeWithStatus(model.DisputeStatusRefunded)).Return(model.Dispute{}, errors.New("disk full"))
+
+	_, err := service.ResolveDispute("dsp-001", dto.DisputeResolutionRequest{Outcome: "refund", Note: "Courier confirmed loss"})
+
+	assert.EqualError(t, err, "failed to update dispute: disk full")
+	mocks.customerService.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestEscalateOverdueDisputes(t *testing.T) {
+	service, mocks := setupDisputeService()
+	first, second := fakeDispute(model.DisputeStatusOpen), fakeDispute(model.DisputeStatusOpen)
+	second.ID = "dsp-002"
+
+	mocks.disputeRepository.On("GetOverdueDisputes", fakeNow).Return([]model.Dispute{first, second}, nil)
+	mocks.disputeRepository.On("UpdateDispute", mock.MatchedBy(func(dispute model.Dispute) bool {
+		last := dispute.Transitions[len(dispute.Transitions)-1]
+		return dispute.ID == "dsp-001" && dispute.Status == model.DisputeStatusUnderReview && last.Reason == "merchant did not respond"
+	})).Return(model.Dispute{}, nil)
+	mocks.disputeRepository.On("UpdateDispute", mock.MatchedBy(func(dispute model.Dispute) bool {
+		return dispute.ID == "dsp-002"
+	})).Return(model.Dispute{}, errors.New("disk full"))
+
+	err := service.EscalateOverdueDisputes()
+
+	assert.EqualError(t, err, "dispute dsp-002: failed to update dispute: disk full")
+	mocks.assertExpectations(t)
+}
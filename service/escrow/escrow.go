@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+
+	paymentRepo "simple-golang-tdd/repository/payment"
+	customerService "simple-golang-tdd/service/customer"
+)
+
+type EscrowService interface {
+	ConfirmDelivery(paymentID string, username string) (model.Payment, error)
+	CancelPayment(paymentID string, merchantID string) (model.Payment, error)
+	ReleaseDuePayments() error
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type escrowServiceImpl struct {
+	paymentRepository paymentRepo.PaymentRepository
+	customerService   customerService.CustomerService
+	now               func() time.Time
+	mutex             sync.Mutex // serialises releases and cancellations so held funds move only once
+}
+
+func NewEscrowService(paymentRepository paymentRepo.PaymentRepository, customerService customerService.CustomerService, now func() time.Time) EscrowService {
+	return &escrowServiceImpl{
+		paymentRepository: paymentRepository,
+		customerService:   customerService,
+		now:               now,
+	}
+}
+
+// ConfirmDelivery releases one of the customer's escrow payments to the
+// merchant once the customer has received the order.
+func (s *escrowServiceImpl) ConfirmDelivery(paymentID string, username string) (model.Payment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.customerService.GetPayment(paymentID, username); err != nil {
+		return model.Payment{}, err
+	}
+
+	return s.customerService.ReleaseEscrow(paymentID, "delivery confirmed by customer")
+}
+
+// CancelPayment returns an escrow payment to the merchant's customer, for
+// orders the merchant cannot fulfil.
+func (s *escrowServiceImpl) CancelPayment(paymentID string, merchantID string) (model.Payment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, err := s.paymentRepository.GetPaymentByID(paymentID)
+	if err != nil || payment.MerchantID != merchantID {
+		return model.Payment{}, customerService.ErrPaymentNotFound
+	}
+
+	return s.customerService.CancelEscrow(paymentID, "cancelled by merchant")
+}
+
+// ReleaseDuePayments releases every escrow payment whose release time has
+// passed without the customer confirming delivery.
+func (s *escrowServiceImpl) ReleaseDuePayments() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payments, err := s.paymentRepository.GetDueEscrowPayments(s.now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to get due escrow payments: %w", err)
+	}
+
+	var errs []error
+	for _, payment := range payments {
+		end := utils.BeginWrite()
+		if _, err := s.customerService.ReleaseEscrow(payment.ID, "released after the escrow period"); err != nil {
+			errs = append(errs, fmt.Errorf("payment %s: %w", payment.ID, err))
+		}
+		end()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Start releases due escrow payments every interval until ctx is cancelled.
+func (s *escrowServiceImpl) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReleaseDuePayments(); err != nil {
+				log.Printf("Error releasing escrow payments: %v", err)
+			}
+		}
+	}
+}
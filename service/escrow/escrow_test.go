@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC)
+
+func setupEscrowService() (EscrowService, *MockPaymentRepository, *MockCustomerService) {
+	paymentRepository := new(MockPaymentRepository)
+	customerSvc := new(MockCustomerService)
+	service := NewEscrowService(paymentRepository, customerSvc, func() time.Time { return fakeNow })
+	return service, paymentRepository, customerSvc
+}
+
+func fakeEscrowPayment(id string) model.Payment {
+	payment := model.NewPayment(id, "cust-001", "merchant-001", 150.0, fakeNow.AddDate(0, 0, -7))
+	releaseAt := fakeNow
+	payment.ReleaseAt = &releaseAt
+	payment.Status = model.PaymentStatusEscrowed
+	return payment
+}
+
+func TestConfirmDelivery_Success(t *testing.T) {
+	service, _, customerSvc := setupEscrowService()
+	released := fakeEscrowPayment("pay-001")
+	released.Status = model.PaymentStatusSucceeded
+
+	customerSvc.On("GetPayment", "pay-001", "user").Return(fakeEscrowPayment("pay-001"), nil)
+	customerSvc.On("ReleaseEscrow", "pay-001", "delivery confirmed by customer").Return(released, nil)
+
+	payment, err := service.ConfirmDelivery("pay-001", "user")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusSucceeded, payment.Status)
+	customerSvc.AssertExpectations(t)
+}
+
+func TestConfirmDelivery_OtherCustomer(t *testing.T) {
+	service, _, customerSvc := setupEscrowService()
+
+	customerSvc.On("GetPayment", "pay-001", "user").Return(model.Payment{}, customerService.ErrPaymentNotFound)
+
+	_, err := service.ConfirmDelivery("pay-001", "user")
+
+	assert.ErrorIs(t, err, customerService.ErrPaymentNotFound)
+	customerSvc.AssertNotCalled(t, "ReleaseEscrow", "pay-001", "delivery confirmed by customer")
+}
+
+func TestCancelPayment_Success(t *testing.T) {
+	service, paymentRepository, customerSvc := setupEscrowService()
+	cancelled := fakeEscrowPayment("pay-001")
+	cancelled.Status = model.PaymentStatusCancelled
+
+	paymentRepository.On("GetPaymentByID", "pay-001").Return(fakeEscrowPayment("pay-001"), nil)
+	customerSvc.On("CancelEscrow", "pay-001", "cancelled by merchant").Return(cancelled, nil)
+
+	payment, err := service.CancelPayment("pay-001", "merchant-001")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusCancelled, payment.Status)
+	customerSvc.AssertExpectations(t)
+}
+
+func TestCancelPayment_OtherMerchant(t *testing.T) {
+	service, paymentRepository, customerSvc := setupEscrowService()
+
+	paymentRepository.On("GetPaymentByID", "pay-001").Return(fakeEscrowPayment("pay-001"), nil)
+
+	_, err := service.CancelPayment("pay-001", "merchant-002")
+
+	assert.ErrorIs(t, err, customerService.ErrPaymentNotFound)
+	customerSvc.AssertNotCalled(t, "CancelEscrow", "pay-001", "cancelled by merchant")
+}
+
+func TestReleaseDuePayments(t *testing.T) {
+	service, paymentRepository, customerSvc := setupEscrowService()
+
+	paymentRepository.On("GetDueEscrowPayments", fakeNow).Return([]model.Payment{fakeEscrowPayment("pay-001"), fakeEscrowPayment("pay-002")}, nil)
+	customerSvc.On("ReleaseEscrow", "pay-001", "released after the escrow period").Return(model.Payment{}, nil)
+	customerSvc.On("ReleaseEscrow", "pay-002", "released after the escrow period").Return(model.Payment{}, errors.New("failed to get merchant: merchant not found by ID"))
+
+	err := service.ReleaseDuePayments()
+
+	assert.EqualError(t, err, "payment pay-002: failed to get merchant: merchant not found by ID")
+	paymentRepository.AssertExpectations(t)
+	customerSvc.AssertExpectations(t)
+}
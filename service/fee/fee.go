@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"simple-golang-tdd/model"
+
+	feeRepo "simple-golang-tdd/repository/fee"
+)
+
+type FeeService interface {
+	CalculateFee(merchant model.Merchant, amount float64) (model.Fee, error)
+}
+
+type feeServiceImpl struct {
+	feeRepository feeRepo.FeeRepository
+}
+
+func NewFeeService(feeRepository feeRepo.FeeRepository) FeeService {
+	return &feeServiceImpl{feeRepository: feeRepository}
+}
+
+// CalculateFee returns the fee the platform takes from a payment of amount to
+// merchant. The most specific rule wins: merchant, then category, then default.
+// Without any matching rule the payment is free. Fee rules are written in
+// model.DefaultCurrency, so amount and the fee are in it too.
+func (s *feeServiceImpl) CalculateFee(merchant model.Merchant, amount float64) (model.Fee, error) {
+	rules, err := s.feeRepository.GetFeeRules()
+	if err != nil {
+		return model.Fee{}, fmt.Errorf("failed to get fee rules: %w", err)
+	}
+
+	rule, found := findFeeRule(rules, merchant)
+	if !found {
+		return model.Fee{}, nil
+	}
+
+	var fee float64
+	switch rule.Type {
+	case model.FeeTypePercentage:
+		fee = amount * rule.Percentage / 100
+	case model.FeeTypeFixed:
+		fee = rule.Fixed
+	case model.FeeTypeTiered:
+		tier, found := findFeeTier(rule.Tiers, amount)
+		if !found {
+			return model.Fee{}, fmt.Errorf("fee rule %s has no tier for amount %.2f", rule.ID, amount)
+		}
+		fee = amount*tier.Percentage/100 + tier.Fixed
+	default:
+		return model.Fee{}, fmt.Errorf("fee rule %s has unknown type %q", rule.ID, rule.Type)
+	}
+
+	// The platform never takes more than the payment itself.
+	fee = math.Min(roundAmount(fee), amount)
+
+	return model.Fee{RuleID: rule.ID, Amount: fee}, nil
+}
+
+func findFeeRule(rules []model.FeeRule, merchant model.Merchant) (model.FeeRule, bool) {
+	var categoryRule, defaultRule *model.FeeRule
+	for i, rule := range rules {
+		switch {
+		case rule.MerchantID != "":
+			if rule.MerchantID == merchant.ID {
+				return rule, true
+			}
+		case rule.Category != "":
+			if rule.Category == merchant.Category && categoryRule == nil {
+				categoryRule = &rules[i]
+			}
+		default:
+			if defaultRule == nil {
+				defaultRule = &rules[i]
+			}
+		}
+	}
+
+	if categoryRule != nil {
+		return *categoryRule, true
+	}
+	if defaultRule != nil {
+		return *defaultRule, true
+	}
+	return model.FeeRule{}, false
+}
+
+func findFeeTier(tiers []model.FeeTier, amount float64) (model.FeeTier, bool) {
+	for _, tier := range tiers {
+		if tier.UpTo == 0 || amount <= tier.UpTo {
+			return tier, true
+		}
+	}
+	return model.FeeTier{}, false
+}
+
+// roundAmount rounds a money amount to the precision of model.DefaultCurrency.
+func roundAmount(amount float64) float64 {
+	currency, _ := model.GetCurrency(model.DefaultCurrency)
+	return currency.Round(amount)
+}
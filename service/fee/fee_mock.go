@@ -0,0 +1,17 @@
+package service
+
+import (
+	"simple-golang-tdd/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFeeRepository is a mock of the FeeRepository interface
+type MockFeeRepository struct {
+	mock.Mock
+}
+
+func (m *MockFeeRepository) GetFeeRules() ([]model.FeeRule, error) {
+	args := m.Called()
+	return args.Get(0).([]model.FeeRule), args.Error(1)
+}
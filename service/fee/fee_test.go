@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeFeeRules = []model.FeeRule{
+	{ID: "fee-default", Type: model.FeeTypePercentage, Percentage: 1},
+	{ID: "fee-grocery", Category: "grocery", Type: model.FeeTypeTiered, Tiers: []model.FeeTier{
+		{UpTo: 100000, Percentage: 0.5},
+		{UpTo: 0, Percentage: 0.3, Fixed: 500},
+	}},
+	{ID: "fee-merchant-001", MerchantID: "merchant-001", Type: model.FeeTypeFixed, Fixed: 1000},
+}
+
+func TestFeeService_CalculateFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []model.FeeRule
+		merchant model.Merchant
+		amount   float64
+		expected model.Fee
+	}{
+		{
+			name:     "default percentage",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-003", Category: "retail"},
+			amount:   12345,
+			expected: model.Fee{RuleID: "fee-default", Amount: 123.45},
+		},
+		{
+			name:     "merchant rule wins over category",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-001", Category: "grocery"},
+			amount:   50000,
+			expected: model.Fee{RuleID: "fee-merchant-001", Amount: 1000},
+		},
+		{
+			name:     "fixed fee capped at amount",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-001"},
+			amount:   300,
+			expected: model.Fee{RuleID: "fee-merchant-001", Amount: 300},
+		},
+		{
+			name:     "first tier",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-002", Category: "grocery"},
+			amount:   100000,
+			expected: model.Fee{RuleID: "fee-grocery", Amount: 500},
+		},
+		{
+			name:     "open ended tier",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-002", Category: "grocery"},
+			amount:   200000,
+			expected: model.Fee{RuleID: "fee-grocery", Amount: 1100},
+		},
+		{
+			name:     "rounded to two decimals",
+			rules:    fakeFeeRules,
+			merchant: model.Merchant{ID: "merchant-003"},
+			amount:   0.5,
+			expected: model.Fee{RuleID: "fee-default", Amount: 0.01},
+		},
+		{
+			name:     "no matching rule is free",
+			rules:    fakeFeeRules[1:2],
+			merchant: model.Merchant{ID: "merchant-003", Category: "retail"},
+			amount:   1000,
+			expected: model.Fee{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFeeRepository := new(MockFeeRepository)
+			mockFeeRepository.On("GetFeeRules").Return(tt.rules, nil)
+			feeService := NewFeeService(mockFeeRepository)
+
+			fee, err := feeService.CalculateFee(tt.merchant, tt.amount)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, fee)
+			mockFeeRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFeeService_CalculateFee_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []model.FeeRule
+		repoErr  error
+		expected string
+	}{
+		{
+			name:     "repository failure",
+			rules:    []model.FeeRule{},
+			repoErr:  errors.New("disk error"),
+			expected: "failed to get fee rules: disk error",
+		},
+		{
+			name:     "unknown fee type",
+			rules:    []model.FeeRule{{ID: "fee-bad", Type: "mystery"}},
+			expected: `fee rule fee-bad has unknown type "mystery"`,
+		},
+		{
+			name:     "no tier for amount",
+			rules:    []model.FeeRule{{ID: "fee-small", Type: model.FeeTypeTiered, Tiers: []model.FeeTier{{UpTo: 100, Percentage: 1}}}},
+			expected: "fee rule fee-small has no tier for amount 500.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFeeRepository := new(MockFeeRepository)
+			mockFeeRepository.On("GetFeeRules").Return(tt.rules, tt.repoErr)
+			feeService := NewFeeService(mockFeeRepository)
+
+			fee, err := feeService.CalculateFee(model.Merchant{ID: "merchant-001"}, 500)
+
+			assert.EqualError(t, err, tt.expected)
+			assert.Empty(t, fee)
+		})
+	}
+}
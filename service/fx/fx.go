@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/model"
+
+	fxRepo "simple-golang-tdd/repository/fx"
+)
+
+var ErrRateNotFound = errors.New("no exchange rate available")
+
+type FXService interface {
+	Convert(amount float64, from, to string) (model.FXConversion, error)
+}
+
+type fxServiceImpl struct {
+	fxRepository fxRepo.FXRepository
+}
+
+func NewFXService(fxRepository fxRepo.FXRepository) FXService {
+	return &fxServiceImpl{fxRepository: fxRepository}
+}
+
+// Convert converts amount from one currency into another at the mid-market
+// rate less the configured spread, rounded to the precision of the target
+// currency. Converting into the same currency is free.
+func (s *fxServiceImpl) Convert(amount float64, from, to string) (model.FXConversion, error) {
+	target, ok := model.GetCurrency(to)
+	if !ok {
+		return model.FXConversion{}, fmt.Errorf("unsupported currency %s", to)
+	}
+
+	if from == to {
+		return model.FXConversion{From: from, To: to, MidRate: 1, Rate: 1, Amount: amount, ConvertedAmount: amount}, nil
+	}
+
+	midRate, err := s.midRate(from, to)
+	if err != nil {
+		return model.FXConversion{}, err
+	}
+
+	spread, err := s.fxRepository.GetSpreadPercentage()
+	if err != nil {
+		return model.FXConversion{}, fmt.Errorf("failed to get spread: %w", err)
+	}
+
+	rate := midRate * (1 - spread/100)
+	return model.FXConversion{
+		From:             from,
+		To:               to,
+		MidRate:          midRate,
+		SpreadPercentage: spread,
+		Rate:             rate,
+		Amount:           amount,
+		ConvertedAmount:  target.Round(amount * rate),
+	}, nil
+}
+
+// midRate finds the rate from the table directly, as the inverse of the
+// opposite rate, or as a cross rate through the default currency.
+func (s *fxServiceImpl) midRate(from, to string) (float64, error) {
+	if rate, err := s.fxRepository.GetRate(from, to); err == nil {
+		return rate.Rate, nil
+	}
+	if rate, err := s.fxRepository.GetRate(to, from); err == nil && rate.Rate > 0 {
+		return 1 / rate.Rate, nil
+	}
+	if from != model.DefaultCurrency && to != model.DefaultCurrency {
+		fromRate, fromErr := s.midRate(from, model.DefaultCurrency)
+		toRate, toErr := s.midRate(model.DefaultCurrency, to)
+		if fromErr == nil && toErr == nil {
+			return fromRate * toRate, nil
+		}
+	}
+	return 0, fmt.Errorf("%w from %s to %s", ErrRateNotFound, from, to)
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"simple-golang-tdd/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFXRepository is a mock of the FXRepository interface
+type MockFXRepository struct {
+	mock.Mock
+}
+
+func (m *MockFXRepository) GetRate(from, to string) (model.ExchangeRate, error) {
+	args := m.Called(from, to)
+	return args.Get(0).(model.ExchangeRate), args.Error(1)
+}
+
+func (m *MockFXRepository) GetSpreadPercentage() (float64, error) {
+	args := m.Called()
+	return args.Get(0).(float64), args.Error(1)
+}
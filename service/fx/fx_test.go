@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var errRateNotFound = errors.New("exchange rate not found")
+
+func setupFXService() (FXService, *MockFXRepository) {
+	mockFXRepository := new(MockFXRepository)
+	mockFXRepository.On("GetRate", "USD", "IDR").Return(model.ExchangeRate{From: "USD", To: "IDR", Rate: 16000}, nil)
+	mockFXRepository.On("GetRate", "SGD", "IDR").Return(model.ExchangeRate{From: "SGD", To: "IDR", Rate: 12000}, nil)
+	mockFXRepository.On("GetRate", mock.Anything, mock.Anything).Return(model.ExchangeRate{}, errRateNotFound)
+	mockFXRepository.On("GetSpreadPercentage").Return(1.0, nil)
+	return NewFXService(mockFXRepository), mockFXRepository
+}
+
+func TestFXService_Convert(t *testing.T) {
+	tests := []struct {
+		name            string
+		amount          float64
+		from            string
+		to              string
+		midRate         float64
+		convertedAmount float64
+	}{
+		{"same currency", 100, "IDR", "IDR", 1, 100},
+		{"direct rate", 10, "USD", "IDR", 16000, 158400},
+		{"inverse rate", 160000, "IDR", "USD", 1.0 / 16000, 9.9},
+		{"cross rate", 100, "SGD", "USD", 12000.0 / 16000, 74.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fxService, _ := setupFXService()
+
+			conversion, err := fxService.Convert(tt.amount, tt.from, tt.to)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.from, conversion.From)
+			assert.Equal(t, tt.to, conversion.To)
+			assert.InDelta(t, tt.midRate, conversion.MidRate, 1e-12)
+			assert.Equal(t, tt.amount, conversion.Amount)
+			assert.Equal(t, tt.convertedAmount, conversion.ConvertedAmount)
+		})
+	}
+}
+
+func TestFXService_Convert_RateNotFound(t *testing.T) {
+	mockFXRepository := new(MockFXRepository)
+	mockFXRepository.On("GetRate", mock.Anything, mock.Anything).Return(model.ExchangeRate{}, errRateNotFound)
+	fxService := NewFXService(mockFXRepository)
+
+	conversion, err := fxService.Convert(100, "USD", "SGD")
+
+	assert.ErrorIs(t, err, ErrRateNotFound)
+	assert.EqualError(t, err, "no exchange rate available from USD to SGD")
+	assert.Empty(t, conversion)
+}
+
+func TestFXService_Convert_UnsupportedCurrency(t *testing.T) {
+	fxService, _ := setupFXService()
+
+	conversion, err := fxService.Convert(100, "IDR", "XXX")
+
+	assert.EqualError(t, err, "unsupported currency XXX")
+	assert.Empty(t, conversion)
+}
@@ -0,0 +1,473 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	accountRepo "simple-golang-tdd/repository/account"
+	customerRepo "simple-golang-tdd/repository/customer"
+	installmentRepo "simple-golang-tdd/repository/installment"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	paymentRepo "simple-golang-tdd/repository/payment"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+	transactionRepo "simple-golang-tdd/repository/transaction"
+	customerService "simple-golang-tdd/service/customer"
+	feeService "simple-golang-tdd/service/fee"
+	limitService "simple-golang-tdd/service/limit"
+)
+
+var (
+	ErrPlanNotFound        = errors.New("installment plan not found")
+	ErrInvalidInstallment  = errors.New("invalid installment payment")
+	ErrInstallmentOverdue  = errors.New("installments are overdue")
+	ErrCreditLimitExceeded = errors.New("pay-later credit limit exceeded")
+)
+
+const (
+	// PayLaterCreditLimit is the most a customer may owe across all plans, in
+	// the default currency.
+	PayLaterCreditLimit = 10000000.0
+
+	// LateFeePercentage of the installment amount is charged once an
+	// installment stays unpaid past LateFeeGracePeriod.
+	LateFeePercentage  = 5.0
+	LateFeeGracePeriod = 3 * 24 * time.Hour
+)
+
+type InstallmentService interface {
+	CreatePlan(request dto.InstallmentPaymentRequest, username string) (model.InstallmentPlan, error)
+	GetInstallments(username string) (model.InstallmentSummary, error)
+	GetPlan(id string, username string) (model.InstallmentPlan, error)
+	PayInstallment(id string, username string) (model.InstallmentPlan, error)
+	CollectDueInstallments() error
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type installmentServiceImpl struct {
+	installmentRepository installmentRepo.InstallmentRepository
+	customerRepository    customerRepo.CustomerRepository
+	merchantRepository    merchantRepo.MerchantRepository
+	paymentRepository     paymentRepo.PaymentRepository
+	accountRepository     accountRepo.AccountRepository
+	feeService            feeService.FeeService
+	limitService          limitService.LimitService
+	transactor            transactionRepo.Transactor
+	now                   func() time.Time
+	mutex                 sync.Mutex // serialises purchases and repayments so a customer's debt is checked and moved once
+}
+
+func NewInstallmentService(installmentRepository installmentRepo.InstallmentRepository, customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, paymentRepository paymentRepo.PaymentRepository, accountRepository accountRepo.AccountRepository, feeService feeService.FeeService, limitService limitService.LimitService, transactor transactionRepo.Transactor, now func() time.Time) InstallmentService {
+	return &installmentServiceImpl{
+		installmentRepository: installmentRepository,
+		customerRepository:    customerRepository,
+		merchantRepository:    merchantRepository,
+		paymentRepository:     paymentRepository,
+		accountRepository:     accountRepository,
+		feeService:            feeService,
+		limitService:          limitService,
+		transactor:            transactor,
+		now:                   now,
+	}
+}
+
+// CreatePlan pays the merchant in full from the platform credit line and
+// splits the amount into monthly installments for the customer. Customers with
+// overdue installments cannot buy on pay-later until they have caught up.
+func (s *installmentServiceImpl) CreatePlan(request dto.InstallmentPaymentRequest, username string) (model.InstallmentPlan, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := customerService.ValidateAmount(request.Amount, model.DefaultCurrency); err != nil {
+		return model.InstallmentPlan{}, err
+	}
+	if request.Installments < 2 {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: at least two installments are required", ErrInvalidInstallment)
+	}
+
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	summary, err := s.summary(customer.ID)
+	if err != nil {
+		return model.InstallmentPlan{}, err
+	}
+	if summary.Overdue {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: %.2f %s must be repaid first", ErrInstallmentOverdue, summary.OverdueAmount, model.DefaultCurrency)
+	}
+	if summary.Outstanding+request.Amount > PayLaterCreditLimit {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: %.2f of %.2f %s is still outstanding", ErrCreditLimitExceeded, summary.Outstanding, PayLaterCreditLimit, model.DefaultCurrency)
+	}
+
+	merchant, err := s.merchantRepository.GetMerchantByID(request.MerchantID)
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	// The credit line is kept in the default currency only.
+	if merchant.SettlementCurrency() != model.DefaultCurrency {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: merchant settles in %s", ErrInvalidInstallment, merchant.SettlementCurrency())
+	}
+
+	fee, err := s.feeService.CalculateFee(merchant, request.Amount)
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+
+	now := s.now().UTC()
+	planID := uuid.New().String()
+
+	payment := model.NewPayment(uuid.New().String(), customer.ID, merchant.ID, request.Amount, now)
+	payment.ApplyFee(fee)
+	payment.PlanID = planID
+
+	// The payment is stored under the limit check, so it counts towards the
+	// limits of the customer's next payment.
+	err = s.limitService.ReserveLimit(customer, request.Amount, func() error {
+		var err error
+		payment, err = s.paymentRepository.CreatePayment(payment)
+		if err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.InstallmentPlan{}, err
+	}
+
+	undo, err := s.fundPayment(merchant, payment)
+	if err != nil {
+		return model.InstallmentPlan{}, s.failPayment(payment, err)
+	}
+
+	plan, err := s.installmentRepository.CreatePlan(model.NewInstallmentPlan(planID, payment, request.Installments, now))
+	if err != nil {
+		return model.InstallmentPlan{}, s.failPayment(payment, rollback(undo, fmt.Errorf("failed to create installment plan: %w", err)))
+	}
+
+	undo = append(undo, func() error {
+		plan.Status = model.InstallmentPlanStatusCancelled
+		_, err := s.installmentRepository.UpdatePlan(plan)
+		return err
+	})
+
+	pending := payment
+	err = payment.TransitionTo(model.PaymentStatusSucceeded, "paid from the pay-later credit line", now)
+	if err == nil {
+		_, err = s.paymentRepository.UpdatePayment(payment)
+	}
+	if err != nil {
+		return model.InstallmentPlan{}, s.failPayment(pending, rollback(undo, fmt.Errorf("failed to mark payment as succeeded: %w", err)))
+	}
+
+	return plan, nil
+}
+
+// fundPayment draws the payment's amount from the platform credit line, pays
+// the merchant its net amount and books the fee as revenue. It returns how to
+// undo the updates, and undoes them itself when one fails.
+func (s *installmentServiceImpl) fundPayment(merchant model.Merchant, payment model.Payment) ([]func() error, error) {
+	var undo []func() error
+
+	if err := s.updateAccountBalance(&undo, model.PlatformCreditAccountID, payment.Amount); err != nil {
+		return nil, rollback(undo, err)
+	}
+
+	var credited model.Merchant
+	err := s.inTransaction(func(_ customerRepo.CustomerRepository, merchants merchantRepo.MerchantRepository) error {
+		var err error
+		credited, err = merchantRepo.AdjustMerchantBalance(merchants, merchant, model.DefaultCurrency, payment.NetAmount)
+		return err
+	})
+	if err != nil {
+		return nil, rollback(undo, fmt.Errorf("failed to update merchant balance: %w", err))
+	}
+	undo = append(undo, func() error {
+		_, err := merchantRepo.AdjustMerchantBalance(s.merchantRepository, credited, model.DefaultCurrency, -payment.NetAmount)
+		return err
+	})
+
+	if payment.Fee > 0 {
+		if err := s.updateAccountBalance(&undo, model.PlatformRevenueAccountID, payment.Fee); err != nil {
+			return nil, rollback(undo, err)
+		}
+	}
+
+	return undo, nil
+}
+
+// failPayment marks the payment as failed with cause as the reason and returns cause.
+func (s *installmentServiceImpl) failPayment(payment model.Payment, cause error) error {
+	if err := payment.TransitionTo(model.PaymentStatusFailed, cause.Error(), s.now().UTC()); err != nil {
+		return fmt.Errorf("%w (%v)", cause, err)
+	}
+	if _, err := s.paymentRepository.UpdatePayment(payment); err != nil {
+		return fmt.Errorf("%w (%v)", cause, err)
+	}
+	return cause
+}
+
+// GetInstallments returns the customer's plans together with what they still
+// owe and what is overdue.
+func (s *installmentServiceImpl) GetInstallments(username string) (model.InstallmentSummary, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.InstallmentSummary{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	return s.summary(customer.ID)
+}
+
+func (s *installmentServiceImpl) summary(customerID string) (model.InstallmentSummary, error) {
+	plans, err := s.installmentRepository.GetPlansByCustomerID(customerID)
+	if err != nil {
+		return model.InstallmentSummary{}, fmt.Errorf("failed to get installment plans: %w", err)
+	}
+
+	now := s.now().UTC()
+	summary := model.InstallmentSummary{Plans: plans}
+	for _, plan := range plans {
+		summary.Outstanding += plan.Outstanding()
+		summary.OverdueAmount += plan.OverdueAmount(now)
+	}
+	summary.Overdue = summary.OverdueAmount > 0
+
+	return summary, nil
+}
+
+func (s *installmentServiceImpl) GetPlan(id string, username string) (model.InstallmentPlan, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	plan, err := s.installmentRepository.GetPlanByID(id)
+	if err != nil || plan.CustomerID != customer.ID {
+		return model.InstallmentPlan{}, ErrPlanNotFound
+	}
+
+	return plan, nil
+}
+
+// PayInstallment pays the plan's next unpaid installment from the customer's
+// balance, ahead of its due date if need be.
+func (s *installmentServiceImpl) PayInstallment(id string, username string) (model.InstallmentPlan, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	plan, err := s.GetPlan(id, username)
+	if err != nil {
+		return model.InstallmentPlan{}, err
+	}
+
+	if plan.Status == model.InstallmentPlanStatusCancelled {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: plan is cancelled", ErrInvalidInstallment)
+	}
+	next := plan.NextUnpaid()
+	if next < 0 {
+		return model.InstallmentPlan{}, fmt.Errorf("%w: plan is already repaid", ErrInvalidInstallment)
+	}
+
+	customer, err := s.customerRepository.GetUserByID(plan.CustomerID)
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if customer.Balance < plan.Installments[next].AmountDue() {
+		return model.InstallmentPlan{}, customerService.ErrInsufficientBalance
+	}
+
+	return s.collect(plan, customer, next)
+}
+
+// CollectDueInstallments applies late fees to installments past their grace
+// period and debits every due installment the customer's balance covers.
+// Installments the balance does not cover stay pending and overdue.
+func (s *installmentServiceImpl) CollectDueInstallments() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	plans, err := s.installmentRepository.GetActivePlans()
+	if err != nil {
+		return fmt.Errorf("failed to get active installment plans: %w", err)
+	}
+
+	now := s.now().UTC()
+	var errs []error
+	for _, plan := range plans {
+		end := utils.BeginWrite()
+		if err := s.collectPlan(plan, now); err != nil {
+			errs = append(errs, fmt.Errorf("plan %s: %w", plan.ID, err))
+		}
+		end()
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *installmentServiceImpl) collectPlan(plan model.InstallmentPlan, now time.Time) error {
+	charged := false
+	for i, installment := range plan.Installments {
+		if installment.IsOverdue(now) && installment.LateFee == 0 && now.After(installment.DueAt.Add(LateFeeGracePeriod)) {
+			plan.Installments[i].LateFee = roundAmount(installment.Amount * LateFeePercentage / 100)
+			charged = true
+		}
+	}
+
+	for {
+		next := plan.NextUnpaid()
+		if next < 0 || plan.Installments[next].DueAt.After(now) {
+			break
+		}
+
+		customer, err := s.customerRepository.GetUserByID(plan.CustomerID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if customer.Balance < plan.Installments[next].AmountDue() {
+			break
+		}
+
+		collected, err := s.collect(plan, customer, next)
+		if errors.Is(err, customerService.ErrInsufficientBalance) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		plan, charged = collected, false
+	}
+
+	if charged {
+		plan.UpdatedAt = now
+		if _, err := s.installmentRepository.UpdatePlan(plan); err != nil {
+			return fmt.Errorf("failed to update installment plan: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// collect debits the customer for the installment at index next, repays the
+// credit line, books any late fee as revenue and marks the installment paid.
+// Every update is undone when a later one fails.
+func (s *installmentServiceImpl) collect(plan model.InstallmentPlan, customer model.Customer, next int) (model.InstallmentPlan, error) {
+	var undo []func() error
+	installment := plan.Installments[next]
+
+	var debited model.Customer
+	err := s.inTransaction(func(customers customerRepo.CustomerRepository, _ merchantRepo.MerchantRepository) error {
+		var err error
+		debited, err = customerRepo.AdjustUserBalance(customers, customer, model.DefaultCurrency, -installment.AmountDue(), func(customer model.Customer) error {
+			if customer.Balance < installment.AmountDue() {
+				return customerService.ErrInsufficientBalance
+			}
+			return nil
+		})
+		return err
+	})
+	if errors.Is(err, customerService.ErrInsufficientBalance) {
+		return model.InstallmentPlan{}, err
+	}
+	if err != nil {
+		return model.InstallmentPlan{}, fmt.Errorf("failed to update user balance: %w", err)
+	}
+	undo = append(undo, func() error {
+		_, err := customerRepo.AdjustUserBalance(s.customerRepository, debited, model.DefaultCurrency, installment.AmountDue(), nil)
+		return err
+	})
+
+	if err := s.updateAccountBalance(&undo, model.PlatformCreditAccountID, -installment.Amount); err != nil {
+		return model.InstallmentPlan{}, rollback(undo, err)
+	}
+
+	if installment.LateFee > 0 {
+		if err := s.updateAccountBalance(&undo, model.PlatformRevenueAccountID, installment.LateFee); err != nil {
+			return model.InstallmentPlan{}, rollback(undo, err)
+		}
+	}
+
+	now := s.now().UTC()
+	plan.Installments = append([]model.Installment(nil), plan.Installments...)
+	plan.Installments[next].Status = model.InstallmentStatusPaid
+	plan.Installments[next].PaidAt = &now
+	if plan.NextUnpaid() < 0 {
+		plan.Status = model.InstallmentPlanStatusCompleted
+	}
+	plan.UpdatedAt = now
+
+	updatedPlan, err := s.installmentRepository.UpdatePlan(plan)
+	if err != nil {
+		return model.InstallmentPlan{}, rollback(undo, fmt.Errorf("failed to update installment plan: %w", err))
+	}
+
+	return updatedPlan, nil
+}
+
+// inTransaction runs fn with customer and merchant repositories bound to one
+// storage transaction. Without a transactor fn runs on the service's own
+// repositories.
+func (s *installmentServiceImpl) inTransaction(fn func(customers customerRepo.CustomerRepository, merchants merchantRepo.MerchantRepository) error) error {
+	if s.transactor == nil {
+		return fn(s.customerRepository, s.merchantRepository)
+	}
+	return s.transactor.InTransaction(func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, _ promotionRepo.PromotionRepository) error {
+		return fn(customerRepository, merchantRepository)
+	})
+}
+
+// updateAccountBalance adds amount to a platform account and registers how to
+// undo it on undo.
+func (s *installmentServiceImpl) updateAccountBalance(undo *[]func() error, id string, amount float64) error {
+	if _, err := s.accountRepository.AdjustAccountBalance(id, amount); err != nil {
+		return fmt.Errorf("failed to update %s balance: %w", id, err)
+	}
+	*undo = append(*undo, func() error {
+		_, err := s.accountRepository.AdjustAccountBalance(id, -amount)
+		return err
+	})
+
+	return nil
+}
+
+// rollback runs undo in reverse and returns cause, annotated with any undo
+// that failed as well.
+func rollback(undo []func() error, cause error) error {
+	for i := len(undo) - 1; i >= 0; i-- {
+		if err := undo[i](); err != nil {
+			cause = fmt.Errorf("%w (rollback failed: %v)", cause, err)
+		}
+	}
+	return cause
+}
+
+// roundAmount rounds amount to the precision of the default currency.
+func roundAmount(amount float64) float64 {
+	currency, _ := model.GetCurrency(model.DefaultCurrency)
+	return currency.Round(amount)
+}
+
+// Start collects due installments every interval until ctx is cancelled.
+func (s *installmentServiceImpl) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CollectDueInstallments(); err != nil {
+				log.Printf("Error collecting installments: %v", err)
+			}
+		}
+	}
+}
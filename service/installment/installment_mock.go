@@ -0,0 +1,192 @@
+package service
+
+import (
+	"simple-golang-tdd/model"
+	"time"
+
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInstallmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockInstallmentRepository) CreatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error) {
+	args := m.Called(plan)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentRepository) GetPlanByID(id string) (model.InstallmentPlan, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentRepository) GetPlansByCustomerID(customerID string) ([]model.InstallmentPlan, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentRepository) GetActivePlans() ([]model.InstallmentPlan, error) {
+	args := m.Called()
+	return args.Get(0).([]model.InstallmentPlan), args.Error(1)
+}
+
+func (m *MockInstallmentRepository) UpdatePlan(plan model.InstallmentPlan) (model.InstallmentPlan, error) {
+	args := m.Called(plan)
+	return args.Get(0).(model.InstallmentPlan), args.Error(1)
+}
+
+// MockCustomerRepository is a mock of the CustomerRepository interface
+type MockCustomerRepository struct {
+	mock.Mock
+}
+
+func (m *MockCustomerRepository) GetUserByUsername(username string) (model.Customer, error) {
+	args := m.Called(username)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) GetUserByID(id string) (model.Customer, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) GetUserBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserBalance(id string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+func (m *MockCustomerRepository) UpdateUserPoints(id string, points int64, expectedVersion int64) (model.Customer, error) {
+	args := m.Called(id, points, expectedVersion)
+	return args.Get(0).(model.Customer), args.Error(1)
+}
+
+type MockMerchantRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantRepository) GetMerchantByID(id string) (model.Merchant, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantByAPIKey(apiKey string) (model.Merchant, error) {
+	args := m.Called(apiKey)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantBalance(id string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) UpdateMerchantWalletBalance(id string, currency string, amount float64, expectedVersion int64) (model.Merchant, error) {
+	args := m.Called(id, currency, amount, expectedVersion)
+	return args.Get(0).(model.Merchant), args.Error(1)
+}
+
+func (m *MockMerchantRepository) GetMerchantBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+type MockPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) CreatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentByID(id string) (model.Payment, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentsByCustomerID(customerID string, since time.Time) ([]model.Payment, error) {
+	args := m.Called(customerID, since)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetDueEscrowPayments(at time.Time) ([]model.Payment, error) {
+	args := m.Called(at)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) GetAccountBalance(id string) (float64, error) {
+	args := m.Called(id)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockAccountRepository) UpdateAccountBalance(id string, amount float64) (model.Account, error) {
+	args := m.Called(id, amount)
+	return args.Get(0).(model.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) AdjustAccountBalance(id string, delta float64) (model.Account, error) {
+	args := m.Called(id, delta)
+	return args.Get(0).(model.Account), args.Error(1)
+}
+
+type MockFeeService struct {
+	mock.Mock
+}
+
+func (m *MockFeeService) CalculateFee(merchant model.Merchant, amount float64) (model.Fee, error) {
+	args := m.Called(merchant, amount)
+	return args.Get(0).(model.Fee), args.Error(1)
+}
+
+type MockLimitService struct {
+	mock.Mock
+}
+
+// ReserveLimit mocks the ReserveLimit method of LimitService, calling record
+// when the mocked limit check passes
+func (m *MockLimitService) ReserveLimit(customer model.Customer, amount float64, record func() error) error {
+	args := m.Called(customer, amount)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return record()
+}
+
+// MockTransactor runs fn on the repositories it was set up with, standing in
+// for one storage transaction.
+type MockTransactor struct {
+	mock.Mock
+	customerRepository *MockCustomerRepository
+	merchantRepository *MockMerchantRepository
+}
+
+func (m *MockTransactor) InTransaction(fn func(customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, promotionRepository promotionRepo.PromotionRepository) error) error {
+	args := m.Called()
+	if err := fn(m.customerRepository, m.merchantRepository, nil); err != nil {
+		return err
+	}
+	return args.Error(0)
+}
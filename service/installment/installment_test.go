@@ -0,0 +1,414 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+type installmentServiceMocks struct {
+	installmentRepository *MockInstallmentRepository
+	customerRepository    *MockCustomerRepository
+	merchantRepository    *MockMerchantRepository
+	paymentRepository     *MockPaymentRepository
+	accountRepository     *MockAccountRepository
+	feeService            *MockFeeService
+	limitService          *MockLimitService
+}
+
+func (m installmentServiceMocks) assertExpectations(t *testing.T) {
+	m.installmentRepository.AssertExpectations(t)
+	m.customerRepository.AssertExpectations(t)
+	m.merchantRepository.AssertExpectations(t)
+	m.paymentRepository.AssertExpectations(t)
+	m.accountRepository.AssertExpectations(t)
+	m.feeService.AssertExpectations(t)
+	m.limitService.AssertExpectations(t)
+}
+
+func setupInstallmentService() (InstallmentService, installmentServiceMocks) {
+	mocks := installmentServiceMocks{
+		installmentRepository: new(MockInstallmentRepository),
+		customerRepository:    new(MockCustomerRepository),
+		merchantRepository:    new(MockMerchantRepository),
+		paymentRepository:     new(MockPaymentRepository),
+		accountRepository:     new(MockAccountRepository),
+		feeService:            new(MockFeeService),
+		limitService:          new(MockLimitService),
+	}
+	service := NewInstallmentService(mocks.installmentRepository, mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, mocks.feeService, mocks.limitService, nil, func() time.Time { return fakeNow })
+	return service, mocks
+}
+
+var (
+	fakeCustomer = model.Customer{ID: "cust-001", Username: "user", Balance: 500.0}
+	fakeMerchant = model.Merchant{ID: "merchant-001", Balance: 1000.0}
+)
+
+// fakePlan is a 3 x 100.00 plan created createdAgo before fakeNow.
+func fakePlan(createdAgo time.Duration) model.InstallmentPlan {
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 300.0, fakeNow.Add(-createdAgo))
+	return model.NewInstallmentPlan("plan-001", payment, 3, fakeNow.Add(-createdAgo))
+}
+
+// planWithPaid matches a plan argument by its status and how many of its
+// installments are paid.
+func planWithPaid(status model.InstallmentPlanStatus, paid int) interface{} {
+	return mock.MatchedBy(func(plan model.InstallmentPlan) bool {
+		count := 0
+		for _, installment := range plan.Installments {
+			if installment.Status == model.InstallmentStatusPaid {
+				count++
+			}
+		}
+		return plan.Status == status && count == paid
+	})
+}
+
+// returnsArgument makes the mocked call return its first argument, for
+// repository methods that store what they are given.
+func returnsArgument(call *mock.Call) {
+	call.Run(func(args mock.Arguments) {
+		call.ReturnArguments = mock.Arguments{args.Get(0), nil}
+	})
+}
+
+func paymentWithStatus(status model.PaymentStatus) interface{} {
+	return mock.MatchedBy(func(payment model.Payment) bool {
+		return payment.Status == status
+	})
+}
+
+func TestCreatePlan_Success(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 3}
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{}, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(fakeMerchant, nil)
+	mocks.limitService.On("ReserveLimit", fakeCustomer, 300.0).Return(nil)
+	mocks.feeService.On("CalculateFee", fakeMerchant, 300.0).Return(model.Fee{Amount: 6.0, RuleID: "fee-001"}, nil)
+	returnsArgument(mocks.paymentRepository.On("CreatePayment", mock.AnythingOfType("model.Payment")))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 1294.0, int64(0)).Return(model.Merchant{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 6.0).Return(model.Account{}, nil)
+	returnsArgument(mocks.installmentRepository.On("CreatePlan", mock.AnythingOfType("model.InstallmentPlan")))
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{}, nil)
+
+	plan, err := service.CreatePlan(request, "user")
+
+	require.NoError(t, err)
+	assert.Equal(t, "cust-001", plan.CustomerID)
+	assert.Equal(t, 300.0, plan.Principal)
+	require.Len(t, plan.Installments, 3)
+	assert.Equal(t, fakeNow.AddDate(0, 1, 0), plan.Installments[0].DueAt)
+	assert.Equal(t, 300.0, plan.Outstanding())
+	payment := mocks.paymentRepository.Calls[0].Arguments.Get(0).(model.Payment)
+	assert.Equal(t, plan.ID, payment.PlanID)
+	assert.Equal(t, payment.ID, plan.PaymentID)
+	mocks.assertExpectations(t)
+}
+
+func TestCreatePlan_DueOnLastDayOfShorterMonths(t *testing.T) {
+	at := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 400.0, at)
+
+	plan := model.NewInstallmentPlan("plan-001", payment, 4, at)
+
+	require.Len(t, plan.Installments, 4)
+	assert.Equal(t, time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), plan.Installments[0].DueAt)
+	assert.Equal(t, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC), plan.Installments[1].DueAt)
+	assert.Equal(t, time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC), plan.Installments[2].DueAt)
+	assert.Equal(t, time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC), plan.Installments[3].DueAt)
+}
+
+func TestCreatePlan_Overdue(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 3}
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{fakePlan(40 * 24 * time.Hour)}, nil)
+
+	plan, err := service.CreatePlan(request, "user")
+
+	require.ErrorIs(t, err, ErrInstallmentOverdue)
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestCreatePlan_CreditLimitExceeded(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: PayLaterCreditLimit, Installments: 12}
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{fakePlan(24 * time.Hour)}, nil)
+
+	plan, err := service.CreatePlan(request, "user")
+
+	require.ErrorIs(t, err, ErrCreditLimitExceeded)
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestCreatePlan_RollbackWhenPlanCannotBeCreated(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 3}
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{}, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(fakeMerchant, nil)
+	mocks.limitService.On("ReserveLimit", fakeCustomer, 300.0).Return(nil)
+	mocks.feeService.On("CalculateFee", fakeMerchant, 300.0).Return(model.Fee{}, nil)
+	returnsArgument(mocks.paymentRepository.On("CreatePayment", mock.AnythingOfType("model.Payment")))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 1300.0, int64(0)).Return(model.Merchant{ID: "merchant-001", Balance: 1300.0, Version: 1}, nil)
+	mocks.installmentRepository.On("CreatePlan", mock.AnythingOfType("model.InstallmentPlan")).Return(model.InstallmentPlan{}, errors.New("disk full"))
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 1000.0, int64(1)).Return(model.Merchant{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -300.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	plan, err := service.CreatePlan(request, "user")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create installment plan")
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestCreatePlan_RollbackWhenPaymentCannotBeMarkedSucceeded(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	request := dto.InstallmentPaymentRequest{MerchantID: "merchant-001", Amount: 300.0, Installments: 3}
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{}, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(fakeMerchant, nil)
+	mocks.limitService.On("ReserveLimit", fakeCustomer, 300.0).Return(nil)
+	mocks.feeService.On("CalculateFee", fakeMerchant, 300.0).Return(model.Fee{}, nil)
+	returnsArgument(mocks.paymentRepository.On("CreatePayment", mock.AnythingOfType("model.Payment")))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 1300.0, int64(0)).Return(model.Merchant{ID: "merchant-001", Balance: 1300.0, Version: 1}, nil)
+	returnsArgument(mocks.installmentRepository.On("CreatePlan", mock.AnythingOfType("model.InstallmentPlan")))
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusSucceeded)).Return(model.Payment{}, errors.New("disk full"))
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusCancelled, 0)))
+	mocks.merchantRepository.On("UpdateMerchantBalance", "merchant-001", 1000.0, int64(1)).Return(model.Merchant{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -300.0).Return(model.Account{}, nil)
+	mocks.paymentRepository.On("UpdatePayment", paymentWithStatus(model.PaymentStatusFailed)).Return(model.Payment{}, nil)
+
+	plan, err := service.CreatePlan(request, "user")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to mark payment as succeeded")
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestGetInstallments_Success(t *testing.T) {
+	service, mocks := setupInstallmentService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlansByCustomerID", "cust-001").Return([]model.InstallmentPlan{fakePlan(40 * 24 * time.Hour)}, nil)
+
+	summary, err := service.GetInstallments("user")
+
+	require.NoError(t, err)
+	assert.Equal(t, 300.0, summary.Outstanding)
+	assert.Equal(t, 100.0, summary.OverdueAmount)
+	assert.True(t, summary.Overdue)
+	assert.Len(t, summary.Plans, 1)
+	mocks.assertExpectations(t)
+}
+
+func TestGetPlan_OtherCustomer(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	plan := fakePlan(24 * time.Hour)
+	plan.CustomerID = "cust-002"
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(plan, nil)
+
+	result, err := service.GetPlan("plan-001", "user")
+
+	require.ErrorIs(t, err, ErrPlanNotFound)
+	assert.Empty(t, result)
+	mocks.assertExpectations(t)
+}
+
+func TestPayInstallment_Success(t *testing.T) {
+	service, mocks := setupInstallmentService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(fakePlan(24*time.Hour), nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(fakeCustomer, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 400.0, int64(0)).Return(model.Customer{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -100.0).Return(model.Account{}, nil)
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusActive, 1)))
+
+	plan, err := service.PayInstallment("plan-001", "user")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.InstallmentStatusPaid, plan.Installments[0].Status)
+	assert.Equal(t, fakeNow, *plan.Installments[0].PaidAt)
+	assert.Equal(t, 200.0, plan.Outstanding())
+	mocks.assertExpectations(t)
+}
+
+func TestPayInstallment_CollectsInTransaction(t *testing.T) {
+	_, mocks := setupInstallmentService()
+	transactor := &MockTransactor{
+		customerRepository: new(MockCustomerRepository),
+		merchantRepository: new(MockMerchantRepository),
+	}
+	service := NewInstallmentService(mocks.installmentRepository, mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, mocks.feeService, mocks.limitService, transactor, func() time.Time { return fakeNow })
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(fakePlan(24*time.Hour), nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(fakeCustomer, nil)
+	transactor.On("InTransaction").Return(nil)
+	transactor.customerRepository.On("UpdateUserBalance", "cust-001", 400.0, int64(0)).Return(model.Customer{ID: "cust-001", Balance: 400.0, Version: 1}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -100.0).Return(model.Account{}, nil)
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusActive, 1)))
+
+	plan, err := service.PayInstallment("plan-001", "user")
+
+	require.NoError(t, err)
+	assert.Equal(t, model.InstallmentStatusPaid, plan.Installments[0].Status)
+	mocks.assertExpectations(t)
+	transactor.AssertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+}
+
+func TestPayInstallment_InsufficientBalance(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	customer := fakeCustomer
+	customer.Balance = 50.0
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(fakePlan(24*time.Hour), nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(customer, nil)
+
+	plan, err := service.PayInstallment("plan-001", "user")
+
+	require.ErrorIs(t, err, customerService.ErrInsufficientBalance)
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestPayInstallment_CancelledPlan(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	cancelled := fakePlan(24 * time.Hour)
+	cancelled.Status = model.InstallmentPlanStatusCancelled
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(cancelled, nil)
+
+	plan, err := service.PayInstallment("plan-001", "user")
+
+	require.ErrorIs(t, err, ErrInvalidInstallment)
+	assert.Equal(t, 0.0, cancelled.Outstanding())
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestPayInstallment_RollbackWhenPlanCannotBeUpdated(t *testing.T) {
+	service, mocks := setupInstallmentService()
+
+	mocks.customerRepository.On("GetUserByUsername", "user").Return(fakeCustomer, nil)
+	mocks.installmentRepository.On("GetPlanByID", "plan-001").Return(fakePlan(24*time.Hour), nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(fakeCustomer, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 400.0, int64(0)).Return(model.Customer{ID: "cust-001", Balance: 400.0, Version: 1}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -100.0).Return(model.Account{}, nil)
+	mocks.installmentRepository.On("UpdatePlan", mock.AnythingOfType("model.InstallmentPlan")).Return(model.InstallmentPlan{}, errors.New("disk full"))
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, 100.0).Return(model.Account{}, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 500.0, int64(1)).Return(model.Customer{}, nil)
+
+	plan, err := service.PayInstallment("plan-001", "user")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to update installment plan")
+	assert.Empty(t, plan)
+	mocks.assertExpectations(t)
+}
+
+func TestCollectDueInstallments_ChargesLateFeeAndCollects(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	// The first installment fell due 9 days ago, past its grace period.
+	plan := fakePlan(40 * 24 * time.Hour)
+
+	mocks.installmentRepository.On("GetActivePlans").Return([]model.InstallmentPlan{plan}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(fakeCustomer, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 395.0, int64(0)).Return(model.Customer{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -100.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformRevenueAccountID, 5.0).Return(model.Account{}, nil)
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusActive, 1)))
+
+	err := service.CollectDueInstallments()
+
+	require.NoError(t, err)
+	updated := mocks.installmentRepository.Calls[1].Arguments.Get(0).(model.InstallmentPlan)
+	assert.Equal(t, 5.0, updated.Installments[0].LateFee)
+	mocks.assertExpectations(t)
+}
+
+func TestCollectDueInstallments_InsufficientBalanceKeepsLateFee(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	plan := fakePlan(40 * 24 * time.Hour)
+	customer := fakeCustomer
+	customer.Balance = 50.0
+
+	mocks.installmentRepository.On("GetActivePlans").Return([]model.InstallmentPlan{plan}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(customer, nil)
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusActive, 0)))
+
+	err := service.CollectDueInstallments()
+
+	require.NoError(t, err)
+	updated := mocks.installmentRepository.Calls[1].Arguments.Get(0).(model.InstallmentPlan)
+	assert.Equal(t, 5.0, updated.Installments[0].LateFee)
+	assert.Equal(t, 105.0, updated.OverdueAmount(fakeNow))
+	mocks.assertExpectations(t)
+}
+
+func TestCollectDueInstallments_CompletesPlan(t *testing.T) {
+	service, mocks := setupInstallmentService()
+	plan := fakePlan(90 * 24 * time.Hour)
+	for i := 0; i < 2; i++ {
+		paidAt := plan.Installments[i].DueAt
+		plan.Installments[i].Status = model.InstallmentStatusPaid
+		plan.Installments[i].PaidAt = &paidAt
+	}
+	plan.Installments[2].DueAt = fakeNow.Add(-time.Hour)
+
+	mocks.installmentRepository.On("GetActivePlans").Return([]model.InstallmentPlan{plan}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(fakeCustomer, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 400.0, int64(0)).Return(model.Customer{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformCreditAccountID, -100.0).Return(model.Account{}, nil)
+	returnsArgument(mocks.installmentRepository.On("UpdatePlan", planWithPaid(model.InstallmentPlanStatusCompleted, 3)))
+
+	err := service.CollectDueInstallments()
+
+	require.NoError(t, err)
+	mocks.assertExpectations(t)
+}
+
+func TestCollectDueInstallments_Error(t *testing.T) {
+	service, mocks := setupInstallmentService()
+
+	mocks.installmentRepository.On("GetActivePlans").Return([]model.InstallmentPlan{fakePlan(40 * 24 * time.Hour)}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(model.Customer{}, errors.New("user not found"))
+
+	err := service.CollectDueInstallments()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plan plan-001")
+	mocks.assertExpectations(t)
+}
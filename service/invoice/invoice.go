@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"strings"
+	"sync"
+	"time"
+
+	invoiceRepo "simple-golang-tdd/repository/invoice"
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrInvalidInvoice  = errors.New("invalid invoice")
+)
+
+// invoiceTokenBytes is the entropy of a payment-link token.
+const invoiceTokenBytes = 24
+
+type InvoiceService interface {
+	CreateInvoice(request dto.InvoiceRequest, merchantID string) (model.Invoice, error)
+	GetInvoice(id string, merchantID string) (model.Invoice, error)
+	GetInvoiceByToken(token string) (model.Invoice, error)
+	PayInvoice(token string, username string) (model.Payment, error)
+}
+
+type invoiceServiceImpl struct {
+	invoiceRepository invoiceRepo.InvoiceRepository
+	customerService   customerService.CustomerService
+	now               func() time.Time
+	payMutexes        sync.Map // invoice ID to *sync.Mutex, so payments of one invoice run one at a time
+}
+
+func NewInvoiceService(invoiceRepository invoiceRepo.InvoiceRepository, customerService customerService.CustomerService, now func() time.Time) InvoiceService {
+	return &invoiceServiceImpl{
+		invoiceRepository: invoiceRepository,
+		customerService:   customerService,
+		now:               now}
+}
+
+func (s *invoiceServiceImpl) CreateInvoice(request dto.InvoiceRequest, merchantID string) (model.Invoice, error) {
+	currency := request.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+
+	now := s.now().UTC()
+	if err := customerService.ValidateAmount(request.Amount, currency); err != nil {
+		return model.Invoice{}, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	if strings.TrimSpace(request.Description) == "" {
+		return model.Invoice{}, fmt.Errorf("%w: description is required", ErrInvalidInvoice)
+	}
+	if !request.ExpiresAt.After(now) {
+		return model.Invoice{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInvoice)
+	}
+
+	token, err := utils.GenerateToken(invoiceTokenBytes)
+	if err != nil {
+		return model.Invoice{}, fmt.Errorf("failed to generate invoice token: %w", err)
+	}
+
+	invoice := model.Invoice{
+		ID:          uuid.New().String(),
+		MerchantID:  merchantID,
+		Amount:      request.Amount,
+		Currency:    currency,
+		Description: request.Description,
+		Token:       token,
+		SingleUse:   request.SingleUse,
+		ExpiresAt:   request.ExpiresAt.UTC(),
+		Status:      model.InvoiceStatusOpen,
+		PaymentIDs:  []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	invoice, err = s.invoiceRepository.CreateInvoice(invoice)
+	if err != nil {
+		return model.Invoice{}, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	return invoice, nil
+}
+
+// GetInvoice returns one of the merchant's own invoices.
+func (s *invoiceServiceImpl) GetInvoice(id string, merchantID string) (model.Invoice, error) {
+	invoice, err := s.invoiceRepository.GetInvoiceByID(id)
+	if err != nil || invoice.MerchantID != merchantID {
+		return model.Invoice{}, ErrInvoiceNotFound
+	}
+
+	return invoice, nil
+}
+
+// GetInvoiceByToken returns the invoice behind a payment link, so the
+// customer can see what they are about to pay.
+func (s *invoiceServiceImpl) GetInvoiceByToken(token string) (model.Invoice, error) {
+	invoice, err := s.invoiceRepository.GetInvoiceByToken(token)
+	if err != nil {
+		return model.Invoice{}, ErrInvoiceNotFound
+	}
+
+	return invoice, nil
+}
+
+// PayInvoice charges the customer for the invoice. Payments of one invoice
+// run one at a time, and a single-use invoice is marked paid before the charge
+// so a second payment fails even when the invoice is saved elsewhere. A failed
+// charge reopens it; a charge the invoice cannot record is refunded.
+func (s *invoiceServiceImpl) PayInvoice(token string, username string) (model.Payment, error) {
+	invoice, err := s.invoiceRepository.GetInvoiceByToken(token)
+	if err != nil {
+		return model.Payment{}, ErrInvoiceNotFound
+	}
+
+	unlock := s.lockInvoice(invoice.ID)
+	defer unlock()
+
+	// Read again under the lock, so an earlier payment of the invoice is seen.
+	invoice, err = s.invoiceRepository.GetInvoiceByToken(token)
+	if err != nil {
+		return model.Payment{}, ErrInvoiceNotFound
+	}
+
+	now := s.now().UTC()
+	if err := invoice.CheckPayable(now); err != nil {
+		return model.Payment{}, err
+	}
+
+	if invoice.SingleUse {
+		if _, err := s.invoiceRepository.MarkInvoicePaid(invoice.ID, now); err != nil {
+			if errors.Is(err, model.ErrInvoicePaid) {
+				return model.Payment{}, err
+			}
+			return model.Payment{}, fmt.Errorf("failed to mark invoice paid: %w", err)
+		}
+	}
+
+	payment, err := s.customerService.Payment(dto.PaymentRequest{
+		MerchantID: invoice.MerchantID,
+		Amount:     invoice.Amount,
+		Currency:   invoice.Currency,
+	}, username)
+	if err != nil {
+		return model.Payment{}, errors.Join(err, s.reopenInvoice(invoice))
+	}
+
+	paid := invoice
+	paid.PaymentIDs = append([]string(nil), invoice.PaymentIDs...)
+	paid.RecordPayment(payment.ID, now)
+	if _, err := s.invoiceRepository.UpdateInvoice(paid); err != nil {
+		// The invoice does not show the payment, so the customer would be
+		// charged again when paying it once more.
+		if _, refundErr := s.customerService.RefundPayment(payment.ID, "the invoice could not record the payment"); refundErr != nil {
+			return model.Payment{}, fmt.Errorf("payment %s succeeded but the invoice could not be updated (%v) nor the payment refunded: %w", payment.ID, err, refundErr)
+		}
+		return model.Payment{}, errors.Join(fmt.Errorf("failed to update invoice, payment %s was refunded: %w", payment.ID, err), s.reopenInvoice(invoice))
+	}
+
+	return payment, nil
+}
+
+// lockInvoice takes the payment lock of the invoice with id.
+func (s *invoiceServiceImpl) lockInvoice(id string) (unlock func()) {
+	value, _ := s.payMutexes.LoadOrStore(id, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// reopenInvoice puts back a single-use invoice marked paid for a payment that
+// did not go through. Other invoices were not changed.
+func (s *invoiceServiceImpl) reopenInvoice(invoice model.Invoice) error {
+	if !invoice.SingleUse {
+		return nil
+	}
+	if _, err := s.invoiceRepository.UpdateInvoice(invoice); err != nil {
+		return fmt.Errorf("failed to reopen invoice %s: %w", invoice.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInvoiceRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRepository) CreateInvoice(invoice model.Invoice) (model.Invoice, error) {
+	args := m.Called(invoice)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) GetInvoiceByID(id string) (model.Invoice, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) GetInvoiceByToken(token string) (model.Invoice, error) {
+	args := m.Called(token)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) UpdateInvoice(invoice model.Invoice) (model.Invoice, error) {
+	args := m.Called(invoice)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) MarkInvoicePaid(id string, at time.Time) (model.Invoice, error) {
+	args := m.Called(id, at)
+	return args.Get(0).(model.Invoice), args.Error(1)
+}
+
+type MockCustomerService struct {
+	mock.Mock
+}
+
+func (m *MockCustomerService) Payment(request dto.PaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) SplitPayment(request dto.SplitPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+// QRPayment mocks the QRPayment method of CustomerService
+func (m *MockCustomerService) QRPayment(request dto.QRPaymentRequest, username string) (model.Payment, error) {
+	args := m.Called(request, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) GetPayment(id string, username string) (model.Payment, error) {
+	args := m.Called(id, username)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) RefundPayment(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) ReleaseEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockCustomerService) CancelEscrow(id string, reason string) (model.Payment, error) {
+	args := m.Called(id, reason)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
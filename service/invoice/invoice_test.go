@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+func setupInvoiceService() (InvoiceService, *MockInvoiceRepository, *MockCustomerService) {
+	invoiceRepository := new(MockInvoiceRepository)
+	customerService := new(MockCustomerService)
+	service := NewInvoiceService(invoiceRepository, customerService, func() time.Time { return fakeNow })
+	return service, invoiceRepository, customerService
+}
+
+func fakeInvoice(singleUse bool) model.Invoice {
+	return model.Invoice{
+		ID:          "inv-001",
+		MerchantID:  "merchant-001",
+		Amount:      150.0,
+		Currency:    model.DefaultCurrency,
+		Description: "Order #1001",
+		Token:       "tok-001",
+		SingleUse:   singleUse,
+		ExpiresAt:   fakeNow.Add(time.Hour),
+		Status:      model.InvoiceStatusOpen,
+		PaymentIDs:  []string{},
+	}
+}
+
+func TestCreateInvoice_Success(t *testing.T) {
+	service, invoiceRepository, _ := setupInvoiceService()
+	request := dto.InvoiceRequest{Amount: 150.0, Description: "Order #1001", ExpiresAt: fakeNow.Add(24 * time.Hour), SingleUse: true}
+
+	invoiceRepository.On("CreateInvoice", mock.AnythingOfType("model.Invoice")).Return(model.Invoice{ID: "inv-001"}, nil)
+
+	_, err := service.CreateInvoice(request, "merchant-001")
+
+	require.NoError(t, err)
+	created := invoiceRepository.Calls[0].Arguments.Get(0).(model.Invoice)
+	assert.Equal(t, "merchant-001", created.MerchantID)
+	assert.Equal(t, model.DefaultCurrency, created.Currency)
+	assert.Equal(t, model.InvoiceStatusOpen, created.Status)
+	assert.True(t, created.SingleUse)
+	assert.GreaterOrEqual(t, len(created.Token), 32)
+}
+
+func TestCreateInvoice_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.InvoiceRequest
+	}{
+		{"over-precise amount", dto.InvoiceRequest{Amount: 1.001, Description: "Order", ExpiresAt: fakeNow.Add(time.Hour)}},
+		{"unsupported currency", dto.InvoiceRequest{Amount: 10, Currency: "EUR", Description: "Order", ExpiresAt: fakeNow.Add(time.Hour)}},
+		{"blank description", dto.InvoiceRequest{Amount: 10, Description: "  ", ExpiresAt: fakeNow.Add(time.Hour)}},
+		{"already expired", dto.InvoiceRequest{Amount: 10, Description: "Order", ExpiresAt: fakeNow}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, invoiceRepository, _ := setupInvoiceService()
+
+			_, err := service.CreateInvoice(tt.request, "merchant-001")
+
+			assert.ErrorIs(t, err, ErrInvalidInvoice)
+			invoiceRepository.AssertNotCalled(t, "CreateInvoice", mock.Anything)
+		})
+	}
+}
+
+func TestGetInvoice_OtherMerchant(t *testing.T) {
+	service, invoiceRepository, _ := setupInvoiceService()
+	invoiceRepository.On("GetInvoiceByID", "inv-001").Return(fakeInvoice(true), nil)
+
+	_, err := service.GetInvoice("inv-001", "merchant-002")
+
+	assert.ErrorIs(t, err, ErrInvoiceNotFound)
+}
+
+func TestPayInvoice_SingleUse(t *testing.T) {
+	service, invoiceRepository, customerSvc := setupInvoiceService()
+	request := dto.PaymentRequest{MerchantID: "merchant-001", Amount: 150.0, Currency: model.DefaultCurrency}
+
+	invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice(true), nil)
+	invoiceRepository.On("MarkInvoicePaid", "inv-001", fakeNow).Return(model.Invoice{}, nil)
+	customerSvc.On("Payment", request, "johndoe").Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusSucceeded}, nil)
+	invoiceRepository.On("UpdateInvoice", mock.MatchedBy(func(invoice model.Invoice) bool {
+		return invoice.Status == model.InvoiceStatusPaid && len(invoice.PaymentIDs) == 1 && invoice.PaymentIDs[0] == "pay-001"
+	})).Return(model.Invoice{}, nil)
+
+	payment, err := service.PayInvoice("tok-001", "johndoe")
+
+	require.NoError(t, err)
+	assert.Equal(t, "pay-001", payment.ID)
+	invoiceRepository.AssertExpectations(t)
+	customerSvc.AssertExpectations(t)
+}
+
+func TestPayInvoice_ReusableStaysOpen(t *testing.T) {
+	service, invoiceRepository, customerSvc := setupInvoiceService()
+
+	invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice(false), nil)
+	customerSvc.On("Payment", mock.Anything, "johndoe").Return(model.Payment{ID: "pay-002"}, nil)
+	invoiceRepository.On("UpdateInvoice", mock.MatchedBy(func(invoice model.Invoice) bool {
+		return invoice.Status == model.InvoiceStatusOpen && invoice.PaidAt != nil
+	})).Return(model.Invoice{}, nil)
+
+	_, err := service.PayInvoice("tok-001", "johndoe")
+
+	require.NoError(t, err)
+	invoiceRepository.AssertExpectations(t)
+	invoiceRepository.AssertNotCalled(t, "MarkInvoicePaid", mock.Anything, mock.Anything)
+}
+
+func TestPayInvoice_NotPayable(t *testing.T) {
+	paid := fakeInvoice(true)
+	paid.Status = model.InvoiceStatusPaid
+	expired := fakeInvoice(false)
+	expired.ExpiresAt = fakeNow
+
+	tests := []struct {
+		name    string
+		invoice model.Invoice
+		wantErr error
+	}{
+		{"paid single-use invoice", paid, model.ErrInvoicePaid},
+		{"expired invoice", expired, model.ErrInvoiceExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, invoiceRepository, customerSvc := setupInvoiceService()
+			invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(tt.invoice, nil)
+
+			_, err := service.PayInvoice("tok-001", "johndoe")
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			customerSvc.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPayInvoice_PaymentFailedReopensInvoice(t *testing.T) {
+	service, invoiceRepository, customerSvc := setupInvoiceService()
+
+	invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice(true), nil)
+	invoiceRepository.On("MarkInvoicePaid", "inv-001", fakeNow).Return(model.Invoice{}, nil)
+	customerSvc.On("Payment", mock.Anything, "johndoe").Return(model.Payment{}, customerService.ErrInsufficientBalance)
+	invoiceRepository.On("UpdateInvoice", fakeInvoice(true)).Return(model.Invoice{}, nil)
+
+	_, err := service.PayInvoice("tok-001", "johndoe")
+
+	assert.ErrorIs(t, err, customerService.ErrInsufficientBalance)
+	invoiceRepository.AssertExpectations(t)
+}
+
+func TestPayInvoice_AlreadyMarkedPaid(t *testing.T) {
+	service, invoiceRepository, customerSvc := setupInvoiceService()
+
+	// Invoice sudah ditandai lunas oleh pembayaran lain setelah dibaca.
+	invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice(true), nil)
+	invoiceRepository.On("MarkInvoicePaid", "inv-001", fakeNow).Return(model.Invoice{}, model.ErrInvoicePaid)
+
+	_, err := service.PayInvoice("tok-001", "johndoe")
+
+	assert.ErrorIs(t, err, model.ErrInvoicePaid)
+	customerSvc.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+}
+
+func TestPayInvoice_UpdateFailedRefundsPayment(t *testing.T) {
+	service, invoiceRepository, customerSvc := setupInvoiceService()
+	saveErr := errors.New("error while updating invoice")
+
+	invoiceRepository.On("GetInvoiceByToken", "tok-001").Return(fakeInvoice(true), nil)
+	invoiceRepository.On("MarkInvoicePaid", "inv-001", fakeNow).Return(model.Invoice{}, nil)
+	customerSvc.On("Payment", mock.Anything, "johndoe").Return(model.Payment{ID: "pay-001"}, nil)
+	invoiceRepository.On("UpdateInvoice", mock.MatchedBy(func(invoice model.Invoice) bool {
+		return len(invoice.PaymentIDs) == 1
+	})).Return(model.Invoice{}, saveErr)
+	customerSvc.On("RefundPayment", "pay-001", mock.Anything).Return(model.Payment{ID: "pay-001", Status: model.PaymentStatusRefunded}, nil)
+	invoiceRepository.On("UpdateInvoice", fakeInvoice(true)).Return(model.Invoice{}, nil)
+
+	_, err := service.PayInvoice("tok-001", "johndoe")
+
+	assert.ErrorIs(t, err, saveErr)
+	assert.Contains(t, err.Error(), "payment pay-001 was refunded")
+	invoiceRepository.AssertExpectations(t)
+	customerSvc.AssertExpectations(t)
+}
+
+func TestPayInvoice_UnknownToken(t *testing.T) {
+	service, invoiceRepository, _ := setupInvoiceService()
+	invoiceRepository.On("GetInvoiceByToken", "tok-404").Return(model.Invoice{}, errors.New("invoice not found by token"))
+
+	_, err := service.PayInvoice("tok-404", "johndoe")
+
+	assert.ErrorIs(t, err, ErrInvoiceNotFound)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"simple-golang-tdd/model"
+	"sync"
+	"time"
+
+	limitRepo "simple-golang-tdd/repository/limit"
+	paymentRepo "simple-golang-tdd/repository/payment"
+)
+
+const (
+	CodePerTransactionLimitExceeded = "PER_TRANSACTION_LIMIT_EXCEEDED"
+	CodeDailyAmountLimitExceeded    = "DAILY_AMOUNT_LIMIT_EXCEEDED"
+	CodeDailyCountLimitExceeded     = "DAILY_COUNT_LIMIT_EXCEEDED"
+	CodeMonthlyAmountLimitExceeded  = "MONTHLY_AMOUNT_LIMIT_EXCEEDED"
+	CodeMonthlyCountLimitExceeded   = "MONTHLY_COUNT_LIMIT_EXCEEDED"
+)
+
+// LimitError is returned when a payment would break one of the customer's
+// spending limits. Code identifies which limit was hit.
+type LimitError struct {
+	Code    string
+	Message string
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+type LimitService interface {
+	ReserveLimit(customer model.Customer, amount float64, record func() error) error
+}
+
+type limitServiceImpl struct {
+	limitRepository   limitRepo.LimitRepository
+	paymentRepository paymentRepo.PaymentRepository
+	now               func() time.Time
+	mutexes           sync.Map // customer ID to *sync.Mutex, so one customer's payments are checked one at a time
+}
+
+func NewLimitService(limitRepository limitRepo.LimitRepository, paymentRepository paymentRepo.PaymentRepository) LimitService {
+	return &limitServiceImpl{
+		limitRepository:   limitRepository,
+		paymentRepository: paymentRepository,
+		now:               time.Now}
+}
+
+// ReserveLimit returns a *LimitError if paying amount now would exceed the
+// per-transaction, daily or monthly limits of the customer's tier. Otherwise
+// it calls record, which stores the payment, before any other payment of the
+// customer is checked, so concurrent payments cannot together exceed the
+// limits. Limits and amount are in the default currency.
+func (s *limitServiceImpl) ReserveLimit(customer model.Customer, amount float64, record func() error) error {
+	value, _ := s.mutexes.LoadOrStore(customer.ID, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := s.checkLimit(customer, amount); err != nil {
+		return err
+	}
+	return record()
+}
+
+func (s *limitServiceImpl) checkLimit(customer model.Customer, amount float64) error {
+	limit, err := s.getLimit(customer.Tier)
+	if err != nil {
+		return fmt.Errorf("failed to get spending limit: %w", err)
+	}
+
+	if limit.PerTransactionMax > 0 && amount > limit.PerTransactionMax {
+		return &LimitError{
+			Code:    CodePerTransactionLimitExceeded,
+			Message: fmt.Sprintf("payment exceeds the per-transaction limit of %.2f", limit.PerTransactionMax),
+		}
+	}
+
+	now := s.now().UTC()
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	payments, err := s.paymentRepository.GetPaymentsByCustomerID(customer.ID, startOfMonth)
+	if err != nil {
+		return fmt.Errorf("failed to get customer payments: %w", err)
+	}
+
+	var dailyAmount, monthlyAmount float64
+	var dailyCount, monthlyCount int
+	for _, payment := range payments {
+		if !countsTowardsLimit(payment) {
+			continue
+		}
+		monthlyAmount += payment.BaseAmount
+		monthlyCount++
+		if !payment.CreatedAt.Before(startOfDay) {
+			dailyAmount += payment.BaseAmount
+			dailyCount++
+		}
+	}
+
+	if err := checkWindow(limit.Daily, dailyAmount+amount, dailyCount+1, "daily", CodeDailyAmountLimitExceeded, CodeDailyCountLimitExceeded); err != nil {
+		return err
+	}
+	return checkWindow(limit.Monthly, monthlyAmount+amount, monthlyCount+1, "monthly", CodeMonthlyAmountLimitExceeded, CodeMonthlyCountLimitExceeded)
+}
+
+// getLimit returns the limit of tier, falling back to the default tier.
+func (s *limitServiceImpl) getLimit(tier string) (model.SpendingLimit, error) {
+	if tier != "" {
+		if limit, err := s.limitRepository.GetLimitByTier(tier); err == nil {
+			return limit, nil
+		}
+	}
+	return s.limitRepository.GetLimitByTier(model.DefaultLimitTier)
+}
+
+// countsTowardsLimit reports whether a past payment uses up the customer's limits.
+// Failed, cancelled, refunded and reversed payments did not end up spending anything.
+func countsTowardsLimit(payment model.Payment) bool {
+	switch payment.Status {
+	case model.PaymentStatusFailed, model.PaymentStatusCancelled, model.PaymentStatusRefunded, model.PaymentStatusReversed:
+		return false
+	}
+	return true
+}
+
+func checkWindow(window model.LimitWindow, amount float64, count int, name, amountCode, countCode string) error {
+	if window.MaxAmount > 0 && amount > window.MaxAmount {
+		return &LimitError{
+			Code:    amountCode,
+			Message: fmt.Sprintf("payment exceeds the %s spending limit of %.2f", name, window.MaxAmount),
+		}
+	}
+	if window.MaxCount > 0 && count > window.MaxCount {
+		return &LimitError{
+			Code:    countCode,
+			Message: fmt.Sprintf("payment exceeds the %s limit of %d transactions", name, window.MaxCount),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"simple-golang-tdd/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLimitRepository is a mock of the LimitRepository interface
+type MockLimitRepository struct {
+	mock.Mock
+}
+
+func (m *MockLimitRepository) GetLimitByTier(tier string) (model.SpendingLimit, error) {
+	args := m.Called(tier)
+	return args.Get(0).(model.SpendingLimit), args.Error(1)
+}
+
+// MockPaymentRepository is a mock of the PaymentRepository interface
+type MockPaymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRepository) CreatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentByID(id string) (model.Payment, error) {
+	args := m.Called(id)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentsByCustomerID(customerID string, since time.Time) ([]model.Payment, error) {
+	args := m.Called(customerID, since)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetDueEscrowPayments(at time.Time) ([]model.Payment, error) {
+	args := m.Called(at)
+	return args.Get(0).([]model.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdatePayment(payment model.Payment) (model.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(model.Payment), args.Error(1)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 4, 27, 12, 0, 0, 0, time.UTC)
+
+var fakeBasicLimit = model.SpendingLimit{
+	Tier:              "basic",
+	PerTransactionMax: 1000,
+	Daily:             model.LimitWindow{MaxAmount: 2000, MaxCount: 3},
+	Monthly:           model.LimitWindow{MaxAmount: 5000, MaxCount: 10},
+}
+
+func setupLimitService(limit model.SpendingLimit, payments []model.Payment) (LimitService, *MockLimitRepository, *MockPaymentRepository) {
+	mockLimitRepository := new(MockLimitRepository)
+	mockPaymentRepository := new(MockPaymentRepository)
+	mockLimitRepository.On("GetLimitByTier", limit.Tier).Return(limit, nil)
+	mockPaymentRepository.On("GetPaymentsByCustomerID", "cust-001", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)).Return(payments, nil)
+
+	limitService := &limitServiceImpl{
+		limitRepository:   mockLimitRepository,
+		paymentRepository: mockPaymentRepository,
+		now:               func() time.Time { return fakeNow },
+	}
+	return limitService, mockLimitRepository, mockPaymentRepository
+}
+
+// noRecord dipakai sebagai record untuk test yang hanya memeriksa limit
+func noRecord() error {
+	return nil
+}
+
+func fakePastPayment(id string, amount float64, createdAt time.Time, status model.PaymentStatus) model.Payment {
+	payment := model.NewPayment(id, "cust-001", "merchant-001", amount, createdAt)
+	payment.Status = status
+	return payment
+}
+
+func TestLimitService_ReserveLimit(t *testing.T) {
+	today := fakeNow.Add(-time.Hour)
+	earlierThisMonth := fakeNow.AddDate(0, 0, -5)
+
+	tests := []struct {
+		name         string
+		amount       float64
+		payments     []model.Payment
+		expectedCode string
+	}{
+		{
+			name:   "within every limit",
+			amount: 500,
+			payments: []model.Payment{
+				fakePastPayment("pay-001", 500, today, model.PaymentStatusSucceeded),
+			},
+		},
+		{
+			name:         "per transaction max",
+			amount:       1000.01,
+			expectedCode: CodePerTransactionLimitExceeded,
+		},
+		{
+			name:   "daily amount",
+			amount: 600,
+			payments: []model.Payment{
+				fakePastPayment("pay-001", 900, today, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-002", 600, today, model.PaymentStatusSucceeded),
+			},
+			expectedCode: CodeDailyAmountLimitExceeded,
+		},
+		{
+			name:   "daily count",
+			amount: 10,
+			payments: []model.Payment{
+				fakePastPayment("pay-001", 10, today, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-002", 10, today, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-003", 10, today, model.PaymentStatusPending),
+			},
+			expectedCode: CodeDailyCountLimitExceeded,
+		},
+		{
+			name:   "monthly amount",
+			amount: 1000,
+			payments: []model.Payment{
+				fakePastPayment("pay-001", 1000, earlierThisMonth, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-002", 1000, earlierThisMonth, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-003", 1000, earlierThisMonth, model.PaymentStatusSucceeded),
+				fakePastPayment("pay-004", 1000, earlierThisMonth, model.PaymentStatusPartiallyRefunded),
+				fakePastPayment("pay-005", 500, today, model.PaymentStatusSucceeded),
+			},
+			expectedCode: CodeMonthlyAmountLimitExceeded,
+		},
+		{
+			name:   "failed and refunded payments do not count",
+			amount: 1000,
+			payments: []model.Payment{
+				fakePastPayment("pay-001", 1000, today, model.PaymentStatusFailed),
+				fakePastPayment("pay-002", 1000, today, model.PaymentStatusRefunded),
+				fakePastPayment("pay-003", 1000, today, model.PaymentStatusReversed),
+				fakePastPayment("pay-004", 1000, today, model.PaymentStatusCancelled),
+				fakePastPayment("pay-005", 500, today, model.PaymentStatusSucceeded),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limitService, _, _ := setupLimitService(fakeBasicLimit, tt.payments)
+
+			recorded := false
+			err := limitService.ReserveLimit(model.Customer{ID: "cust-001", Tier: "basic"}, tt.amount, func() error {
+				recorded = true
+				return nil
+			})
+			assert.Equal(t, tt.expectedCode == "", recorded)
+
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var limitErr *LimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tt.expectedCode, limitErr.Code)
+		})
+	}
+}
+
+func TestLimitService_ReserveLimit_MonthlyCount(t *testing.T) {
+	limit := model.SpendingLimit{Tier: "basic", Monthly: model.LimitWindow{MaxCount: 1}}
+	limitService, _, _ := setupLimitService(limit, []model.Payment{
+		fakePastPayment("pay-001", 10, fakeNow.AddDate(0, 0, -3), model.PaymentStatusSucceeded),
+	})
+
+	err := limitService.ReserveLimit(model.Customer{ID: "cust-001", Tier: "basic"}, 10, noRecord)
+
+	assert.EqualError(t, err, "payment exceeds the monthly limit of 1 transactions")
+}
+
+func TestLimitService_ReserveLimit_FallsBackToDefaultTier(t *testing.T) {
+	defaultLimit := model.SpendingLimit{Tier: model.DefaultLimitTier, PerTransactionMax: 100}
+	limitService, mockLimitRepository, _ := setupLimitService(defaultLimit, nil)
+	mockLimitRepository.On("GetLimitByTier", "gold").Return(model.SpendingLimit{}, errors.New("spending limit not found for tier"))
+
+	err := limitService.ReserveLimit(model.Customer{ID: "cust-001", Tier: "gold"}, 200, noRecord)
+
+	assert.EqualError(t, err, "payment exceeds the per-transaction limit of 100.00")
+}
+
+func TestLimitService_ReserveLimit_NoLimitConfigured(t *testing.T) {
+	mockLimitRepository := new(MockLimitRepository)
+	mockLimitRepository.On("GetLimitByTier", model.DefaultLimitTier).Return(model.SpendingLimit{}, errors.New("spending limit not found for tier"))
+	limitService := NewLimitService(mockLimitRepository, new(MockPaymentRepository))
+
+	err := limitService.ReserveLimit(model.Customer{ID: "cust-001"}, 200, noRecord)
+
+	assert.EqualError(t, err, "failed to get spending limit: spending limit not found for tier")
+}
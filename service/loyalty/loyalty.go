@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+
+	accountRepo "simple-golang-tdd/repository/account"
+	customerRepo "simple-golang-tdd/repository/customer"
+	loyaltyRepo "simple-golang-tdd/repository/loyalty"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	paymentRepo "simple-golang-tdd/repository/payment"
+	promotionRepo "simple-golang-tdd/repository/promotion"
+	rewardRepo "simple-golang-tdd/repository/reward"
+	transactionRepo "simple-golang-tdd/repository/transaction"
+
+	"github.com/google/uuid"
+)
+
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// RewardHoldingPeriod is how long a reward is held before it is credited, so
+// that rewards on payments refunded in the meantime are never paid out.
+const RewardHoldingPeriod = 7 * 24 * time.Hour
+
+type LoyaltyService interface {
+	AccrueRewards(payment model.Payment) ([]model.Reward, error)
+	GetLoyalty(username string) (model.LoyaltySummary, error)
+	RedeemPoints(request dto.RedeemPointsRequest, username string) (model.LoyaltySummary, error)
+	CreditDueRewards() error
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type loyaltyServiceImpl struct {
+	loyaltyRepository  loyaltyRepo.LoyaltyRepository
+	rewardRepository   rewardRepo.RewardRepository
+	customerRepository customerRepo.CustomerRepository
+	merchantRepository merchantRepo.MerchantRepository
+	paymentRepository  paymentRepo.PaymentRepository
+	accountRepository  accountRepo.AccountRepository
+	transactor         transactionRepo.Transactor
+	now                func() time.Time
+	mutex              sync.Mutex // serialises changes to points and cashback balances
+}
+
+func NewLoyaltyService(loyaltyRepository loyaltyRepo.LoyaltyRepository, rewardRepository rewardRepo.RewardRepository, customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, paymentRepository paymentRepo.PaymentRepository, accountRepository accountRepo.AccountRepository, transactor transactionRepo.Transactor, now func() time.Time) LoyaltyService {
+	return &loyaltyServiceImpl{
+		loyaltyRepository:  loyaltyRepository,
+		rewardRepository:   rewardRepository,
+		customerRepository: customerRepository,
+		merchantRepository: merchantRepository,
+		paymentRepository:  paymentRepository,
+		accountRepository:  accountRepository,
+		transactor:         transactor,
+		now:                now,
+	}
+}
+
+// rewardBase is the part of a payment one merchant's reward rule applies to,
+// in DefaultCurrency.
+type rewardBase struct {
+	merchantID string
+	amount     float64
+}
+
+// rewardBases lists what the customer actually paid to each merchant of a
+// payment, after any discount.
+func rewardBases(payment model.Payment) []rewardBase {
+	rate := payment.BaseAmount / payment.Amount
+	if len(payment.Legs) == 0 {
+		return []rewardBase{{merchantID: payment.MerchantID, amount: payment.ChargedAmount() * rate}}
+	}
+
+	bases := make([]rewardBase, 0, len(payment.Legs))
+	for _, leg := range payment.Legs {
+		bases = append(bases, rewardBase{merchantID: leg.MerchantID, amount: leg.Amount * rate})
+	}
+	return bases
+}
+
+// AccrueRewards records the rewards a succeeded payment earns, one per
+// merchant with a matching rule. They are credited once the holding period
+// has passed.
+func (s *loyaltyServiceImpl) AccrueRewards(payment model.Payment) ([]model.Reward, error) {
+	rules, err := s.loyaltyRepository.GetRewardRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward rules: %w", err)
+	}
+
+	now := s.now().UTC()
+	rewards := []model.Reward{}
+	for _, base := range rewardBases(payment) {
+		merchant, err := s.merchantRepository.GetMerchantByID(base.merchantID)
+		if err != nil {
+			return rewards, fmt.Errorf("failed to get merchant: %w", err)
+		}
+
+		rule, found := findRewardRule(rules, merchant)
+		if !found {
+			continue
+		}
+
+		amount := rule.Reward(base.amount)
+		if amount <= 0 {
+			continue
+		}
+
+		reward, err := s.rewardRepository.CreateReward(model.Reward{
+			ID:          uuid.New().String(),
+			CustomerID:  payment.CustomerID,
+			PaymentID:   payment.ID,
+			MerchantID:  merchant.ID,
+			RuleID:      rule.ID,
+			Type:        rule.Type,
+			Amount:      amount,
+			Status:      model.RewardStatusPending,
+			AvailableAt: now.Add(RewardHoldingPeriod),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+		if err != nil {
+			return rewards, fmt.Errorf("failed to create reward: %w", err)
+		}
+		rewards = append(rewards, reward)
+	}
+
+	return rewards, nil
+}
+
+// findRewardRule picks the most specific rule for the merchant: merchant, then
+// category, then default.
+func findRewardRule(rules []model.RewardRule, merchant model.Merchant) (model.RewardRule, bool) {
+	var categoryRule, defaultRule *model.RewardRule
+	for i, rule := range rules {
+		switch {
+		case rule.MerchantID != "":
+			if rule.MerchantID == merchant.ID {
+				return rule, true
+			}
+		case rule.Category != "":
+			if rule.Category == merchant.Category && categoryRule == nil {
+				categoryRule = &rules[i]
+			}
+		default:
+			if defaultRule == nil {
+				defaultRule = &rules[i]
+			}
+		}
+	}
+
+	if categoryRule != nil {
+		return *categoryRule, true
+	}
+	if defaultRule != nil {
+		return *defaultRule, true
+	}
+	return model.RewardRule{}, false
+}
+
+func (s *loyaltyServiceImpl) GetLoyalty(username string) (model.LoyaltySummary, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.LoyaltySummary{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	rewards, err := s.rewardRepository.GetRewardsByCustomerID(customer.ID)
+	if err != nil {
+		return model.LoyaltySummary{}, fmt.Errorf("failed to get rewards: %w", err)
+	}
+
+	summary := model.LoyaltySummary{Points: customer.Points, Balance: customer.Balance, Rewards: rewards}
+	for _, reward := range rewards {
+		if reward.Status != model.RewardStatusPending {
+			continue
+		}
+		if reward.Type == model.RewardTypePoints {
+			summary.PendingPoints += int64(reward.Amount)
+		} else {
+			summary.PendingCashback += reward.Amount
+		}
+	}
+
+	return summary, nil
+}
+
+// RedeemPoints converts points into wallet balance at PointValue each.
+func (s *loyaltyServiceImpl) RedeemPoints(request dto.RedeemPointsRequest, username string) (model.LoyaltySummary, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.LoyaltySummary{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	enoughPoints := func(customer model.Customer) error {
+		if request.Points <= 0 || request.Points > customer.Points {
+			return fmt.Errorf("%w: %d points available", ErrInsufficientPoints, customer.Points)
+		}
+		return nil
+	}
+
+	if err := enoughPoints(customer); err != nil {
+		return model.LoyaltySummary{}, err
+	}
+
+	var updated model.Customer
+	err = s.payOut(float64(request.Points)*model.PointValue, func(customers customerRepo.CustomerRepository) error {
+		redeemed, err := customerRepo.AdjustUserPoints(customers, customer, -request.Points, enoughPoints)
+		if errors.Is(err, ErrInsufficientPoints) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update user points: %w", err)
+		}
+
+		updated, err = customerRepo.AdjustUserBalance(customers, redeemed, model.DefaultCurrency, float64(request.Points)*model.PointValue, nil)
+		if err != nil {
+			// Inside a storage transaction the points come back with its rollback.
+			if s.transactor == nil {
+				if _, rollbackErr := customerRepo.AdjustUserPoints(customers, redeemed, request.Points, nil); rollbackErr != nil {
+					err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+				}
+			}
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.LoyaltySummary{}, err
+	}
+
+	return model.LoyaltySummary{Points: updated.Points, Balance: updated.Balance}, nil
+}
+
+// CreditDueRewards credits every reward whose holding period has passed, or
+// cancels it if its payment no longer stands.
+func (s *loyaltyServiceImpl) CreditDueRewards() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now().UTC()
+
+	rewards, err := s.rewardRepository.GetDueRewards(now)
+	if err != nil {
+		return fmt.Errorf("failed to get due rewards: %w", err)
+	}
+
+	var errs []error
+	for _, reward := range rewards {
+		end := utils.BeginWrite()
+		if err := s.creditReward(reward, now); err != nil {
+			errs = append(errs, fmt.Errorf("reward %s: %w", reward.ID, err))
+		}
+		end()
+	}
+
+	return errors.Join(errs...)
+}
+
+// creditReward adds the reward to the customer's points or balance. A reward
+// whose payment has been refunded or reversed is cancelled instead.
+func (s *loyaltyServiceImpl) creditReward(reward model.Reward, now time.Time) error {
+	payment, err := s.paymentRepository.GetPaymentByID(reward.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	reward.UpdatedAt = now
+	if payment.Status != model.PaymentStatusSucceeded {
+		reward.Status = model.RewardStatusCancelled
+		if _, err := s.rewardRepository.UpdateReward(reward); err != nil {
+			return fmt.Errorf("failed to update reward: %w", err)
+		}
+		return nil
+	}
+
+	customer, err := s.customerRepository.GetUserByID(reward.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	undo, err := s.creditCustomer(customer, reward)
+	if err != nil {
+		return err
+	}
+
+	reward.Status = model.RewardStatusCredited
+	reward.CreditedAt = &now
+	if _, err := s.rewardRepository.UpdateReward(reward); err != nil {
+		// Leave the reward pending without its credit so the next run retries it.
+		if undoErr := undo(); undoErr != nil {
+			err = fmt.Errorf("%w (rollback failed: %v)", err, undoErr)
+		}
+		return fmt.Errorf("failed to update reward: %w", err)
+	}
+
+	return nil
+}
+
+// creditCustomer adds the reward to the customer and returns how to undo it.
+func (s *loyaltyServiceImpl) creditCustomer(customer model.Customer, reward model.Reward) (func() error, error) {
+	if reward.Type == model.RewardTypePoints {
+		points := int64(reward.Amount)
+		var credited model.Customer
+		err := s.inTransaction(func(customers customerRepo.CustomerRepository) error {
+			var err error
+			credited, err = customerRepo.AdjustUserPoints(customers, customer, points, nil)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update user points: %w", err)
+		}
+		return func() error {
+			_, err := customerRepo.AdjustUserPoints(s.customerRepository, credited, -points, nil)
+			return err
+		}, nil
+	}
+
+	var credited model.Customer
+	err := s.payOut(reward.Amount, func(customers customerRepo.CustomerRepository) error {
+		var err error
+		credited, err = customerRepo.AdjustUserBalance(customers, customer, model.DefaultCurrency, reward.Amount, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		if _, err := customerRepo.AdjustUserBalance(s.customerRepository, credited, model.DefaultCurrency, -reward.Amount, nil); err != nil {
+			return err
+		}
+		_, err := s.accountRepository.AdjustAccountBalance(model.PlatformLoyaltyAccountID, -reward.Amount)
+		return err
+	}, nil
+}
+
+// payOut runs credit, which credits amount to a customer, in one transaction
+// with booking amount on the platform loyalty account, the way discounts are
+// booked on the promotions account. The account is not part of the storage
+// transaction, so its update is undone when the transaction fails.
+func (s *loyaltyServiceImpl) payOut(amount float64, credit func(customers customerRepo.CustomerRepository) error) error {
+	booked := false
+	err := s.inTransaction(func(customers customerRepo.CustomerRepository) error {
+		if _, err := s.accountRepository.AdjustAccountBalance(model.PlatformLoyaltyAccountID, amount); err != nil {
+			return fmt.Errorf("failed to update %s balance: %w", model.PlatformLoyaltyAccountID, err)
+		}
+		booked = true
+		return credit(customers)
+	})
+	if err != nil && booked {
+		if _, rollbackErr := s.accountRepository.AdjustAccountBalance(model.PlatformLoyaltyAccountID, -amount); rollbackErr != nil {
+			err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+	}
+	return err
+}
+
+// inTransaction runs fn with a customer repository bound to one storage
+// transaction, so the updates it makes commit or roll back together. Without
+// a transactor fn runs on the service's own repository.
+func (s *loyaltyServiceImpl) inTransaction(fn func(customers customerRepo.CustomerRepository) error) error {
+	if s.transactor == nil {
+		return fn(s.customerRepository)
+	}
+
+	return s.transactor.InTransaction(func(customerRepository customerRepo.CustomerRepository, _ merchantRepo.MerchantRepository, _ promotionRepo.PromotionRepository) error {
+		return fn(customerRepository)
+	})
+}
+
+// Start credits due rewards every interval until ctx is cancelled.
+func (s *loyaltyServiceImpl) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CreditDueRewards(); err != nil {
+				log.Printf("Error crediting rewards: %v", err)
+			}
+		}
+	}
+}
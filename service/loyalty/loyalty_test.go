@@ -0,0 +1,327 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeNow = time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+type loyaltyServiceMocks struct {
+	loyaltyRepository  *MockLoyaltyRepository
+	rewardRepository   *MockRewardRepository
+	customerRepository *MockCustomerRepository
+	merchantRepository *MockMerchantRepository
+	paymentRepository  *MockPaymentRepository
+	accountRepository  *MockAccountRepository
+}
+
+func (m loyaltyServiceMocks) assertExpectations(t *testing.T) {
+	m.loyaltyRepository.AssertExpectations(t)
+	m.rewardRepository.AssertExpectations(t)
+	m.customerRepository.AssertExpectations(t)
+	m.merchantRepository.AssertExpectations(t)
+	m.paymentRepository.AssertExpectations(t)
+	m.accountRepository.AssertExpectations(t)
+}
+
+func setupLoyaltyService() (LoyaltyService, loyaltyServiceMocks) {
+	mocks := loyaltyServiceMocks{
+		loyaltyRepository:  new(MockLoyaltyRepository),
+		rewardRepository:   new(MockRewardRepository),
+		customerRepository: new(MockCustomerRepository),
+		merchantRepository: new(MockMerchantRepository),
+		paymentRepository:  new(MockPaymentRepository),
+		accountRepository:  new(MockAccountRepository),
+	}
+	loyaltyService := NewLoyaltyService(mocks.loyaltyRepository, mocks.rewardRepository, mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, nil, func() time.Time { return fakeNow })
+	return loyaltyService, mocks
+}
+
+var fakeRewardRules = []model.RewardRule{
+	{ID: "reward-default", Type: model.RewardTypePoints, Percentage: 1},
+	{ID: "reward-grocery", Category: "grocery", Type: model.RewardTypeCashback, Percentage: 2, MaxReward: 150},
+	{ID: "reward-merchant-002", MerchantID: "merchant-002", Type: model.RewardTypePoints, Percentage: 5},
+}
+
+func fakeReward(id string, rewardType model.RewardType, amount float64) model.Reward {
+	return model.Reward{
+		ID:          id,
+		CustomerID:  "cust-001",
+		PaymentID:   "pay-" + id,
+		MerchantID:  "merchant-001",
+		Type:        rewardType,
+		Amount:      amount,
+		Status:      model.RewardStatusPending,
+		AvailableAt: fakeNow,
+	}
+}
+
+func TestLoyaltyService_AccrueRewards(t *testing.T) {
+	tests := []struct {
+		name       string
+		merchant   model.Merchant
+		amount     float64
+		discount   float64
+		wantRuleID string
+		wantType   model.RewardType
+		wantAmount float64
+	}{
+		{"default points", model.Merchant{ID: "merchant-001", Category: "retail"}, 10050.0, 0, "reward-default", model.RewardTypePoints, 100},
+		{"category cashback", model.Merchant{ID: "merchant-003", Category: "grocery"}, 5000.0, 0, "reward-grocery", model.RewardTypeCashback, 100},
+		{"cashback capped", model.Merchant{ID: "merchant-003", Category: "grocery"}, 50000.0, 0, "reward-grocery", model.RewardTypeCashback, 150},
+		{"merchant rule wins", model.Merchant{ID: "merchant-002", Category: "grocery"}, 1000.0, 0, "reward-merchant-002", model.RewardTypePoints, 50},
+		{"earned on the discounted amount", model.Merchant{ID: "merchant-001"}, 10000.0, 2000.0, "reward-default", model.RewardTypePoints, 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loyaltyService, mocks := setupLoyaltyService()
+
+			payment := model.NewPayment("pay-001", "cust-001", tt.merchant.ID, tt.amount, fakeNow)
+			payment.Discount = tt.discount
+			mocks.loyaltyRepository.On("GetRewardRules").Return(fakeRewardRules, nil)
+			mocks.merchantRepository.On("GetMerchantByID", tt.merchant.ID).Return(tt.merchant, nil)
+			mocks.rewardRepository.On("CreateReward", mock.Anything).Return(model.Reward{ID: "rwd-001"}, nil)
+
+			rewards, err := loyaltyService.AccrueRewards(payment)
+
+			require.NoError(t, err)
+			require.Len(t, rewards, 1)
+			created := mocks.rewardRepository.Calls[0].Arguments.Get(0).(model.Reward)
+			assert.Equal(t, tt.wantRuleID, created.RuleID)
+			assert.Equal(t, tt.wantType, created.Type)
+			assert.Equal(t, tt.wantAmount, created.Amount)
+			assert.Equal(t, model.RewardStatusPending, created.Status)
+			assert.Equal(t, fakeNow.Add(RewardHoldingPeriod), created.AvailableAt)
+			mocks.assertExpectations(t)
+		})
+	}
+}
+
+func TestLoyaltyService_AccrueRewards_SplitPayment(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	payment := model.NewPayment("pay-001", "cust-001", "", 3000.0, fakeNow)
+	payment.ApplyLegs([]model.PaymentLeg{
+		{MerchantID: "merchant-001", Amount: 1000.0, NetAmount: 1000.0},
+		{MerchantID: "merchant-002", Amount: 2000.0, NetAmount: 2000.0},
+	})
+	mocks.loyaltyRepository.On("GetRewardRules").Return(fakeRewardRules, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001"}, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-002").Return(model.Merchant{ID: "merchant-002"}, nil)
+	mocks.rewardRepository.On("CreateReward", mock.MatchedBy(func(reward model.Reward) bool {
+		return reward.MerchantID == "merchant-001" && reward.Amount == 10
+	})).Return(model.Reward{ID: "rwd-001"}, nil)
+	mocks.rewardRepository.On("CreateReward", mock.MatchedBy(func(reward model.Reward) bool {
+		return reward.MerchantID == "merchant-002" && reward.Amount == 100
+	})).Return(model.Reward{ID: "rwd-002"}, nil)
+
+	rewards, err := loyaltyService.AccrueRewards(payment)
+
+	require.NoError(t, err)
+	assert.Len(t, rewards, 2)
+	mocks.assertExpectations(t)
+}
+
+func TestLoyaltyService_AccrueRewards_NoRule(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	payment := model.NewPayment("pay-001", "cust-001", "merchant-001", 1000.0, fakeNow)
+	mocks.loyaltyRepository.On("GetRewardRules").Return([]model.RewardRule{}, nil)
+	mocks.merchantRepository.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001"}, nil)
+
+	rewards, err := loyaltyService.AccrueRewards(payment)
+
+	require.NoError(t, err)
+	assert.Empty(t, rewards)
+	mocks.rewardRepository.AssertNotCalled(t, "CreateReward", mock.Anything)
+}
+
+func TestLoyaltyService_CreditDueRewards(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	points := fakeReward("rwd-points", model.RewardTypePoints, 100)
+	cashback := fakeReward("rwd-cashback", model.RewardTypeCashback, 250)
+	refunded := fakeReward("rwd-refunded", model.RewardTypePoints, 40)
+	customer := model.Customer{ID: "cust-001", Balance: 1000.0, Points: 10}
+
+	mocks.rewardRepository.On("GetDueRewards", fakeNow).Return([]model.Reward{points, cashback, refunded}, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-rwd-points").Return(model.Payment{Status: model.PaymentStatusSucceeded}, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-rwd-cashback").Return(model.Payment{Status: model.PaymentStatusSucceeded}, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-rwd-refunded").Return(model.Payment{Status: model.PaymentStatusRefunded}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(customer, nil)
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(110), int64(0)).Return(customer, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 250.0).Return(model.Account{}, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 1250.0, int64(0)).Return(customer, nil)
+	mocks.rewardRepository.On("UpdateReward", mock.MatchedBy(func(reward model.Reward) bool {
+		return reward.ID != "rwd-refunded" && reward.Status == model.RewardStatusCredited && reward.CreditedAt != nil
+	})).Return(model.Reward{}, nil).Twice()
+	mocks.rewardRepository.On("UpdateReward", mock.MatchedBy(func(reward model.Reward) bool {
+		return reward.ID == "rwd-refunded" && reward.Status == model.RewardStatusCancelled
+	})).Return(model.Reward{}, nil).Once()
+
+	err := loyaltyService.CreditDueRewards()
+
+	assert.NoError(t, err)
+	mocks.customerRepository.AssertNotCalled(t, "UpdateUserPoints", "cust-001", int64(50), mock.Anything)
+	mocks.assertExpectations(t)
+}
+
+func TestLoyaltyService_CreditDueRewards_UpdateFailedUndoesCredit(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	reward := fakeReward("rwd-points", model.RewardTypePoints, 100)
+	customer := model.Customer{ID: "cust-001", Points: 10}
+
+	mocks.rewardRepository.On("GetDueRewards", fakeNow).Return([]model.Reward{reward}, nil)
+	mocks.paymentRepository.On("GetPaymentByID", "pay-rwd-points").Return(model.Payment{Status: model.PaymentStatusSucceeded}, nil)
+	mocks.customerRepository.On("GetUserByID", "cust-001").Return(customer, nil)
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(110), int64(0)).Return(model.Customer{ID: "cust-001", Points: 110, Version: 1}, nil)
+	mocks.rewardRepository.On("UpdateReward", mock.Anything).Return(model.Reward{}, errors.New("disk full"))
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(10), int64(1)).Return(customer, nil)
+
+	err := loyaltyService.CreditDueRewards()
+
+	assert.EqualError(t, err, "reward rwd-points: failed to update reward: disk full")
+	mocks.assertExpectations(t)
+}
+
+func TestLoyaltyService_GetLoyalty(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	credited := fakeReward("rwd-credited", model.RewardTypePoints, 500)
+	credited.Status = model.RewardStatusCredited
+	rewards := []model.Reward{
+		fakeReward("rwd-points", model.RewardTypePoints, 100),
+		fakeReward("rwd-cashback", model.RewardTypeCashback, 250.5),
+		credited,
+	}
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}, nil)
+	mocks.rewardRepository.On("GetRewardsByCustomerID", "cust-001").Return(rewards, nil)
+
+	summary, err := loyaltyService.GetLoyalty("johndoe")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), summary.Points)
+	assert.Equal(t, int64(100), summary.PendingPoints)
+	assert.Equal(t, 250.5, summary.PendingCashback)
+	assert.Len(t, summary.Rewards, 3)
+	mocks.assertExpectations(t)
+}
+
+func TestLoyaltyService_RedeemPoints(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	customer := model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(customer, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(200), int64(0)).Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 200}, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 1300.0, int64(0)).Return(model.Customer{ID: "cust-001", Balance: 1300.0, Points: 200}, nil)
+
+	summary, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), summary.Points)
+	assert.Equal(t, 1300.0, summary.Balance)
+	mocks.assertExpectations(t)
+}
+
+// Helper function untuk inisialisasi loyalty service dengan transactor mock
+func setupTransactionalLoyaltyService() (LoyaltyService, loyaltyServiceMocks, *MockTransactor) {
+	_, mocks := setupLoyaltyService()
+	transactor := &MockTransactor{
+		customerRepository: new(MockCustomerRepository),
+		merchantRepository: new(MockMerchantRepository),
+	}
+	loyaltyService := NewLoyaltyService(mocks.loyaltyRepository, mocks.rewardRepository, mocks.customerRepository, mocks.merchantRepository, mocks.paymentRepository, mocks.accountRepository, transactor, func() time.Time { return fakeNow })
+	return loyaltyService, mocks, transactor
+}
+
+func TestLoyaltyService_RedeemPoints_InTransaction(t *testing.T) {
+	loyaltyService, mocks, transactor := setupTransactionalLoyaltyService()
+
+	customer := model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(customer, nil)
+	transactor.On("InTransaction").Return(nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 300.0).Return(model.Account{}, nil)
+	transactor.customerRepository.On("UpdateUserPoints", "cust-001", int64(200), int64(0)).Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 200, Version: 1}, nil)
+	transactor.customerRepository.On("UpdateUserBalance", "cust-001", 1300.0, int64(1)).Return(model.Customer{ID: "cust-001", Balance: 1300.0, Points: 200, Version: 2}, nil)
+
+	summary, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), summary.Points)
+	assert.Equal(t, 1300.0, summary.Balance)
+	mocks.assertExpectations(t)
+	transactor.AssertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+}
+
+func TestLoyaltyService_RedeemPoints_FailedTransactionNeedsNoPointsRollback(t *testing.T) {
+	loyaltyService, mocks, transactor := setupTransactionalLoyaltyService()
+
+	customer := model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(customer, nil)
+	transactor.On("InTransaction").Return(nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, -300.0).Return(model.Account{}, nil)
+	transactor.customerRepository.On("UpdateUserPoints", "cust-001", int64(200), int64(0)).Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 200, Version: 1}, nil)
+	transactor.customerRepository.On("UpdateUserBalance", "cust-001", 1300.0, int64(1)).Return(model.Customer{}, errors.New("disk full"))
+
+	_, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	assert.EqualError(t, err, "failed to update user balance: disk full")
+	transactor.customerRepository.AssertNumberOfCalls(t, "UpdateUserPoints", 1)
+	mocks.assertExpectations(t)
+	transactor.customerRepository.AssertExpectations(t)
+}
+
+func TestLoyaltyService_RedeemPoints_Insufficient(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(model.Customer{ID: "cust-001", Points: 100}, nil)
+
+	_, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+	mocks.customerRepository.AssertNotCalled(t, "UpdateUserPoints", mock.Anything, mock.Anything, mock.Anything)
+	mocks.accountRepository.AssertNotCalled(t, "AdjustAccountBalance", mock.Anything, mock.Anything)
+}
+
+func TestLoyaltyService_RedeemPoints_BalanceUpdateFailedRestoresPoints(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	customer := model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(customer, nil)
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(200), int64(0)).Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 200, Version: 1}, nil)
+	mocks.customerRepository.On("UpdateUserBalance", "cust-001", 1300.0, int64(1)).Return(model.Customer{}, errors.New("disk full"))
+	mocks.customerRepository.On("UpdateUserPoints", "cust-001", int64(500), int64(1)).Return(customer, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 300.0).Return(model.Account{}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, -300.0).Return(model.Account{}, nil)
+
+	_, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	assert.EqualError(t, err, "failed to update user balance: disk full")
+	mocks.assertExpectations(t)
+}
+
+func TestLoyaltyService_RedeemPoints_AccountUpdateFailed(t *testing.T) {
+	loyaltyService, mocks := setupLoyaltyService()
+
+	mocks.customerRepository.On("GetUserByUsername", "johndoe").Return(model.Customer{ID: "cust-001", Balance: 1000.0, Points: 500}, nil)
+	mocks.accountRepository.On("AdjustAccountBalance", model.PlatformLoyaltyAccountID, 300.0).Return(model.Account{}, errors.New("disk full"))
+
+	_, err := loyaltyService.RedeemPoints(dto.RedeemPointsRequest{Points: 300}, "johndoe")
+
+	assert.EqualError(t, err, "failed to update platform-loyalty balance: disk full")
+	mocks.customerRepository.AssertNotCalled(t, "UpdateUserPoints", mock.Anything, mock.Anything, mock.Anything)
+	mocks.assertExpectations(t)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/qr"
+
+	merchantRepo "simple-golang-tdd/repository/merchant"
+)
+
+var ErrInvalidQRAmount = errors.New("invalid QR amount")
+
+type QRService interface {
+	GenerateQR(merchantID string, amount float64) (dto.QRResponse, error)
+}
+
+type qrServiceImpl struct {
+	merchantRepository merchantRepo.MerchantRepository
+}
+
+func NewQRService(merchantRepository merchantRepo.MerchantRepository) QRService {
+	return &qrServiceImpl{merchantRepository: merchantRepository}
+}
+
+// GenerateQR builds the QRIS payload of a merchant. Without an amount the
+// code is static and can be printed at the till; with one it is dynamic and
+// fixes the amount for a single purchase.
+func (s *qrServiceImpl) GenerateQR(merchantID string, amount float64) (dto.QRResponse, error) {
+	merchant, err := s.merchantRepository.GetMerchantByID(merchantID)
+	if err != nil {
+		return dto.QRResponse{}, err
+	}
+
+	if amount == 0 {
+		payload, err := qr.EncodeStatic(merchant)
+		if err != nil {
+			return dto.QRResponse{}, err
+		}
+		return dto.QRResponse{Payload: payload}, nil
+	}
+
+	payload, err := qr.EncodeDynamic(merchant, amount)
+	if err != nil {
+		return dto.QRResponse{}, fmt.Errorf("%w: %v", ErrInvalidQRAmount, err)
+	}
+	return dto.QRResponse{Payload: payload, Dynamic: true, Amount: amount}, nil
+}
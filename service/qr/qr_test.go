@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/qr"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRService_GenerateQR(t *testing.T) {
+	merchant := model.Merchant{ID: "merchant-001", Name: "Toko Budi", Category: "retail"}
+
+	tests := []struct {
+		name        string
+		amount      float64
+		wantDynamic bool
+	}{
+		{"static", 0, false},
+		{"dynamic", 25000.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchantRepository := new(MockMerchantRepository)
+			merchantRepository.On("GetMerchantByID", "merchant-001").Return(merchant, nil)
+			qrService := NewQRService(merchantRepository)
+
+			resp, err := qrService.GenerateQR("merchant-001", tt.amount)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDynamic, resp.Dynamic)
+			payload, err := qr.Parse(resp.Payload)
+			require.NoError(t, err)
+			assert.Equal(t, "merchant-001", payload.MerchantID)
+			assert.Equal(t, tt.wantDynamic, payload.Dynamic)
+			assert.Equal(t, tt.amount, payload.Amount)
+			merchantRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQRService_GenerateQR_InvalidAmount(t *testing.T) {
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByID", "merchant-001").Return(model.Merchant{ID: "merchant-001", Name: "Toko Budi"}, nil)
+	qrService := NewQRService(merchantRepository)
+
+	_, err := qrService.GenerateQR("merchant-001", 10.001)
+
+	assert.ErrorIs(t, err, ErrInvalidQRAmount)
+}
+
+func TestQRService_GenerateQR_MerchantNotFound(t *testing.T) {
+	merchantRepository := new(MockMerchantRepository)
+	merchantRepository.On("GetMerchantByID", "missing").Return(model.Merchant{}, errors.New("merchant not found by ID"))
+	qrService := NewQRService(merchantRepository)
+
+	_, err := qrService.GenerateQR("missing", 0)
+
+	assert.EqualError(t, err, "merchant not found by ID")
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"simple-golang-tdd/utils"
+	"sync"
+	"time"
+
+	customerRepo "simple-golang-tdd/repository/customer"
+	merchantRepo "simple-golang-tdd/repository/merchant"
+	scheduleRepo "simple-golang-tdd/repository/schedule"
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+const (
+	// MaxScheduleRetries is how many times an occurrence is retried after an
+	// insufficient balance before it is skipped.
+	MaxScheduleRetries = 3
+	// ScheduleRetryInterval is the wait between retries of an occurrence.
+	ScheduleRetryInterval = time.Hour
+)
+
+type ScheduleService interface {
+	CreateSchedule(request dto.ScheduleRequest, username string) (model.Schedule, error)
+	GetSchedules(username string) ([]model.Schedule, error)
+	GetSchedule(id string, username string) (model.Schedule, error)
+	UpdateSchedule(id string, request dto.ScheduleRequest, username string) (model.Schedule, error)
+	DeleteSchedule(id string, username string) error
+	RunDueSchedules() error
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type scheduleServiceImpl struct {
+	scheduleRepository scheduleRepo.ScheduleRepository
+	customerRepository customerRepo.CustomerRepository
+	merchantRepository merchantRepo.MerchantRepository
+	customerService    customerService.CustomerService
+	now                func() time.Time
+	mutex              sync.Mutex // serialises the customer's changes with saving the outcome of a run
+}
+
+// NewScheduleService creates the scheduler. now is its clock, so tests can move time forward.
+func NewScheduleService(scheduleRepository scheduleRepo.ScheduleRepository, customerRepository customerRepo.CustomerRepository, merchantRepository merchantRepo.MerchantRepository, customerService customerService.CustomerService, now func() time.Time) ScheduleService {
+	return &scheduleServiceImpl{
+		scheduleRepository: scheduleRepository,
+		customerRepository: customerRepository,
+		merchantRepository: merchantRepository,
+		customerService:    customerService,
+		now:                now}
+}
+
+func (s *scheduleServiceImpl) CreateSchedule(request dto.ScheduleRequest, username string) (model.Schedule, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	now := s.now().UTC()
+	if err := s.validateScheduleRequest(request, now); err != nil {
+		return model.Schedule{}, err
+	}
+
+	schedule := model.Schedule{
+		ID:         uuid.New().String(),
+		CustomerID: customer.ID,
+		Username:   customer.Username,
+		CreatedAt:  now,
+	}
+	applyScheduleRequest(&schedule, request, now)
+
+	schedule, err = s.scheduleRepository.CreateSchedule(schedule)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (s *scheduleServiceImpl) GetSchedules(username string) ([]model.Schedule, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	schedules, err := s.scheduleRepository.GetSchedulesByCustomerID(customer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+func (s *scheduleServiceImpl) GetSchedule(id string, username string) (model.Schedule, error) {
+	customer, err := s.customerRepository.GetUserByUsername(username)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	schedule, err := s.scheduleRepository.GetScheduleByID(id)
+	if err != nil || schedule.CustomerID != customer.ID {
+		return model.Schedule{}, ErrScheduleNotFound
+	}
+
+	return schedule, nil
+}
+
+// UpdateSchedule replaces the schedule's payment and timing and restarts it from request.StartAt.
+func (s *scheduleServiceImpl) UpdateSchedule(id string, request dto.ScheduleRequest, username string) (model.Schedule, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, err := s.GetSchedule(id, username)
+	if err != nil {
+		return model.Schedule{}, err
+	}
+
+	now := s.now().UTC()
+	if err := s.validateScheduleRequest(request, now); err != nil {
+		return model.Schedule{}, err
+	}
+
+	applyScheduleRequest(&schedule, request, now)
+
+	schedule, err = s.scheduleRepository.UpdateSchedule(schedule)
+	if err != nil {
+		return model.Schedule{}, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (s *scheduleServiceImpl) DeleteSchedule(id string, username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.GetSchedule(id, username); err != nil {
+		return err
+	}
+
+	if err := s.scheduleRepository.DeleteSchedule(id); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+
+	return nil
+}
+
+// RunDueSchedules pays every schedule that is due at the current time.
+func (s *scheduleServiceImpl) RunDueSchedules() error {
+	now := s.now().UTC()
+
+	schedules, err := s.scheduleRepository.GetDueSchedules(now)
+	if err != nil {
+		return fmt.Errorf("failed to get due schedules: %w", err)
+	}
+
+	var errs []error
+	for _, schedule := range schedules {
+		end := utils.BeginWrite()
+		if err := s.runSchedule(schedule, now); err != nil {
+			errs = append(errs, err)
+		}
+		end()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Start runs due schedules every interval until ctx is cancelled.
+func (s *scheduleServiceImpl) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RunDueSchedules(); err != nil {
+				log.Printf("Error running scheduled payments: %v", err)
+			}
+		}
+	}
+}
+
+// runSchedule makes the schedule's payment and moves it to its next run. An
+// occurrence that fails on insufficient balance is retried later; any other
+// failure skips it. The schedule is saved at its next run before paying, so a
+// crash or a failed save after the payment skips the occurrence instead of
+// paying it twice. The outcome is saved on the schedule as it is after the
+// payment: one the customer deleted in the meantime stays deleted, and one
+// they changed keeps its new timing.
+func (s *scheduleServiceImpl) runSchedule(schedule model.Schedule, now time.Time) error {
+	claimed := schedule
+	claimed.LastRunAt = &now
+	claimed.UpdatedAt = now
+	claimed.Advance(now)
+	if _, err := s.scheduleRepository.UpdateSchedule(claimed); err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", schedule.ID, err)
+	}
+
+	request := dto.PaymentRequest{
+		MerchantID: schedule.MerchantID,
+		Amount:     schedule.Amount,
+		Currency:   schedule.Currency,
+	}
+
+	payment, err := s.customerService.Payment(request, schedule.Username)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, getErr := s.scheduleRepository.GetScheduleByID(schedule.ID)
+	if getErr != nil {
+		return fmt.Errorf("failed to get schedule %s after its payment: %w", schedule.ID, getErr)
+	}
+	changed := !current.UpdatedAt.Equal(claimed.UpdatedAt)
+	if changed {
+		schedule = current
+	}
+	schedule.LastRunAt = &now
+	schedule.UpdatedAt = now
+
+	switch {
+	case changed && err != nil:
+		// Changed by the customer during the payment: keep their changes and
+		// only record what happened to the payment.
+		schedule.LastError = err.Error()
+	case changed:
+		schedule.LastPayment = payment.ID
+		schedule.LastError = ""
+	case err == nil:
+		schedule.LastPayment = payment.ID
+		schedule.LastError = ""
+		schedule.Advance(now)
+	case errors.Is(err, customerService.ErrInsufficientBalance) && schedule.Retries < MaxScheduleRetries:
+		schedule.Retries++
+		schedule.LastError = err.Error()
+		schedule.NextRunAt = now.Add(ScheduleRetryInterval)
+	case schedule.Frequency == model.ScheduleFrequencyOnce:
+		schedule.LastError = err.Error()
+		schedule.Status = model.ScheduleStatusFailed
+	default:
+		schedule.LastError = err.Error()
+		schedule.Advance(now)
+	}
+
+	if _, err := s.scheduleRepository.UpdateSchedule(schedule); err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", schedule.ID, err)
+	}
+
+	return nil
+}
+
+// validateScheduleRequest checks the request the way a payment would be
+// checked, so a schedule that can never be paid is rejected when it is made.
+func (s *scheduleServiceImpl) validateScheduleRequest(request dto.ScheduleRequest, now time.Time) error {
+	switch model.ScheduleFrequency(request.Frequency) {
+	case model.ScheduleFrequencyOnce, model.ScheduleFrequencyDaily, model.ScheduleFrequencyWeekly, model.ScheduleFrequencyMonthly:
+	default:
+		return fmt.Errorf("%w: unknown frequency %q", ErrInvalidSchedule, request.Frequency)
+	}
+
+	currency := request.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if err := customerService.ValidateAmount(request.Amount, currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	if !request.StartAt.After(now) {
+		return fmt.Errorf("%w: start_at must be in the future", ErrInvalidSchedule)
+	}
+
+	if request.EndAt != nil {
+		if request.Frequency == string(model.ScheduleFrequencyOnce) {
+			return fmt.Errorf("%w: end_at is only allowed for recurring schedules", ErrInvalidSchedule)
+		}
+		if request.EndAt.Before(request.StartAt) {
+			return fmt.Errorf("%w: end_at must not be before start_at", ErrInvalidSchedule)
+		}
+	}
+
+	if _, err := s.merchantRepository.GetMerchantByID(request.MerchantID); err != nil {
+		return fmt.Errorf("%w: merchant %s not found", ErrInvalidSchedule, request.MerchantID)
+	}
+
+	return nil
+}
+
+// applyScheduleRequest copies the request onto the schedule and (re)starts it at request.StartAt.
+func applyScheduleRequest(schedule *model.Schedule, request dto.ScheduleRequest, now time.Time) {
+	schedule.MerchantID = request.MerchantID
+	schedule.Amount = request.Amount
+	schedule.Currency = request.Currency
+	schedule.Frequency = model.ScheduleFrequency(request.Frequency)
+	schedule.StartAt = request.StartAt.UTC()
+	schedule.EndAt = nil
+	if request.EndAt != nil {
+		endAt := request.EndAt.UTC()
+		schedule.EndAt = &endAt
+	}
+	schedule.Status = model.ScheduleStatusActive
+	schedule.Occurrence = 0
+	schedule.NextRunAt = schedule.StartAt
+	schedule.Retries = 0
+	schedule.UpdatedAt = now
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"errors"
+	"simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"testing"
+	"time"
+
+	customerService "simple-golang-tdd/service/customer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeClock is a clock tests can move forward.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+type scheduleServiceMocks struct {
+	scheduleRepo    *MockScheduleRepository
+	customerRepo    *MockCustomerRepository
+	merchantRepo    *MockMerchantRepository
+	customerService *MockCustomerService
+	clock           *fakeClock
+}
+
+func setupScheduleService() (ScheduleService, scheduleServiceMocks) {
+	mocks := scheduleServiceMocks{
+		scheduleRepo:    new(MockScheduleRepository),
+		customerRepo:    new(MockCustomerRepository),
+		merchantRepo:    new(MockMerchantRepository),
+		customerService: new(MockCustomerService),
+		clock:           &fakeClock{now: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)},
+	}
+	service := NewScheduleService(mocks.scheduleRepo, mocks.customerRepo, mocks.merchantRepo, mocks.customerService, mocks.clock.Now)
+	return service, mocks
+}
+
+// Helper function untuk menyimpan setiap schedule yang di-update di mock
+// repository, sehingga GetScheduleByID membaca ulang versi terakhir.
+func storeSchedules(mocks scheduleServiceMocks) {
+	var stored model.Schedule
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(model.Schedule)
+	}).Return(model.Schedule{}, nil)
+	call := mocks.scheduleRepo.On("GetScheduleByID", "sched-001")
+	call.Run(func(args mock.Arguments) {
+		call.ReturnArguments = mock.Arguments{stored, nil}
+	})
+}
+
+var fakeCustomer = model.Customer{ID: "cust-001", Username: "john_doe", Balance: 1000}
+
+var fakeMerchant = model.Merchant{ID: "merchant-001", Currency: "IDR"}
+
+func fakeSchedule(frequency model.ScheduleFrequency, startAt time.Time) model.Schedule {
+	return model.Schedule{
+		ID:         "sched-001",
+		CustomerID: fakeCustomer.ID,
+		Username:   fakeCustomer.Username,
+		MerchantID: "merchant-001",
+		Amount:     100,
+		Frequency:  frequency,
+		StartAt:    startAt,
+		Status:     model.ScheduleStatusActive,
+		NextRunAt:  startAt,
+	}
+}
+
+func TestCreateSchedule_Success(t *testing.T) {
+	service, mocks := setupScheduleService()
+	startAt := mocks.clock.Now().Add(time.Hour)
+	request := dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "monthly", StartAt: startAt}
+
+	mocks.customerRepo.On("GetUserByUsername", "john_doe").Return(fakeCustomer, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-001").Return(fakeMerchant, nil)
+	mocks.scheduleRepo.On("CreateSchedule", mock.AnythingOfType("model.Schedule")).Return(model.Schedule{ID: "sched-001"}, nil)
+
+	created, err := service.CreateSchedule(request, "john_doe")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sched-001", created.ID)
+	schedule := mocks.scheduleRepo.Calls[0].Arguments.Get(0).(model.Schedule)
+	assert.NotEmpty(t, schedule.ID)
+	assert.Equal(t, fakeCustomer.ID, schedule.CustomerID)
+	assert.Equal(t, model.ScheduleStatusActive, schedule.Status)
+	assert.Equal(t, startAt, schedule.NextRunAt)
+	assert.Equal(t, 0, schedule.Occurrence)
+}
+
+func TestCreateSchedule_InvalidRequest(t *testing.T) {
+	now := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	beforeStart := now.Add(30 * time.Minute)
+
+	tests := []struct {
+		name    string
+		request dto.ScheduleRequest
+	}{
+		{"unknown frequency", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "yearly", StartAt: future}},
+		{"non-positive amount", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 0, Frequency: "daily", StartAt: future}},
+		{"too many decimals", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100.123, Frequency: "daily", StartAt: future}},
+		{"unsupported currency", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Currency: "XYZ", Frequency: "daily", StartAt: future}},
+		{"unknown merchant", dto.ScheduleRequest{MerchantID: "merchant-404", Amount: 100, Frequency: "daily", StartAt: future}},
+		{"start in the past", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "daily", StartAt: past}},
+		{"end before start", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "daily", StartAt: future, EndAt: &beforeStart}},
+		{"end on one-off schedule", dto.ScheduleRequest{MerchantID: "merchant-001", Amount: 100, Frequency: "once", StartAt: future, EndAt: &future}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mocks := setupScheduleService()
+			mocks.customerRepo.On("GetUserByUsername", "john_doe").Return(fakeCustomer, nil)
+			mocks.merchantRepo.On("GetMerchantByID", "merchant-001").Return(fakeMerchant, nil)
+			mocks.merchantRepo.On("GetMerchantByID", "merchant-404").Return(model.Merchant{}, errors.New("merchant not found by ID"))
+
+			_, err := service.CreateSchedule(tt.request, "john_doe")
+
+			assert.ErrorIs(t, err, ErrInvalidSchedule)
+			mocks.scheduleRepo.AssertNotCalled(t, "CreateSchedule", mock.Anything)
+		})
+	}
+}
+
+func TestGetSchedule_OtherCustomer(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+	schedule.CustomerID = "cust-002"
+
+	mocks.customerRepo.On("GetUserByUsername", "john_doe").Return(fakeCustomer, nil)
+	mocks.scheduleRepo.On("GetScheduleByID", "sched-001").Return(schedule, nil)
+
+	_, err := service.GetSchedule("sched-001", "john_doe")
+
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+}
+
+func TestUpdateSchedule_RestartsSchedule(t *testing.T) {
+	service, mocks := setupScheduleService()
+	existing := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now().Add(-48*time.Hour))
+	existing.Occurrence = 2
+	existing.Retries = 1
+	startAt := mocks.clock.Now().Add(24 * time.Hour)
+	request := dto.ScheduleRequest{MerchantID: "merchant-002", Amount: 250, Frequency: "weekly", StartAt: startAt}
+
+	mocks.customerRepo.On("GetUserByUsername", "john_doe").Return(fakeCustomer, nil)
+	mocks.scheduleRepo.On("GetScheduleByID", "sched-001").Return(existing, nil)
+	mocks.merchantRepo.On("GetMerchantByID", "merchant-002").Return(model.Merchant{ID: "merchant-002"}, nil)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Return(model.Schedule{}, nil)
+
+	_, err := service.UpdateSchedule("sched-001", request, "john_doe")
+
+	assert.NoError(t, err)
+	schedule := mocks.scheduleRepo.Calls[1].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, "merchant-002", schedule.MerchantID)
+	assert.Equal(t, model.ScheduleFrequencyWeekly, schedule.Frequency)
+	assert.Equal(t, startAt, schedule.NextRunAt)
+	assert.Equal(t, 0, schedule.Occurrence)
+	assert.Equal(t, 0, schedule.Retries)
+}
+
+func TestDeleteSchedule_NotFound(t *testing.T) {
+	service, mocks := setupScheduleService()
+
+	mocks.customerRepo.On("GetUserByUsername", "john_doe").Return(fakeCustomer, nil)
+	mocks.scheduleRepo.On("GetScheduleByID", "sched-404").Return(model.Schedule{}, errors.New("schedule not found by ID"))
+
+	err := service.DeleteSchedule("sched-404", "john_doe")
+
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	mocks.scheduleRepo.AssertNotCalled(t, "DeleteSchedule", mock.Anything)
+}
+
+func TestRunDueSchedules_RecurringAdvance(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency model.ScheduleFrequency
+		startAt   time.Time
+		nextRunAt time.Time
+	}{
+		{"daily", model.ScheduleFrequencyDaily, time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"weekly", model.ScheduleFrequencyWeekly, time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 9, 0, 0, 0, time.UTC)},
+		{"monthly clamps to end of month", model.ScheduleFrequencyMonthly, time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mocks := setupScheduleService()
+			schedule := fakeSchedule(tt.frequency, tt.startAt)
+			payment := model.NewPayment("pay-001", fakeCustomer.ID, "merchant-001", 100, mocks.clock.Now())
+
+			mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+			mocks.customerService.On("Payment", dto.PaymentRequest{MerchantID: "merchant-001", Amount: 100}, "john_doe").Return(payment, nil)
+			storeSchedules(mocks)
+
+			err := service.RunDueSchedules()
+
+			assert.NoError(t, err)
+			updated := mocks.scheduleRepo.Calls[3].Arguments.Get(0).(model.Schedule)
+			assert.Equal(t, tt.nextRunAt, updated.NextRunAt)
+			assert.Equal(t, 1, updated.Occurrence)
+			assert.Equal(t, "pay-001", updated.LastPayment)
+			assert.Equal(t, model.ScheduleStatusActive, updated.Status)
+		})
+	}
+}
+
+func TestRunDueSchedules_SavesNextRunBeforePaying(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Run(func(args mock.Arguments) {
+		// Saat schedule disimpan pertama kali, pembayaran belum dilakukan.
+		if len(mocks.scheduleRepo.Calls) == 2 {
+			mocks.customerService.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+		}
+	}).Return(model.Schedule{}, nil)
+	get := mocks.scheduleRepo.On("GetScheduleByID", "sched-001")
+	get.Run(func(args mock.Arguments) {
+		get.ReturnArguments = mock.Arguments{mocks.scheduleRepo.Calls[1].Arguments.Get(0), nil}
+	})
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{ID: "pay-001"}, nil)
+
+	err := service.RunDueSchedules()
+
+	assert.NoError(t, err)
+	claimed := mocks.scheduleRepo.Calls[1].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, 1, claimed.Occurrence)
+	assert.Equal(t, time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC), claimed.NextRunAt)
+}
+
+func TestRunDueSchedules_SkipsMissedOccurrences(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+	mocks.clock.Advance(72*time.Hour + time.Minute)
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{ID: "pay-001"}, nil)
+	storeSchedules(mocks)
+
+	err := service.RunDueSchedules()
+
+	assert.NoError(t, err)
+	mocks.customerService.AssertNumberOfCalls(t, "Payment", 1)
+	updated := mocks.scheduleRepo.Calls[3].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, 4, updated.Occurrence)
+	assert.Equal(t, time.Date(2024, 2, 4, 9, 0, 0, 0, time.UTC), updated.NextRunAt)
+}
+
+func TestRunDueSchedules_CompletesAfterEndAt(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+	endAt := mocks.clock.Now().Add(12 * time.Hour)
+	schedule.EndAt = &endAt
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{ID: "pay-001"}, nil)
+	storeSchedules(mocks)
+
+	err := service.RunDueSchedules()
+
+	assert.NoError(t, err)
+	updated := mocks.scheduleRepo.Calls[3].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, model.ScheduleStatusCompleted, updated.Status)
+}
+
+func TestRunDueSchedules_RetriesOnInsufficientBalance(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyOnce, mocks.clock.Now())
+
+	// Every retry fails until they are used up, after which the one-off schedule fails.
+	current := schedule
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{}, customerService.ErrInsufficientBalance)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Run(func(args mock.Arguments) {
+		current = args.Get(0).(model.Schedule)
+	}).Return(model.Schedule{}, nil)
+	get := mocks.scheduleRepo.On("GetScheduleByID", "sched-001")
+	get.Run(func(args mock.Arguments) {
+		get.ReturnArguments = mock.Arguments{current, nil}
+	})
+
+	for attempt := 1; attempt <= MaxScheduleRetries; attempt++ {
+		mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{current}, nil).Once()
+		assert.NoError(t, service.RunDueSchedules())
+		assert.Equal(t, attempt, current.Retries)
+		assert.Equal(t, model.ScheduleStatusActive, current.Status)
+		assert.Equal(t, mocks.clock.Now().Add(ScheduleRetryInterval), current.NextRunAt)
+		mocks.clock.Advance(ScheduleRetryInterval)
+	}
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{current}, nil).Once()
+	assert.NoError(t, service.RunDueSchedules())
+	assert.Equal(t, model.ScheduleStatusFailed, current.Status)
+	assert.Equal(t, customerService.ErrInsufficientBalance.Error(), current.LastError)
+	mocks.customerService.AssertNumberOfCalls(t, "Payment", MaxScheduleRetries+1)
+}
+
+func TestRunDueSchedules_OtherErrorSkipsOccurrence(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyWeekly, mocks.clock.Now())
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{}, errors.New("failed to get merchant"))
+	storeSchedules(mocks)
+
+	err := service.RunDueSchedules()
+
+	assert.NoError(t, err)
+	updated := mocks.scheduleRepo.Calls[3].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, 0, updated.Retries)
+	assert.Equal(t, 1, updated.Occurrence)
+	assert.Equal(t, "failed to get merchant", updated.LastError)
+	assert.Equal(t, model.ScheduleStatusActive, updated.Status)
+}
+
+func TestRunDueSchedules_KeepsChangesMadeDuringPayment(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+	changed := fakeSchedule(model.ScheduleFrequencyWeekly, mocks.clock.Now().Add(48*time.Hour))
+	changed.Amount = 250
+	changed.UpdatedAt = mocks.clock.Now().Add(time.Second)
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Return(model.Schedule{}, nil)
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{ID: "pay-001"}, nil)
+	mocks.scheduleRepo.On("GetScheduleByID", "sched-001").Return(changed, nil)
+
+	err := service.RunDueSchedules()
+
+	assert.NoError(t, err)
+	updated := mocks.scheduleRepo.Calls[3].Arguments.Get(0).(model.Schedule)
+	assert.Equal(t, model.ScheduleFrequencyWeekly, updated.Frequency)
+	assert.Equal(t, 250.0, updated.Amount)
+	assert.Equal(t, 0, updated.Occurrence)
+	assert.Equal(t, changed.NextRunAt, updated.NextRunAt)
+	assert.Equal(t, "pay-001", updated.LastPayment)
+}
+
+func TestRunDueSchedules_DeletedDuringPayment(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyDaily, mocks.clock.Now())
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Return(model.Schedule{}, nil)
+	mocks.customerService.On("Payment", mock.Anything, "john_doe").Return(model.Payment{ID: "pay-001"}, nil)
+	mocks.scheduleRepo.On("GetScheduleByID", "sched-001").Return(model.Schedule{}, errors.New("schedule not found by ID"))
+
+	err := service.RunDueSchedules()
+
+	assert.EqualError(t, err, "failed to get schedule sched-001 after its payment: schedule not found by ID")
+	mocks.scheduleRepo.AssertNumberOfCalls(t, "UpdateSchedule", 1)
+}
+
+func TestRunDueSchedules_UpdateError(t *testing.T) {
+	service, mocks := setupScheduleService()
+	schedule := fakeSchedule(model.ScheduleFrequencyOnce, mocks.clock.Now())
+
+	mocks.scheduleRepo.On("GetDueSchedules", mocks.clock.Now()).Return([]model.Schedule{schedule}, nil)
+	mocks.scheduleRepo.On("UpdateSchedule", mock.AnythingOfType("model.Schedule")).Return(model.Schedule{}, errors.New("error while updating schedule"))
+
+	err := service.RunDueSchedules()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sched-001")
+	mocks.customerService.AssertNotCalled(t, "Payment", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// envelopeEncryption names the cipher in every envelope, so a file or journal
+// record can be told apart from plain JSON.
+const envelopeEncryption = "AES-256-GCM"
+
+var (
+	// ErrUnknownKey is returned when data was encrypted with a key that is not
+	// in the keyring.
+	ErrUnknownKey = errors.New("unknown data encryption key")
+	// ErrDecrypt is returned when encrypted data does not decrypt, because it
+	// was changed or the key with its ID is not the key it was encrypted with.
+	ErrDecrypt = errors.New("data does not decrypt")
+)
+
+// envelope is encrypted JSON stored as JSON. The data is encrypted with a
+// data key made for this envelope alone, which is stored wrapped (encrypted)
+// with the keyring key named by KeyID. The key ID is authenticated with the
+// wrapped key and the file name with the data, so neither can be changed, nor
+// the envelope copied into another file, without failing decryption.
+type envelope struct {
+	Encryption string `json:"encryption"`
+	KeyID      string `json:"key_id"`
+	KeyNonce   []byte `json:"key_nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// dataKeySize is the size of the AES-256 data key of each envelope.
+const dataKeySize = 32
+
+// Keyring holds the data encryption keys by ID. Data is encrypted with the
+// current key and decrypted with whichever key its envelope names, so a key
+// can be rotated by making a new key current while the old one still reads
+// the data encrypted before.
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// ParseKeyring parses keys written as "<id>:<base64 key>", separated by
+// commas or newlines. Every key is 32 bytes. The first key is the current one;
+// blank lines and lines starting with # are skipped.
+func ParseKeyring(spec string) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(line, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" || strings.ContainsAny(id, " \t") {
+			return nil, errors.New("data encryption key must be written as <id>:<base64 key>")
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate data encryption key %s", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("data encryption key %s is not valid base64: %v", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("data encryption key %s must be 32 bytes, got %d", id, len(key))
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id] = aead
+		if keyring.currentID == "" {
+			keyring.currentID = id
+		}
+	}
+
+	if keyring.currentID == "" {
+		return nil, errors.New("no data encryption key given")
+	}
+	return keyring, nil
+}
+
+// LoadKeyring parses the keyring in keyFile or, without a key file, in keys.
+// It returns a nil keyring when neither is set.
+func LoadKeyring(keyFile string, keys string) (*Keyring, error) {
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return ParseKeyring(string(content))
+	}
+	if keys != "" {
+		return ParseKeyring(keys)
+	}
+	return nil, nil
+}
+
+// CurrentKeyID returns the ID of the key new data is encrypted with.
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext, to be stored in the file at path, with a new data
+// key wrapped by the current key.
+func (k *Keyring) seal(path string, plaintext []byte) (envelope, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return envelope{}, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return envelope{}, err
+	}
+	master := k.keys[k.currentID]
+	keyNonce := make([]byte, master.NonceSize())
+	if _, err := rand.Read(keyNonce); err != nil {
+		return envelope{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return envelope{}, err
+	}
+	return envelope{
+		Encryption: envelopeEncryption,
+		KeyID:      k.currentID,
+		KeyNonce:   keyNonce,
+		WrappedKey: master.Seal(nil, keyNonce, dataKey, []byte(k.currentID)),
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(filepath.Base(path))),
+	}, nil
+}
+
+// open unwraps the data key of e and decrypts the data, which must have been
+// sealed for a file with the same name as path.
+func (k *Keyring) open(path string, e envelope) ([]byte, error) {
+	var master cipher.AEAD
+	if k != nil {
+		master = k.keys[e.KeyID]
+	}
+	if master == nil {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, e.KeyID)
+	}
+	if len(e.KeyNonce) != master.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := master.Open(nil, e.KeyNonce, e.WrappedKey, []byte(e.KeyID))
+	if err != nil || len(dataKey) != dataKeySize {
+		return nil, ErrDecrypt
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, []byte(filepath.Base(path)))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// encryption is the keyring and the files encrypted with it, shared by every
+// repository that reads and writes JSON files.
+var encryption struct {
+	sync.RWMutex
+	keyring *Keyring
+	paths   map[string]bool
+}
+
+// EncryptedFiles are the files in the data directory kept encrypted at rest.
+var EncryptedFiles = []string{"customers.json", "merchants.json"}
+
+// EncryptedPaths returns the paths of EncryptedFiles in dataDir.
+func EncryptedPaths(dataDir string) []string {
+	paths := make([]string, 0, len(EncryptedFiles))
+	for _, name := range EncryptedFiles {
+		paths = append(paths, filepath.Join(dataDir, name))
+	}
+	return paths
+}
+
+// SetEncryption makes SaveJSONFile encrypt the files at paths, and journals
+// opened next to them, with the current key of keyring. LoadJSONFile and
+// journal replay decrypt data encrypted with any key in keyring, whatever its
+// path, and still read plain JSON, so files are encrypted on their next save.
+// A nil keyring turns encryption off.
+func SetEncryption(keyring *Keyring, paths ...string) {
+	encryption.Lock()
+	defer encryption.Unlock()
+
+	encryption.keyring = keyring
+	encryption.paths = map[string]bool{}
+	if keyring == nil {
+		return
+	}
+	for _, path := range paths {
+		encryption.paths[filepath.Clean(path)] = true
+		encryption.paths[filepath.Clean(JournalPath(path))] = true
+	}
+}
+
+// encryptionKeyring returns the keyring to encrypt the file at path with, or
+// nil when it is stored as plain JSON.
+func encryptionKeyring(path string) *Keyring {
+	encryption.RLock()
+	defer encryption.RUnlock()
+
+	if !encryption.paths[filepath.Clean(path)] {
+		return nil
+	}
+	return encryption.keyring
+}
+
+// encrypt returns data, to be stored in the file at path, encrypted with
+// keyring as an envelope, or data itself for a nil keyring.
+func encrypt(keyring *Keyring, path string, data []byte) ([]byte, error) {
+	if keyring == nil {
+		return data, nil
+	}
+	e, err := keyring.seal(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// decrypt returns the plain JSON in data, read from the file at path, which is
+// either plain JSON or an envelope encrypted with a key of the configured
+// keyring.
+func decrypt(path string, data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return data, nil
+	}
+	var e envelope
+	if err := json.Unmarshal(trimmed, &e); err != nil || e.Encryption == "" {
+		return data, nil
+	}
+	if e.Encryption != envelopeEncryption {
+		return nil, fmt.Errorf("unsupported data encryption %s", e.Encryption)
+	}
+
+	encryption.RLock()
+	keyring := encryption.keyring
+	encryption.RUnlock()
+	return keyring.open(path, e)
+}
+
+// ReencryptFile rewrites the JSON file at path and its journal, if any, with
+// the current key, or as plain JSON when path is not encrypted. Nothing else
+// may write either file meanwhile.
+func ReencryptFile(path string) error {
+	var data json.RawMessage
+	if err := LoadJSONFile(path, &data); err != nil {
+		return err
+	}
+	if err := SaveJSONFile(path, data); err != nil {
+		return err
+	}
+
+	journalPath := JournalPath(path)
+	if _, err := os.Stat(journalPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	var records []json.RawMessage
+	err := ReplayJournal(journalPath, func(record json.RawMessage) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keyring := encryptionKeyring(journalPath)
+	var content bytes.Buffer
+	for _, record := range records {
+		line, err := encrypt(keyring, journalPath, record)
+		if err != nil {
+			return err
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+	return WriteFileAtomic(journalPath, func(w io.Writer) error {
+		_, err := w.Write(content.Bytes())
+		return err
+	})
+}
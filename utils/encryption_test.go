@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	newKey = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+// setupEncryption encrypts path with a keyring parsed from spec until the test ends.
+func setupEncryption(t *testing.T, spec string, path string) {
+	keyring, err := ParseKeyring(spec)
+	require.NoError(t, err)
+	SetEncryption(keyring, path)
+	t.Cleanup(func() { SetEncryption(nil) })
+}
+
+// readEnvelope reads the envelope saved at path.
+func readEnvelope(t *testing.T, path string) envelope {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "cust-001")
+	var e envelope
+	require.NoError(t, json.Unmarshal(content, &e))
+	return e
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestSaveJSONFile_Encrypted(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+
+	// The plain file written before encryption was turned on still loads.
+	var customers []map[string]interface{}
+	require.NoError(t, LoadJSONFile(path, &customers))
+	require.NoError(t, SaveJSONFile(path, customers))
+
+	e := readEnvelope(t, path)
+	assert.Equal(t, "AES-256-GCM", e.Encryption)
+	assert.Equal(t, "k1", e.KeyID)
+	var saved []map[string]interface{}
+	require.NoError(t, LoadJSONFile(path, &saved))
+	assert.Equal(t, customers, saved)
+	assertNoTempFiles(t, path)
+}
+
+func TestSaveJSONFile_NewDataKeyPerSave(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	first := readEnvelope(t, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	second := readEnvelope(t, path)
+
+	assert.NotEmpty(t, first.WrappedKey)
+	assert.NotEqual(t, first.WrappedKey, second.WrappedKey)
+	var saved []string
+	require.NoError(t, LoadJSONFile(path, &saved))
+	assert.Equal(t, []string{"cust-001"}, saved)
+}
+
+func TestJournal_Encrypted(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	setupEncryption(t, "k1:"+oldKey, strings.TrimSuffix(path, ".journal"))
+	journal, _ := openJournal(t, path)
+
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-001", Balance: 900}))
+
+	e := readEnvelope(t, path)
+	assert.Equal(t, "k1", e.KeyID)
+	_, replayed := openJournal(t, path)
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 900}}, replayed)
+}
+
+func TestReencryptFile_RotatesKey(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []journalRecord{{ID: "cust-001", Balance: 1000}}))
+	journal, _ := openJournal(t, JournalPath(path))
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-001", Balance: 900}))
+	require.NoError(t, journal.Close())
+
+	// k2 becomes current while k1 still reads the data encrypted before.
+	setupEncryption(t, "k2:"+newKey+"\nk1:"+oldKey, path)
+	require.NoError(t, ReencryptFile(path))
+
+	assert.Equal(t, "k2", readEnvelope(t, path).KeyID)
+	assert.Equal(t, "k2", readEnvelope(t, JournalPath(path)).KeyID)
+	setupEncryption(t, "k2:"+newKey, path)
+	var saved []journalRecord
+	require.NoError(t, LoadJSONFile(path, &saved))
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 1000}}, saved)
+	_, replayed := openJournal(t, JournalPath(path))
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 900}}, replayed)
+}
+
+func TestLoadKeyring_NotConfigured(t *testing.T) {
+	keyring, err := LoadKeyring("", "")
+
+	assert.NoError(t, err)
+	assert.Nil(t, keyring)
+}
+
+func TestLoadKeyring_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "data.keys")
+	content := "# newest key first\nk2:" + newKey + "\n\nk1:" + oldKey + "\n"
+	require.NoError(t, os.WriteFile(keyFile, []byte(content), 0600))
+
+	keyring, err := LoadKeyring(keyFile, "k3:"+oldKey)
+
+	require.NoError(t, err)
+	assert.Equal(t, "k2", keyring.CurrentKeyID())
+	assert.Len(t, keyring.keys, 2)
+}
+
+// ========== ERROR CASES ==========
+
+func TestLoadJSONFile_UnknownKey(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	setupEncryption(t, "k2:"+newKey, path)
+
+	var saved []string
+	err := LoadJSONFile(path, &saved)
+
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.EqualError(t, err, "error while decrypting "+path+": unknown data encryption key k1")
+}
+
+func TestLoadJSONFile_NoKeyring(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	SetEncryption(nil)
+
+	var saved []string
+	err := LoadJSONFile(path, &saved)
+
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestLoadJSONFile_TamperedCiphertext(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	e := readEnvelope(t, path)
+	e.Ciphertext[0] ^= 0xff
+	content, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	var saved []string
+	err = LoadJSONFile(path, &saved)
+
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestLoadJSONFile_ChangedKeyID(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	e := readEnvelope(t, path)
+	e.KeyID = "k2"
+	content, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	// The same key under another ID must not decrypt it either.
+	setupEncryption(t, "k2:"+oldKey, path)
+
+	var saved []string
+	err = LoadJSONFile(path, &saved)
+
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestLoadJSONFile_TamperedWrappedKey(t *testing.T) {
+	path := setupFile(t)
+	setupEncryption(t, "k1:"+oldKey, path)
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	e := readEnvelope(t, path)
+	e.WrappedKey[0] ^= 0xff
+	content, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	var saved []string
+	err = LoadJSONFile(path, &saved)
+
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestLoadJSONFile_CopiedToAnotherFile(t *testing.T) {
+	path := setupFile(t)
+	other := filepath.Join(filepath.Dir(path), "merchants.json")
+	keyring, err := ParseKeyring("k1:" + oldKey)
+	require.NoError(t, err)
+	SetEncryption(keyring, path, other)
+	t.Cleanup(func() { SetEncryption(nil) })
+	require.NoError(t, SaveJSONFile(path, []string{"cust-001"}))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(other, content, 0644))
+
+	var saved []string
+	err = LoadJSONFile(other, &saved)
+
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"":                               "no data encryption key given",
+		"k1":                             "data encryption key must be written as <id>:<base64 key>",
+		"k1:not-base64!":                 "data encryption key k1 is not valid base64: illegal base64 data at input byte 3",
+		"k1:" + oldKey[:8]:               "data encryption key k1 must be 32 bytes, got 6",
+		"k1:" + oldKey + ",k1:" + newKey: "duplicate data encryption key k1",
+	}
+	for spec, expected := range tests {
+		keyring, err := ParseKeyring(spec)
+
+		assert.EqualError(t, err, expected, spec)
+		assert.Nil(t, keyring)
+	}
+}
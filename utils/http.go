@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"errors"
+	"net/http"
 	dto "simple-golang-tdd/dto"
+	"simple-golang-tdd/model"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +20,17 @@ func ErrorResponse(c *gin.Context, status int, message string) {
 	c.JSON(status, response)
 }
 
+func ErrorCodeResponse(c *gin.Context, status int, code string, message string) {
+
+	response := dto.ErrorResponse{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+
+	c.JSON(status, response)
+}
+
 func SuccessResponse(c *gin.Context, status int, message string, data interface{}) {
 
 	response := dto.SuccessResponse{
@@ -26,3 +41,43 @@ func SuccessResponse(c *gin.Context, status int, message string, data interface{
 
 	c.JSON(status, response)
 }
+
+// SetETag sets the ETag of the response to version, the version of the
+// customer or merchant it returns, for the client to send back in If-Match.
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// RequireIfMatchVersion returns the version in the If-Match header of the
+// request, to update the record with as its expected version. It replies 428
+// when the header is missing and 400 when it is not an ETag set by SetETag,
+// and returns false.
+func RequireIfMatchVersion(c *gin.Context) (int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		ErrorResponse(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return 0, false
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, "invalid If-Match header")
+		return 0, false
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 0 {
+		ErrorResponse(c, http.StatusBadRequest, "invalid If-Match header")
+		return 0, false
+	}
+	return version, true
+}
+
+// ConflictResponse replies 412 and returns true when err is a
+// model.ErrConflict, an update made with an If-Match version the record is no
+// longer at.
+func ConflictResponse(c *gin.Context, err error) bool {
+	if !errors.Is(err, model.ErrConflict) {
+		return false
+	}
+	ErrorResponse(c, http.StatusPreconditionFailed, err.Error())
+	return true
+}
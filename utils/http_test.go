@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simple-golang-tdd/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Helper function untuk membuat context request dengan header If-Match
+func setupIfMatchContext(ifMatch string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+	if ifMatch != "" {
+		c.Request.Header.Set("If-Match", ifMatch)
+	}
+	return c, w
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestSetETag_Success(t *testing.T) {
+	c, w := setupIfMatchContext("")
+
+	SetETag(c, 3)
+
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func TestRequireIfMatchVersion_Success(t *testing.T) {
+	c, _ := setupIfMatchContext(`"3"`)
+
+	version, ok := RequireIfMatchVersion(c)
+
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), version)
+	assert.False(t, c.Writer.Written())
+}
+
+func TestConflictResponse_Conflict(t *testing.T) {
+	c, w := setupIfMatchContext(`"3"`)
+	err := &model.ConflictError{Record: "customer", ID: "cust-001", Expected: 3, Actual: 4}
+
+	handled := ConflictResponse(c, err)
+
+	assert.True(t, handled)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.JSONEq(t, `{"status": 412, "message": "customer cust-001 was changed by another update: expected version 3, found 4"}`, w.Body.String())
+}
+
+// ========== ERROR CASES ==========
+
+func TestRequireIfMatchVersion_Missing(t *testing.T) {
+	c, w := setupIfMatchContext("")
+
+	_, ok := RequireIfMatchVersion(c)
+
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.JSONEq(t, `{"status": 428, "message": "If-Match header is required"}`, w.Body.String())
+}
+
+func TestRequireIfMatchVersion_Invalid(t *testing.T) {
+	for _, header := range []string{"3", `W/"3"`, `"abc"`, `"-1"`} {
+		c, w := setupIfMatchContext(header)
+
+		_, ok := RequireIfMatchVersion(c)
+
+		assert.False(t, ok, header)
+		assert.Equal(t, http.StatusBadRequest, w.Code, header)
+		assert.JSONEq(t, `{"status": 400, "message": "invalid If-Match header"}`, w.Body.String(), header)
+	}
+}
+
+func TestConflictResponse_OtherError(t *testing.T) {
+	c, _ := setupIfMatchContext(`"3"`)
+
+	handled := ConflictResponse(c, errors.New("customer not found"))
+
+	assert.False(t, handled)
+	assert.False(t, c.Writer.Written())
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// JournalCompactThreshold is the number of records after which a journal
+// should be compacted into its snapshot file.
+const JournalCompactThreshold = 500
+
+// Journal is an append-only log of JSON records kept next to a JSON snapshot
+// file. Each record is one line and is fsynced before Append returns, so an
+// update costs one small write instead of rewriting the whole snapshot.
+type Journal struct {
+	file    *os.File
+	path    string
+	keyring *Keyring
+	size    int64
+	records int
+}
+
+// JournalPath returns the path of the journal kept next to the snapshot at
+// filePath.
+func JournalPath(filePath string) string {
+	return filePath + ".journal"
+}
+
+// OpenJournal replays the journal at path, calling apply with each record in
+// the order it was appended, and opens it for appending. The journal is
+// created when it does not exist. A torn last record left by a crash
+// mid-append is dropped; any other record that is not valid JSON is an error.
+// Replayed records are migrated like the snapshot (see jsonMigrations), and
+// records are encrypted when path is set up for it (see SetEncryption).
+func OpenJournal(path string, apply func(record json.RawMessage) error) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{file: file, path: path, keyring: encryptionKeyring(path)}
+	if err := journal.replay(migrateJournal(path, apply)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return journal, nil
+}
+
+func (j *Journal) replay(apply func(record json.RawMessage) error) error {
+	size, records, err := replayJournal(j.file, j.path, apply)
+	if err != nil {
+		return err
+	}
+	j.records = records
+	// Whatever follows the last complete record was never fully written.
+	return j.truncate(size)
+}
+
+// ReplayJournal calls apply with each record of the journal at path like
+// OpenJournal, but only reads it: a missing journal has no records and a torn
+// last record, which may still be being written, is skipped.
+func ReplayJournal(path string, apply func(record json.RawMessage) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, _, err = replayJournal(file, path, migrateJournal(path, apply))
+	return err
+}
+
+// SnapshotStamp identifies what is on disk in a JSON snapshot file and its
+// journal by their sizes and modification times. Every append, compaction or
+// restore changes it, so a reader that does not own the files can tell when
+// to load them again.
+type SnapshotStamp struct {
+	snapshot fileStamp
+	journal  fileStamp
+}
+
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+// StampSnapshot returns the SnapshotStamp of the snapshot at filePath and its
+// journal. A missing journal stamps as empty.
+func StampSnapshot(filePath string) (SnapshotStamp, error) {
+	snapshot, err := stampFile(filePath)
+	if err != nil {
+		return SnapshotStamp{}, err
+	}
+	journal, err := stampFile(JournalPath(filePath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return SnapshotStamp{}, err
+	}
+	return SnapshotStamp{snapshot: snapshot, journal: journal}, nil
+}
+
+func stampFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}, nil
+}
+
+// migrateJournal returns apply for records migrated like the snapshot the
+// journal at path is kept next to.
+func migrateJournal(path string, apply func(record json.RawMessage) error) func(record json.RawMessage) error {
+	snapshotPath := strings.TrimSuffix(path, ".journal")
+	return func(record json.RawMessage) error {
+		migrated, err := migrateJournalRecord(snapshotPath, record)
+		if err != nil {
+			return err
+		}
+		return apply(migrated)
+	}
+}
+
+// replayJournal applies every complete record read from r, the journal at
+// path, and returns the size in bytes and the number of those records.
+func replayJournal(r io.Reader, path string, apply func(record json.RawMessage) error) (size int64, records int, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, records, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		record := bytes.TrimSpace(line)
+		if !json.Valid(record) {
+			return 0, 0, fmt.Errorf("invalid journal record %d", records+1)
+		}
+		record, err = decrypt(path, record)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error while decrypting journal record %d: %w", records+1, err)
+		}
+		if err := apply(record); err != nil {
+			return 0, 0, fmt.Errorf("error while replaying journal record %d: %v", records+1, err)
+		}
+		size += int64(len(line))
+		records++
+	}
+}
+
+// Append writes record as one JSON line and fsyncs it. A failed append is cut
+// off again so the next record starts on a line of its own.
+func (j *Journal) Append(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line, err = encrypt(j.keyring, j.path, line)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := j.file.Write(line); err != nil {
+		j.truncate(j.size)
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		j.truncate(j.size)
+		return err
+	}
+
+	j.size += int64(len(line))
+	j.records++
+	return nil
+}
+
+// Len returns the number of records in the journal.
+func (j *Journal) Len() int {
+	return j.records
+}
+
+// Reset empties the journal once its records are saved in the snapshot.
+func (j *Journal) Reset() error {
+	if err := j.truncate(0); err != nil {
+		return err
+	}
+	j.records = 0
+	return nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+func (j *Journal) truncate(size int64) error {
+	if err := j.file.Truncate(size); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.size = size
+	return nil
+}
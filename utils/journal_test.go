@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type journalRecord struct {
+	ID      string  `json:"id"`
+	Balance float64 `json:"balance"`
+}
+
+// openJournal opens the journal at path and collects the replayed records.
+func openJournal(t *testing.T, path string) (*Journal, []journalRecord) {
+	var replayed []journalRecord
+	journal, err := OpenJournal(path, func(record json.RawMessage) error {
+		var r journalRecord
+		if err := json.Unmarshal(record, &r); err != nil {
+			return err
+		}
+		replayed = append(replayed, r)
+		return nil
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { journal.Close() })
+	return journal, replayed
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestJournal_ReplaysAppendedRecords(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	journal, replayed := openJournal(t, path)
+	assert.Empty(t, replayed)
+
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-001", Balance: 900}))
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-001", Balance: 800}))
+	assert.Equal(t, 2, journal.Len())
+
+	reopened, replayed := openJournal(t, path)
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 900}, {ID: "cust-001", Balance: 800}}, replayed)
+	assert.Equal(t, 2, reopened.Len())
+}
+
+func TestJournal_DropsTornLastRecord(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	content := "{\"id\":\"cust-001\",\"balance\":900}\n{\"id\":\"cust-0"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	journal, replayed := openJournal(t, path)
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 900}}, replayed)
+
+	// The next record starts on a line of its own.
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-002", Balance: 100}))
+	_, replayed = openJournal(t, path)
+	assert.Equal(t, []journalRecord{{ID: "cust-001", Balance: 900}, {ID: "cust-002", Balance: 100}}, replayed)
+}
+
+func TestJournal_Reset(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	journal, _ := openJournal(t, path)
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-001", Balance: 900}))
+
+	require.NoError(t, journal.Reset())
+	require.NoError(t, journal.Append(journalRecord{ID: "cust-002", Balance: 100}))
+
+	_, replayed := openJournal(t, path)
+	assert.Equal(t, []journalRecord{{ID: "cust-002", Balance: 100}}, replayed)
+}
+
+func TestReplayJournal_OnlyReads(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	content := "{\"id\":\"cust-001\",\"balance\":900}\n{\"id\":\"cust-0"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	var replayed []string
+	err := ReplayJournal(path, func(record json.RawMessage) error {
+		replayed = append(replayed, string(record))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"{\"id\":\"cust-001\",\"balance\":900}"}, replayed)
+	// The torn record may still be being written by the owner of the journal.
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(written))
+}
+
+func TestReplayJournal_MissingJournal(t *testing.T) {
+	err := ReplayJournal(filepath.Join(t.TempDir(), "customers.json.journal"), func(json.RawMessage) error {
+		t.Fatal("no record expected")
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
+
+// ========== ERROR CASES ==========
+
+func TestOpenJournal_InvalidRecord(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	content := "{\"id\":\"cust-001\",\"balance\":900}\nnot json\n{\"id\":\"cust-002\",\"balance\":100}\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	journal, err := OpenJournal(path, func(json.RawMessage) error { return nil })
+
+	require.EqualError(t, err, "invalid journal record 2")
+	assert.Nil(t, journal)
+}
+
+func TestOpenJournal_ApplyError(t *testing.T) {
+	path := JournalPath(filepath.Join(t.TempDir(), "customers.json"))
+	require.NoError(t, os.WriteFile(path, []byte("{\"id\":\"cust-404\"}\n"), 0644))
+
+	journal, err := OpenJournal(path, func(json.RawMessage) error { return os.ErrNotExist })
+
+	require.EqualError(t, err, "error while replaying journal record 1: file does not exist")
+	assert.Nil(t, journal)
+}
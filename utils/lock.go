@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockFileName is the file in a locked directory that holds the lock and the
+// PID of the process holding it.
+const LockFileName = ".lock"
+
+var (
+	// ErrDirLocked is returned by LockDir when another process holds the lock.
+	ErrDirLocked = errors.New("directory is locked by another process")
+	// ErrReadOnly is returned by updates to data opened read-only, which
+	// runs without the lock.
+	ErrReadOnly = errors.New("data is read-only")
+)
+
+// DirLock is an advisory lock on a directory. It only keeps out processes
+// that take the same lock; it does not stop anything from opening the files.
+type DirLock struct {
+	file *os.File
+}
+
+// LockDir takes an exclusive advisory lock on dir. It fails at once, with an
+// error wrapping ErrDirLocked and naming the holder's PID, when another
+// process already holds the lock. The lock is released by Unlock or when the
+// process exits.
+func LockDir(dir string) (*DirLock, error) {
+	path := filepath.Join(dir, LockFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrDirLocked) {
+			return nil, fmt.Errorf("%s: %w%s", dir, ErrDirLocked, lockHolder(path))
+		}
+		return nil, err
+	}
+
+	// Record who holds the lock for the error another process gets.
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &DirLock{file: file}, nil
+}
+
+// lockHolder describes the process recorded in the lock file at path, if any.
+func lockHolder(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	pid := strings.TrimSpace(string(content))
+	if pid == "" {
+		return ""
+	}
+	return " (pid " + pid + ")"
+}
+
+// Unlock releases the lock.
+func (l *DirLock) Unlock() error {
+	return l.file.Close()
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package utils
+
+import "os"
+
+// lockFile does nothing where flock is not available; the directory is not
+// protected from a second process there.
+func lockFile(file *os.File) error {
+	return nil
+}
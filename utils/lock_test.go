@@ -0,0 +1,65 @@
+//go:build unix
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ========== SUCCESS CASES ==========
+
+func TestLockDir_Success(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := LockDir(dir)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	content, err := os.ReadFile(filepath.Join(dir, LockFileName))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(content))
+}
+
+func TestLockDir_AfterUnlock(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockDir(dir)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+
+	lock, err = LockDir(dir)
+
+	require.NoError(t, err)
+	lock.Unlock()
+}
+
+// ========== ERROR CASES ==========
+
+func TestLockDir_AlreadyLocked(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockDir(dir)
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	// flock locks belong to the open file, so a second open conflicts even
+	// within one process, just as another process would.
+	second, err := LockDir(dir)
+
+	require.ErrorIs(t, err, ErrDirLocked)
+	assert.EqualError(t, err, fmt.Sprintf("%s: directory is locked by another process (pid %d)", dir, os.Getpid()))
+	assert.Nil(t, second)
+}
+
+func TestLockDir_MissingDirectory(t *testing.T) {
+	lock, err := LockDir(filepath.Join(t.TempDir(), "missing"))
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrDirLocked)
+	assert.Nil(t, lock)
+}
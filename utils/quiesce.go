@@ -0,0 +1,24 @@
+package utils
+
+import "sync"
+
+// writes is held for reading by every operation that writes data and for
+// writing by Quiesce, so Quiesce sees the data between two operations.
+var writes sync.RWMutex
+
+// BeginWrite marks the start of an operation that writes data, waiting while
+// Quiesce runs. The returned function marks its end. Operations must not
+// nest: a second BeginWrite waits behind a pending Quiesce that waits for the
+// first.
+func BeginWrite() (end func()) {
+	writes.RLock()
+	return writes.RUnlock
+}
+
+// Quiesce runs fn once every operation started with BeginWrite has ended,
+// keeping new ones from starting until fn returns.
+func Quiesce(fn func() error) error {
+	writes.Lock()
+	defer writes.Unlock()
+	return fn()
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// migration is one change to the records of a data file. Migrations of a file
+// are applied in version order to records saved at an older version.
+type migration struct {
+	version     int
+	description string
+	// migrate changes one record in place; nil when the version changes
+	// nothing in the records. It must leave a record that is already in the
+	// new form unchanged, because journal records, which carry no version,
+	// go through every migration when they are replayed.
+	migrate func(record map[string]interface{}) error
+}
+
+// versionedFile is the envelope every data file is saved in. Files written
+// before versioning are plain JSON and read as version 0.
+type versionedFile struct {
+	Version int         `json:"version"`
+	Data    interface{} `json:"data"`
+}
+
+// versionedFileHeader reads a versionedFile, telling a missing version apart
+// from version 0.
+type versionedFileHeader struct {
+	Version *int            `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// envelopeMigration is the first version of every data file: the records as
+// they were before versioning, now inside the version envelope.
+var envelopeMigration = migration{version: 1, description: "wrap the records in the version envelope"}
+
+// RecordChange is one field of one record changed by migrating a data file.
+type RecordChange struct {
+	Record string
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// MigrationPlan reports how a data file is migrated to the version this build
+// saves it at.
+type MigrationPlan struct {
+	Path        string
+	FromVersion int
+	ToVersion   int
+	Migrations  []string
+	Changes     []RecordChange
+}
+
+// fileMigrations returns the migrations of the data file at path, named by its
+// file name. Files without migrations of their own only have the envelope.
+func fileMigrations(path string) []migration {
+	if list, ok := jsonMigrations[filepath.Base(path)]; ok {
+		return list
+	}
+	return []migration{envelopeMigration}
+}
+
+// MigratedFiles returns the names of the data files that have migrations of
+// their own, in name order.
+func MigratedFiles() []string {
+	names := make([]string, 0, len(jsonMigrations))
+	for name := range jsonMigrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaVersion returns the version the data file at path is saved at.
+func schemaVersion(path string) int {
+	list := fileMigrations(path)
+	return list[len(list)-1].version
+}
+
+// unwrapVersion returns the records in data, a data file either in the version
+// envelope or from before versioning, and the version they were saved at.
+func unwrapVersion(data []byte) (json.RawMessage, int) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var file versionedFileHeader
+		if err := json.Unmarshal(trimmed, &file); err == nil && file.Version != nil && file.Data != nil {
+			return file.Data, *file.Version
+		}
+	}
+	return trimmed, 0
+}
+
+// pendingMigrations returns the migrations of the data file at path after
+// version, and the version they bring it to.
+func pendingMigrations(path string, version int) ([]migration, int, error) {
+	list := fileMigrations(path)
+	latest := list[len(list)-1].version
+	if version > latest {
+		return nil, 0, fmt.Errorf("%s is at version %d, newer than version %d this build supports", filepath.Base(path), version, latest)
+	}
+
+	var pending []migration
+	for _, m := range list {
+		if m.version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, latest, nil
+}
+
+// changesRecords tells whether any of migrations changes records.
+func changesRecords(migrations []migration) bool {
+	for _, m := range migrations {
+		if m.migrate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// upgrade migrates the records of the data file at path from version to the
+// version this build saves it at.
+func upgrade(path string, version int, records json.RawMessage) (json.RawMessage, error) {
+	pending, _, err := pendingMigrations(path, version)
+	if err != nil {
+		return nil, err
+	}
+	if !changesRecords(pending) {
+		return records, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(records, &value); err != nil {
+		return nil, err
+	}
+	if err := applyMigrations(pending, value); err != nil {
+		return nil, fmt.Errorf("error while migrating %s: %v", filepath.Base(path), err)
+	}
+	return json.Marshal(value)
+}
+
+// applyMigrations runs migrations on every record of data, the objects of an
+// array or data itself when it is one object.
+func applyMigrations(migrations []migration, data interface{}) error {
+	for _, m := range migrations {
+		if m.migrate == nil {
+			continue
+		}
+		switch value := data.(type) {
+		case []interface{}:
+			for i, element := range value {
+				record, ok := element.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("migration %d: record %d is not an object", m.version, i)
+				}
+				if err := m.migrate(record); err != nil {
+					return fmt.Errorf("migration %d: record %d: %v", m.version, i, err)
+				}
+			}
+		case map[string]interface{}:
+			if err := m.migrate(value); err != nil {
+				return fmt.Errorf("migration %d: %v", m.version, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateJournalRecord brings one journal record of the data file at path to
+// the current version by running every migration on it.
+func migrateJournalRecord(path string, record json.RawMessage) (json.RawMessage, error) {
+	list := fileMigrations(path)
+	if !changesRecords(list) {
+		return record, nil
+	}
+
+	var value map[string]interface{}
+	if err := json.Unmarshal(record, &value); err != nil {
+		return nil, err
+	}
+	if err := applyMigrations(list, value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// diffRecords lists the fields that differ between the records before and
+// after migrating. Records are named by their id, or by their position.
+func diffRecords(before interface{}, after interface{}) []RecordChange {
+	var changes []RecordChange
+	switch b := before.(type) {
+	case []interface{}:
+		a := after.([]interface{})
+		for i := range b {
+			before, _ := b[i].(map[string]interface{})
+			after, _ := a[i].(map[string]interface{})
+			name := fmt.Sprintf("#%d", i)
+			if id, ok := before["id"].(string); ok {
+				name = id
+			}
+			changes = append(changes, diffFields(name, before, after)...)
+		}
+	case map[string]interface{}:
+		changes = diffFields("", b, after.(map[string]interface{}))
+	}
+	return changes
+}
+
+func diffFields(record string, before map[string]interface{}, after map[string]interface{}) []RecordChange {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	var changes []RecordChange
+	for _, field := range names {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, RecordChange{Record: record, Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	return changes
+}
+
+// PlanMigration reports what migrating the data file at path would change,
+// without writing it.
+func PlanMigration(path string) (MigrationPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	data, err = decrypt(path, data)
+	if err != nil {
+		return MigrationPlan{}, fmt.Errorf("error while decrypting %s: %w", path, err)
+	}
+	records, version := unwrapVersion(data)
+
+	pending, latest, err := pendingMigrations(path, version)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	plan := MigrationPlan{Path: path, FromVersion: version, ToVersion: latest}
+	for _, m := range pending {
+		plan.Migrations = append(plan.Migrations, fmt.Sprintf("%d: %s", m.version, m.description))
+	}
+	if !changesRecords(pending) {
+		return plan, nil
+	}
+
+	var before, after interface{}
+	if err := json.Unmarshal(records, &before); err != nil {
+		return MigrationPlan{}, err
+	}
+	if err := json.Unmarshal(records, &after); err != nil {
+		return MigrationPlan{}, err
+	}
+	if err := applyMigrations(pending, after); err != nil {
+		return MigrationPlan{}, fmt.Errorf("error while migrating %s: %v", filepath.Base(path), err)
+	}
+	plan.Changes = diffRecords(before, after)
+	return plan, nil
+}
+
+// MigrateFile saves the data file at path at the current version. Its journal
+// is left as it is: journal records are migrated whenever they are replayed.
+// Nothing else may write the file meanwhile.
+func MigrateFile(path string) error {
+	var data json.RawMessage
+	if err := LoadJSONFile(path, &data); err != nil {
+		return err
+	}
+	return SaveJSONFile(path, data)
+}
@@ -0,0 +1,20 @@
+package utils
+
+// jsonMigrations lists the migrations of each data file by file name, like the
+// SQLite migrations in database/migrations.go. Version 1 of every file is
+// envelopeMigration. Append new migrations with the next version; never edit
+// one that has been released.
+var jsonMigrations = map[string][]migration{
+	// Files from before versioning may lack tier, wallets and points, which
+	// were added later and read as their zero value when missing.
+	"customers.json": {
+		envelopeMigration,
+	},
+	// Files from before versioning may lack category, currency, wallets and
+	// api_key_hash, which were added later and read as their zero value when
+	// missing.
+	"merchants.json": {
+		envelopeMigration,
+	},
+}
+
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renameFullName is a migration as a later model change would add one: the
+// full_name field of older records becomes name.
+var renameFullName = migration{
+	version:     2,
+	description: "rename full_name to name",
+	migrate: func(record map[string]interface{}) error {
+		if fullName, ok := record["full_name"]; ok {
+			record["name"] = fullName
+			delete(record, "full_name")
+		}
+		return nil
+	},
+}
+
+type migratedRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// fixtureFile is the name of the data file the tests register migrations for,
+// so they never depend on the migrations of a real data file.
+const fixtureFile = "fixture.json"
+
+// setupMigrations registers migrations for fixtureFile until the test ends.
+func setupMigrations(t *testing.T, migrations ...migration) {
+	jsonMigrations[fixtureFile] = migrations
+	t.Cleanup(func() {
+		delete(jsonMigrations, fixtureFile)
+	})
+}
+
+// writeDataFile writes content as fixtureFile in a fresh directory.
+func writeDataFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), fixtureFile)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestLoadJSONFile_MigratesOlderVersions(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+	files := map[string]string{
+		"before versioning": `[{"id": "cust-001", "full_name": "John Doe"}]`,
+		"version 1":         `{"version": 1, "data": [{"id": "cust-001", "full_name": "John Doe"}]}`,
+		"version 2":         `{"version": 2, "data": [{"id": "cust-001", "name": "John Doe"}]}`,
+	}
+	for name, content := range files {
+		path := writeDataFile(t, content)
+
+		var records []migratedRecord
+		require.NoError(t, LoadJSONFile(path, &records), name)
+
+		assert.Equal(t, []migratedRecord{{ID: "cust-001", Name: "John Doe"}}, records, name)
+	}
+}
+
+func TestMigrateFile_SavesCurrentVersion(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+	path := writeDataFile(t, `[{"id": "cust-001", "full_name": "John Doe"}]`)
+
+	require.NoError(t, MigrateFile(path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": 2, "data": [{"id": "cust-001", "name": "John Doe"}]}`, string(content))
+}
+
+func TestPlanMigration_DryRun(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+	content := `[{"id": "cust-001", "full_name": "John Doe"}, {"id": "cust-002", "name": "Jane Smith"}]`
+	path := writeDataFile(t, content)
+
+	plan, err := PlanMigration(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, MigrationPlan{
+		Path:        path,
+		FromVersion: 0,
+		ToVersion:   2,
+		Migrations:  []string{"1: wrap the records in the version envelope", "2: rename full_name to name"},
+		Changes: []RecordChange{
+			{Record: "cust-001", Field: "full_name", Before: "John Doe", After: nil},
+			{Record: "cust-001", Field: "name", Before: nil, After: "John Doe"},
+		},
+	}, plan)
+	unchanged, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(unchanged))
+}
+
+func TestPlanMigration_UpToDate(t *testing.T) {
+	path := writeDataFile(t, `{"version": 1, "data": []}`)
+
+	plan, err := PlanMigration(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, MigrationPlan{Path: path, FromVersion: 1, ToVersion: 1}, plan)
+}
+
+func TestReplayJournal_MigratesRecords(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+	path := writeDataFile(t, `{"version": 1, "data": []}`)
+	journal := "{\"id\":\"cust-001\",\"full_name\":\"John Doe\"}\n{\"id\":\"cust-001\",\"name\":\"Johnny\"}\n"
+	require.NoError(t, os.WriteFile(JournalPath(path), []byte(journal), 0644))
+
+	var replayed []migratedRecord
+	err := ReplayJournal(JournalPath(path), func(record json.RawMessage) error {
+		var r migratedRecord
+		if err := json.Unmarshal(record, &r); err != nil {
+			return err
+		}
+		replayed = append(replayed, r)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []migratedRecord{{ID: "cust-001", Name: "John Doe"}, {ID: "cust-001", Name: "Johnny"}}, replayed)
+}
+
+func TestMigratedFiles_ListsRegisteredFiles(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+
+	assert.Equal(t, []string{"customers.json", fixtureFile, "merchants.json"}, MigratedFiles())
+}
+
+func TestLoadJSONFile_UnregisteredFileOnlyHasEnvelope(t *testing.T) {
+	setupMigrations(t, envelopeMigration, renameFullName)
+	path := filepath.Join(t.TempDir(), "other.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "cust-001", "full_name": "John Doe"}]`), 0644))
+
+	var records []map[string]interface{}
+	require.NoError(t, LoadJSONFile(path, &records))
+	plan, err := PlanMigration(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"id": "cust-001", "full_name": "John Doe"}}, records)
+	assert.Equal(t, 1, plan.ToVersion)
+}
+
+// ========== ERROR CASES ==========
+
+func TestLoadJSONFile_NewerVersion(t *testing.T) {
+	path := writeDataFile(t, `{"version": 3, "data": []}`)
+
+	var records []migratedRecord
+	err := LoadJSONFile(path, &records)
+
+	assert.EqualError(t, err, "fixture.json is at version 3, newer than version 1 this build supports")
+}
+
+func TestLoadJSONFile_FailingMigration(t *testing.T) {
+	setupMigrations(t, envelopeMigration, migration{
+		version:     2,
+		description: "broken",
+		migrate: func(record map[string]interface{}) error {
+			return errors.New("missing id")
+		},
+	})
+	path := writeDataFile(t, `[{"name": "John Doe"}]`)
+
+	var records []migratedRecord
+	err := LoadJSONFile(path, &records)
+
+	assert.EqualError(t, err, "error while migrating fixture.json: migration 2: record 0: missing id")
+}
@@ -2,10 +2,16 @@ package utils
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -36,24 +42,109 @@ func MarshalJSON(t *testing.T, v interface{}) string {
 	return string(bytes)
 }
 
+// LoadJSONFile reads the JSON file at filePath into target, decrypting it
+// first when it was saved encrypted (see SetEncryption). Records saved at an
+// older version are migrated to the current one (see jsonMigrations).
 func LoadJSONFile(filePath string, target interface{}) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, target)
+	data, err = decrypt(filePath, data)
+	if err != nil {
+		return fmt.Errorf("error while decrypting %s: %w", filePath, err)
+	}
+	records, version := unwrapVersion(data)
+	records, err = upgrade(filePath, version, records)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(records, target)
 }
 
+// SaveJSONFile writes data to filePath as indented JSON inside the version
+// envelope, encrypted when filePath is set up for it (see SetEncryption). The
+// file is replaced atomically, so a crash or full disk mid-write leaves the
+// previous content intact instead of a truncated file.
 func SaveJSONFile(filePath string, data interface{}) error {
-	file, err := os.Create(filePath)
+	keyring := encryptionKeyring(filePath)
+	file := versionedFile{Version: schemaVersion(filePath), Data: data}
+	return WriteFileAtomic(filePath, func(w io.Writer) error {
+		var content bytes.Buffer
+		encoder := json.NewEncoder(&content)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file); err != nil {
+			return err
+		}
+		if keyring == nil {
+			_, err := w.Write(content.Bytes())
+			return err
+		}
+
+		encrypted, err := encrypt(keyring, filePath, content.Bytes())
+		if err != nil {
+			return err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, encrypted, "", "  "); err != nil {
+			return err
+		}
+		indented.WriteByte('\n')
+		_, err = w.Write(indented.Bytes())
+		return err
+	})
+}
+
+// WriteFileAtomic writes to a temporary file next to filePath, fsyncs it and
+// renames it over filePath, then fsyncs the directory so the rename itself
+// survives a crash. The temporary file is removed when any step fails.
+func WriteFileAtomic(filePath string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(filePath)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	tempPath := file.Name()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	// CreateTemp makes the file private; keep the mode of the file it replaces.
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	if err = file.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err = write(file); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tempPath, filePath); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(data)
+// syncDir fsyncs a directory, making renames and new entries in it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
 // extractTokenFromHeader extracts the token from the Authorization header
@@ -75,3 +166,18 @@ func ExtractTokenFromHeader(c *gin.Context) (string, error) {
 	// Return the token part
 	return tokenParts[1], nil
 }
+
+// HashAPIKey returns the SHA-256 hex digest under which an API key is stored.
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken returns a random, URL-safe token of n bytes of entropy.
+func GenerateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
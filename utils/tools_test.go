@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const previousContent = "[\n  {\n    \"id\": \"cust-001\",\n    \"balance\": 1000\n  }\n]\n"
+
+// setupFile writes previousContent to a file in a fresh directory.
+func setupFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "customers.json")
+	require.NoError(t, os.WriteFile(path, []byte(previousContent), 0640))
+	return path
+}
+
+// assertNoTempFiles checks that no temporary file was left next to path.
+func assertNoTempFiles(t *testing.T, path string) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Base(path), entries[0].Name())
+}
+
+// ========== SUCCESS CASES ==========
+
+func TestSaveJSONFile_Success(t *testing.T) {
+	path := setupFile(t)
+
+	err := SaveJSONFile(path, []map[string]interface{}{{"id": "cust-001", "balance": 750}})
+	require.NoError(t, err)
+
+	var saved []map[string]interface{}
+	require.NoError(t, LoadJSONFile(path, &saved))
+	assert.Equal(t, 750.0, saved[0]["balance"])
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	assertNoTempFiles(t, path)
+}
+
+func TestSaveJSONFile_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disputes.json")
+
+	err := SaveJSONFile(path, []string{})
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"version\": 1,\n  \"data\": []\n}\n", string(content))
+	assertNoTempFiles(t, path)
+}
+
+// ========== ERROR CASES ==========
+
+func TestWriteFileAtomic_FailingWriterKeepsPreviousContent(t *testing.T) {
+	path := setupFile(t)
+
+	// The writer gets part of the new content out before the disk fills up.
+	err := WriteFileAtomic(path, func(w io.Writer) error {
+		if _, err := w.Write([]byte("[\n  {\n    \"id\": \"cu")); err != nil {
+			return err
+		}
+		return errors.New("no space left on device")
+	})
+
+	require.EqualError(t, err, "no space left on device")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, previousContent, string(content))
+	assertNoTempFiles(t, path)
+}
+
+func TestSaveJSONFile_EncodeErrorKeepsPreviousContent(t *testing.T) {
+	path := setupFile(t)
+
+	err := SaveJSONFile(path, map[string]interface{}{"balance": make(chan int)})
+
+	require.Error(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, previousContent, string(content))
+	assertNoTempFiles(t, path)
+}
+
+func TestSaveJSONFile_MissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "customers.json")
+
+	err := SaveJSONFile(path, []string{})
+
+	require.Error(t, err)
+	assert.NoFileExists(t, path)
+}